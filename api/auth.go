@@ -0,0 +1,531 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const (
+	// The name of the session cookie
+	sessionCookieName = "oc_session"
+
+	// How long a session lasts without being seen. The expiry slides forward as the session is used
+	sessionExpiration = 7 * 24 * time.Hour
+
+	// How often the last seen time (and expiry) of a session is updated, to avoid a write on
+	// every request
+	sessionTouchInterval = time.Minute
+
+	// The fiber locals key holding the logged in user
+	localsUserKey = "user"
+
+	// The fiber locals key holding the session of the request, when authenticated by a session
+	localsSessionKey = "session"
+
+	// The fiber locals key holding the API token of the request, when authenticated by a token
+	localsTokenKey = "token"
+
+	// A bcrypt hash of a random password, at the default cost. A login for an unknown username is
+	// compared against it, so it takes as long as a login for a known username
+	dummyPasswordHash = "$2a$10$SkTmM3.WIvajswqfJJYzsu86MVuUjxxzt7inC7BvamHSk6d8BY4SC"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type authAPI struct {
+	logger *slog.Logger
+	dao    *dao.DAO
+	router *Router
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initAuthRoutes initializes the auth routes. These routes are registered before the auth
+// middleware so they are always reachable
+func (r *Router) initAuthRoutes() {
+	authAPI := authAPI{
+		logger: r.config.Logger,
+		dao:    r.dao,
+		router: r,
+	}
+
+	authGroup := r.api.Group("/auth")
+	authGroup.Get("/status", authAPI.status)
+	authGroup.Post("/bootstrap", authAPI.bootstrap)
+	authGroup.Post("/login", authAPI.login)
+	authGroup.Post("/logout", authAPI.logout)
+	authGroup.Get("/oidc/login", authAPI.oidcLogin)
+	authGroup.Get("/oidc/callback", authAPI.oidcCallback)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// status returns whether an admin exists and the logged in user (if any). The UI uses this to
+// decide between the first-run, login and app views. A user authenticated by the proxy auth header
+// is returned without needing to log in
+func (api *authAPI) status(c *fiber.Ctx) error {
+	// Looked up first, as a proxy user may be created as the first admin
+	user, err := api.router.proxyUser(c)
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up proxy user", err)
+	}
+
+	hasAdmin, err := api.router.adminExists(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up auth status", err)
+	}
+
+	if user == nil {
+		user, _, err = api.router.sessionUser(c)
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error looking up session", err)
+		}
+	}
+
+	resp := &authStatusResponse{HasAdmin: hasAdmin, OIDC: api.router.config.OIDC != nil}
+	if user != nil && !user.Disabled {
+		resp.User = userResponseHelper([]*models.User{user})[0]
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// bootstrap creates the first admin and logs them in. It is only available while no admin exists
+func (api *authAPI) bootstrap(c *fiber.Ctx) error {
+	req := &authRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	hasAdmin, err := api.router.adminExists(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up auth status", err)
+	}
+
+	if hasAdmin {
+		return errorResponse(c, fiber.StatusForbidden, "An admin already exists", nil)
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		return errorResponse(c, fiber.StatusBadRequest, "A username is required", nil)
+	}
+
+	hash, err := security.HashPassword(req.Password)
+	if err != nil {
+		return passwordErrorResponse(c, err)
+	}
+
+	user := &models.User{Username: req.Username, PasswordHash: hash}
+	if err := api.dao.BootstrapAdmin(c.Context(), user); err != nil {
+		if errors.Is(err, dao.ErrAdminExists) {
+			return errorResponse(c, fiber.StatusForbidden, "An admin already exists", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error creating admin", err)
+	}
+
+	api.router.hasAdmin.Store(true)
+
+	if err := api.router.startSession(c, user); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error creating session", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(userResponseHelper([]*models.User{user})[0])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// login validates the username and password and starts a session. The same error is returned
+// for an unknown user and a wrong password. A disabled user cannot log in
+//
+// Failed logins are throttled per username and per IP address (see `LoginThrottleConfig`). While
+// either must wait, the login is rejected without checking the password
+func (api *authAPI) login(c *fiber.Ctx) error {
+	req := &authRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	if strings.TrimSpace(req.Username) == "" || req.Password == "" {
+		return errorResponse(c, fiber.StatusBadRequest, "A username and password are required", nil)
+	}
+
+	if wait := api.router.loginWait(req.Username, clientIP(c)); wait > 0 {
+		return api.router.loginThrottled(c, req.Username, wait)
+	}
+
+	user := &models.User{Username: req.Username}
+	if err := api.dao.GetUserByUsername(c.Context(), user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			security.ComparePassword(dummyPasswordHash, req.Password)
+			api.router.loginFailed(c, req.Username, "unknown user")
+			return errorResponse(c, fiber.StatusUnauthorized, "Invalid username or password", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up user", err)
+	}
+
+	// Compared before the disabled check, so a disabled user also pays the bcrypt cost
+	if !security.ComparePassword(user.PasswordHash, req.Password) {
+		api.router.loginFailed(c, req.Username, "wrong password")
+		return errorResponse(c, fiber.StatusUnauthorized, "Invalid username or password", nil)
+	}
+
+	if user.Disabled {
+		return errorResponse(c, fiber.StatusForbidden, "Account is disabled", nil)
+	}
+
+	// Each targeted username is still limited by its own failures, so a successful login may clear
+	// the failures of a shared IP address
+	api.router.usernameThrottle.Reset(usernameThrottleKey(req.Username))
+	api.router.ipThrottle.Reset(clientIP(c))
+
+	if err := api.router.startSession(c, user); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error creating session", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(userResponseHelper([]*models.User{user})[0])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// logout deletes the current session, if any
+func (api *authAPI) logout(c *fiber.Ctx) error {
+	if token := c.Cookies(sessionCookieName); token != "" {
+		options := &database.Options{
+			Where: squirrel.Eq{models.SESSION_TABLE + "." + models.SESSION_TOKEN_HASH: security.HashToken(token)},
+		}
+
+		if err := api.dao.Delete(c.Context(), &models.Session{}, options); err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error deleting session", err)
+		}
+	}
+
+	clearSessionCookie(c)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Router helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// adminExists returns whether an admin has been created. Once an admin exists it is cached, as
+// the app never returns to the first-run state
+func (r *Router) adminExists(ctx context.Context) (bool, error) {
+	if r.hasAdmin.Load() {
+		return true, nil
+	}
+
+	hasAdmin, err := r.dao.GetHasAdmin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if hasAdmin {
+		r.hasAdmin.Store(true)
+	}
+
+	return hasAdmin, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// provisionUser creates a user authenticated by an external source, such as the proxy auth header
+// or an OpenID Connect provider. The user is given a random password, as they do not log in with
+// one. When no admin exists yet, an admin is created as the first admin (see `dao.BootstrapAdmin`)
+func (r *Router) provisionUser(ctx context.Context, user *models.User) error {
+	hash, err := security.HashPassword(security.RandomString(32))
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+
+	if user.Role == types.UserRoleAdmin {
+		hasAdmin, err := r.adminExists(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !hasAdmin {
+			err := r.dao.BootstrapAdmin(ctx, user)
+			if err == nil {
+				r.hasAdmin.Store(true)
+				return nil
+			}
+
+			if !errors.Is(err, dao.ErrAdminExists) {
+				return err
+			}
+		}
+	}
+
+	return r.dao.CreateUser(ctx, user)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// syncRole updates the role of a user to the role given by an external source. The last admin
+// keeps their role, so the app cannot be left without one. A disabled user is left as is
+func (r *Router) syncRole(ctx context.Context, user *models.User, role types.UserRole) error {
+	if user.Role == role || user.Disabled {
+		return nil
+	}
+
+	previousRole := user.Role
+	user.Role = role
+
+	if err := r.dao.UpdateUser(ctx, user); err != nil {
+		user.Role = previousRole
+
+		if !errors.Is(err, dao.ErrLastAdmin) {
+			return err
+		}
+
+		// Logged at debug level, as the role is synced on every request of a proxied user
+		r.config.Logger.Debug(
+			"Not demoting the last admin",
+			slog.Any("type", types.LogTypeRequest),
+			slog.String("username", user.Username),
+		)
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// startSession starts a new session for the user and sets the session cookie. The device that
+// logged in is recorded. Any session the request already had is deleted, so a new token is always
+// issued to prevent session fixation
+func (r *Router) startSession(c *fiber.Ctx, user *models.User) error {
+	if token := c.Cookies(sessionCookieName); token != "" {
+		options := &database.Options{
+			Where: squirrel.Eq{models.SESSION_TABLE + "." + models.SESSION_TOKEN_HASH: security.HashToken(token)},
+		}
+
+		if err := r.dao.Delete(c.Context(), &models.Session{}, options); err != nil {
+			return err
+		}
+	}
+
+	expiresAt, err := types.ParseDateTime(time.Now().Add(sessionExpiration))
+	if err != nil {
+		return err
+	}
+
+	token := security.RandomString(64)
+
+	session := &models.Session{
+		UserID:     user.ID,
+		TokenHash:  security.HashToken(token),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		IPAddress:  clientIP(c),
+		ExpiresAt:  expiresAt,
+		LastSeenAt: types.NowDateTime(),
+	}
+
+	if err := r.dao.CreateSession(c.Context(), session); err != nil {
+		return err
+	}
+
+	setSessionCookie(c, token, session.ExpiresAt.Time())
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// sessionUser returns the user and session of the session cookie. The user is looked up on every
+// request so a deleted or disabled user is logged out immediately. Nil is returned when there is no
+// logged in user
+//
+// The expiry of the session slides forward as it is used. To avoid a write on every request, the
+// session is only touched when it was last seen more than `sessionTouchInterval` ago
+func (r *Router) sessionUser(c *fiber.Ctx) (*models.User, *models.Session, error) {
+	token := c.Cookies(sessionCookieName)
+	if token == "" {
+		return nil, nil, nil
+	}
+
+	session := &models.Session{TokenHash: security.HashToken(token)}
+	if err := r.dao.GetSessionByHash(c.Context(), session); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			clearSessionCookie(c)
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	if session.IsExpired() {
+		clearSessionCookie(c)
+		return nil, nil, r.dao.Delete(c.Context(), session, nil)
+	}
+
+	user := &models.User{Base: models.Base{ID: session.UserID}}
+	if err := r.dao.GetById(c.Context(), user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			clearSessionCookie(c)
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	if user.Disabled {
+		clearSessionCookie(c)
+		return nil, nil, r.dao.Delete(c.Context(), session, nil)
+	}
+
+	if time.Since(session.LastSeenAt.Time()) >= sessionTouchInterval {
+		if err := r.dao.TouchSession(c.Context(), session, sessionExpiration); err != nil {
+			return nil, nil, err
+		}
+
+		setSessionCookie(c, token, session.ExpiresAt.Time())
+	}
+
+	return user, session, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// tokenUser returns the user and API token for a bearer token. Nil is returned when the token is
+// unknown or expired, or the user is disabled. The last used time of the token is recorded
+func (r *Router) tokenUser(c *fiber.Ctx, bearer string) (*models.User, *models.ApiToken, error) {
+	token := &models.ApiToken{TokenHash: security.HashToken(bearer)}
+	if err := r.dao.GetApiTokenByHash(c.Context(), token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	if token.IsExpired() {
+		return nil, nil, nil
+	}
+
+	user := &models.User{Base: models.Base{ID: token.UserID}}
+	if err := r.dao.GetById(c.Context(), user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	if user.Disabled {
+		return nil, nil, nil
+	}
+
+	if err := r.dao.TouchApiToken(c.Context(), token); err != nil {
+		return nil, nil, err
+	}
+
+	return user, token, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// bearerToken returns the token from the `Authorization: Bearer` header. False is returned when the
+// header is not set
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[7:]), true
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// currentUserId returns the ID of the logged in user. An empty string is returned when there is no
+// logged in user, which is the case while no admin exists (first-run). Progress recorded without a
+// user is given to the first admin
+func currentUserId(c *fiber.Ctx) string {
+	if user, ok := c.Locals(localsUserKey).(*models.User); ok {
+		return user.ID
+	}
+
+	return ""
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// clientIP returns the IP address of the client. When it is read from the client IP header of a
+// trusted proxy, fiber reuses its memory once the handler returns, so it is copied
+func clientIP(c *fiber.Ctx) string {
+	return strings.Clone(c.IP())
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// currentUsername returns the username of the logged in user, or an empty string
+func currentUsername(c *fiber.Ctx) string {
+	if user, ok := c.Locals(localsUserKey).(*models.User); ok {
+		return user.Username
+	}
+
+	return ""
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// setSessionCookie sets the HTTP-only session cookie
+func setSessionCookie(c *fiber.Ctx, token string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// clearSessionCookie expires the session cookie
+func clearSessionCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// currentSessionId returns the ID of the session of the request. An empty string is returned when
+// the request is not authenticated by a session
+func currentSessionId(c *fiber.Ctx) string {
+	if session, ok := c.Locals(localsSessionKey).(*models.Session); ok {
+		return session.ID
+	}
+
+	return ""
+}
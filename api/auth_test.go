@@ -0,0 +1,456 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestAuth_Status(t *testing.T) {
+	t.Run("200 (no admin)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/auth/status", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp authStatusResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.False(t, resp.HasAdmin)
+		require.Nil(t, resp.User)
+	})
+
+	t.Run("200 (logged out)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/auth/status", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp authStatusResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.True(t, resp.HasAdmin)
+		require.Nil(t, resp.User)
+	})
+
+	t.Run("200 (logged in)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/status", nil)
+		req.AddCookie(cookie)
+
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp authStatusResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.True(t, resp.HasAdmin)
+		require.NotNil(t, resp.User)
+		require.Equal(t, "admin", resp.User.Username)
+		require.Equal(t, types.UserRoleAdmin, resp.User.Role)
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.PARAM_TABLE)
+		require.NoError(t, err)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/auth/status", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestAuth_Bootstrap(t *testing.T) {
+	t.Run("201 (created)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		resp := authRequestHelper(t, router, "/api/auth/bootstrap", `{"username": " admin ", "password": "password"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var userResp userResponse
+		body, _ := io.ReadAll(resp.Body)
+		require.NoError(t, json.Unmarshal(body, &userResp))
+		require.Equal(t, "admin", userResp.Username)
+		require.Equal(t, types.UserRoleAdmin, userResp.Role)
+
+		// Logged in
+		cookie := sessionCookieHelper(t, resp)
+		require.True(t, cookie.HttpOnly)
+
+		// The password is hashed
+		user := &models.User{Username: "admin"}
+		require.NoError(t, router.dao.GetUserByUsername(ctx, user))
+		require.NotEqual(t, "password", user.PasswordHash)
+		require.True(t, security.ComparePassword(user.PasswordHash, "password"))
+
+		hasAdmin, err := router.dao.GetHasAdmin(ctx)
+		require.NoError(t, err)
+		require.True(t, hasAdmin)
+
+		// The session is valid
+		req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+		req.AddCookie(cookie)
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("400 (invalid data)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		resp := authRequestHelper(t, router, "/api/auth/bootstrap", `bob`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = authRequestHelper(t, router, "/api/auth/bootstrap", `{"username": " ", "password": "password"}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = authRequestHelper(t, router, "/api/auth/bootstrap", `{"username": "admin", "password": "short"}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = authRequestHelper(t, router, "/api/auth/bootstrap", `{"username": "admin", "password": "`+strings.Repeat("a", 73)+`"}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("403 (admin exists)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		resp := authRequestHelper(t, router, "/api/auth/bootstrap", `{"username": "admin", "password": "password"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		resp = authRequestHelper(t, router, "/api/auth/bootstrap", `{"username": "admin2", "password": "password"}`)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		count, err := router.dao.Count(ctx, &models.User{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestAuth_Login(t *testing.T) {
+	t.Run("200 (logged in)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "admin", "password": "password"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var userResp userResponse
+		body, _ := io.ReadAll(resp.Body)
+		require.NoError(t, json.Unmarshal(body, &userResp))
+		require.Equal(t, "admin", userResp.Username)
+
+		cookie := sessionCookieHelper(t, resp)
+		require.True(t, cookie.HttpOnly)
+		require.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	})
+
+	t.Run("400 (invalid data)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `bob`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = authRequestHelper(t, router, "/api/auth/login", `{"username": "admin"}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("401 (invalid credentials)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "admin", "password": "wrong password"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		require.Empty(t, resp.Cookies())
+
+		resp = authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": "password"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		require.Empty(t, resp.Cookies())
+	})
+
+	t.Run("dummy hash", func(t *testing.T) {
+		// An unknown username is compared against the dummy hash, which must cost as much as a real
+		// password hash
+		cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+		require.NoError(t, err)
+		require.Equal(t, bcrypt.DefaultCost, cost)
+		require.False(t, security.ComparePassword(dummyPasswordHash, "password"))
+	})
+
+	t.Run("new session", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		cookie1 := loginHelper(t, router, "admin", "password")
+		cookie2 := loginHelper(t, router, "admin", "password")
+		require.NotEqual(t, cookie1.Value, cookie2.Value)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestAuth_Logout(t *testing.T) {
+	t.Run("204 (logged out)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil)
+		req.AddCookie(cookie)
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, status)
+
+		// The session is deleted
+		count, err := router.dao.Count(context.Background(), &models.Session{}, nil)
+		require.NoError(t, err)
+		require.Zero(t, count)
+
+		// The session is no longer valid
+		req = httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+		req.AddCookie(cookie)
+
+		status, _, err = requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("204 (no session)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestAuth_Middleware(t *testing.T) {
+	t.Run("200 (no admin)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/tags", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("401 (no session)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		for _, path := range []string{"/api/tags", "/api/courses", "/api/fileSystem", "/api/logs", "/api/settings"} {
+			status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, path, nil))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusUnauthorized, status, path)
+		}
+	})
+
+	t.Run("401 (invalid session)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "invalid"})
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("401 (deleted user)", func(t *testing.T) {
+		router, ctx := setup(t)
+		user := createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		require.NoError(t, router.dao.Delete(ctx, user, nil))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+		req.AddCookie(cookie)
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("200 (logged in)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+		req.AddCookie(cookie)
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("200 (sliding expiry)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		// Age the session so it is due to be touched
+		session := &models.Session{TokenHash: security.HashToken(cookie.Value)}
+		require.NoError(t, router.dao.GetSessionByHash(ctx, session))
+
+		lastSeenAt, err := types.ParseDateTime(time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		expiresAt, err := types.ParseDateTime(time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		session.LastSeenAt = lastSeenAt
+		session.ExpiresAt = expiresAt
+		_, err = router.dao.Update(ctx, session)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+		req.AddCookie(cookie)
+
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// The expiry and cookie slide forward
+		require.NoError(t, router.dao.GetSessionByHash(ctx, session))
+		require.True(t, session.ExpiresAt.Time().After(time.Now().Add(sessionExpiration-time.Minute)))
+		require.True(t, session.LastSeenAt.Time().After(lastSeenAt.Time()))
+
+		refreshed := sessionCookieHelper(t, resp)
+		require.Equal(t, cookie.Value, refreshed.Value)
+		require.True(t, refreshed.Expires.After(time.Now().Add(sessionExpiration-time.Minute)))
+	})
+
+	t.Run("401 (expired session)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		session := &models.Session{TokenHash: security.HashToken(cookie.Value)}
+		require.NoError(t, router.dao.GetSessionByHash(ctx, session))
+
+		expiresAt, err := types.ParseDateTime(time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+
+		session.ExpiresAt = expiresAt
+		_, err = router.dao.Update(ctx, session)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+		req.AddCookie(cookie)
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		// The expired session is deleted
+		require.ErrorIs(t, router.dao.GetById(ctx, session), sql.ErrNoRows)
+	})
+
+	t.Run("ui", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.NoError(t, err)
+		require.NotEqual(t, http.StatusUnauthorized, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// createUserHelper creates a user with a hashed password and marks that an admin exists when the
+// user is an admin
+func createUserHelper(t *testing.T, router *Router, username, password string, role types.UserRole) *models.User {
+	t.Helper()
+
+	hash, err := security.HashPassword(password)
+	require.NoError(t, err)
+
+	user := &models.User{Username: username, PasswordHash: hash, Role: role}
+
+	if role == types.UserRoleAdmin {
+		hasAdmin, err := router.dao.GetHasAdmin(context.Background())
+		require.NoError(t, err)
+
+		if !hasAdmin {
+			require.NoError(t, router.dao.BootstrapAdmin(context.Background(), user))
+			return user
+		}
+	}
+
+	require.NoError(t, router.dao.CreateUser(context.Background(), user))
+	return user
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// loginHelper logs in and returns the session cookie
+func loginHelper(t *testing.T, router *Router, username, password string) *http.Cookie {
+	t.Helper()
+
+	body, err := json.Marshal(&authRequest{Username: username, Password: password})
+	require.NoError(t, err)
+
+	resp := authRequestHelper(t, router, "/api/auth/login", string(body))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	return sessionCookieHelper(t, resp)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func authRequestHelper(t *testing.T, router *Router, path, body string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := router.router.Test(req)
+	require.NoError(t, err)
+
+	return resp
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func sessionCookieHelper(t *testing.T, resp *http.Response) *http.Cookie {
+	t.Helper()
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookieName {
+			return cookie
+		}
+	}
+
+	require.FailNow(t, "session cookie not found")
+	return nil
+}
@@ -2,14 +2,19 @@ package api
 
 import (
 	"log/slog"
+	"net/netip"
 	"net/url"
 	"strings"
+	"sync/atomic"
 
 	"github.com/geerew/off-course/dao"
 	"github.com/geerew/off-course/database"
 	"github.com/geerew/off-course/utils"
 	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/cardimage"
 	"github.com/geerew/off-course/utils/coursescan"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/transcode"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -22,6 +27,13 @@ type Router struct {
 	config *RouterConfig
 	dao    *dao.DAO
 	logDao *dao.DAO
+
+	// Auth
+	hasAdmin atomic.Bool
+
+	// Failed logins, per username and per IP address
+	usernameThrottle *security.Throttle
+	ipThrottle       *security.Throttle
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -32,18 +44,41 @@ type RouterConfig struct {
 	Logger       *slog.Logger
 	AppFs        *appFs.AppFs
 	CourseScan   *coursescan.CourseScan
+	Transcoder   *transcode.Transcoder
+	Cards        *cardimage.Cache
 	Port         string
 	IsProduction bool
+
+	// Trusted reverse-proxy header authentication. Disabled when nil
+	ProxyAuth *ProxyAuthConfig
+
+	// Login through an OpenID Connect provider. Disabled when nil
+	OIDC *OIDCConfig
+
+	// How failed logins are throttled. Defaults to `DefaultLoginThrottleConfig` when nil
+	LoginThrottle *LoginThrottleConfig
+
+	// The reverse proxies in front of the app. The IP address of a client connecting through one of
+	// them is read from ClientIPHeader. Otherwise, the remote address is the client IP address
+	TrustedProxies []netip.Prefix
+	ClientIPHeader string
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // New creates a new router
 func NewRouter(config *RouterConfig) *Router {
+	loginThrottle := config.LoginThrottle
+	if loginThrottle == nil {
+		loginThrottle = DefaultLoginThrottleConfig()
+	}
+
 	r := &Router{
-		config: config,
-		dao:    dao.NewDAO(config.DbManager.DataDb),
-		logDao: dao.NewDAO(config.DbManager.LogsDb),
+		config:           config,
+		dao:              dao.NewDAO(config.DbManager.DataDb),
+		logDao:           dao.NewDAO(config.DbManager.LogsDb),
+		usernameThrottle: security.NewThrottle(loginThrottle.Username),
+		ipThrottle:       security.NewThrottle(loginThrottle.IP),
 	}
 
 	r.initRouter()
@@ -65,7 +100,7 @@ func (router *Router) Serve() error {
 
 // initRouter initializes the router (API and UI)
 func (r *Router) initRouter() {
-	r.router = fiber.New()
+	r.router = fiber.New(r.fiberConfig())
 
 	// Middleware
 	r.router.Use(loggerMiddleware(r.config))
@@ -76,11 +111,46 @@ func (r *Router) initRouter() {
 
 	// API
 	r.api = r.router.Group("/api")
+	r.initAuthRoutes()
+	r.initSharedRoutes()
+
+	// Every route below requires a logged in user once an admin exists
+	r.api.Use(authMiddleware(r))
+	r.applyPermissions(routePermissions)
+
 	r.initFsRoutes()
 	r.initCourseRoutes()
 	r.initScanRoutes()
 	r.initTagRoutes()
 	r.initLogRoutes()
+	r.initSettingsRoutes()
+	r.initSearchRoutes()
+	r.initUserRoutes()
+	r.initLockoutRoutes()
+	r.initShareRoutes()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// fiberConfig builds the fiber config. When there are trusted proxies, `c.IP()` resolves the client
+// IP address from the client IP header of the requests they forward, so the login throttle and the
+// share views see the client rather than the proxy
+func (r *Router) fiberConfig() fiber.Config {
+	config := fiber.Config{}
+
+	if len(r.config.TrustedProxies) == 0 || r.config.ClientIPHeader == "" {
+		return config
+	}
+
+	config.ProxyHeader = r.config.ClientIPHeader
+	config.EnableTrustedProxyCheck = true
+	config.EnableIPValidation = true
+
+	for _, prefix := range r.config.TrustedProxies {
+		config.TrustedProxies = append(config.TrustedProxies, prefix.String())
+	}
+
+	return config
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
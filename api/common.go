@@ -1,14 +1,25 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"mime"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/geerew/off-course/dao"
 	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
 	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/archive"
+	"github.com/geerew/off-course/utils/epub"
+	"github.com/geerew/off-course/utils/preview"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/transcode"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/spf13/afero"
@@ -20,13 +31,14 @@ func courseResponseHelper(courses []*models.Course) []*courseResponse {
 	responses := []*courseResponse{}
 	for _, course := range courses {
 		c := &courseResponse{
-			ID:        course.ID,
-			Title:     course.Title,
-			Path:      course.Path,
-			HasCard:   course.CardPath != "",
-			Available: course.Available,
-			CreatedAt: course.CreatedAt,
-			UpdatedAt: course.UpdatedAt,
+			ID:           course.ID,
+			Title:        course.Title,
+			Path:         course.Path,
+			HasCard:      course.CardPath != "",
+			CardOverride: course.CardOverride,
+			Available:    course.Available,
+			CreatedAt:    course.CreatedAt,
+			UpdatedAt:    course.UpdatedAt,
 
 			// Scan status
 			ScanStatus: course.ScanStatus.String(),
@@ -70,6 +82,8 @@ func assetResponseHelper(assets []*models.Asset) []*assetResponse {
 		progress := &assetProgressResponse{}
 		if asset.Progress != nil {
 			progress.VideoPos = asset.Progress.VideoPos
+			progress.ScrollPos = asset.Progress.ScrollPos
+			progress.SlidePos = asset.Progress.SlidePos
 			progress.Completed = asset.Progress.Completed
 			progress.CompletedAt = asset.Progress.CompletedAt
 		}
@@ -84,9 +98,20 @@ func assetResponseHelper(assets []*models.Asset) []*assetResponse {
 			Type:      asset.Type,
 			CreatedAt: asset.CreatedAt,
 			UpdatedAt: asset.UpdatedAt,
+			URL:       asset.URL,
+
+			// Media
+			Duration:    asset.Duration,
+			Width:       asset.Width,
+			Height:      asset.Height,
+			VideoCodec:  asset.VideoCodec,
+			AudioCodec:  asset.AudioCodec,
+			AudioTracks: asset.AudioTracks,
+			Playback:    assetPlayback(asset),
 
 			Progress:    progress,
 			Attachments: attachmentResponseHelper(asset.Attachments),
+			Subtitles:   subtitleResponseHelper(asset.Subtitles),
 		})
 
 	}
@@ -99,11 +124,18 @@ func assetResponseHelper(assets []*models.Asset) []*assetResponse {
 func attachmentResponseHelper(attachments []*models.Attachment) []*attachmentResponse {
 	responses := []*attachmentResponse{}
 	for _, attachment := range attachments {
+		attachmentType := attachmentTypeFile
+		if attachment.URL != "" {
+			attachmentType = attachmentTypeLink
+		}
+
 		responses = append(responses, &attachmentResponse{
 			ID:        attachment.ID,
 			AssetId:   attachment.AssetID,
 			Title:     attachment.Title,
 			Path:      attachment.Path,
+			Type:      attachmentType,
+			URL:       attachment.URL,
 			CreatedAt: attachment.CreatedAt,
 			UpdatedAt: attachment.UpdatedAt,
 		})
@@ -114,6 +146,76 @@ func attachmentResponseHelper(attachments []*models.Attachment) []*attachmentRes
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+func subtitleResponseHelper(subtitles []*models.Subtitle) []*subtitleResponse {
+	responses := []*subtitleResponse{}
+	for _, subtitle := range subtitles {
+		responses = append(responses, &subtitleResponse{
+			ID:        subtitle.ID,
+			AssetId:   subtitle.AssetID,
+			Language:  subtitle.Language,
+			Format:    subtitle.Format,
+			CreatedAt: subtitle.CreatedAt,
+			UpdatedAt: subtitle.UpdatedAt,
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// chapterMarkerResponseHelper builds the chapter marker responses. A marker ends at the start of the
+// next marker and the last marker ends at the end of the asset (duration in seconds)
+func chapterMarkerResponseHelper(markers []*models.ChapterMarker, duration int) []*chapterMarkerResponse {
+	responses := []*chapterMarkerResponse{}
+	for i, marker := range markers {
+		end := max(duration*1000, marker.Start)
+		if i+1 < len(markers) {
+			end = markers[i+1].Start
+		}
+
+		responses = append(responses, &chapterMarkerResponse{
+			ID:    marker.ID,
+			Title: marker.Title,
+			Start: marker.Start,
+			End:   end,
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// transcriptMatchResponseHelper builds the transcript search responses. The start and end are in
+// milliseconds and the link opens the asset in the UI at the start of the cue (in seconds)
+func transcriptMatchResponseHelper(matches []*dao.TranscriptMatch) []*transcriptMatchResponse {
+	responses := []*transcriptMatchResponse{}
+	for _, match := range matches {
+		link := url.Values{}
+		link.Set("id", match.CourseID)
+		link.Set("a", match.AssetID)
+		link.Set("t", strconv.FormatInt(match.Start/1000, 10))
+
+		responses = append(responses, &transcriptMatchResponse{
+			CourseID:    match.CourseID,
+			CourseTitle: match.CourseTitle,
+			AssetID:     match.AssetID,
+			AssetTitle:  match.AssetTitle,
+			Chapter:     match.Chapter,
+			Language:    match.Language,
+			Start:       match.Start,
+			End:         match.End,
+			Text:        match.Text,
+			Link:        "/course?" + link.Encode(),
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 func scanResponseHelper(scans []*models.Scan) []*scanResponse {
 	responses := []*scanResponse{}
 	for _, scan := range scans {
@@ -184,6 +286,156 @@ func logsResponseHelper(logs []*models.Log) []*logResponse {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+func userResponseHelper(users []*models.User) []*userResponse {
+	responses := []*userResponse{}
+
+	for _, user := range users {
+		responses = append(responses, &userResponse{
+			ID:        user.ID,
+			Username:  user.Username,
+			Role:      user.Role,
+			Disabled:  user.Disabled,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func apiTokenResponseHelper(tokens []*models.ApiToken) []*apiTokenResponse {
+	responses := []*apiTokenResponse{}
+
+	for _, token := range tokens {
+		responses = append(responses, &apiTokenResponse{
+			ID:         token.ID,
+			Name:       token.Name,
+			Scope:      token.Scope,
+			ExpiresAt:  token.ExpiresAt,
+			LastUsedAt: token.LastUsedAt,
+			CreatedAt:  token.CreatedAt,
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// shareResponseHelper builds the share responses, signing the token of each share with the secret
+func shareResponseHelper(shares []*models.Share, secret string) []*shareResponse {
+	responses := []*shareResponse{}
+
+	for _, share := range shares {
+		responses = append(responses, &shareResponse{
+			ID:        share.ID,
+			UserID:    share.UserID,
+			CourseID:  share.CourseID,
+			AssetID:   share.AssetID,
+			ExpiresAt: share.ExpiresAt,
+			MaxViews:  share.MaxViews,
+			Views:     share.Views,
+			Revoked:   share.Revoked,
+			Expired:   share.IsExpired(),
+			CreatedAt: share.CreatedAt,
+			Token:     security.SignToken(secret, share.ID),
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func shareAccessResponseHelper(accesses []*models.ShareAccess) []*shareAccessResponse {
+	responses := []*shareAccessResponse{}
+
+	for _, access := range accesses {
+		responses = append(responses, &shareAccessResponse{
+			ID:        access.ID,
+			IPAddress: access.IPAddress,
+			UserAgent: access.UserAgent,
+			Path:      access.Path,
+			CreatedAt: access.CreatedAt,
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// sessionResponseHelper builds the session responses. The session with the current ID is marked
+// as current
+func sessionResponseHelper(sessions []*models.Session, currentId string) []*sessionResponse {
+	responses := []*sessionResponse{}
+
+	for _, session := range sessions {
+		responses = append(responses, &sessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			ExpiresAt:  session.ExpiresAt,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    currentId != "" && session.ID == currentId,
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// lockoutResponseHelper builds the lockout responses of a username or IP address throttle
+func lockoutResponseHelper(lockoutType string, states []*security.ThrottleState) []*lockoutResponse {
+	responses := []*lockoutResponse{}
+
+	for _, state := range states {
+		responses = append(responses, &lockoutResponse{
+			Type:        lockoutType,
+			Value:       state.Key,
+			Failures:    state.Failures,
+			LastFailure: state.LastFailure,
+			RetryAt:     state.RetryAt,
+			Locked:      state.Locked,
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// assetPlayback returns how a video asset should be played. Videos a browser cannot play need to
+// be streamed through ffmpeg. An empty string is returned for non-video assets
+func assetPlayback(asset *models.Asset) string {
+	if !asset.Type.IsVideo() {
+		return ""
+	}
+
+	if transcode.IsBrowserPlayable(filepath.Ext(asset.Path), asset.VideoCodec, asset.AudioCodec) {
+		return "direct"
+	}
+
+	return "transcode"
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func settingsResponseHelper(settings *dao.ProgressSettings, ffmpegPath string, slidesGroupDirectories bool) *settingsResponse {
+	return &settingsResponse{
+		ProgressMode:           settings.Mode,
+		ProgressUntimedWeight:  settings.UntimedWeight,
+		FFmpegPath:             ffmpegPath,
+		SlidesGroupDirectories: slidesGroupDirectories,
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 // handleVideo handles the video streaming logic
 func handleVideo(c *fiber.Ctx, appFs *appFs.AppFs, asset *models.Asset) error {
 	// Open the video
@@ -276,3 +528,183 @@ func handleHtml(c *fiber.Ctx, appFs *appFs.AppFs, asset *models.Asset) error {
 	c.Set(fiber.HeaderContentType, "text/html")
 	return c.Status(fiber.StatusOK).Send(content)
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// handleDocument handles serving markdown, plain text and Jupyter notebook files. They are rendered
+// to a sanitized HTML page, which the client tracks the scroll position of
+func handleDocument(c *fiber.Ctx, appFs *appFs.AppFs, asset *models.Asset) error {
+	content, err := afero.ReadFile(appFs.Fs, asset.Path)
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error reading file", err)
+	}
+
+	var body string
+	switch {
+	case asset.Type.IsMarkdown():
+		body, err = preview.RenderMarkdown(content)
+	case asset.Type.IsNotebook():
+		body, err = preview.RenderNotebook(content)
+	default:
+		body = preview.RenderText(content)
+	}
+
+	if err != nil {
+		if errors.Is(err, preview.ErrInvalidNotebook) {
+			return errorResponse(c, fiber.StatusUnprocessableEntity, "Invalid notebook", err)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error rendering document", err)
+	}
+
+	page := "<!DOCTYPE html><html><head><meta charset=\"utf-8\"><title>" + html.EscapeString(asset.Title) +
+		"</title></head><body class=\"" + asset.Type.String() + "\">" + body + "</body></html>"
+
+	// The page is sanitized, but as a second line of defense, scripts are not allowed to run
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; img-src 'self' data:; style-src 'self' 'unsafe-inline'")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(fiber.StatusOK).SendString(page)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// epubResponseHelper builds the response for an EPUB, along with the reading progress of its spine
+// items
+func epubResponseHelper(asset *models.Asset, book *epub.Book, progress []*models.SpineProgress) *epubResponse {
+	baseURL := "/api/courses/" + url.PathEscape(asset.CourseID) + "/assets/" + url.PathEscape(asset.ID) + "/epub/"
+
+	resp := &epubResponse{
+		Title: book.Title,
+		Spine: make([]*epubSpineItemResponse, 0, len(book.Spine)),
+	}
+
+	if resp.Title == "" {
+		resp.Title = asset.Title
+	}
+
+	scrollPos := map[int]int{}
+	var lastRead *models.SpineProgress
+	for _, p := range progress {
+		scrollPos[p.SpineIndex] = p.ScrollPos
+
+		// Progress is ordered by spine index, so ties go to the furthest spine item
+		if lastRead == nil || !p.UpdatedAt.Time().Before(lastRead.UpdatedAt.Time()) {
+			lastRead = p
+		}
+	}
+
+	if lastRead != nil && lastRead.SpineIndex < len(book.Spine) {
+		resp.Position = lastRead.SpineIndex
+	}
+
+	for _, item := range book.Spine {
+		resp.Spine = append(resp.Spine, &epubSpineItemResponse{
+			Index:     item.Index,
+			Path:      item.Path,
+			Linear:    item.Linear,
+			URL:       baseURL + escapeEntryPath(item.Path),
+			ScrollPos: scrollPos[item.Index],
+		})
+	}
+
+	var convert func(entries []*epub.TOCEntry) []*epubTOCEntryResponse
+	convert = func(entries []*epub.TOCEntry) []*epubTOCEntryResponse {
+		responses := []*epubTOCEntryResponse{}
+		for _, entry := range entries {
+			r := &epubTOCEntryResponse{
+				Title:      entry.Title,
+				Path:       entry.Path,
+				Fragment:   entry.Fragment,
+				SpineIndex: entry.SpineIndex,
+				Children:   convert(entry.Children),
+			}
+
+			if entry.Path != "" {
+				r.URL = baseURL + escapeEntryPath(entry.Path)
+				if entry.Fragment != "" {
+					r.URL += "#" + url.PathEscape(entry.Fragment)
+				}
+			}
+
+			responses = append(responses, r)
+		}
+
+		return responses
+	}
+
+	resp.TOC = convert(book.TOC)
+
+	return resp
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// slidesResponseHelper builds the response for the slides of an asset, along with the last slide
+// viewed
+func slidesResponseHelper(asset *models.Asset, paths []string) *slidesResponse {
+	baseURL := "/api/courses/" + url.PathEscape(asset.CourseID) + "/assets/" + url.PathEscape(asset.ID) + "/slides/"
+
+	resp := &slidesResponse{
+		Slides: make([]*slideResponse, 0, len(paths)),
+	}
+
+	if asset.Progress != nil && asset.Progress.SlidePos < len(paths) {
+		resp.Position = asset.Progress.SlidePos
+	}
+
+	for i, path := range paths {
+		resp.Slides = append(resp.Slides, &slideResponse{
+			Index: i,
+			Name:  filepath.Base(path),
+			URL:   baseURL + strconv.Itoa(i),
+		})
+	}
+
+	return resp
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func archiveEntryResponseHelper(entries []*archive.Entry) []*archiveEntryResponse {
+	responses := []*archiveEntryResponse{}
+	for _, entry := range entries {
+		responses = append(responses, &archiveEntryResponse{
+			Path:           entry.Path,
+			Size:           entry.Size,
+			CompressedSize: entry.CompressedSize,
+			Modified:       entry.Modified,
+			IsDir:          entry.IsDir,
+		})
+	}
+
+	return responses
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// attachmentURL returns the API URL of an attachment
+func attachmentURL(courseID, assetID, attachmentID string) string {
+	return "/api/courses/" + url.PathEscape(courseID) + "/assets/" + url.PathEscape(assetID) + "/attachments/" + url.PathEscape(attachmentID)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// escapeEntryPath escapes each segment of the path of an archive entry
+func escapeEntryPath(p string) string {
+	return strings.Join(utils.Map(strings.Split(p, "/"), url.PathEscape), "/")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// entryBody is the response body of an archive entry. The entry and the archive are closed once
+// the body has been sent
+type entryBody struct {
+	io.Reader
+	entry   io.Closer
+	archive io.Closer
+}
+
+// Close implements the `io.Closer` interface
+func (b *entryBody) Close() error {
+	return errors.Join(b.entry.Close(), b.archive.Close())
+}
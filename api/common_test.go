@@ -1,18 +1,24 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"image"
+	"image/png"
 	"io"
 	"net/http"
+	"path/filepath"
 	"sync"
 	"testing"
 
 	"github.com/geerew/off-course/database"
 	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/cardimage"
 	"github.com/geerew/off-course/utils/coursescan"
 	"github.com/geerew/off-course/utils/logger"
 	"github.com/geerew/off-course/utils/pagination"
+	"github.com/geerew/off-course/utils/transcode"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
 )
@@ -49,12 +55,36 @@ func setup(t *testing.T) (*Router, context.Context) {
 		Logger: logger,
 	})
 
-	// Router
-	config := &RouterConfig{
-		DbManager:  dbManager,
+	dataDir := t.TempDir()
+
+	transcoder := transcode.New(&transcode.Config{
+		CacheDir:      filepath.Join(dataDir, "transcode"),
+		ThumbnailsDir: filepath.Join(dataDir, "thumbnails"),
+		Logger:        logger,
+	})
+
+	cards := cardimage.New(&cardimage.Config{
+		CacheDir:   filepath.Join(dataDir, "cards"),
+		UploadsDir: "/oc_data/card_uploads",
 		AppFs:      appFs,
-		CourseScan: courseScan,
 		Logger:     logger,
+	})
+
+	// Failed logins lock out as usual, but without backing off in between, so tests can fail a
+	// login several times in a row
+	loginThrottle := DefaultLoginThrottleConfig()
+	loginThrottle.Username.BaseDelay = 0
+	loginThrottle.IP.BaseDelay = 0
+
+	// Router
+	config := &RouterConfig{
+		DbManager:     dbManager,
+		AppFs:         appFs,
+		CourseScan:    courseScan,
+		Transcoder:    transcoder,
+		Cards:         cards,
+		Logger:        logger,
+		LoginThrottle: loginThrottle,
 	}
 
 	router := NewRouter(config)
@@ -94,3 +124,15 @@ func unmarshalHelper[T any](t *testing.T, body []byte) (pagination.PaginationRes
 
 	return respData, resp
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// testPNG returns a PNG of the given size
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+
+	return buf.Bytes()
+}
@@ -1,11 +1,19 @@
 package api
 
 import (
+	"bytes"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/Masterminds/squirrel"
@@ -14,11 +22,19 @@ import (
 	"github.com/geerew/off-course/models"
 	"github.com/geerew/off-course/utils"
 	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/archive"
+	"github.com/geerew/off-course/utils/cardimage"
 	"github.com/geerew/off-course/utils/coursescan"
+	"github.com/geerew/off-course/utils/epub"
 	"github.com/geerew/off-course/utils/pagination"
+	"github.com/geerew/off-course/utils/preview"
+	"github.com/geerew/off-course/utils/slides"
+	"github.com/geerew/off-course/utils/subtitles"
+	"github.com/geerew/off-course/utils/transcode"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/spf13/afero"
+	"github.com/valyala/fasthttp"
 )
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -27,19 +43,31 @@ type coursesAPI struct {
 	logger     *slog.Logger
 	appFs      *appFs.AppFs
 	courseScan *coursescan.CourseScan
+	transcoder *transcode.Transcoder
+	cards      *cardimage.Cache
 	dao        *dao.DAO
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// initCourseRoutes initializes the course routes
-func (r *Router) initCourseRoutes() {
-	coursesAPI := coursesAPI{
+// newCoursesAPI creates the courses API. The handlers are also served, read-only, through share
+// links (see `initSharedRoutes`)
+func newCoursesAPI(r *Router) coursesAPI {
+	return coursesAPI{
 		logger:     r.config.Logger,
 		appFs:      r.config.AppFs,
 		courseScan: r.config.CourseScan,
+		transcoder: r.config.Transcoder,
+		cards:      r.config.Cards,
 		dao:        r.dao,
 	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initCourseRoutes initializes the course routes
+func (r *Router) initCourseRoutes() {
+	coursesAPI := newCoursesAPI(r)
 
 	courseGroup := r.api.Group("/courses")
 
@@ -52,17 +80,43 @@ func (r *Router) initCourseRoutes() {
 	// Course card
 	courseGroup.Head("/:id/card", coursesAPI.getCard)
 	courseGroup.Get("/:id/card", coursesAPI.getCard)
+	courseGroup.Put("/:id/card", coursesAPI.updateCard)
+	courseGroup.Delete("/:id/card", coursesAPI.deleteCard)
 
 	// Course asset
 	courseGroup.Get("/:id/assets", coursesAPI.getAssets)
 	courseGroup.Get("/:id/assets/:asset", coursesAPI.getAsset)
 	courseGroup.Get("/:id/assets/:asset/serve", coursesAPI.serveAsset)
+	courseGroup.Get("/:id/assets/:asset/stream/:file", coursesAPI.streamAsset)
 	courseGroup.Put("/:id/assets/:asset/progress", coursesAPI.updateAssetProgress)
 
 	// Course asset attachments
 	courseGroup.Get("/:id/assets/:asset/attachments", coursesAPI.getAttachments)
 	courseGroup.Get("/:id/assets/:asset/attachments/:attachment", coursesAPI.getAttachment)
 	courseGroup.Get("/:id/assets/:asset/attachments/:attachment/serve", coursesAPI.serveAttachment)
+	courseGroup.Get("/:id/assets/:asset/attachments/:attachment/preview", coursesAPI.previewAttachment)
+	courseGroup.Get("/:id/assets/:asset/attachments/:attachment/entries", coursesAPI.getAttachmentEntries)
+	courseGroup.Get("/:id/assets/:asset/attachments/:attachment/entries/*", coursesAPI.serveAttachmentEntry)
+
+	// Course asset subtitles
+	courseGroup.Get("/:id/assets/:asset/subtitles/:lang", coursesAPI.serveSubtitle)
+
+	// Course asset chapter markers
+	courseGroup.Get("/:id/assets/:asset/chapters", coursesAPI.getChapterMarkers)
+
+	// Course asset EPUB
+	courseGroup.Get("/:id/assets/:asset/epub", coursesAPI.getEPUB)
+	courseGroup.Put("/:id/assets/:asset/epub/progress", coursesAPI.updateEPUBProgress)
+	courseGroup.Get("/:id/assets/:asset/epub/*", coursesAPI.serveEPUBResource)
+
+	// Course asset slides
+	courseGroup.Get("/:id/assets/:asset/slides", coursesAPI.getSlides)
+	courseGroup.Get("/:id/assets/:asset/slides/:index", coursesAPI.serveSlide)
+
+	// Course asset thumbnails
+	courseGroup.Get("/:id/assets/:asset/thumbnails.vtt", coursesAPI.getThumbnails)
+	courseGroup.Get("/:id/assets/:asset/thumbnails.jpg", coursesAPI.serveSprite)
+	courseGroup.Get("/:id/assets/:asset/poster.jpg", coursesAPI.servePoster)
 
 	// Course tags
 	courseGroup.Get("/:id/tags", coursesAPI.getTags)
@@ -118,11 +172,11 @@ func (api coursesAPI) getCourses(c *fiber.Ctx) error {
 
 		switch unescapedProgress {
 		case "not started":
-			courseIDs, err = api.dao.PluckIDsForNotStartedCourses(c.Context(), nil)
+			courseIDs, err = api.dao.PluckIDsForNotStartedCourses(c.Context(), currentUserId(c), nil)
 		case "started":
-			courseIDs, err = api.dao.PluckIDsForStartedCourses(c.Context(), nil)
+			courseIDs, err = api.dao.PluckIDsForStartedCourses(c.Context(), currentUserId(c), nil)
 		case "completed":
-			courseIDs, err = api.dao.PluckIDsForCompletedCourses(c.Context(), nil)
+			courseIDs, err = api.dao.PluckIDsForCompletedCourses(c.Context(), currentUserId(c), nil)
 		}
 
 		if err != nil {
@@ -179,7 +233,23 @@ func (api coursesAPI) getCourses(c *fiber.Ctx) error {
 		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up courses", err)
 	}
 
-	pResult, err := options.Pagination.BuildResult(courseResponseHelper(courses))
+	if err := api.dao.LoadCourseProgress(c.Context(), courses, currentUserId(c)); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course progress", err)
+	}
+
+	responses := courseResponseHelper(courses)
+
+	// Include the total duration of each course
+	durations, err := api.dao.SumDurationsByCourse(c.Context(), utils.Map(courses, func(course *models.Course) string { return course.ID }))
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course durations", err)
+	}
+
+	for _, resp := range responses {
+		resp.Duration = durations[resp.ID]
+	}
+
+	pResult, err := options.Pagination.BuildResult(responses)
 	if err != nil {
 		return errorResponse(c, fiber.StatusInternalServerError, "Error building pagination result", err)
 	}
@@ -203,7 +273,31 @@ func (api coursesAPI) getCourse(c *fiber.Ctx) error {
 		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(courseResponseHelper([]*models.Course{course})[0])
+	if err := api.dao.LoadCourseProgress(c.Context(), []*models.Course{course}, currentUserId(c)); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course progress", err)
+	}
+
+	resp := courseResponseHelper([]*models.Course{course})[0]
+
+	// Include the total duration of the course and of each chapter
+	durations, err := api.dao.SumDurationsByChapter(c.Context(), course.ID)
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course durations", err)
+	}
+
+	chapters := make([]string, 0, len(durations))
+	for chapter := range durations {
+		chapters = append(chapters, chapter)
+	}
+
+	slices.Sort(chapters)
+
+	for _, chapter := range chapters {
+		resp.Duration += durations[chapter]
+		resp.Chapters = append(resp.Chapters, &courseChapterResponse{Title: chapter, Duration: durations[chapter]})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -262,6 +356,9 @@ func (api coursesAPI) deleteCourse(c *fiber.Ctx) error {
 		return errorResponse(c, fiber.StatusInternalServerError, "Error deleting course", err)
 	}
 
+	// Best effort, as the course has already been deleted
+	api.cards.RemoveUpload(id)
+
 	return c.Status(fiber.StatusNoContent).Send(nil)
 }
 
@@ -270,6 +367,131 @@ func (api coursesAPI) deleteCourse(c *fiber.Ctx) error {
 func (api coursesAPI) getCard(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	// An empty size serves the original image
+	size := c.Query("size")
+	if _, ok := cardimage.Sizes[size]; size != "" && !ok {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid card size", nil)
+	}
+
+	course := &models.Course{Base: models.Base{ID: id}}
+	err := api.dao.GetById(c.Context(), course)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errorResponse(c, fiber.StatusNotFound, "Course not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course", err)
+	}
+
+	if path := api.cardSource(course); path != "" {
+		if size == "" {
+			// The fiber function sendFile(...) does not support using a custom FS. Therefore, use
+			// SendFile() from the filesystem middleware.
+			return filesystem.SendFile(c, afero.NewHttpFs(api.appFs.Fs), path)
+		}
+
+		// An image that cannot be decoded falls through to the placeholder
+		if out, err := api.cards.Derivative(path, size); err == nil {
+			// SendFile keeps a status set before it is called
+			c.Status(fiber.StatusOK)
+			return c.SendFile(out)
+		} else if !errors.Is(err, cardimage.ErrInvalidImage) {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error generating card", err)
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "image/svg+xml")
+	return c.Status(fiber.StatusOK).Send(cardimage.Placeholder(course.Title))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// updateCard sets the card of a course to either an uploaded image (multipart `card` field) or an
+// image within the course (JSON `path`). The card is kept when the course is scanned
+func (api coursesAPI) updateCard(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	course := &models.Course{Base: models.Base{ID: id}}
+	err := api.dao.GetById(c.Context(), course)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errorResponse(c, fiber.StatusNotFound, "Course not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course", err)
+	}
+
+	cardPath := ""
+
+	if header, err := c.FormFile("card"); err == nil {
+		file, err := header.Open()
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error reading card", err)
+		}
+		defer file.Close()
+
+		cardPath, err = api.cards.SaveUpload(course.ID, header.Filename, file)
+		if err != nil {
+			if errors.Is(err, cardimage.ErrInvalidImage) {
+				return errorResponse(c, fiber.StatusBadRequest, "Invalid image", err)
+			}
+
+			return errorResponse(c, fiber.StatusInternalServerError, "Error saving card", err)
+		}
+	} else {
+		req := &courseCardRequest{}
+		if err := c.BodyParser(req); err != nil {
+			return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+		}
+
+		if req.Path == "" {
+			return errorResponse(c, fiber.StatusBadRequest, "A card file or path is required", nil)
+		}
+
+		cardPath = utils.NormalizeWindowsDrive(req.Path)
+
+		// Relative paths are relative to the course
+		if !filepath.IsAbs(cardPath) {
+			cardPath = filepath.Join(course.Path, cardPath)
+		}
+
+		rel, err := filepath.Rel(course.Path, cardPath)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return errorResponse(c, fiber.StatusBadRequest, "The card must be within the course", nil)
+		}
+
+		if !cardimage.IsImage(cardPath) {
+			return errorResponse(c, fiber.StatusBadRequest, "Invalid image", nil)
+		}
+
+		if exists, err := afero.Exists(api.appFs.Fs, cardPath); err != nil || !exists {
+			return errorResponse(c, fiber.StatusBadRequest, "Card not found", err)
+		}
+
+		// Replacing an upload with a course image
+		if err := api.cards.RemoveUpload(course.ID); err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error removing uploaded card", err)
+		}
+	}
+
+	course.CardPath = cardPath
+	course.CardOverride = true
+
+	if err := api.dao.UpdateCourse(c.Context(), course); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error updating course", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(courseResponseHelper([]*models.Course{course})[0])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// deleteCard removes the card set through the API. The course is scanned to detect its card
+func (api coursesAPI) deleteCard(c *fiber.Ctx) error {
+	id := c.Params("id")
+
 	course := &models.Course{Base: models.Base{ID: id}}
 	err := api.dao.GetById(c.Context(), course)
 
@@ -281,18 +503,42 @@ func (api coursesAPI) getCard(c *fiber.Ctx) error {
 		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course", err)
 	}
 
-	if course.CardPath == "" {
-		return errorResponse(c, fiber.StatusNotFound, "Course has no card", nil)
+	if !course.CardOverride {
+		return c.Status(fiber.StatusNoContent).Send(nil)
+	}
+
+	if err := api.cards.RemoveUpload(course.ID); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error removing uploaded card", err)
+	}
+
+	course.CardPath = ""
+	course.CardOverride = false
+
+	if err := api.dao.UpdateCourse(c.Context(), course); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error updating course", err)
+	}
+
+	if _, err := api.courseScan.Add(c.Context(), course.ID); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error creating scan job", err)
 	}
 
-	_, err = api.appFs.Fs.Stat(course.CardPath)
-	if os.IsNotExist(err) {
-		return errorResponse(c, fiber.StatusNotFound, "Course card not found", nil)
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// cardSource returns the path of the image to use as the card of the course. This is the card
+// when it exists, otherwise the first image in the course. An empty string is returned when the
+// course has no image
+func (api coursesAPI) cardSource(course *models.Course) string {
+	if course.CardPath != "" {
+		if exists, err := afero.Exists(api.appFs.Fs, course.CardPath); err == nil && exists {
+			return course.CardPath
+		}
 	}
 
-	// The fiber function sendFile(...) does not support using a custom FS. Therefore, use
-	// SendFile() from the filesystem middleware.
-	return filesystem.SendFile(c, afero.NewHttpFs(api.appFs.Fs), course.CardPath)
+	// An unavailable course has no fallback
+	return api.cards.Fallback(course.Path)
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -313,6 +559,10 @@ func (api coursesAPI) getAssets(c *fiber.Ctx) error {
 		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up assets", err)
 	}
 
+	if err := api.dao.LoadAssetProgress(c.Context(), assets, currentUserId(c)); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset progress", err)
+	}
+
 	pResult, err := options.Pagination.BuildResult(assetResponseHelper(assets))
 	if err != nil {
 		return errorResponse(c, fiber.StatusInternalServerError, "Error building pagination result", err)
@@ -341,6 +591,10 @@ func (api coursesAPI) getAsset(c *fiber.Ctx) error {
 		return errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
 	}
 
+	if err := api.dao.LoadAssetProgress(c.Context(), []*models.Asset{asset}, currentUserId(c)); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset progress", err)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(assetResponseHelper([]*models.Asset{asset})[0])
 }
 
@@ -373,6 +627,27 @@ func (api coursesAPI) serveAsset(c *fiber.Ctx) error {
 		return handleVideo(c, api.appFs, asset)
 	} else if asset.Type.IsHTML() {
 		return handleHtml(c, api.appFs, asset)
+	} else if asset.Type.IsDocument() {
+		return handleDocument(c, api.appFs, asset)
+	} else if asset.Type.IsEPUB() {
+		// The book is read through the EPUB routes. Serving the asset downloads it
+		c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(asset.Path)}))
+		return filesystem.SendFile(c, afero.NewHttpFs(api.appFs.Fs), asset.Path)
+	} else if asset.Type.IsLink() && asset.URL != "" {
+		// Links redirect to their target
+		return c.Redirect(asset.URL, fiber.StatusFound)
+	} else if asset.Type.IsSlides() {
+		// The slides are viewed through the slides routes. Serving the asset serves the first slide
+		paths, err := slides.List(api.appFs.Fs, asset.Path)
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error listing slides", err)
+		}
+
+		if len(paths) == 0 {
+			return errorResponse(c, fiber.StatusNotFound, "Slide not found", nil)
+		}
+
+		return filesystem.SendFile(c, afero.NewHttpFs(api.appFs.Fs), paths[0])
 	}
 
 	// TODO: Handle PDF
@@ -381,6 +656,114 @@ func (api coursesAPI) serveAsset(c *fiber.Ctx) error {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// streamAsset serves a video remuxed (or transcoded) by ffmpeg into a format the browser can play.
+// The file is either `video.mp4` (fragmented MP4), `index.m3u8` (HLS playlist) or one of the HLS
+// segments listed in the playlist
+//
+// The output is generated in the background on the first request and cached. A HLS playlist is
+// served as soon as its first segments are written and grows until ffmpeg finishes. When the output
+// is not ready in time, a 503 with a Retry-After header is returned and the client should retry.
+// When ffmpeg is not configured, a 503 is returned and the client should fallback to serving the
+// asset as is
+func (api coursesAPI) streamAsset(c *fiber.Ctx) error {
+	id := c.Params("id")
+	assetId := c.Params("asset")
+	file := c.Params("file")
+
+	var format transcode.Format
+	switch {
+	case file == transcode.MP4File:
+		format = transcode.FormatMP4
+	case file == transcode.PlaylistFile || transcode.IsSegmentFile(file):
+		format = transcode.FormatHLS
+	default:
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid stream file", nil)
+	}
+
+	asset := &models.Asset{Base: models.Base{ID: assetId}}
+	err := api.dao.GetById(c.Context(), asset)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errorResponse(c, fiber.StatusNotFound, "Asset not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset", err)
+	}
+
+	if asset.CourseID != id {
+		return errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+	}
+
+	if !asset.Type.IsVideo() {
+		return errorResponse(c, fiber.StatusBadRequest, "Asset is not a video", nil)
+	}
+
+	ffmpegPath, err := api.dao.GetFFmpegPath(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up ffmpeg path", err)
+	}
+
+	if ffmpegPath == "" || api.transcoder == nil {
+		return errorResponse(c, fiber.StatusServiceUnavailable, "ffmpeg is not configured", nil)
+	}
+
+	// Check for invalid path
+	if exists, err := afero.Exists(api.appFs.Fs, asset.Path); err != nil || !exists {
+		return errorResponse(c, fiber.StatusBadRequest, "Asset does not exist", nil)
+	}
+
+	src := &transcode.Source{
+		Path:       asset.Path,
+		Key:        asset.Hash,
+		VideoCodec: asset.VideoCodec,
+		AudioCodec: asset.AudioCodec,
+	}
+
+	dir, err := api.transcoder.Prepare(c.Context(), ffmpegPath, src, format)
+	if err != nil {
+		if errors.Is(err, transcode.ErrNotReady) {
+			c.Set(fiber.HeaderRetryAfter, "5")
+			return errorResponse(c, fiber.StatusServiceUnavailable, "Stream is being prepared", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error transcoding asset", err)
+	}
+
+	path := filepath.Join(dir, file)
+	if _, err := os.Stat(path); err != nil {
+		return errorResponse(c, fiber.StatusNotFound, "Stream file not found", nil)
+	}
+
+	// The playlist is rewritten as segments are added, so it is read on every request rather than
+	// served through the file cache of SendFile
+	if file == transcode.PlaylistFile {
+		playlist, err := os.ReadFile(path)
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error serving stream", err)
+		}
+
+		c.Set(fiber.HeaderContentType, "application/vnd.apple.mpegurl")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+
+		return c.Status(fiber.StatusOK).Send(playlist)
+	}
+
+	// SendFile keeps a status set before it is called, so reset it to let ranges through
+	c.Status(fiber.StatusOK)
+
+	if err := c.SendFile(path); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error serving stream", err)
+	}
+
+	if filepath.Ext(file) == ".ts" {
+		c.Set(fiber.HeaderContentType, "video/mp2t")
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 func (api coursesAPI) updateAssetProgress(c *fiber.Ctx) error {
 	id := c.Params("id")
 	assetId := c.Params("asset")
@@ -406,10 +789,27 @@ func (api coursesAPI) updateAssetProgress(c *fiber.Ctx) error {
 
 	assetProgress := &models.AssetProgress{
 		AssetID:   assetId,
+		UserID:    currentUserId(c),
 		VideoPos:  req.VideoPos,
+		ScrollPos: req.ScrollPos,
 		Completed: req.Completed,
 	}
 
+	// For slides, the scroll position is derived from the last slide viewed and viewing the last
+	// slide completes the asset
+	if asset.Type.IsSlides() {
+		paths, err := slides.List(api.appFs.Fs, asset.Path)
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error listing slides", err)
+		}
+
+		if len(paths) > 0 {
+			assetProgress.SlidePos = max(0, min(req.SlidePos, len(paths)-1))
+			assetProgress.ScrollPos = (assetProgress.SlidePos + 1) * 100 / len(paths)
+			assetProgress.Completed = req.Completed || assetProgress.SlidePos == len(paths)-1
+		}
+	}
+
 	err = api.dao.CreateOrUpdateAssetProgress(c.Context(), assetProgress)
 	if err != nil {
 		return errorResponse(c, fiber.StatusInternalServerError, "Error updating asset", err)
@@ -500,44 +900,673 @@ func (api coursesAPI) getAttachment(c *fiber.Ctx) error {
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 func (api coursesAPI) serveAttachment(c *fiber.Ctx) error {
-	id := c.Params("id")
-	assetId := c.Params("asset")
-	attachmentID := c.Params("attachment")
+	attachment, err := api.lookupAttachment(c)
+	if attachment == nil {
+		return err
+	}
 
-	asset := &models.Asset{Base: models.Base{ID: assetId}}
-	err := api.dao.GetById(c.Context(), asset)
+	// Links redirect to their target
+	if attachment.URL != "" {
+		return c.Redirect(attachment.URL, fiber.StatusFound)
+	}
+
+	// Images are displayed inline when requested, for previews. Other files are always downloaded,
+	// so HTML and the like cannot run in the context of the app
+	disposition := `attachment; filename="` + attachment.Title + `"`
+	if kind, _ := preview.Detect(attachment.Path, nil); kind == preview.KindImage && c.QueryBool("inline") {
+		disposition = "inline"
+	}
+
+	c.Set(fiber.HeaderContentDisposition, disposition)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return filesystem.SendFile(c, afero.NewHttpFs(api.appFs.Fs), attachment.Path)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// previewAttachment returns the preview of an attachment
+func (api coursesAPI) previewAttachment(c *fiber.Ctx) error {
+	attachment, err := api.lookupAttachment(c)
+	if attachment == nil {
+		return err
+	}
+
+	info, err := api.appFs.Fs.Stat(attachment.Path)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return errorResponse(c, fiber.StatusNotFound, "Asset not found", nil)
-		}
+		return errorResponse(c, fiber.StatusInternalServerError, "Error reading attachment", err)
+	}
 
-		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset", err)
+	file, err := api.appFs.Fs.Open(attachment.Path)
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error reading attachment", err)
 	}
+	defer file.Close()
 
-	if asset.CourseID != id {
-		return errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+	downloadURL := attachmentURL(c.Params("id"), attachment.AssetID, attachment.ID) + "/serve"
+
+	return previewResponse(c, attachment.Path, info.Size(), file, downloadURL, true)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getAttachmentEntries lists the entries of a ZIP attachment
+func (api coursesAPI) getAttachmentEntries(c *fiber.Ctx) error {
+	a, err := api.openAttachmentArchive(c)
+	if a == nil {
+		return err
 	}
+	defer a.Close()
 
-	attachment := &models.Attachment{Base: models.Base{ID: attachmentID}}
-	err = api.dao.GetById(c.Context(), attachment)
+	return c.Status(fiber.StatusOK).JSON(archiveEntryResponseHelper(a.Entries()))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// serveAttachmentEntry streams a file entry of a ZIP attachment, decompressing it on the fly. A
+// single byte range is supported. As for attachments, `inline` displays images inline and
+// `preview` returns the preview of the entry
+func (api coursesAPI) serveAttachmentEntry(c *fiber.Ctx) error {
+	name, err := url.PathUnescape(c.Params("*"))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return errorResponse(c, fiber.StatusNotFound, "Attachment not found", nil)
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid entry", err)
+	}
+
+	a, err := api.openAttachmentArchive(c)
+	if a == nil {
+		return err
+	}
+
+	entry, err := a.Entry(name)
+	if err != nil {
+		a.Close()
+		return errorResponse(c, fiber.StatusNotFound, "Entry not found", nil)
+	}
+
+	if c.QueryBool("preview") {
+		defer a.Close()
+
+		rc, err := a.OpenEntry(entry.Path, 0)
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error reading entry", err)
 		}
+		defer rc.Close()
 
-		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up attachment", err)
+		entryURL := attachmentURL(c.Params("id"), c.Params("asset"), c.Params("attachment")) + "/entries/" + escapeEntryPath(entry.Path)
+
+		// Nested archives are not browsable
+		return previewResponse(c, entry.Path, entry.Size, rc, entryURL, false)
 	}
 
-	if attachment.AssetID != assetId {
-		return errorResponse(c, fiber.StatusBadRequest, "Attachment does not belong to asset", nil)
+	start, end := int64(0), entry.Size-1
+	status := fiber.StatusOK
+
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+
+	if byteRange := c.Get(fiber.HeaderRange); byteRange != "" && entry.Size > 0 {
+		s, e, err := fasthttp.ParseByteRange([]byte(byteRange), int(entry.Size))
+		if err != nil {
+			a.Close()
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", entry.Size))
+			return errorResponse(c, fiber.StatusRequestedRangeNotSatisfiable, "Invalid range", nil)
+		}
+
+		start, end = int64(s), int64(e)
+		status = fiber.StatusPartialContent
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, entry.Size))
 	}
 
-	if exists, err := afero.Exists(api.appFs.Fs, attachment.Path); err != nil || !exists {
-		return errorResponse(c, fiber.StatusBadRequest, "Attachment does not exist", err)
+	rc, err := a.OpenEntry(entry.Path, start)
+	if err != nil {
+		a.Close()
+		return errorResponse(c, fiber.StatusInternalServerError, "Error reading entry", err)
 	}
 
-	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+attachment.Title+`"`)
-	return filesystem.SendFile(c, afero.NewHttpFs(api.appFs.Fs), attachment.Path)
+	filename := path.Base(entry.Path)
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	if kind, _ := preview.Detect(filename, nil); kind == preview.KindImage && c.QueryBool("inline") {
+		disposition = "inline"
+	}
+
+	c.Set(fiber.HeaderContentDisposition, disposition)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Type(strings.TrimPrefix(filepath.Ext(filename), "."))
+
+	// The entry and the archive are closed once the body has been sent
+	length := end - start + 1
+	c.Status(status).Response().SetBodyStream(&entryBody{Reader: io.LimitReader(rc, length), entry: rc, archive: a}, int(length))
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// previewResponse writes the preview of a file read from r. Markdown is rendered to sanitized
+// HTML, code and text are returned as is (truncated when large) and images point to downloadURL
+// with `inline` set. ZIP archives are reported as such when archives is true. Anything else,
+// including large images, falls back to downloadURL
+func previewResponse(c *fiber.Ctx, name string, size int64, r io.Reader, downloadURL string, archives bool) error {
+	head := make([]byte, preview.SniffSize)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error reading file", err)
+	}
+
+	head = head[:n]
+	kind, _ := preview.Detect(name, head)
+
+	resp := &attachmentPreviewResponse{
+		Kind: string(kind),
+		Size: size,
+		URL:  downloadURL,
+	}
+
+	switch kind {
+	case preview.KindImage:
+		if size > preview.MaxImageSize {
+			resp.Kind = string(preview.KindDownload)
+		} else {
+			resp.URL += "?inline=true"
+		}
+	case preview.KindArchive:
+		if !archives {
+			resp.Kind = string(preview.KindDownload)
+		}
+	case preview.KindMarkdown, preview.KindCode, preview.KindText:
+		p, err := preview.Text(name, io.MultiReader(bytes.NewReader(head), r))
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error previewing file", err)
+		}
+
+		resp.Language = p.Language
+		resp.Content = p.Content
+		resp.HTML = p.HTML
+		resp.Truncated = p.Truncated
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// openAttachmentArchive looks up the attachment and opens it as a ZIP archive. When the archive is
+// nil, an error response has been written
+func (api coursesAPI) openAttachmentArchive(c *fiber.Ctx) (*archive.Archive, error) {
+	attachment, err := api.lookupAttachment(c)
+	if attachment == nil {
+		return nil, err
+	}
+
+	if !archive.IsArchive(attachment.Path) {
+		return nil, errorResponse(c, fiber.StatusBadRequest, "Attachment is not a ZIP archive", nil)
+	}
+
+	a, err := archive.Open(api.appFs.Fs, attachment.Path)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalidArchive) {
+			return nil, errorResponse(c, fiber.StatusBadRequest, "Invalid ZIP archive", err)
+		}
+
+		return nil, errorResponse(c, fiber.StatusInternalServerError, "Error opening archive", err)
+	}
+
+	return a, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// lookupAttachment looks up the attachment and ensures it exists on disk. When the attachment is
+// nil, an error response has been written
+func (api coursesAPI) lookupAttachment(c *fiber.Ctx) (*models.Attachment, error) {
+	id := c.Params("id")
+	assetId := c.Params("asset")
+	attachmentID := c.Params("attachment")
+
+	asset := &models.Asset{Base: models.Base{ID: assetId}}
+	err := api.dao.GetById(c.Context(), asset)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errorResponse(c, fiber.StatusNotFound, "Asset not found", nil)
+		}
+
+		return nil, errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset", err)
+	}
+
+	if asset.CourseID != id {
+		return nil, errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+	}
+
+	attachment := &models.Attachment{Base: models.Base{ID: attachmentID}}
+	err = api.dao.GetById(c.Context(), attachment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errorResponse(c, fiber.StatusNotFound, "Attachment not found", nil)
+		}
+
+		return nil, errorResponse(c, fiber.StatusInternalServerError, "Error looking up attachment", err)
+	}
+
+	if attachment.AssetID != assetId {
+		return nil, errorResponse(c, fiber.StatusBadRequest, "Attachment does not belong to asset", nil)
+	}
+
+	if exists, err := afero.Exists(api.appFs.Fs, attachment.Path); err != nil || !exists {
+		return nil, errorResponse(c, fiber.StatusBadRequest, "Attachment does not exist", err)
+	}
+
+	return attachment, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// serveSubtitle serves the subtitle of an asset for the given language, converting it to WebVTT
+func (api coursesAPI) serveSubtitle(c *fiber.Ctx) error {
+	id := c.Params("id")
+	assetId := c.Params("asset")
+	lang := strings.ToLower(c.Params("lang"))
+
+	asset := &models.Asset{Base: models.Base{ID: assetId}}
+	err := api.dao.GetById(c.Context(), asset)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errorResponse(c, fiber.StatusNotFound, "Asset not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset", err)
+	}
+
+	if asset.CourseID != id {
+		return errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+	}
+
+	subtitle := &models.Subtitle{AssetID: assetId, Language: lang}
+	err = api.dao.GetSubtitleByLanguage(c.Context(), subtitle)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errorResponse(c, fiber.StatusNotFound, "Subtitle not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up subtitle", err)
+	}
+
+	file, err := api.appFs.Fs.Open(subtitle.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errorResponse(c, fiber.StatusBadRequest, "Subtitle does not exist", err)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error opening subtitle", err)
+	}
+	defer file.Close()
+
+	vtt, err := subtitles.ToWebVTT(file, subtitle.Format)
+	if err != nil {
+		if errors.Is(err, subtitles.ErrMalformed) || errors.Is(err, subtitles.ErrUnsupported) {
+			return errorResponse(c, fiber.StatusUnprocessableEntity, "Unable to convert subtitle", err)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error reading subtitle", err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/vtt; charset=utf-8")
+	return c.Status(fiber.StatusOK).Send(vtt)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getChapterMarkers returns the chapter markers embedded in an asset. When the `format` query param
+// is `vtt`, the markers are returned as a WebVTT chapters track
+func (api coursesAPI) getChapterMarkers(c *fiber.Ctx) error {
+	id := c.Params("id")
+	assetId := c.Params("asset")
+	format := strings.ToLower(c.Query("format", "json"))
+
+	if format != "json" && format != "vtt" {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid format", nil)
+	}
+
+	asset := &models.Asset{Base: models.Base{ID: assetId}}
+	err := api.dao.GetById(c.Context(), asset)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errorResponse(c, fiber.StatusNotFound, "Asset not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset", err)
+	}
+
+	if asset.CourseID != id {
+		return errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+	}
+
+	markers, err := api.dao.ListChapterMarkers(c.Context(), asset.ID)
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up chapter markers", err)
+	}
+
+	responses := chapterMarkerResponseHelper(markers, asset.Duration)
+
+	if format == "vtt" {
+		cues := make([]*subtitles.Cue, 0, len(responses))
+		for i, marker := range responses {
+			cues = append(cues, &subtitles.Cue{
+				ID:    strconv.Itoa(i + 1),
+				Start: int64(marker.Start),
+				End:   int64(marker.End),
+				Text:  subtitles.EscapeText(marker.Title),
+			})
+		}
+
+		c.Set(fiber.HeaderContentType, "text/vtt; charset=utf-8")
+		return c.Status(fiber.StatusOK).Send(subtitles.WriteWebVTT(cues))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(responses)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getEPUB returns the table of contents and spine of an EPUB asset, along with the reading progress
+// of each spine item
+func (api coursesAPI) getEPUB(c *fiber.Ctx) error {
+	book, asset, err := api.openEPUB(c)
+	if book == nil {
+		return err
+	}
+	defer book.Close()
+
+	progress, err := api.dao.ListSpineProgress(c.Context(), asset.ID, currentUserId(c))
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up EPUB progress", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(epubResponseHelper(asset, book, progress))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// serveEPUBResource serves a file from inside an EPUB, such as a spine item (XHTML), a stylesheet or
+// an image. Relative links between the files of the EPUB resolve against this route
+func (api coursesAPI) serveEPUBResource(c *fiber.Ctx) error {
+	name, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid resource", err)
+	}
+
+	book, _, err := api.openEPUB(c)
+	if book == nil {
+		return err
+	}
+
+	resource, rc, err := book.OpenResource(name)
+	if err != nil {
+		book.Close()
+
+		if errors.Is(err, epub.ErrResourceNotFound) {
+			return errorResponse(c, fiber.StatusNotFound, "Resource not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error reading resource", err)
+	}
+
+	// EPUBs may contain scripts. They are not allowed to run, but the document is kept same origin
+	// so the reader can track the scroll position
+	c.Set(fiber.HeaderContentSecurityPolicy, "sandbox allow-same-origin; default-src 'self' data:; style-src 'self' 'unsafe-inline'; script-src 'none'; object-src 'none'")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentType, resource.MediaType)
+
+	// The resource and the book are closed once the body has been sent
+	c.Status(fiber.StatusOK).Response().SetBodyStream(&entryBody{Reader: rc, entry: rc, archive: book}, int(resource.Size))
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (api coursesAPI) updateEPUBProgress(c *fiber.Ctx) error {
+	req := &epubProgressRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	book, asset, err := api.openEPUB(c)
+	if book == nil {
+		return err
+	}
+	defer book.Close()
+
+	if req.SpineIndex < 0 || req.SpineIndex >= len(book.Spine) {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid spine index", nil)
+	}
+
+	// Auxiliary content is not part of the reading order, so it does not count towards progress
+	if !book.Spine[req.SpineIndex].Linear {
+		return errorResponse(c, fiber.StatusBadRequest, "Spine item is not in the reading order", nil)
+	}
+
+	spineProgress := &models.SpineProgress{
+		AssetID:    asset.ID,
+		UserID:     currentUserId(c),
+		SpineIndex: req.SpineIndex,
+		ScrollPos:  req.ScrollPos,
+	}
+
+	err = api.dao.CreateOrUpdateSpineProgress(c.Context(), spineProgress, book.LinearCount())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error updating EPUB progress", err)
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// openEPUB looks up the asset and opens it as an EPUB. When the book is nil, an error response has
+// been written. Otherwise the book must be closed once done
+func (api coursesAPI) openEPUB(c *fiber.Ctx) (*epub.Book, *models.Asset, error) {
+	id := c.Params("id")
+	assetId := c.Params("asset")
+
+	asset := &models.Asset{Base: models.Base{ID: assetId}}
+	err := api.dao.GetById(c.Context(), asset)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, errorResponse(c, fiber.StatusNotFound, "Asset not found", nil)
+		}
+
+		return nil, nil, errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset", err)
+	}
+
+	if asset.CourseID != id {
+		return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+	}
+
+	if !asset.Type.IsEPUB() {
+		return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Asset is not an EPUB", nil)
+	}
+
+	if exists, err := afero.Exists(api.appFs.Fs, asset.Path); err != nil || !exists {
+		return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Asset does not exist", nil)
+	}
+
+	book, err := epub.Open(api.appFs.Fs, asset.Path)
+	if err != nil {
+		if errors.Is(err, epub.ErrInvalidEPUB) {
+			return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Invalid EPUB", err)
+		}
+
+		return nil, nil, errorResponse(c, fiber.StatusInternalServerError, "Error opening EPUB", err)
+	}
+
+	return book, asset, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getSlides returns the slides of a slides asset in order, along with the last slide viewed. A single
+// image has one slide
+func (api coursesAPI) getSlides(c *fiber.Ctx) error {
+	paths, asset, err := api.listSlides(c)
+	if paths == nil {
+		return err
+	}
+
+	if err := api.dao.LoadAssetProgress(c.Context(), []*models.Asset{asset}, currentUserId(c)); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset progress", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(slidesResponseHelper(asset, paths))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// serveSlide serves a slide of a slides asset by its index
+func (api coursesAPI) serveSlide(c *fiber.Ctx) error {
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil || index < 0 {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid slide index", nil)
+	}
+
+	paths, _, err := api.listSlides(c)
+	if paths == nil {
+		return err
+	}
+
+	if index >= len(paths) {
+		return errorResponse(c, fiber.StatusNotFound, "Slide not found", nil)
+	}
+
+	return filesystem.SendFile(c, afero.NewHttpFs(api.appFs.Fs), paths[index])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// listSlides looks up the asset and lists its slides. When the slides are nil, an error response
+// has been written
+func (api coursesAPI) listSlides(c *fiber.Ctx) ([]string, *models.Asset, error) {
+	id := c.Params("id")
+	assetId := c.Params("asset")
+
+	asset := &models.Asset{Base: models.Base{ID: assetId}}
+	err := api.dao.GetById(c.Context(), asset)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, errorResponse(c, fiber.StatusNotFound, "Asset not found", nil)
+		}
+
+		return nil, nil, errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset", err)
+	}
+
+	if asset.CourseID != id {
+		return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+	}
+
+	if !asset.Type.IsSlides() {
+		return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Asset is not slides", nil)
+	}
+
+	if exists, err := afero.Exists(api.appFs.Fs, asset.Path); err != nil || !exists {
+		return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Asset does not exist", nil)
+	}
+
+	paths, err := slides.List(api.appFs.Fs, asset.Path)
+	if err != nil {
+		return nil, nil, errorResponse(c, fiber.StatusInternalServerError, "Error listing slides", err)
+	}
+
+	return paths, asset, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getThumbnails returns a WebVTT thumbnails track for the asset, with each cue pointing to a
+// region of the sprite sheet. The thumbnails are generated in the background, so a 404 is returned
+// until they are
+func (api coursesAPI) getThumbnails(c *fiber.Ctx) error {
+	asset, thumbnails, err := api.lookupThumbnails(c)
+	if err != nil || thumbnails == nil {
+		return err
+	}
+
+	spriteURL := "/api/courses/" + url.PathEscape(asset.CourseID) + "/assets/" + url.PathEscape(asset.ID) + "/thumbnails.jpg"
+
+	c.Set(fiber.HeaderContentType, "text/vtt; charset=utf-8")
+	return c.Status(fiber.StatusOK).Send(subtitles.WriteWebVTT(thumbnails.Cues(spriteURL)))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// serveSprite serves the thumbnails sprite sheet of the asset
+func (api coursesAPI) serveSprite(c *fiber.Ctx) error {
+	return api.serveThumbnail(c, transcode.SpriteFile)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// servePoster serves the poster image of the asset
+func (api coursesAPI) servePoster(c *fiber.Ctx) error {
+	return api.serveThumbnail(c, transcode.PosterFile)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// serveThumbnail serves a thumbnail file of the asset
+func (api coursesAPI) serveThumbnail(c *fiber.Ctx, file string) error {
+	asset, thumbnails, err := api.lookupThumbnails(c)
+	if err != nil || thumbnails == nil {
+		return err
+	}
+
+	// SendFile keeps a status set before it is called
+	c.Status(fiber.StatusOK)
+
+	if err := c.SendFile(api.transcoder.ThumbnailPath(asset.ID, file)); err != nil {
+		return errorResponse(c, fiber.StatusNotFound, "Thumbnails not found", nil)
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// lookupThumbnails looks up the asset and its thumbnails. When the thumbnails are nil, an error
+// response has been written
+func (api coursesAPI) lookupThumbnails(c *fiber.Ctx) (*models.Asset, *transcode.Thumbnails, error) {
+	id := c.Params("id")
+	assetId := c.Params("asset")
+
+	asset := &models.Asset{Base: models.Base{ID: assetId}}
+	err := api.dao.GetById(c.Context(), asset)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, errorResponse(c, fiber.StatusNotFound, "Asset not found", nil)
+		}
+
+		return nil, nil, errorResponse(c, fiber.StatusInternalServerError, "Error looking up asset", err)
+	}
+
+	if asset.CourseID != id {
+		return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+	}
+
+	if !asset.Type.IsVideo() {
+		return nil, nil, errorResponse(c, fiber.StatusBadRequest, "Asset is not a video", nil)
+	}
+
+	if api.transcoder == nil {
+		return nil, nil, errorResponse(c, fiber.StatusNotFound, "Thumbnails not found", nil)
+	}
+
+	thumbnails, err := api.transcoder.Thumbnails(asset.ID, asset.Hash)
+	if err != nil {
+		if errors.Is(err, transcode.ErrNoThumbnails) {
+			return nil, nil, errorResponse(c, fiber.StatusNotFound, "Thumbnails not found", nil)
+		}
+
+		return nil, nil, errorResponse(c, fiber.StatusInternalServerError, "Error looking up thumbnails", err)
+	}
+
+	return asset, thumbnails, nil
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
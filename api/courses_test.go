@@ -1,14 +1,22 @@
 package api
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -16,8 +24,11 @@ import (
 	"github.com/Masterminds/squirrel"
 	"github.com/geerew/off-course/database"
 	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/mocks"
 	"github.com/geerew/off-course/utils/pagination"
+	"github.com/geerew/off-course/utils/preview"
 	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/transcode"
 	"github.com/geerew/off-course/utils/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/spf13/afero"
@@ -56,6 +67,41 @@ func TestCourses_GetCourses(t *testing.T) {
 		require.Len(t, coursesResp, 5)
 	})
 
+	t.Run("200 (duration)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		courses := []*models.Course{}
+		for i := range 2 {
+			course := &models.Course{Title: fmt.Sprintf("course %d", i), Path: fmt.Sprintf("/course %d", i)}
+			require.NoError(t, router.dao.CreateCourse(ctx, course))
+			courses = append(courses, course)
+		}
+
+		for j := range 3 {
+			asset := &models.Asset{
+				CourseID: courses[0].ID,
+				Title:    fmt.Sprintf("asset %d", j+1),
+				Prefix:   sql.NullInt16{Int16: int16(j + 1), Valid: true},
+				Type:     *types.NewAsset("mp4"),
+				Path:     fmt.Sprintf("/course 0/%d asset.mp4", j+1),
+				Hash:     security.RandomString(64),
+				Duration: 60 * (j + 1),
+			}
+			require.NoError(t, router.dao.CreateAsset(ctx, asset))
+		}
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/?orderBy="+models.COURSE_TABLE+".created_at%20asc", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		_, coursesResp := unmarshalHelper[courseResponse](t, body)
+		require.Len(t, coursesResp, 2)
+		require.Equal(t, courses[0].ID, coursesResp[0].ID)
+		require.Equal(t, 360, coursesResp[0].Duration)
+		require.Equal(t, courses[1].ID, coursesResp[1].ID)
+		require.Zero(t, coursesResp[1].Duration)
+	})
+
 	t.Run("200 (orderBy)", func(t *testing.T) {
 		router, ctx := setup(t)
 
@@ -360,6 +406,43 @@ func TestCourses_GetCourse(t *testing.T) {
 		require.Equal(t, courses[1].ID, courseResp.ID)
 	})
 
+	t.Run("200 (chapters)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		for j, chapter := range []string{"02 Chapter 2", "01 Chapter 1", "01 Chapter 1", ""} {
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    fmt.Sprintf("asset %d", j+1),
+				Prefix:   sql.NullInt16{Int16: int16(j + 1), Valid: true},
+				Chapter:  chapter,
+				Type:     *types.NewAsset("mp4"),
+				Path:     fmt.Sprintf("/course 1/%s/%d asset.mp4", chapter, j+1),
+				Hash:     security.RandomString(64),
+				Duration: 30,
+			}
+			require.NoError(t, router.dao.CreateAsset(ctx, asset))
+		}
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID, nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var courseResp courseResponse
+		err = json.Unmarshal(body, &courseResp)
+		require.NoError(t, err)
+		require.Equal(t, 120, courseResp.Duration)
+		require.Len(t, courseResp.Chapters, 3)
+		require.Equal(t, "", courseResp.Chapters[0].Title)
+		require.Equal(t, 30, courseResp.Chapters[0].Duration)
+		require.Equal(t, "01 Chapter 1", courseResp.Chapters[1].Title)
+		require.Equal(t, 60, courseResp.Chapters[1].Duration)
+		require.Equal(t, "02 Chapter 2", courseResp.Chapters[2].Title)
+		require.Equal(t, 30, courseResp.Chapters[2].Duration)
+	})
+
 	t.Run("404 (not found)", func(t *testing.T) {
 		router, _ := setup(t)
 
@@ -573,7 +656,29 @@ func TestCourses_GetCard(t *testing.T) {
 		require.Contains(t, string(body), "Course not found")
 	})
 
-	t.Run("404 (no card)", func(t *testing.T) {
+	t.Run("200 (resized)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{
+			Title:    "course 1",
+			Path:     "/course 1",
+			CardPath: "/course 1/card.png",
+		}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, course.CardPath, testPNG(t, 800, 400), os.ModePerm))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/card?size=sm", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(body))
+		require.NoError(t, err)
+		require.Equal(t, 320, cfg.Width)
+		require.Equal(t, 160, cfg.Height)
+	})
+
+	t.Run("200 (first image)", func(t *testing.T) {
 		router, ctx := setup(t)
 
 		course := &models.Course{
@@ -582,13 +687,41 @@ func TestCourses_GetCard(t *testing.T) {
 		}
 		require.NoError(t, router.dao.CreateCourse(ctx, course))
 
-		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/card", nil))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, "/course 1/01 video.mp4", []byte("video"), os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, "/course 1/chapter 2/diagram.png", testPNG(t, 100, 50), os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, "/course 1/chapter 1/slide.png", testPNG(t, 200, 100), os.ModePerm))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/card?size=md", nil))
 		require.NoError(t, err)
-		require.Equal(t, http.StatusNotFound, status)
-		require.Contains(t, string(body), "Course has no card")
+		require.Equal(t, http.StatusOK, status)
+
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(body))
+		require.NoError(t, err)
+		require.Equal(t, 200, cfg.Width)
+	})
+
+	t.Run("200 (placeholder)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{
+			Title: "course 1",
+			Path:  "/course 1",
+		}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		for _, query := range []string{"", "?size=sm"} {
+			resp, err := router.router.Test(httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/card"+query, nil))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, "image/svg+xml", resp.Header.Get(fiber.HeaderContentType))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Contains(t, string(body), ">C1</text>")
+		}
 	})
 
-	t.Run("404 (card not found)", func(t *testing.T) {
+	t.Run("200 (card not found)", func(t *testing.T) {
 		router, ctx := setup(t)
 
 		course := &models.Course{
@@ -600,8 +733,38 @@ func TestCourses_GetCard(t *testing.T) {
 
 		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/card", nil))
 		require.NoError(t, err)
-		require.Equal(t, http.StatusNotFound, status)
-		require.Contains(t, string(body), "Course card not found")
+		require.Equal(t, http.StatusOK, status)
+		require.Contains(t, string(body), "<svg")
+	})
+
+	t.Run("200 (invalid image)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{
+			Title:    "course 1",
+			Path:     "/course 1",
+			CardPath: "/course 1/card.png",
+		}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, course.CardPath, []byte("test"), os.ModePerm))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/card?size=sm", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+		require.Contains(t, string(body), "<svg")
+	})
+
+	t.Run("400 (invalid size)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/card?size=huge", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Invalid card size")
 	})
 
 	t.Run("500 (internal error)", func(t *testing.T) {
@@ -618,6 +781,255 @@ func TestCourses_GetCard(t *testing.T) {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+func TestCourses_UpdateCard(t *testing.T) {
+	uploadRequest := func(t *testing.T, courseID, filename string, data []byte) *http.Request {
+		t.Helper()
+
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+
+		part, err := w.CreateFormFile("card", filename)
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		req := httptest.NewRequest(http.MethodPut, "/api/courses/"+courseID+"/card", &buf)
+		req.Header.Set(fiber.HeaderContentType, w.FormDataContentType())
+
+		return req
+	}
+
+	pathRequest := func(courseID, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/api/courses/"+courseID+"/card", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return req
+	}
+
+	t.Run("200 (upload)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1", CardPath: "/course 1/card.jpg"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		status, body, err := requestHelper(t, router, uploadRequest(t, course.ID, "my card.PNG", testPNG(t, 20, 10)))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp courseResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.True(t, resp.HasCard)
+		require.True(t, resp.CardOverride)
+
+		courseResult := &models.Course{Base: models.Base{ID: course.ID}}
+		require.NoError(t, router.dao.GetById(ctx, courseResult))
+		require.Equal(t, "/oc_data/card_uploads/"+course.ID+".png", courseResult.CardPath)
+		require.True(t, courseResult.CardOverride)
+
+		// The upload is served as the card
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/card", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, testPNG(t, 20, 10), body)
+
+		// A new upload replaces the previous one
+		var jpg bytes.Buffer
+		require.NoError(t, jpeg.Encode(&jpg, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil))
+
+		status, _, err = requestHelper(t, router, uploadRequest(t, course.ID, "card.jpg", jpg.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		matches, err := afero.Glob(router.config.AppFs.Fs, "/oc_data/card_uploads/"+course.ID+".*")
+		require.NoError(t, err)
+		require.Equal(t, []string{"/oc_data/card_uploads/" + course.ID + ".jpg"}, matches)
+	})
+
+	t.Run("200 (path)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, "/course 1/chapter 1/cover.png", testPNG(t, 10, 10), os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, "/course 1/slides.jpg", []byte("jpg"), os.ModePerm))
+
+		// Absolute
+		status, _, err := requestHelper(t, router, pathRequest(course.ID, `{"path": "/course 1/chapter 1/cover.png"}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		courseResult := &models.Course{Base: models.Base{ID: course.ID}}
+		require.NoError(t, router.dao.GetById(ctx, courseResult))
+		require.Equal(t, "/course 1/chapter 1/cover.png", courseResult.CardPath)
+		require.True(t, courseResult.CardOverride)
+
+		// Relative to the course
+		status, _, err = requestHelper(t, router, pathRequest(course.ID, `{"path": "slides.jpg"}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		courseResult = &models.Course{Base: models.Base{ID: course.ID}}
+		require.NoError(t, router.dao.GetById(ctx, courseResult))
+		require.Equal(t, "/course 1/slides.jpg", courseResult.CardPath)
+	})
+
+	t.Run("200 (path replaces upload)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, "/course 1/cover.png", testPNG(t, 10, 10), os.ModePerm))
+
+		status, _, err := requestHelper(t, router, uploadRequest(t, course.ID, "card.png", testPNG(t, 20, 10)))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		status, _, err = requestHelper(t, router, pathRequest(course.ID, `{"path": "cover.png"}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		exists, err := afero.Exists(router.config.AppFs.Fs, "/oc_data/card_uploads/"+course.ID+".png")
+		require.NoError(t, err)
+		require.False(t, exists)
+	})
+
+	t.Run("400 (invalid upload)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		for _, tt := range []struct{ filename, data string }{{"card.txt", "text"}, {"card.png", "not a png"}} {
+			status, body, err := requestHelper(t, router, uploadRequest(t, course.ID, tt.filename, []byte(tt.data)))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, status, tt.filename)
+			require.Contains(t, string(body), "Invalid image")
+		}
+	})
+
+	t.Run("400 (invalid path)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, "/course 1/notes.txt", []byte("notes"), os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, "/course 10/card.png", testPNG(t, 10, 10), os.ModePerm))
+
+		tests := []struct {
+			body     string
+			expected string
+		}{
+			{`{`, "Error parsing data"},
+			{`{"path": ""}`, "A card file or path is required"},
+			{`{"path": "/course 10/card.png"}`, "The card must be within the course"},
+			{`{"path": "../course 10/card.png"}`, "The card must be within the course"},
+			{`{"path": "/course 1"}`, "The card must be within the course"},
+			{`{"path": "notes.txt"}`, "Invalid image"},
+			{`{"path": "missing.png"}`, "Card not found"},
+		}
+
+		for _, tt := range tests {
+			status, body, err := requestHelper(t, router, pathRequest(course.ID, tt.body))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, status, tt.body)
+			require.Contains(t, string(body), tt.expected, tt.body)
+		}
+	})
+
+	t.Run("404 (course not found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, body, err := requestHelper(t, router, pathRequest("invalid", `{"path": "card.png"}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Course not found")
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.COURSE_TABLE)
+		require.NoError(t, err)
+
+		status, _, err := requestHelper(t, router, pathRequest("invalid", `{"path": "card.png"}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_DeleteCard(t *testing.T) {
+	t.Run("204 (upload)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		cardPath, err := router.config.Cards.SaveUpload(course.ID, "card.png", bytes.NewReader(testPNG(t, 10, 10)))
+		require.NoError(t, err)
+
+		course.CardPath = cardPath
+		course.CardOverride = true
+		require.NoError(t, router.dao.UpdateCourse(ctx, course))
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodDelete, "/api/courses/"+course.ID+"/card", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, status)
+
+		courseResult := &models.Course{Base: models.Base{ID: course.ID}}
+		require.NoError(t, router.dao.GetById(ctx, courseResult))
+		require.Empty(t, courseResult.CardPath)
+		require.False(t, courseResult.CardOverride)
+
+		// The upload is removed and a scan is queued to detect the card
+		exists, err := afero.Exists(router.config.AppFs.Fs, cardPath)
+		require.NoError(t, err)
+		require.False(t, exists)
+		require.True(t, courseResult.ScanStatus.IsWaiting())
+	})
+
+	t.Run("204 (no override)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "course 1", Path: "/course 1", CardPath: "/course 1/card.png"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodDelete, "/api/courses/"+course.ID+"/card", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, status)
+
+		courseResult := &models.Course{Base: models.Base{ID: course.ID}}
+		require.NoError(t, router.dao.GetById(ctx, courseResult))
+		require.Equal(t, "/course 1/card.png", courseResult.CardPath)
+	})
+
+	t.Run("404 (course not found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodDelete, "/api/courses/invalid/card", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Course not found")
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.COURSE_TABLE)
+		require.NoError(t, err)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodDelete, "/api/courses/invalid/card", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 func TestCourses_GetAssets(t *testing.T) {
 	t.Run("200 (empty)", func(t *testing.T) {
 		router, ctx := setup(t)
@@ -893,12 +1305,50 @@ func TestCourses_GetAsset(t *testing.T) {
 		require.Equal(t, attachments[3].ID, assetResp.Attachments[1].ID)
 	})
 
-	t.Run("400 (invalid asset for course)", func(t *testing.T) {
+	t.Run("200 (playback)", func(t *testing.T) {
 		router, ctx := setup(t)
 
-		course1 := &models.Course{Title: "course 1", Path: "/course 1"}
-		require.NoError(t, router.dao.CreateCourse(ctx, course1))
-
+		course := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		for _, tt := range []struct {
+			path       string
+			videoCodec string
+			expected   string
+		}{
+			{"/course 1/01 asset.mp4", "h264", "direct"},
+			{"/course 1/02 asset.mkv", "h264", "transcode"},
+			{"/course 1/03 asset.mp4", "hevc", "transcode"},
+			{"/course 1/04 asset.html", "", ""},
+		} {
+			asset := &models.Asset{
+				CourseID:   course.ID,
+				Title:      filepath.Base(tt.path),
+				Prefix:     sql.NullInt16{Int16: 1, Valid: true},
+				Type:       *types.NewAsset(filepath.Ext(tt.path)[1:]),
+				Path:       tt.path,
+				Hash:       security.RandomString(64),
+				VideoCodec: tt.videoCodec,
+			}
+			require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID, nil)
+			status, body, err := requestHelper(t, router, req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, status)
+
+			var assetResp assetResponse
+			require.NoError(t, json.Unmarshal(body, &assetResp))
+			require.Equal(t, tt.expected, assetResp.Playback, tt.path)
+		}
+	})
+
+	t.Run("400 (invalid asset for course)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course1 := &models.Course{Title: "course 1", Path: "/course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course1))
+
 		course2 := &models.Course{Title: "course 2", Path: "/course 2"}
 		require.NoError(t, router.dao.CreateCourse(ctx, course2))
 
@@ -1035,6 +1485,111 @@ func TestCourses_ServeAsset(t *testing.T) {
 		require.Equal(t, "html data", string(body))
 	})
 
+	t.Run("200 (documents)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		tests := []struct {
+			ext      string
+			data     string
+			expected string
+		}{
+			{"md", "# Title\n<script>alert(1)</script>", "<h1>Title</h1>"},
+			{"txt", "a <b> c", "<pre>a &lt;b&gt; c</pre>"},
+			{"ipynb", `{"nbformat": 4, "cells": [{"cell_type": "code", "source": "print(1)", "outputs": [{"output_type": "stream", "name": "stdout", "text": "1"}]}]}`, `<pre class="nb-output nb-stream">1</pre>`},
+		}
+
+		for i, tt := range tests {
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    "asset 1",
+				Prefix:   sql.NullInt16{Int16: int16(i + 1), Valid: true},
+				Type:     *types.NewAsset(tt.ext),
+				Path:     fmt.Sprintf("/%s/asset 1.%s", security.RandomString(4), tt.ext),
+				Hash:     security.RandomString(64),
+			}
+			require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+			require.Nil(t, router.config.AppFs.Fs.MkdirAll(filepath.Dir(asset.Path), os.ModePerm))
+			require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, asset.Path, []byte(tt.data), os.ModePerm))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/serve", nil)
+			resp, err := router.router.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode, tt.ext)
+			require.Equal(t, fiber.MIMETextHTMLCharsetUTF8, resp.Header.Get(fiber.HeaderContentType))
+			require.Contains(t, resp.Header.Get(fiber.HeaderContentSecurityPolicy), "default-src 'none'")
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Contains(t, string(body), "<title>asset 1</title>", tt.ext)
+			require.Contains(t, string(body), tt.expected, tt.ext)
+			require.NotContains(t, string(body), "<script>", tt.ext)
+		}
+	})
+
+	t.Run("302 (link)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Docs",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("webloc"),
+			Path:     "/Course 1/01 Docs.webloc",
+			Hash:     security.RandomString(64),
+			URL:      "https://example.com/docs",
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, asset.Path, []byte("webloc"), os.ModePerm))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID, nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var respData assetResponse
+		require.NoError(t, json.Unmarshal(body, &respData))
+		require.Equal(t, "https://example.com/docs", respData.URL)
+
+		req = httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/serve", nil)
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		require.Equal(t, "https://example.com/docs", resp.Header.Get(fiber.HeaderLocation))
+	})
+
+	t.Run("422 (invalid notebook)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("ipynb"),
+			Path:     fmt.Sprintf("/%s/asset 1.ipynb", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		require.Nil(t, router.config.AppFs.Fs.MkdirAll(filepath.Dir(asset.Path), os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, asset.Path, []byte("not json"), os.ModePerm))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/serve", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+		require.Contains(t, string(body), "Invalid notebook")
+	})
+
 	t.Run("400 (invalid asset for course)", func(t *testing.T) {
 		router, ctx := setup(t)
 
@@ -1143,6 +1698,222 @@ func TestCourses_ServeAsset(t *testing.T) {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+func TestCourses_StreamAsset(t *testing.T) {
+	// createVideo creates a course with a video asset, written to the app filesystem
+	createVideo := func(t *testing.T, router *Router, ctx context.Context, name string) (*models.Course, *models.Asset) {
+		t.Helper()
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID:   course.ID,
+			Title:      "asset 1",
+			Prefix:     sql.NullInt16{Int16: 1, Valid: true},
+			Type:       *types.NewAsset("mkv"),
+			Path:       "/Course 1/" + name,
+			Hash:       security.RandomString(64),
+			VideoCodec: "hevc",
+			AudioCodec: "aac",
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		require.Nil(t, router.config.AppFs.Fs.MkdirAll(filepath.Dir(asset.Path), os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, asset.Path, []byte("video"), os.ModePerm))
+
+		return course, asset
+	}
+
+	// enableFFmpeg configures a ffmpeg stub
+	enableFFmpeg := func(t *testing.T, router *Router, ctx context.Context) {
+		t.Helper()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("ffmpeg stub requires a unix shell")
+		}
+
+		ffmpegPath, err := mocks.StubFFmpeg(t.TempDir())
+		require.NoError(t, err)
+		require.NoError(t, router.dao.UpdateFFmpegPath(ctx, ffmpegPath))
+	}
+
+	t.Run("200 (mp4)", func(t *testing.T) {
+		router, ctx := setup(t)
+		enableFFmpeg(t, router, ctx)
+		course, asset := createVideo(t, router, ctx, "01 video.mkv")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/video.mp4", nil)
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "video/mp4", resp.Header.Get(fiber.HeaderContentType))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "fragmented mp4", string(body))
+
+		// Range
+		req = httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/video.mp4", nil)
+		req.Header.Set("Range", "bytes=0-9")
+
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusPartialContent, status)
+		require.Equal(t, "fragmented", string(body))
+	})
+
+	t.Run("200 (hls)", func(t *testing.T) {
+		router, ctx := setup(t)
+		enableFFmpeg(t, router, ctx)
+		course, asset := createVideo(t, router, ctx, "01 video.mkv")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/index.m3u8", nil)
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/vnd.apple.mpegurl", resp.Header.Get(fiber.HeaderContentType))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), "segment00000.ts")
+
+		req = httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/segment00000.ts", nil)
+		resp, err = router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "video/mp2t", resp.Header.Get(fiber.HeaderContentType))
+
+		body, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "segment", string(body))
+	})
+
+	t.Run("400 (invalid file)", func(t *testing.T) {
+		router, ctx := setup(t)
+		course, asset := createVideo(t, router, ctx, "01 video.mkv")
+
+		for _, file := range []string{"video.mkv", "segment1.ts", "..%2Fvideo.mp4"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/"+file, nil)
+			status, body, err := requestHelper(t, router, req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, status)
+			require.Contains(t, string(body), "Invalid stream file")
+		}
+	})
+
+	t.Run("400 (not a video)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("html"),
+			Path:     "/Course 1/01 asset.html",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/video.mp4", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset is not a video")
+	})
+
+	t.Run("400 (invalid course)", func(t *testing.T) {
+		router, ctx := setup(t)
+		_, asset := createVideo(t, router, ctx, "01 video.mkv")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/invalid/assets/"+asset.ID+"/stream/video.mp4", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset does not belong to course")
+	})
+
+	t.Run("400 (invalid path)", func(t *testing.T) {
+		router, ctx := setup(t)
+		enableFFmpeg(t, router, ctx)
+		course, asset := createVideo(t, router, ctx, "01 video.mkv")
+
+		require.NoError(t, router.config.AppFs.Fs.Remove(asset.Path))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/video.mp4", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset does not exist")
+	})
+
+	t.Run("404 (asset not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/invalid/stream/video.mp4", nil)
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("404 (segment not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+		enableFFmpeg(t, router, ctx)
+		course, asset := createVideo(t, router, ctx, "01 video.mkv")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/segment00099.ts", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Stream file not found")
+	})
+
+	t.Run("500 (ffmpeg error)", func(t *testing.T) {
+		router, ctx := setup(t)
+		enableFFmpeg(t, router, ctx)
+		course, asset := createVideo(t, router, ctx, "01 fail.mkv")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/video.mp4", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+		require.Contains(t, string(body), "Error transcoding asset")
+	})
+
+	t.Run("500 (asset internal error)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.ASSET_TABLE)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/invalid/stream/video.mp4", nil)
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+
+	t.Run("503 (ffmpeg not configured)", func(t *testing.T) {
+		router, ctx := setup(t)
+		course, asset := createVideo(t, router, ctx, "01 video.mkv")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/stream/video.mp4", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, status)
+		require.Contains(t, string(body), "ffmpeg is not configured")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 func TestCourses_updateAssetProgress(t *testing.T) {
 	t.Run("200 (found)", func(t *testing.T) {
 		router, ctx := setup(t)
@@ -1177,7 +1948,7 @@ func TestCourses_updateAssetProgress(t *testing.T) {
 		require.Equal(t, http.StatusNoContent, status)
 
 		assetResult := &models.Asset{Base: models.Base{ID: asset.ID}}
-		require.NoError(t, router.dao.GetById(ctx, assetResult))
+		require.NoError(t, router.dao.LoadAssetProgress(ctx, []*models.Asset{assetResult}, ""))
 		require.Equal(t, 45, assetResult.Progress.VideoPos)
 		require.False(t, assetResult.Progress.Completed)
 		require.True(t, assetResult.Progress.CompletedAt.IsZero())
@@ -1195,7 +1966,7 @@ func TestCourses_updateAssetProgress(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, http.StatusNoContent, status)
 
-		require.NoError(t, router.dao.GetById(ctx, assetResult))
+		require.NoError(t, router.dao.LoadAssetProgress(ctx, []*models.Asset{assetResult}, ""))
 		require.Equal(t, 45, assetResult.Progress.VideoPos)
 		require.True(t, assetResult.Progress.Completed)
 		require.False(t, assetResult.Progress.CompletedAt.IsZero())
@@ -1214,12 +1985,46 @@ func TestCourses_updateAssetProgress(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, http.StatusNoContent, status)
 
-		require.NoError(t, router.dao.GetById(ctx, assetResult))
+		require.NoError(t, router.dao.LoadAssetProgress(ctx, []*models.Asset{assetResult}, ""))
 		require.Equal(t, 10, assetResult.Progress.VideoPos)
 		require.False(t, assetResult.Progress.Completed)
 		require.True(t, assetResult.Progress.CompletedAt.IsZero())
 	})
 
+	t.Run("200 (scroll position)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("md"),
+			Path:     fmt.Sprintf("/%s/asset 1.md", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		req := httptest.NewRequest(http.MethodPut, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/progress", strings.NewReader(`{"scrollPos": 35}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, status)
+
+		req = httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID, nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var assetResp assetResponse
+		require.NoError(t, json.Unmarshal(body, &assetResp))
+		require.Equal(t, 35, assetResp.Progress.ScrollPos)
+		require.Equal(t, 0, assetResp.Progress.VideoPos)
+	})
+
 	t.Run("400 (invalid data)", func(t *testing.T) {
 		router, _ := setup(t)
 
@@ -1272,14 +2077,16 @@ func TestCourses_updateAssetProgress(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, status)
 		require.Contains(t, string(body), "Asset does not belong to course")
 	})
-}
-
-// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-func TestCourses_GetAttachments(t *testing.T) {
-	t.Run("200 (empty)", func(t *testing.T) {
+	t.Run("200 (per user)", func(t *testing.T) {
 		router, ctx := setup(t)
 
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "user", "password", types.UserRoleUser)
+
+		adminCookie := loginHelper(t, router, "admin", "password")
+		userCookie := loginHelper(t, router, "user", "password")
+
 		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
 		require.NoError(t, router.dao.CreateCourse(ctx, course))
 
@@ -1294,24 +2101,89 @@ func TestCourses_GetAttachments(t *testing.T) {
 		}
 		require.NoError(t, router.dao.CreateAsset(ctx, asset))
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments", nil)
-		status, body, err := requestHelper(t, router, req)
-		require.NoError(t, err)
+		// The user completes the asset
+		assetPath := "/api/courses/" + course.ID + "/assets/" + asset.ID
+		status, _ := userRequestHelper(t, router, http.MethodPut, assetPath+"/progress", `{"videoPos": 10, "completed": true}`, userCookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		// The user sees their progress
+		status, body := userRequestHelper(t, router, http.MethodGet, assetPath, "", userCookie)
 		require.Equal(t, http.StatusOK, status)
 
-		paginationResp, _ := unmarshalHelper[attachmentResponse](t, body)
-		require.Zero(t, int(paginationResp.TotalItems))
-		require.Zero(t, len(paginationResp.Items))
-	})
+		var assetResp assetResponse
+		require.NoError(t, json.Unmarshal(body, &assetResp))
+		require.NotNil(t, assetResp.Progress)
+		require.True(t, assetResp.Progress.Completed)
 
-	t.Run("200 (found)", func(t *testing.T) {
-		router, ctx := setup(t)
+		status, body = userRequestHelper(t, router, http.MethodGet, "/api/courses/?progress=completed", "", userCookie)
+		require.Equal(t, http.StatusOK, status)
 
-		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
-		require.NoError(t, router.dao.CreateCourse(ctx, course))
+		paginationResp, coursesResp := unmarshalHelper[courseResponse](t, body)
+		require.Equal(t, 1, int(paginationResp.TotalItems))
+		require.Equal(t, 100, coursesResp[0].Progress.Percent)
 
-		asset := &models.Asset{
-			CourseID: course.ID,
+		// The admin does not
+		status, body = userRequestHelper(t, router, http.MethodGet, assetPath, "", adminCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		assetResp = assetResponse{}
+		require.NoError(t, json.Unmarshal(body, &assetResp))
+		require.False(t, assetResp.Progress.Completed)
+		require.Zero(t, assetResp.Progress.VideoPos)
+
+		status, body = userRequestHelper(t, router, http.MethodGet, "/api/courses/?progress=completed", "", adminCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, _ = unmarshalHelper[courseResponse](t, body)
+		require.Zero(t, int(paginationResp.TotalItems))
+
+		status, body = userRequestHelper(t, router, http.MethodGet, "/api/courses/?progress=not%20started", "", adminCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, coursesResp = unmarshalHelper[courseResponse](t, body)
+		require.Equal(t, 1, int(paginationResp.TotalItems))
+		require.Zero(t, coursesResp[0].Progress.Percent)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_GetAttachments(t *testing.T) {
+	t.Run("200 (empty)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, _ := unmarshalHelper[attachmentResponse](t, body)
+		require.Zero(t, int(paginationResp.TotalItems))
+		require.Zero(t, len(paginationResp.Items))
+	})
+
+	t.Run("200 (found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
 			Title:    "asset 1",
 			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
 			Chapter:  "Chapter 1",
@@ -1498,144 +2370,1429 @@ func TestCourses_GetAttachments(t *testing.T) {
 		}
 		require.NoError(t, router.dao.CreateAsset(ctx, asset))
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course1.ID+"/assets/"+asset.ID+"/attachments", nil)
-		status, body, err := requestHelper(t, router, req)
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course1.ID+"/assets/"+asset.ID+"/attachments", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset does not belong to course")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_GetAttachment(t *testing.T) {
+	t.Run("200 (found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		attachment := &models.Attachment{
+			AssetID: asset.ID,
+			Title:   "attachment 1",
+			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
+		}
+		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/"+attachment.ID, nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var respData attachmentResponse
+		err = json.Unmarshal(body, &respData)
+		require.NoError(t, err)
+		require.Equal(t, attachment.ID, respData.ID)
+	})
+
+	t.Run("200 (link)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		file := &models.Attachment{AssetID: asset.ID, Title: "notes.zip", Path: "/Course 1/01 notes.zip"}
+		require.NoError(t, router.dao.CreateAttachment(ctx, file))
+
+		link := &models.Attachment{AssetID: asset.ID, Title: "Docs", Path: "/Course 1/01 Docs.url", URL: "https://example.com/docs"}
+		require.NoError(t, router.dao.CreateAttachment(ctx, link))
+
+		attachmentsURL := "/api/courses/" + course.ID + "/assets/" + asset.ID + "/attachments/"
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, attachmentsURL+file.ID, nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var respData attachmentResponse
+		require.NoError(t, json.Unmarshal(body, &respData))
+		require.Equal(t, attachmentTypeFile, respData.Type)
+		require.Empty(t, respData.URL)
+
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, attachmentsURL+link.ID, nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		require.NoError(t, json.Unmarshal(body, &respData))
+		require.Equal(t, attachmentTypeLink, respData.Type)
+		require.Equal(t, "Docs", respData.Title)
+		require.Equal(t, "https://example.com/docs", respData.URL)
+	})
+
+	t.Run("400 (invalid asset for course)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course1 := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course1))
+
+		course2 := &models.Course{Title: "Course 2", Path: "/course/2"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course2))
+
+		asset := &models.Asset{
+			CourseID: course2.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course1.ID+"/assets/"+asset.ID+"/attachments/invalid", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset does not belong to course")
+	})
+
+	t.Run("400 (invalid attachment for asset)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset1 := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset1))
+
+		asset2 := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 2",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 2",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 2", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset2))
+
+		attachment := &models.Attachment{
+			AssetID: asset1.ID,
+			Title:   "attachment 1",
+			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
+		}
+		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset2.ID+"/attachments/"+attachment.ID, nil)
+
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Attachment does not belong to asset")
+	})
+
+	t.Run("404 (course not found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/invalid/assets/invalid/attachments/invalid", nil)
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("404 (asset not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/invalid/attachments/invalid", nil)
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("404 (attachment not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/invalid", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Attachment not found")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_ServeAttachment(t *testing.T) {
+	t.Run("200 (ok)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		attachment := &models.Attachment{
+			AssetID: asset.ID,
+			Title:   "attachment 1",
+			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
+		}
+		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+
+		require.Nil(t, router.config.AppFs.Fs.MkdirAll(filepath.Dir(attachment.Path), os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, attachment.Path, []byte("hello"), os.ModePerm))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/"+attachment.ID+"/serve", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "hello", string(body))
+	})
+
+	t.Run("302 (link)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		attachment := &models.Attachment{AssetID: asset.ID, Title: "Docs", Path: "/Course 1/01 Docs.url", URL: "https://example.com/docs"}
+		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, attachment.Path, []byte("[InternetShortcut]\nURL=https://example.com/docs"), os.ModePerm))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/"+attachment.ID+"/serve", nil)
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		require.Equal(t, "https://example.com/docs", resp.Header.Get(fiber.HeaderLocation))
+	})
+
+	t.Run("400 (invalid path)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		attachment := &models.Attachment{
+			AssetID: asset.ID,
+			Title:   "attachment 1",
+			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
+		}
+		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/"+attachment.ID+"/serve", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Attachment does not exist")
+	})
+
+	t.Run("400 (invalid asset for course)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course1 := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course1))
+
+		course2 := &models.Course{Title: "Course 2", Path: "/course/2"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course2))
+
+		asset := &models.Asset{
+			CourseID: course2.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course1.ID+"/assets/"+asset.ID+"/attachments/invalid/serve", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset does not belong to course")
+	})
+
+	t.Run("400 (invalid attachment for asset)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		assets := []*models.Asset{}
+		for j := range 2 {
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    fmt.Sprintf("asset %d", j+1),
+				Prefix:   sql.NullInt16{Int16: int16(j + 1), Valid: true},
+				Chapter:  fmt.Sprintf("Chapter %d", j+1),
+				Type:     *types.NewAsset("mp4"),
+				Path:     fmt.Sprintf("/%s/asset %d", security.RandomString(4), j+1),
+				Hash:     security.RandomString(64),
+			}
+			require.NoError(t, router.dao.CreateAsset(ctx, asset))
+			assets = append(assets, asset)
+		}
+
+		attachment := &models.Attachment{
+			AssetID: assets[0].ID,
+			Title:   "attachment 1",
+			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
+		}
+		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+assets[1].ID+"/attachments/"+attachment.ID+"/serve", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Attachment does not belong to asset")
+	})
+
+	t.Run("404 (asset not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/invalid/attachments/invalid/serve", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Asset not found")
+	})
+
+	t.Run("404 (attachment not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Chapter:  "Chapter 1",
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/invalid/serve", nil)
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Attachment not found")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_PreviewAttachment(t *testing.T) {
+	previewHelper := func(t *testing.T, router *Router, url string) *attachmentPreviewResponse {
+		t.Helper()
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/preview", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp attachmentPreviewResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+
+		return &resp
+	}
+
+	t.Run("200 (markdown)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "notes.md", []byte("# Notes\n\n<script>alert(1)</script>"))
+
+		resp := previewHelper(t, router, url)
+		require.Equal(t, "markdown", resp.Kind)
+		require.Contains(t, resp.HTML, "<h1")
+		require.NotContains(t, resp.HTML, "<script")
+		require.Empty(t, resp.Content)
+		require.Equal(t, url+"/serve", resp.URL)
+	})
+
+	t.Run("200 (code)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "main.py", []byte("print('hi')\n"))
+
+		resp := previewHelper(t, router, url)
+		require.Equal(t, "code", resp.Kind)
+		require.Equal(t, "python", resp.Language)
+		require.Equal(t, "print('hi')\n", resp.Content)
+		require.EqualValues(t, 12, resp.Size)
+	})
+
+	t.Run("200 (text truncated)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "notes.txt", bytes.Repeat([]byte("a"), preview.MaxTextSize+10))
+
+		resp := previewHelper(t, router, url)
+		require.Equal(t, "text", resp.Kind)
+		require.True(t, resp.Truncated)
+		require.Len(t, resp.Content, preview.MaxTextSize)
+		require.EqualValues(t, preview.MaxTextSize+10, resp.Size)
+	})
+
+	t.Run("200 (image)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "diagram.png", testPNG(t, 10, 10))
+
+		resp := previewHelper(t, router, url)
+		require.Equal(t, "image", resp.Kind)
+		require.Equal(t, url+"/serve?inline=true", resp.URL)
+
+		// The image is served inline
+		res, err := router.router.Test(httptest.NewRequest(http.MethodGet, resp.URL, nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "inline", res.Header.Get(fiber.HeaderContentDisposition))
+	})
+
+	t.Run("200 (download)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		for _, tt := range []struct {
+			filename string
+			data     []byte
+		}{
+			{"course.pdf", []byte("%PDF-1.4")},
+			{"data.bin", []byte{0x00, 0x01, 0x02}},
+			{"huge.png", make([]byte, preview.MaxImageSize+1)},
+		} {
+			url := attachmentHelper(t, router, ctx, tt.filename, tt.data)
+
+			resp := previewHelper(t, router, url)
+			require.Equal(t, "download", resp.Kind, tt.filename)
+			require.Equal(t, url+"/serve", resp.URL, tt.filename)
+			require.Empty(t, resp.Content, tt.filename)
+
+			// Clean up, as the course path is shared
+			_, err := router.config.DbManager.DataDb.Exec("DELETE FROM " + models.COURSE_TABLE)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("200 (html is never inline)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "page.html", []byte("<script>alert(1)</script>"))
+
+		resp := previewHelper(t, router, url)
+		require.Equal(t, "code", resp.Kind)
+		require.Equal(t, "xml", resp.Language)
+
+		res, err := router.router.Test(httptest.NewRequest(http.MethodGet, url+"/serve?inline=true", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, `attachment; filename="page.html"`, res.Header.Get(fiber.HeaderContentDisposition))
+	})
+
+	t.Run("400 (invalid path)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "notes.md", []byte("notes"))
+
+		require.NoError(t, router.config.AppFs.Fs.Remove("/Course 1/01 notes.md"))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/preview", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Attachment does not exist")
+	})
+
+	t.Run("404 (attachment not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "notes.md", []byte("notes"))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, filepath.Dir(url)+"/invalid/preview", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Attachment not found")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_AttachmentEntries(t *testing.T) {
+	zipHelper := func(t *testing.T) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+
+		for _, entry := range []struct {
+			name    string
+			method  uint16
+			content []byte
+		}{
+			{"exercises/", zip.Store, nil},
+			{"exercises/01 start.py", zip.Deflate, []byte("print('start')\n")},
+			{"notes/read me.md", zip.Deflate, []byte("# Read me")},
+			{"images/diagram.png", zip.Store, testPNG(t, 10, 10)},
+			{"data.txt", zip.Deflate, []byte("0123456789")},
+			{"../evil.sh", zip.Deflate, []byte("evil")},
+		} {
+			f, err := w.CreateHeader(&zip.FileHeader{Name: entry.name, Method: entry.method})
+			require.NoError(t, err)
+
+			_, err = f.Write(entry.content)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, w.Close())
+
+		return buf.Bytes()
+	}
+
+	t.Run("200 (list)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "Resources.zip", zipHelper(t))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/entries", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var entries []*archiveEntryResponse
+		require.NoError(t, json.Unmarshal(body, &entries))
+
+		paths := []string{}
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+
+		require.Equal(t, []string{"data.txt", "exercises", "exercises/01 start.py", "images/diagram.png", "notes/read me.md"}, paths)
+		require.True(t, entries[1].IsDir)
+		require.EqualValues(t, 10, entries[0].Size)
+	})
+
+	t.Run("200 (serve)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "Resources.zip", zipHelper(t))
+
+		resp, err := router.router.Test(httptest.NewRequest(http.MethodGet, url+"/entries/exercises/01%20start.py", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, `attachment; filename="01 start.py"`, resp.Header.Get(fiber.HeaderContentDisposition))
+		require.Equal(t, "bytes", resp.Header.Get(fiber.HeaderAcceptRanges))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "print('start')\n", string(body))
+	})
+
+	t.Run("206 (range)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "Resources.zip", zipHelper(t))
+
+		for _, tt := range []struct {
+			byteRange    string
+			contentRange string
+			expected     string
+		}{
+			{"bytes=2-5", "bytes 2-5/10", "2345"},
+			{"bytes=7-", "bytes 7-9/10", "789"},
+			{"bytes=-3", "bytes 7-9/10", "789"},
+		} {
+			req := httptest.NewRequest(http.MethodGet, url+"/entries/data.txt", nil)
+			req.Header.Set(fiber.HeaderRange, tt.byteRange)
+
+			resp, err := router.router.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusPartialContent, resp.StatusCode, tt.byteRange)
+			require.Equal(t, tt.contentRange, resp.Header.Get(fiber.HeaderContentRange), tt.byteRange)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(body), tt.byteRange)
+		}
+
+		// Stored entries are read directly from the archive
+		req := httptest.NewRequest(http.MethodGet, url+"/entries/images/diagram.png?inline=true", nil)
+		req.Header.Set(fiber.HeaderRange, "bytes=1-3")
+
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		require.Equal(t, "inline", resp.Header.Get(fiber.HeaderContentDisposition))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "PNG", string(body))
+	})
+
+	t.Run("200 (preview)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "Resources.zip", zipHelper(t))
+
+		// The archive itself
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/preview", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp attachmentPreviewResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Equal(t, "archive", resp.Kind)
+
+		// An entry
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/entries/notes/read%20me.md?preview=true", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		resp = attachmentPreviewResponse{}
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Equal(t, "markdown", resp.Kind)
+		require.Contains(t, resp.HTML, "Read me</h1>")
+		require.Equal(t, url+"/entries/notes/read%20me.md", resp.URL)
+
+		// An image entry
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/entries/images/diagram.png?preview=true", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		resp = attachmentPreviewResponse{}
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Equal(t, "image", resp.Kind)
+		require.Equal(t, url+"/entries/images/diagram.png?inline=true", resp.URL)
+	})
+
+	t.Run("400 (not an archive)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "notes.txt", []byte("notes"))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/entries", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Attachment is not a ZIP archive")
+	})
+
+	t.Run("400 (invalid archive)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "Resources.zip", []byte("not a zip"))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/entries", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Invalid ZIP archive")
+	})
+
+	t.Run("404 (entry not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "Resources.zip", zipHelper(t))
+
+		for _, name := range []string{"missing.txt", "exercises", "../evil.sh", "..%2Fevil.sh", "%2E%2E/%2E%2E/etc/passwd"} {
+			status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/entries/"+name, nil))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusNotFound, status, name)
+			require.Contains(t, string(body), "Entry not found", name)
+		}
+	})
+
+	t.Run("416 (invalid range)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "Resources.zip", zipHelper(t))
+
+		req := httptest.NewRequest(http.MethodGet, url+"/entries/data.txt", nil)
+		req.Header.Set(fiber.HeaderRange, "bytes=20-30")
+
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+		require.Equal(t, "bytes */10", resp.Header.Get(fiber.HeaderContentRange))
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_ServeSubtitle(t *testing.T) {
+	t.Run("200 (srt)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		subtitle := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: "/Course 1/01 asset 1.en.srt"}
+		require.NoError(t, router.dao.CreateSubtitle(ctx, subtitle))
+
+		require.Nil(t, router.config.AppFs.Fs.MkdirAll(course.Path, os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, subtitle.Path, []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"), os.ModePerm))
+
+		// The language is case-insensitive
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/subtitles/EN", nil)
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/vtt; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/subtitles/en", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nHello\n", string(body))
+
+		// The subtitle is listed on the asset
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID, nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var assetResp assetResponse
+		require.NoError(t, json.Unmarshal(body, &assetResp))
+		require.Len(t, assetResp.Subtitles, 1)
+		require.Equal(t, "en", assetResp.Subtitles[0].Language)
+		require.Equal(t, "srt", assetResp.Subtitles[0].Format)
+	})
+
+	t.Run("400 (invalid path)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		require.NoError(t, router.dao.CreateSubtitle(ctx, &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: "/Course 1/01 asset 1.en.srt"}))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/subtitles/en", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Subtitle does not exist")
+	})
+
+	t.Run("400 (invalid asset for course)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course1 := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course1))
+
+		course2 := &models.Course{Title: "Course 2", Path: "/Course 2"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course2))
+
+		asset := &models.Asset{
+			CourseID: course1.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course2.ID+"/assets/"+asset.ID+"/subtitles/en", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset does not belong to course")
+	})
+
+	t.Run("404 (asset not found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/invalid/assets/invalid/subtitles/en", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Asset not found")
+	})
+
+	t.Run("404 (subtitle not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/subtitles/fr", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Subtitle not found")
+	})
+
+	t.Run("422 (malformed)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		subtitle := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "ass", Path: "/Course 1/01 asset 1.en.ass"}
+		require.NoError(t, router.dao.CreateSubtitle(ctx, subtitle))
+
+		require.Nil(t, router.config.AppFs.Fs.MkdirAll(course.Path, os.ModePerm))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, subtitle.Path, []byte("not a subtitle"), os.ModePerm))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/subtitles/en", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+		require.Contains(t, string(body), "Unable to convert subtitle")
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.SUBTITLE_TABLE)
+		require.NoError(t, err)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/subtitles/en", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_GetChapterMarkers(t *testing.T) {
+	t.Run("200 (found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+			Duration: 90,
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		require.NoError(t, router.dao.ReplaceChapterMarkers(ctx, asset.ID, []*models.ChapterMarker{
+			{Title: "Intro", Start: 0},
+			{Title: "Tips & <tricks>", Start: 30000},
+		}))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/chapters", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var markersResp []*chapterMarkerResponse
+		require.NoError(t, json.Unmarshal(body, &markersResp))
+		require.Len(t, markersResp, 2)
+		require.Equal(t, "Intro", markersResp[0].Title)
+		require.Zero(t, markersResp[0].Start)
+		require.Equal(t, 30000, markersResp[0].End)
+		require.Equal(t, 30000, markersResp[1].Start)
+		require.Equal(t, 90000, markersResp[1].End)
+	})
+
+	t.Run("200 (vtt)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+			Duration: 90,
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		require.NoError(t, router.dao.ReplaceChapterMarkers(ctx, asset.ID, []*models.ChapterMarker{
+			{Title: "Intro", Start: 0},
+			{Title: "Tips & <tricks>", Start: 30000},
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/chapters?format=vtt", nil)
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/vtt; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n\n1\n00:00:00.000 --> 00:00:30.000\nIntro\n\n2\n00:00:30.000 --> 00:01:30.000\nTips &amp; &lt;tricks&gt;\n", string(body))
+	})
+
+	t.Run("200 (empty)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/chapters", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "[]", string(body))
+	})
+
+	t.Run("400 (invalid format)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/1/assets/2/chapters?format=srt", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("400 (invalid course)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/invalid/assets/"+asset.ID+"/chapters", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset does not belong to course")
+	})
+
+	t.Run("404 (asset not found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/1/assets/2/chapters", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.CHAPTER_MARKER_TABLE)
+		require.NoError(t, err)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/chapters", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCourses_EPUB(t *testing.T) {
+	// epubHelper creates a course and an EPUB asset, and returns the URL of the asset
+	epubHelper := func(t *testing.T, router *Router, ctx context.Context, data []byte) string {
+		t.Helper()
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "book",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("epub"),
+			Path:     "/Course 1/01 book.epub",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, asset.Path, data, os.ModePerm))
+
+		return "/api/courses/" + course.ID + "/assets/" + asset.ID
+	}
+
+	bookHelper := func(t *testing.T) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+
+		for name, content := range map[string]string{
+			"mimetype":               "application/epub+zip",
+			"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`,
+			"OEBPS/content.opf": `<package><metadata><title>The Book</title></metadata><manifest>
+				<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+				<item id="c1" href="text/chapter 1.xhtml" media-type="application/xhtml+xml"/>
+				<item id="c2" href="text/chapter2.xhtml" media-type="application/xhtml+xml"/>
+				<item id="notes" href="text/notes.xhtml" media-type="application/xhtml+xml"/>
+				<item id="css" href="book.css" media-type="text/css"/>
+				</manifest><spine><itemref idref="c1"/><itemref idref="c2"/><itemref idref="notes" linear="no"/></spine></package>`,
+			"OEBPS/nav.xhtml": `<html><body><nav epub:type="toc"><ol>
+				<li><a href="text/chapter%201.xhtml">One</a></li>
+				<li><a href="text/chapter2.xhtml#s1">Two</a></li>
+				</ol></nav></body></html>`,
+			"OEBPS/text/chapter 1.xhtml": "<html><body>one<script>alert(1)</script></body></html>",
+			"OEBPS/text/chapter2.xhtml":  "<html><body>two</body></html>",
+			"OEBPS/text/notes.xhtml":     "<html><body>notes</body></html>",
+			"OEBPS/book.css":             "body {}",
+		} {
+			f, err := w.Create(name)
+			require.NoError(t, err)
+
+			_, err = f.Write([]byte(content))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, w.Close())
+
+		return buf.Bytes()
+	}
+
+	t.Run("200 (book)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := epubHelper(t, router, ctx, bookHelper(t))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/epub", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp epubResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+
+		require.Equal(t, "The Book", resp.Title)
+		require.Zero(t, resp.Position)
+
+		require.Len(t, resp.Spine, 3)
+		require.Equal(t, "OEBPS/text/chapter 1.xhtml", resp.Spine[0].Path)
+		require.Equal(t, url+"/epub/OEBPS/text/chapter%201.xhtml", resp.Spine[0].URL)
+		require.True(t, resp.Spine[0].Linear)
+		require.False(t, resp.Spine[2].Linear)
+
+		require.Len(t, resp.TOC, 2)
+		require.Equal(t, "One", resp.TOC[0].Title)
+		require.Equal(t, 0, resp.TOC[0].SpineIndex)
+		require.Equal(t, "Two", resp.TOC[1].Title)
+		require.Equal(t, 1, resp.TOC[1].SpineIndex)
+		require.Equal(t, url+"/epub/OEBPS/text/chapter2.xhtml#s1", resp.TOC[1].URL)
+	})
+
+	t.Run("200 (resource)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := epubHelper(t, router, ctx, bookHelper(t))
+
+		resp, err := router.router.Test(httptest.NewRequest(http.MethodGet, url+"/epub/OEBPS/text/chapter%201.xhtml", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/xhtml+xml", resp.Header.Get(fiber.HeaderContentType))
+		require.Contains(t, resp.Header.Get(fiber.HeaderContentSecurityPolicy), "script-src 'none'")
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "<html><body>one<script>alert(1)</script></body></html>", string(body))
+
+		// Media type from the manifest
+		resp, err = router.router.Test(httptest.NewRequest(http.MethodGet, url+"/epub/OEBPS/book.css", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/css", resp.Header.Get(fiber.HeaderContentType))
+	})
+
+	t.Run("200 (download)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := epubHelper(t, router, ctx, bookHelper(t))
+
+		resp, err := router.router.Test(httptest.NewRequest(http.MethodGet, url+"/serve", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, `attachment; filename="01 book.epub"`, resp.Header.Get(fiber.HeaderContentDisposition))
+	})
+
+	t.Run("204 (progress)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := epubHelper(t, router, ctx, bookHelper(t))
+
+		for _, data := range []string{`{"spineIndex": 0, "scrollPos": 100}`, `{"spineIndex": 1, "scrollPos": 50}`} {
+			req := httptest.NewRequest(http.MethodPut, url+"/epub/progress", strings.NewReader(data))
+			req.Header.Set("Content-Type", "application/json")
+
+			status, _, err := requestHelper(t, router, req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusNoContent, status)
+		}
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/epub", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp epubResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Equal(t, 1, resp.Position)
+		require.Equal(t, 100, resp.Spine[0].ScrollPos)
+		require.Equal(t, 50, resp.Spine[1].ScrollPos)
+		require.Zero(t, resp.Spine[2].ScrollPos)
+
+		// The asset progress is the average of the spine items in the reading order
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, url, nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var assetResp assetResponse
+		require.NoError(t, json.Unmarshal(body, &assetResp))
+		require.Equal(t, 75, assetResp.Progress.ScrollPos)
+		require.False(t, assetResp.Progress.Completed)
+	})
+
+	t.Run("400 (progress)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := epubHelper(t, router, ctx, bookHelper(t))
+
+		for data, expected := range map[string]string{
+			`bob`:                                "Error parsing data",
+			`{"spineIndex": 3, "scrollPos": 10}`: "Invalid spine index",
+			`{"spineIndex": -1}`:                 "Invalid spine index",
+			`{"spineIndex": 2, "scrollPos": 10}`: "Spine item is not in the reading order",
+		} {
+			req := httptest.NewRequest(http.MethodPut, url+"/epub/progress", strings.NewReader(data))
+			req.Header.Set("Content-Type", "application/json")
+
+			status, body, err := requestHelper(t, router, req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, status, data)
+			require.Contains(t, string(body), expected, data)
+		}
+	})
+
+	t.Run("400 (not an epub)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := attachmentHelper(t, router, ctx, "file.txt", []byte("text"))
+		url = url[:strings.Index(url, "/attachments/")]
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/epub", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset is not an EPUB")
+	})
+
+	t.Run("400 (invalid epub)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := epubHelper(t, router, ctx, []byte("not a zip"))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/epub", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Invalid EPUB")
+	})
+
+	t.Run("404 (resource not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := epubHelper(t, router, ctx, bookHelper(t))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/epub/OEBPS/missing.xhtml", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Resource not found")
+	})
+
+	t.Run("404 (asset not found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/invalid/assets/invalid/epub", nil))
 		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, status)
-		require.Contains(t, string(body), "Asset does not belong to course")
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Asset not found")
 	})
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-func TestCourses_GetAttachment(t *testing.T) {
-	t.Run("200 (found)", func(t *testing.T) {
-		router, ctx := setup(t)
+func TestCourses_Slides(t *testing.T) {
+	// slidesHelper creates a course and a slide deck asset with 3 slides, and returns the URL of
+	// the asset
+	slidesHelper := func(t *testing.T, router *Router, ctx context.Context) string {
+		t.Helper()
 
 		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
 		require.NoError(t, router.dao.CreateCourse(ctx, course))
 
 		asset := &models.Asset{
 			CourseID: course.ID,
-			Title:    "asset 1",
+			Title:    "deck",
 			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 1",
-			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Type:     *types.NewAsset("png"),
+			Path:     "/Course 1/01 deck",
 			Hash:     security.RandomString(64),
 		}
 		require.NoError(t, router.dao.CreateAsset(ctx, asset))
 
-		attachment := &models.Attachment{
-			AssetID: asset.ID,
-			Title:   "attachment 1",
-			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
+		for _, name := range []string{"slide10.png", "slide2.png", "slide1.png", ".DS_Store"} {
+			require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, filepath.Join(asset.Path, name), []byte(name), os.ModePerm))
 		}
-		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/"+attachment.ID, nil)
-		status, body, err := requestHelper(t, router, req)
+		return "/api/courses/" + course.ID + "/assets/" + asset.ID
+	}
+
+	t.Run("200 (slides)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := slidesHelper(t, router, ctx)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/slides", nil))
 		require.NoError(t, err)
 		require.Equal(t, http.StatusOK, status)
 
-		var respData attachmentResponse
-		err = json.Unmarshal(body, &respData)
-		require.NoError(t, err)
-		require.Equal(t, attachment.ID, respData.ID)
-	})
+		var resp slidesResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
 
-	t.Run("400 (invalid asset for course)", func(t *testing.T) {
-		router, ctx := setup(t)
+		require.Zero(t, resp.Position)
+		require.Len(t, resp.Slides, 3)
 
-		course1 := &models.Course{Title: "Course 1", Path: "/Course 1"}
-		require.NoError(t, router.dao.CreateCourse(ctx, course1))
+		for i, name := range []string{"slide1.png", "slide2.png", "slide10.png"} {
+			require.Equal(t, i, resp.Slides[i].Index)
+			require.Equal(t, name, resp.Slides[i].Name)
+			require.Equal(t, fmt.Sprintf("%s/slides/%d", url, i), resp.Slides[i].URL)
+		}
+	})
 
-		course2 := &models.Course{Title: "Course 2", Path: "/course/2"}
-		require.NoError(t, router.dao.CreateCourse(ctx, course2))
+	t.Run("200 (slide)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := slidesHelper(t, router, ctx)
 
-		asset := &models.Asset{
-			CourseID: course2.ID,
-			Title:    "asset 1",
-			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 1",
-			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
-			Hash:     security.RandomString(64),
-		}
-		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/slides/2", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "slide10.png", string(body))
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course1.ID+"/assets/"+asset.ID+"/attachments/invalid", nil)
-		status, body, err := requestHelper(t, router, req)
+		// Serving the asset serves the first slide
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/serve", nil))
 		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, status)
-		require.Contains(t, string(body), "Asset does not belong to course")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "slide1.png", string(body))
 	})
 
-	t.Run("400 (invalid attachment for asset)", func(t *testing.T) {
+	t.Run("200 (single image)", func(t *testing.T) {
 		router, ctx := setup(t)
 
 		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
 		require.NoError(t, router.dao.CreateCourse(ctx, course))
 
-		asset1 := &models.Asset{
+		asset := &models.Asset{
 			CourseID: course.ID,
-			Title:    "asset 1",
+			Title:    "diagram",
 			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 1",
-			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Type:     *types.NewAsset("png"),
+			Path:     "/Course 1/01 diagram.png",
 			Hash:     security.RandomString(64),
 		}
-		require.NoError(t, router.dao.CreateAsset(ctx, asset1))
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, asset.Path, []byte("diagram"), os.ModePerm))
 
-		asset2 := &models.Asset{
-			CourseID: course.ID,
-			Title:    "asset 2",
-			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 2",
-			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 2", security.RandomString(4)),
-			Hash:     security.RandomString(64),
-		}
-		require.NoError(t, router.dao.CreateAsset(ctx, asset2))
+		url := "/api/courses/" + course.ID + "/assets/" + asset.ID
 
-		attachment := &models.Attachment{
-			AssetID: asset1.ID,
-			Title:   "attachment 1",
-			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
-		}
-		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/slides", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset2.ID+"/attachments/"+attachment.ID, nil)
+		var resp slidesResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Len(t, resp.Slides, 1)
+		require.Equal(t, "01 diagram.png", resp.Slides[0].Name)
 
-		status, body, err := requestHelper(t, router, req)
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/slides/0", nil))
 		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, status)
-		require.Contains(t, string(body), "Attachment does not belong to asset")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "diagram", string(body))
 	})
 
-	t.Run("404 (course not found)", func(t *testing.T) {
-		router, _ := setup(t)
+	t.Run("204 (progress)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := slidesHelper(t, router, ctx)
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/invalid/assets/invalid/attachments/invalid", nil)
-		status, _, err := requestHelper(t, router, req)
+		progressHelper := func(slidePos int) *models.Asset {
+			req := httptest.NewRequest(http.MethodPut, url+"/progress", strings.NewReader(fmt.Sprintf(`{"slidePos":%d}`, slidePos)))
+			req.Header.Set("Content-Type", "application/json")
+
+			status, _, err := requestHelper(t, router, req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusNoContent, status)
+
+			asset := &models.Asset{Base: models.Base{ID: filepath.Base(url)}}
+			require.NoError(t, router.dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+			require.NotNil(t, asset.Progress)
+
+			return asset
+		}
+
+		asset := progressHelper(1)
+		require.Equal(t, 1, asset.Progress.SlidePos)
+		require.Equal(t, 66, asset.Progress.ScrollPos)
+		require.False(t, asset.Progress.Completed)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/slides", nil))
 		require.NoError(t, err)
-		require.Equal(t, http.StatusNotFound, status)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp slidesResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Equal(t, 1, resp.Position)
+
+		// Clamped to the last slide, which completes the asset
+		asset = progressHelper(10)
+		require.Equal(t, 2, asset.Progress.SlidePos)
+		require.Equal(t, 100, asset.Progress.ScrollPos)
+		require.True(t, asset.Progress.Completed)
 	})
 
-	t.Run("404 (asset not found)", func(t *testing.T) {
+	t.Run("400 (invalid slide index)", func(t *testing.T) {
 		router, ctx := setup(t)
+		url := slidesHelper(t, router, ctx)
 
-		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
-		require.NoError(t, router.dao.CreateCourse(ctx, course))
-
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/invalid/attachments/invalid", nil)
-		status, _, err := requestHelper(t, router, req)
-		require.NoError(t, err)
-		require.Equal(t, http.StatusNotFound, status)
+		for _, index := range []string{"bob", "-1"} {
+			status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/slides/"+index, nil))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, status)
+			require.Contains(t, string(body), "Invalid slide index")
+		}
 	})
 
-	t.Run("404 (attachment not found)", func(t *testing.T) {
+	t.Run("400 (not slides)", func(t *testing.T) {
 		router, ctx := setup(t)
 
 		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
@@ -1643,28 +3800,45 @@ func TestCourses_GetAttachment(t *testing.T) {
 
 		asset := &models.Asset{
 			CourseID: course.ID,
-			Title:    "asset 1",
+			Title:    "video",
 			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 1",
 			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Path:     "/Course 1/01 video.mp4",
 			Hash:     security.RandomString(64),
 		}
 		require.NoError(t, router.dao.CreateAsset(ctx, asset))
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/invalid", nil)
-		status, body, err := requestHelper(t, router, req)
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/slides", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Asset is not slides")
+	})
+
+	t.Run("404 (slide not found)", func(t *testing.T) {
+		router, ctx := setup(t)
+		url := slidesHelper(t, router, ctx)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, url+"/slides/3", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Contains(t, string(body), "Slide not found")
+	})
+
+	t.Run("404 (asset not found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses/invalid/assets/invalid/slides", nil))
 		require.NoError(t, err)
 		require.Equal(t, http.StatusNotFound, status)
-		require.Contains(t, string(body), "Attachment not found")
 	})
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-func TestCourses_ServeAttachment(t *testing.T) {
-	t.Run("200 (ok)", func(t *testing.T) {
-		router, ctx := setup(t)
+func TestCourses_Thumbnails(t *testing.T) {
+	// createVideo creates a course with a video asset
+	createVideo := func(t *testing.T, router *Router, ctx context.Context) (*models.Course, *models.Asset) {
+		t.Helper()
 
 		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
 		require.NoError(t, router.dao.CreateCourse(ctx, course))
@@ -1673,31 +3847,75 @@ func TestCourses_ServeAttachment(t *testing.T) {
 			CourseID: course.ID,
 			Title:    "asset 1",
 			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 1",
 			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Path:     "/Course 1/01 video.mp4",
 			Hash:     security.RandomString(64),
+			Duration: 25,
+			Width:    1280,
+			Height:   720,
 		}
 		require.NoError(t, router.dao.CreateAsset(ctx, asset))
 
-		attachment := &models.Attachment{
-			AssetID: asset.ID,
-			Title:   "attachment 1",
-			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
+		return course, asset
+	}
+
+	// generate generates the thumbnails of the asset using a ffmpeg stub
+	generate := func(t *testing.T, router *Router, asset *models.Asset) {
+		t.Helper()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("ffmpeg stub requires a unix shell")
 		}
-		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
 
-		require.Nil(t, router.config.AppFs.Fs.MkdirAll(filepath.Dir(attachment.Path), os.ModePerm))
-		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, attachment.Path, []byte("hello"), os.ModePerm))
+		ffmpegPath, err := mocks.StubFFmpeg(t.TempDir())
+		require.NoError(t, err)
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/"+attachment.ID+"/serve", nil)
-		status, body, err := requestHelper(t, router, req)
+		src := &transcode.Source{Path: asset.Path, Key: asset.Hash, Duration: asset.Duration, Width: asset.Width, Height: asset.Height}
+		_, err = router.config.Transcoder.GenerateThumbnails(context.Background(), ffmpegPath, asset.ID, src)
 		require.NoError(t, err)
-		require.Equal(t, http.StatusOK, status)
-		require.Equal(t, "hello", string(body))
+	}
+
+	t.Run("200 (vtt)", func(t *testing.T) {
+		router, ctx := setup(t)
+		course, asset := createVideo(t, router, ctx)
+		generate(t, router, asset)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/thumbnails.vtt", nil)
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/vtt; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		sprite := "/api/courses/" + course.ID + "/assets/" + asset.ID + "/thumbnails.jpg"
+		expected := "WEBVTT\n\n" +
+			"00:00:00.000 --> 00:00:10.000\n" + sprite + "#xywh=0,0,160,90\n\n" +
+			"00:00:10.000 --> 00:00:20.000\n" + sprite + "#xywh=160,0,160,90\n\n" +
+			"00:00:20.000 --> 00:00:25.000\n" + sprite + "#xywh=320,0,160,90\n"
+		require.Equal(t, expected, string(body))
 	})
 
-	t.Run("400 (invalid path)", func(t *testing.T) {
+	t.Run("200 (images)", func(t *testing.T) {
+		router, ctx := setup(t)
+		course, asset := createVideo(t, router, ctx)
+		generate(t, router, asset)
+
+		for _, file := range []string{"thumbnails.jpg", "poster.jpg"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/"+file, nil)
+			resp, err := router.router.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, "image/jpeg", resp.Header.Get(fiber.HeaderContentType))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, "jpeg", string(body))
+		}
+	})
+
+	t.Run("400 (not a video)", func(t *testing.T) {
 		router, ctx := setup(t)
 
 		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
@@ -1707,87 +3925,55 @@ func TestCourses_ServeAttachment(t *testing.T) {
 			CourseID: course.ID,
 			Title:    "asset 1",
 			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 1",
-			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
+			Type:     *types.NewAsset("pdf"),
+			Path:     "/Course 1/01 asset.pdf",
 			Hash:     security.RandomString(64),
 		}
 		require.NoError(t, router.dao.CreateAsset(ctx, asset))
 
-		attachment := &models.Attachment{
-			AssetID: asset.ID,
-			Title:   "attachment 1",
-			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
-		}
-		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
-
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/"+attachment.ID+"/serve", nil)
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/thumbnails.vtt", nil)
 		status, body, err := requestHelper(t, router, req)
 		require.NoError(t, err)
 		require.Equal(t, http.StatusBadRequest, status)
-		require.Contains(t, string(body), "Attachment does not exist")
+		require.Contains(t, string(body), "Asset is not a video")
 	})
 
-	t.Run("400 (invalid asset for course)", func(t *testing.T) {
+	t.Run("400 (invalid course)", func(t *testing.T) {
 		router, ctx := setup(t)
+		_, asset := createVideo(t, router, ctx)
 
-		course1 := &models.Course{Title: "Course 1", Path: "/Course 1"}
-		require.NoError(t, router.dao.CreateCourse(ctx, course1))
-
-		course2 := &models.Course{Title: "Course 2", Path: "/course/2"}
-		require.NoError(t, router.dao.CreateCourse(ctx, course2))
-
-		asset := &models.Asset{
-			CourseID: course2.ID,
-			Title:    "asset 1",
-			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 1",
-			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
-			Hash:     security.RandomString(64),
-		}
-		require.NoError(t, router.dao.CreateAsset(ctx, asset))
-
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course1.ID+"/assets/"+asset.ID+"/attachments/invalid/serve", nil)
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/invalid/assets/"+asset.ID+"/poster.jpg", nil)
 		status, body, err := requestHelper(t, router, req)
 		require.NoError(t, err)
 		require.Equal(t, http.StatusBadRequest, status)
 		require.Contains(t, string(body), "Asset does not belong to course")
 	})
 
-	t.Run("400 (invalid attachment for asset)", func(t *testing.T) {
+	t.Run("404 (not generated)", func(t *testing.T) {
 		router, ctx := setup(t)
-
-		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
-		require.NoError(t, router.dao.CreateCourse(ctx, course))
-
-		assets := []*models.Asset{}
-		for j := range 2 {
-			asset := &models.Asset{
-				CourseID: course.ID,
-				Title:    fmt.Sprintf("asset %d", j+1),
-				Prefix:   sql.NullInt16{Int16: int16(j + 1), Valid: true},
-				Chapter:  fmt.Sprintf("Chapter %d", j+1),
-				Type:     *types.NewAsset("mp4"),
-				Path:     fmt.Sprintf("/%s/asset %d", security.RandomString(4), j+1),
-				Hash:     security.RandomString(64),
-			}
-			require.NoError(t, router.dao.CreateAsset(ctx, asset))
-			assets = append(assets, asset)
+		course, asset := createVideo(t, router, ctx)
+
+		for _, file := range []string{"thumbnails.vtt", "thumbnails.jpg", "poster.jpg"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/"+file, nil)
+			status, body, err := requestHelper(t, router, req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusNotFound, status)
+			require.Contains(t, string(body), "Thumbnails not found")
 		}
+	})
 
-		attachment := &models.Attachment{
-			AssetID: assets[0].ID,
-			Title:   "attachment 1",
-			Path:    fmt.Sprintf("/%s/attachment 1", security.RandomString(4)),
-		}
-		require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+	t.Run("404 (stale)", func(t *testing.T) {
+		router, ctx := setup(t)
+		course, asset := createVideo(t, router, ctx)
+		generate(t, router, asset)
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+assets[1].ID+"/attachments/"+attachment.ID+"/serve", nil)
-		status, body, err := requestHelper(t, router, req)
+		asset.Hash = security.RandomString(64)
+		require.NoError(t, router.dao.UpdateAsset(ctx, asset))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/thumbnails.vtt", nil)
+		status, _, err := requestHelper(t, router, req)
 		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, status)
-		require.Contains(t, string(body), "Attachment does not belong to asset")
+		require.Equal(t, http.StatusNotFound, status)
 	})
 
 	t.Run("404 (asset not found)", func(t *testing.T) {
@@ -1796,35 +3982,26 @@ func TestCourses_ServeAttachment(t *testing.T) {
 		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
 		require.NoError(t, router.dao.CreateCourse(ctx, course))
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/invalid/attachments/invalid/serve", nil)
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/invalid/thumbnails.vtt", nil)
 		status, body, err := requestHelper(t, router, req)
 		require.NoError(t, err)
 		require.Equal(t, http.StatusNotFound, status)
 		require.Contains(t, string(body), "Asset not found")
 	})
 
-	t.Run("404 (attachment not found)", func(t *testing.T) {
+	t.Run("500 (asset internal error)", func(t *testing.T) {
 		router, ctx := setup(t)
 
 		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
 		require.NoError(t, router.dao.CreateCourse(ctx, course))
 
-		asset := &models.Asset{
-			CourseID: course.ID,
-			Title:    "asset 1",
-			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
-			Chapter:  "Chapter 1",
-			Type:     *types.NewAsset("mp4"),
-			Path:     fmt.Sprintf("/%s/asset 1", security.RandomString(4)),
-			Hash:     security.RandomString(64),
-		}
-		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.ASSET_TABLE)
+		require.NoError(t, err)
 
-		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/attachments/invalid/serve", nil)
-		status, body, err := requestHelper(t, router, req)
+		req := httptest.NewRequest(http.MethodGet, "/api/courses/"+course.ID+"/assets/invalid/thumbnails.vtt", nil)
+		status, _, err := requestHelper(t, router, req)
 		require.NoError(t, err)
-		require.Equal(t, http.StatusNotFound, status)
-		require.Contains(t, string(body), "Attachment not found")
+		require.Equal(t, http.StatusInternalServerError, status)
 	})
 }
 
@@ -2075,3 +4252,35 @@ func TestCourses_DeleteTag(t *testing.T) {
 		require.Equal(t, http.StatusInternalServerError, status)
 	})
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// attachmentHelper creates a course, asset and attachment with the given filename and content on
+// disk, and returns the URL of the attachment
+func attachmentHelper(t *testing.T, router *Router, ctx context.Context, filename string, data []byte) string {
+	t.Helper()
+
+	course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+	require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+	asset := &models.Asset{
+		CourseID: course.ID,
+		Title:    "asset 1",
+		Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+		Type:     *types.NewAsset("mp4"),
+		Path:     "/Course 1/01 asset 1.mp4",
+		Hash:     security.RandomString(64),
+	}
+	require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+	attachment := &models.Attachment{
+		AssetID: asset.ID,
+		Title:   filename,
+		Path:    "/Course 1/01 " + filename,
+	}
+	require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+
+	require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, attachment.Path, data, os.ModePerm))
+
+	return "/api/courses/" + course.ID + "/assets/" + asset.ID + "/attachments/" + attachment.ID
+}
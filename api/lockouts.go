@@ -0,0 +1,193 @@
+package api
+
+import (
+	"log/slog"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const (
+	// A lockout of a username
+	lockoutTypeUsername = "username"
+
+	// A lockout of an IP address
+	lockoutTypeIP = "ip"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// LoginThrottleConfig defines how failed logins are throttled. Failures are counted per username
+// and per IP address. After every failure the username and IP must wait before trying again,
+// with the wait doubling every time, until they are locked out
+type LoginThrottleConfig struct {
+	Username security.ThrottlePolicy
+	IP       security.ThrottlePolicy
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DefaultLoginThrottleConfig returns the default login throttling, which locks out a username
+// after 5 failures and an IP address after 20 failures, for 15 minutes
+func DefaultLoginThrottleConfig() *LoginThrottleConfig {
+	return &LoginThrottleConfig{
+		Username: security.ThrottlePolicy{
+			MaxFailures:     5,
+			BaseDelay:       time.Second,
+			MaxDelay:        30 * time.Second,
+			LockoutDuration: 15 * time.Minute,
+		},
+		IP: security.ThrottlePolicy{
+			MaxFailures:     20,
+			BaseDelay:       time.Second,
+			MaxDelay:        30 * time.Second,
+			LockoutDuration: 15 * time.Minute,
+		},
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type lockoutsAPI struct {
+	logger *slog.Logger
+	router *Router
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initLockoutRoutes initializes the lockout routes
+func (r *Router) initLockoutRoutes() {
+	lockoutsAPI := lockoutsAPI{
+		logger: r.config.Logger,
+		router: r,
+	}
+
+	lockoutGroup := r.api.Group("/lockouts")
+	lockoutGroup.Get("", lockoutsAPI.getLockouts)
+	lockoutGroup.Delete("", lockoutsAPI.deleteLockouts)
+	lockoutGroup.Delete("/:type/:value", lockoutsAPI.deleteLockout)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getLockouts returns the usernames and IP addresses with failed logins, most recent first. This
+// includes those backing off, not just those locked out
+func (api *lockoutsAPI) getLockouts(c *fiber.Ctx) error {
+	resp := lockoutResponseHelper(lockoutTypeUsername, api.router.usernameThrottle.States())
+	resp = append(resp, lockoutResponseHelper(lockoutTypeIP, api.router.ipThrottle.States())...)
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// deleteLockouts clears the failed logins of every username and IP address
+func (api *lockoutsAPI) deleteLockouts(c *fiber.Ctx) error {
+	api.router.usernameThrottle.ResetAll()
+	api.router.ipThrottle.ResetAll()
+
+	api.logger.Info(
+		"Cleared all login lockouts",
+		slog.Any("type", types.LogTypeRequest),
+		slog.String("by", currentUsername(c)),
+	)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// deleteLockout clears the failed logins of a username or IP address
+func (api *lockoutsAPI) deleteLockout(c *fiber.Ctx) error {
+	value, err := url.PathUnescape(c.Params("value"))
+	if err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid value", err)
+	}
+
+	var throttle *security.Throttle
+	switch c.Params("type") {
+	case lockoutTypeUsername:
+		throttle = api.router.usernameThrottle
+		value = usernameThrottleKey(value)
+	case lockoutTypeIP:
+		throttle = api.router.ipThrottle
+	default:
+		return errorResponse(c, fiber.StatusBadRequest, "The type must be username or ip", nil)
+	}
+
+	if !throttle.Reset(value) {
+		return errorResponse(c, fiber.StatusNotFound, "Lockout not found", nil)
+	}
+
+	api.logger.Info(
+		"Cleared login lockout",
+		slog.Any("type", types.LogTypeRequest),
+		slog.String("lockout_type", c.Params("type")),
+		slog.String("value", value),
+		slog.String("by", currentUsername(c)),
+	)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Router helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// loginWait returns how long a login for the username from the IP address must wait. Zero is
+// returned when the login may be attempted now
+func (r *Router) loginWait(username, ip string) time.Duration {
+	return max(r.usernameThrottle.Wait(usernameThrottleKey(username)), r.ipThrottle.Wait(ip))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// loginFailed records a failed login against the username and IP address and logs it
+func (r *Router) loginFailed(c *fiber.Ctx, username, reason string) {
+	usernameState := r.usernameThrottle.Fail(usernameThrottleKey(username))
+	ipState := r.ipThrottle.Fail(clientIP(c))
+
+	r.config.Logger.Warn(
+		"Login failed",
+		slog.Any("type", types.LogTypeRequest),
+		slog.String("username", username),
+		slog.String("ip", clientIP(c)),
+		slog.String("reason", reason),
+		slog.Int("username_failures", usernameState.Failures),
+		slog.Int("ip_failures", ipState.Failures),
+		slog.Bool("locked", usernameState.Locked || ipState.Locked),
+	)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// loginThrottled responds to a login that must wait, setting the `Retry-After` header
+func (r *Router) loginThrottled(c *fiber.Ctx, username string, wait time.Duration) error {
+	r.config.Logger.Warn(
+		"Login throttled",
+		slog.Any("type", types.LogTypeRequest),
+		slog.String("username", username),
+		slog.String("ip", clientIP(c)),
+		slog.Duration("retry_after", wait),
+	)
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+
+	return errorResponse(c, fiber.StatusTooManyRequests, "Too many failed logins, try again later", nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// usernameThrottleKey returns the throttle key of a username. Usernames differing only in case or
+// surrounding space share their failures
+func usernameThrottleKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
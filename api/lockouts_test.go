@@ -0,0 +1,277 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestLockouts_Login(t *testing.T) {
+	t.Run("429 (username locked out)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+
+		for range 5 {
+			resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "admin", "password": "wrong"}`)
+			require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+
+		// The correct password is rejected, as is a different case of the username
+		for _, username := range []string{"admin", "ADMIN"} {
+			resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "`+username+`", "password": "password"}`)
+			require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+			require.Equal(t, "900", resp.Header.Get("Retry-After"))
+		}
+
+		// Other users are not affected
+		loginHelper(t, router, "bob", "password")
+	})
+
+	t.Run("429 (unknown username locked out)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		for range 5 {
+			resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "nobody", "password": "wrong"}`)
+			require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "nobody", "password": "wrong"}`)
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	})
+
+	t.Run("429 (ip locked out)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		// Spread across usernames, so no username is locked out
+		for i := range 20 {
+			username := []string{"alice", "bob", "carol", "dave", "eve"}[i%5]
+			resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "`+username+`", "password": "wrong"}`)
+			require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "admin", "password": "password"}`)
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	})
+
+	t.Run("429 (ip behind a trusted proxy)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		// The test requests come from 0.0.0.0
+		trusted, err := ParseTrustedProxies("0.0.0.0")
+		require.NoError(t, err)
+
+		router.config.TrustedProxies = trusted
+		router.config.ClientIPHeader = "X-Forwarded-For"
+		router.initRouter()
+
+		login := func(clientIP, username, password string) int {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"username": "`+username+`", "password": "`+password+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", clientIP)
+
+			resp, err := router.router.Test(req)
+			require.NoError(t, err)
+
+			return resp.StatusCode
+		}
+
+		for i := range 20 {
+			username := []string{"alice", "bob", "carol", "dave", "eve"}[i%5]
+			require.Equal(t, http.StatusUnauthorized, login("10.0.0.1", username, "wrong"))
+		}
+
+		require.Equal(t, http.StatusTooManyRequests, login("10.0.0.1", "admin", "password"))
+
+		// Another client of the same proxy is not locked out
+		require.Equal(t, http.StatusOK, login("10.0.0.2", "admin", "password"))
+
+		states := router.ipThrottle.States()
+		require.Len(t, states, 1)
+		require.Equal(t, "10.0.0.1", states[0].Key)
+	})
+
+	t.Run("429 (backoff)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		router.usernameThrottle = security.NewThrottle(security.ThrottlePolicy{
+			MaxFailures:     5,
+			BaseDelay:       time.Minute,
+			MaxDelay:        time.Hour,
+			LockoutDuration: 2 * time.Hour,
+		})
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "admin", "password": "wrong"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = authRequestHelper(t, router, "/api/auth/login", `{"username": "admin", "password": "password"}`)
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		require.Equal(t, "60", resp.Header.Get("Retry-After"))
+	})
+
+	t.Run("200 (success resets failures)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		for range 2 {
+			for range 4 {
+				resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "admin", "password": "wrong"}`)
+				require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			}
+
+			loginHelper(t, router, "admin", "password")
+		}
+	})
+
+	t.Run("200 (success resets ip failures)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		// Spread across usernames, so no username is locked out
+		for i := range 19 {
+			username := []string{"alice", "bob", "carol", "dave", "eve"}[i%5]
+			resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "`+username+`", "password": "wrong"}`)
+			require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+
+		loginHelper(t, router, "admin", "password")
+		require.Empty(t, router.ipThrottle.States())
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "alice", "password": "wrong"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestLockouts_GetLockouts(t *testing.T) {
+	t.Run("200 (found)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		for range 5 {
+			authRequestHelper(t, router, "/api/auth/login", `{"username": "Bob", "password": "wrong"}`)
+		}
+
+		status, body := userRequestHelper(t, router, http.MethodGet, "/api/lockouts", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		var lockouts []*lockoutResponse
+		require.NoError(t, json.Unmarshal(body, &lockouts))
+		require.Len(t, lockouts, 2)
+
+		require.Equal(t, lockoutTypeUsername, lockouts[0].Type)
+		require.Equal(t, "bob", lockouts[0].Value)
+		require.Equal(t, 5, lockouts[0].Failures)
+		require.True(t, lockouts[0].Locked)
+		require.True(t, lockouts[0].RetryAt.After(time.Now()))
+
+		require.Equal(t, lockoutTypeIP, lockouts[1].Type)
+		require.Equal(t, "0.0.0.0", lockouts[1].Value)
+		require.Equal(t, 5, lockouts[1].Failures)
+		require.False(t, lockouts[1].Locked)
+	})
+
+	t.Run("200 (empty)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodGet, "/api/lockouts", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+		require.JSONEq(t, `[]`, string(body))
+	})
+
+	t.Run("403 (user)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "bob", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/lockouts", "", cookie)
+		require.Equal(t, http.StatusForbidden, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestLockouts_DeleteLockout(t *testing.T) {
+	t.Run("204 (username)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		for range 5 {
+			authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": "wrong"}`)
+		}
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": "password"}`)
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/lockouts/username/Bob", "", cookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		loginHelper(t, router, "bob", "password")
+
+		status, _ = userRequestHelper(t, router, http.MethodDelete, "/api/lockouts/username/bob", "", cookie)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("204 (ip)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": "wrong"}`)
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/lockouts/ip/0.0.0.0", "", cookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		// IPv6 addresses are escaped
+		router.ipThrottle.Fail("::1")
+		status, _ = userRequestHelper(t, router, http.MethodDelete, "/api/lockouts/ip/%3A%3A1", "", cookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		require.Empty(t, router.ipThrottle.States())
+	})
+
+	t.Run("204 (all)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		for range 5 {
+			authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": "wrong"}`)
+		}
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/lockouts", "", cookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		require.Empty(t, router.usernameThrottle.States())
+		require.Empty(t, router.ipThrottle.States())
+	})
+
+	t.Run("400 (invalid type)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/lockouts/email/bob", "", cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+	})
+}
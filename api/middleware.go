@@ -87,3 +87,72 @@ func corsMiddleWare() func(c *fiber.Ctx) error {
 		AllowMethods: "GET, POST, PUT, DELETE, HEAD, PATCH",
 	})
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// authMiddleware requires a logged in user once an admin exists. Until then the app is in its
+// first-run state and requests are let through so the admin can be bootstrapped. The logged in
+// user and their session are stored in the fiber locals
+//
+// A request with an `Authorization: Bearer` header is authenticated by the API token rather than the
+// session. The token is also stored in the fiber locals, so its scope can be checked
+//
+// When proxy auth is configured, a request from a trusted proxy carrying the user header is
+// authenticated as that user before anything else (see `proxyUser`)
+func authMiddleware(r *Router) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		user, err := r.proxyUser(c)
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error looking up proxy user", err)
+		}
+
+		if user != nil {
+			if user.Disabled {
+				return errorResponse(c, fiber.StatusForbidden, "Account is disabled", nil)
+			}
+
+			c.Locals(localsUserKey, user)
+
+			return c.Next()
+		}
+
+		hasAdmin, err := r.adminExists(c.Context())
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error looking up auth status", err)
+		}
+
+		if !hasAdmin {
+			return c.Next()
+		}
+
+		if bearer, ok := bearerToken(c); ok {
+			user, token, err := r.tokenUser(c, bearer)
+			if err != nil {
+				return errorResponse(c, fiber.StatusInternalServerError, "Error looking up token", err)
+			}
+
+			if user == nil {
+				return errorResponse(c, fiber.StatusUnauthorized, "Invalid token", nil)
+			}
+
+			c.Locals(localsUserKey, user)
+			c.Locals(localsTokenKey, token)
+
+			return c.Next()
+		}
+
+		user, session, err := r.sessionUser(c)
+		if err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error looking up session", err)
+		}
+
+		if user == nil {
+			return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+		}
+
+		c.Locals(localsUserKey, user)
+		c.Locals(localsSessionKey, session)
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/oidc"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const (
+	// The name of the cookie holding the state, nonce and PKCE verifier of a login in progress
+	oidcCookieName = "oc_oidc"
+
+	// How long the user has to sign in at the provider
+	oidcLoginExpiration = 10 * time.Minute
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// OIDCConfig defines the configuration for logging in through an OpenID Connect provider. A user
+// is matched by the subject of their ID token and is created on their first login
+type OIDCConfig struct {
+	Provider *oidc.Provider
+
+	// The claim used as the username of a new user, such as `preferred_username`. Falls back to
+	// `email` and then the subject when the claim is missing
+	UsernameClaim string
+
+	// The claim holding the groups or roles of the user, such as `groups`. When empty, new users
+	// are users and the role of an existing user is left as is
+	RoleClaim string
+
+	// Users whose role claim contains this value are admins. Everyone else is a user
+	AdminValue string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// username returns the username for the claims
+func (config *OIDCConfig) username(claims oidc.Claims) string {
+	for _, name := range []string{config.UsernameClaim, "email"} {
+		if name == "" {
+			continue
+		}
+
+		if username := strings.TrimSpace(claims.String(name)); username != "" {
+			return username
+		}
+	}
+
+	return claims.Subject()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// role maps the role claim to a role. False is returned when the role is not managed by the
+// provider
+func (config *OIDCConfig) role(claims oidc.Claims) (types.UserRole, bool) {
+	if config.RoleClaim == "" {
+		return types.UserRoleUser, false
+	}
+
+	if config.AdminValue != "" && slices.Contains(claims.Strings(config.RoleClaim), config.AdminValue) {
+		return types.UserRoleAdmin, true
+	}
+
+	return types.UserRoleUser, true
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcLogin redirects to the provider to sign in. The state, nonce and PKCE verifier are kept in a
+// short-lived cookie, to be checked when the provider redirects back
+func (api *authAPI) oidcLogin(c *fiber.Ctx) error {
+	config := api.router.config.OIDC
+	if config == nil {
+		return errorResponse(c, fiber.StatusNotFound, "OpenID Connect is not configured", nil)
+	}
+
+	state := security.RandomString(32)
+	nonce := security.RandomString(32)
+	verifier := oidc.NewVerifier()
+
+	authURL, err := config.Provider.AuthCodeURL(c.Context(), state, nonce, verifier)
+	if err != nil {
+		return errorResponse(c, fiber.StatusBadGateway, "Error contacting the OpenID Connect provider", err)
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcCookieName,
+		Value:    strings.Join([]string{state, nonce, verifier}, "."),
+		Path:     "/api/auth/oidc",
+		Expires:  time.Now().Add(oidcLoginExpiration),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcCallback completes a login when the provider redirects back. The code is exchanged for an
+// ID token, the user is looked up by its subject (and created on their first login) and a session
+// is started
+//
+// A new user whose username is already taken is rejected rather than linked to the existing
+// account, as the provider cannot vouch for a local account
+func (api *authAPI) oidcCallback(c *fiber.Ctx) error {
+	config := api.router.config.OIDC
+	if config == nil {
+		return errorResponse(c, fiber.StatusNotFound, "OpenID Connect is not configured", nil)
+	}
+
+	cookie := c.Cookies(oidcCookieName)
+	clearOIDCCookie(c)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		api.logOIDCFailure(c, "OpenID Connect provider returned an error", slog.String("error", providerErr))
+		return errorResponse(c, fiber.StatusUnauthorized, "Login failed at the OpenID Connect provider", errors.New(providerErr))
+	}
+
+	parts := strings.Split(cookie, ".")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(c.Query("state"))) != 1 {
+		api.logOIDCFailure(c, "OpenID Connect state mismatch")
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid or expired login, please try again", nil)
+	}
+
+	if c.Query("code") == "" {
+		return errorResponse(c, fiber.StatusBadRequest, "A code is required", nil)
+	}
+
+	claims, err := config.Provider.Login(c.Context(), c.Query("code"), parts[2], parts[1])
+	if err != nil {
+		api.logOIDCFailure(c, "OpenID Connect login failed", slog.String("error", err.Error()))
+		return errorResponse(c, fiber.StatusUnauthorized, "OpenID Connect login failed", err)
+	}
+
+	user, status, err := api.oidcUser(c, config, claims)
+	if err != nil {
+		return errorResponse(c, status, "Error logging in", err)
+	}
+
+	if user.Disabled {
+		return errorResponse(c, fiber.StatusForbidden, "Account is disabled", nil)
+	}
+
+	if err := api.router.startSession(c, user); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error creating session", err)
+	}
+
+	return c.Redirect("/", fiber.StatusFound)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcUser returns the user for the claims, creating them on their first login. The role of an
+// existing user follows the role claim. On error, the status to respond with is also returned
+func (api *authAPI) oidcUser(c *fiber.Ctx, config *OIDCConfig, claims oidc.Claims) (*models.User, int, error) {
+	role, managed := config.role(claims)
+
+	user := &models.User{OIDCSubject: claims.Subject()}
+	err := api.dao.GetUserByOIDCSubject(c.Context(), user)
+	if err == nil {
+		if managed {
+			if err := api.router.syncRole(c.Context(), user, role); err != nil {
+				return nil, fiber.StatusInternalServerError, err
+			}
+		}
+
+		return user, 0, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	username := config.username(claims)
+
+	existing := &models.User{Username: username}
+	if err := api.dao.GetUserByUsername(c.Context(), existing); err == nil {
+		api.logOIDCFailure(c, "OpenID Connect username is already in use", slog.String("username", username))
+		return nil, fiber.StatusConflict, errors.New("username is already in use")
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	user = &models.User{Username: username, Role: role, OIDCSubject: claims.Subject()}
+	if err := api.router.provisionUser(c.Context(), user); err != nil {
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	api.logger.Info(
+		"Created user from OpenID Connect",
+		slog.Any("type", types.LogTypeRequest),
+		slog.String("username", user.Username),
+		slog.String("role", user.Role.String()),
+	)
+
+	return user, 0, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// logOIDCFailure logs a failed OpenID Connect login
+func (api *authAPI) logOIDCFailure(c *fiber.Ctx, message string, attrs ...any) {
+	attrs = append([]any{slog.Any("type", types.LogTypeRequest), slog.String("ip", clientIP(c))}, attrs...)
+	api.logger.Warn(message, attrs...)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// clearOIDCCookie expires the login cookie, so a state can only be used once
+func clearOIDCCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcCookieName,
+		Value:    "",
+		Path:     "/api/auth/oidc",
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/mocks"
+	"github.com/geerew/off-course/utils/oidc"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestOIDC_Role(t *testing.T) {
+	config := &OIDCConfig{RoleClaim: "groups", AdminValue: "admins"}
+
+	role, managed := config.role(oidc.Claims{"groups": []any{"dev", "admins"}})
+	require.True(t, managed)
+	require.Equal(t, types.UserRoleAdmin, role)
+
+	role, managed = config.role(oidc.Claims{"groups": "admins"})
+	require.True(t, managed)
+	require.Equal(t, types.UserRoleAdmin, role)
+
+	role, managed = config.role(oidc.Claims{"groups": []any{"dev"}})
+	require.True(t, managed)
+	require.Equal(t, types.UserRoleUser, role)
+
+	role, managed = config.role(oidc.Claims{})
+	require.True(t, managed)
+	require.Equal(t, types.UserRoleUser, role)
+
+	config.RoleClaim = ""
+	role, managed = config.role(oidc.Claims{"groups": "admins"})
+	require.False(t, managed)
+	require.Equal(t, types.UserRoleUser, role)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestOIDC_Username(t *testing.T) {
+	config := &OIDCConfig{UsernameClaim: "preferred_username"}
+
+	require.Equal(t, "bob", config.username(oidc.Claims{"sub": "1", "preferred_username": " bob ", "email": "bob@example.com"}))
+	require.Equal(t, "bob@example.com", config.username(oidc.Claims{"sub": "1", "email": "bob@example.com"}))
+	require.Equal(t, "1", config.username(oidc.Claims{"sub": "1"}))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestOIDC_Login(t *testing.T) {
+	t.Run("302 (first admin)", func(t *testing.T) {
+		router, ctx := setup(t)
+		issuer := oidcHelper(t, router)
+		issuer.Claims["sub"] = "alice-sub"
+		issuer.Claims["preferred_username"] = "alice"
+		issuer.Claims["groups"] = []string{"admins"}
+
+		cookie := oidcLoginHelper(t, router, issuer)
+
+		user := &models.User{OIDCSubject: "alice-sub"}
+		require.NoError(t, router.dao.GetUserByOIDCSubject(ctx, user))
+		require.Equal(t, "alice", user.Username)
+		require.Equal(t, types.UserRoleAdmin, user.Role)
+
+		hasAdmin, err := router.dao.GetHasAdmin(ctx)
+		require.NoError(t, err)
+		require.True(t, hasAdmin)
+
+		// The session works
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/users", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("302 (provisioned)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		issuer := oidcHelper(t, router)
+		issuer.Claims["preferred_username"] = "bob"
+		issuer.Claims["groups"] = []string{"dev"}
+
+		cookie := oidcLoginHelper(t, router, issuer)
+
+		user := &models.User{OIDCSubject: "subject"}
+		require.NoError(t, router.dao.GetUserByOIDCSubject(ctx, user))
+		require.Equal(t, "bob", user.Username)
+		require.Equal(t, types.UserRoleUser, user.Role)
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/courses", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/users", "", cookie)
+		require.Equal(t, http.StatusForbidden, status)
+
+		// Logging in again finds the same user, even when the username claim changes
+		issuer.Claims["preferred_username"] = "robert"
+		oidcLoginHelper(t, router, issuer)
+
+		count, err := router.dao.Count(ctx, &models.User{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("302 (role follows claim)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		issuer := oidcHelper(t, router)
+		issuer.Claims["preferred_username"] = "bob"
+		issuer.Claims["groups"] = []string{"admins"}
+
+		oidcLoginHelper(t, router, issuer)
+
+		user := &models.User{OIDCSubject: "subject"}
+		require.NoError(t, router.dao.GetUserByOIDCSubject(ctx, user))
+		require.Equal(t, types.UserRoleAdmin, user.Role)
+
+		issuer.Claims["groups"] = []string{}
+		oidcLoginHelper(t, router, issuer)
+
+		require.NoError(t, router.dao.GetUserByOIDCSubject(ctx, user))
+		require.Equal(t, types.UserRoleUser, user.Role)
+
+		// Without a role claim, the role is managed in the app
+		user.Role = types.UserRoleAdmin
+		require.NoError(t, router.dao.UpdateUser(ctx, user))
+
+		router.config.OIDC.RoleClaim = ""
+		oidcLoginHelper(t, router, issuer)
+
+		require.NoError(t, router.dao.GetUserByOIDCSubject(ctx, user))
+		require.Equal(t, types.UserRoleAdmin, user.Role)
+	})
+
+	t.Run("302 (replaces session)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		adminCookie := loginHelper(t, router, "admin", "password")
+
+		issuer := oidcHelper(t, router)
+		issuer.Claims["preferred_username"] = "bob"
+
+		loginResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		callback := oidcAuthorizeHelper(t, loginResp.Header.Get("Location"))
+
+		resp := oidcRequestHelper(t, router, callback, []*http.Cookie{oidcCookieHelper(t, loginResp), adminCookie})
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+
+		count, err := router.dao.Count(ctx, &models.Session{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("404 (not configured)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		for _, path := range []string{"/api/auth/oidc/login", "/api/auth/oidc/callback"} {
+			resp := oidcRequestHelper(t, router, path, nil)
+			require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		}
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/auth/status", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var respData authStatusResponse
+		require.NoError(t, json.Unmarshal(body, &respData))
+		require.False(t, respData.OIDC)
+	})
+
+	t.Run("502 (provider unreachable)", func(t *testing.T) {
+		router, _ := setup(t)
+		issuer := oidcHelper(t, router)
+		issuer.Close()
+
+		resp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestOIDC_Callback(t *testing.T) {
+	t.Run("400 (missing cookie)", func(t *testing.T) {
+		router, _ := setup(t)
+		oidcHelper(t, router)
+
+		loginResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		callback := oidcAuthorizeHelper(t, loginResp.Header.Get("Location"))
+
+		resp := oidcRequestHelper(t, router, callback, nil)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("400 (state mismatch)", func(t *testing.T) {
+		router, _ := setup(t)
+		oidcHelper(t, router)
+
+		// The cookie of one login does not match the state of another
+		firstResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		secondResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		callback := oidcAuthorizeHelper(t, secondResp.Header.Get("Location"))
+
+		resp := oidcRequestHelper(t, router, callback, []*http.Cookie{oidcCookieHelper(t, firstResp)})
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("401 (provider error)", func(t *testing.T) {
+		router, _ := setup(t)
+		oidcHelper(t, router)
+
+		loginResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+
+		resp := oidcRequestHelper(t, router, "/api/auth/oidc/callback?error=access_denied", []*http.Cookie{oidcCookieHelper(t, loginResp)})
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("401 (code reused)", func(t *testing.T) {
+		router, _ := setup(t)
+		oidcHelper(t, router)
+
+		loginResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		cookie := oidcCookieHelper(t, loginResp)
+		callback := oidcAuthorizeHelper(t, loginResp.Header.Get("Location"))
+
+		resp := oidcRequestHelper(t, router, callback, []*http.Cookie{cookie})
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+
+		resp = oidcRequestHelper(t, router, callback, []*http.Cookie{cookie})
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("401 (invalid ID token)", func(t *testing.T) {
+		router, _ := setup(t)
+		issuer := oidcHelper(t, router)
+		issuer.Claims["aud"] = "other"
+
+		loginResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		callback := oidcAuthorizeHelper(t, loginResp.Header.Get("Location"))
+
+		resp := oidcRequestHelper(t, router, callback, []*http.Cookie{oidcCookieHelper(t, loginResp)})
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("403 (disabled)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		issuer := oidcHelper(t, router)
+		issuer.Claims["preferred_username"] = "bob"
+		oidcLoginHelper(t, router, issuer)
+
+		user := &models.User{OIDCSubject: "subject"}
+		require.NoError(t, router.dao.GetUserByOIDCSubject(ctx, user))
+		user.Disabled = true
+		require.NoError(t, router.dao.UpdateUser(ctx, user))
+
+		loginResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		callback := oidcAuthorizeHelper(t, loginResp.Header.Get("Location"))
+
+		resp := oidcRequestHelper(t, router, callback, []*http.Cookie{oidcCookieHelper(t, loginResp)})
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("409 (username taken)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		issuer := oidcHelper(t, router)
+		issuer.Claims["preferred_username"] = "admin"
+
+		loginResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+		callback := oidcAuthorizeHelper(t, loginResp.Header.Get("Location"))
+
+		resp := oidcRequestHelper(t, router, callback, []*http.Cookie{oidcCookieHelper(t, loginResp)})
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		// The local account is not linked
+		user := &models.User{Username: "admin"}
+		require.NoError(t, router.dao.GetUserByUsername(ctx, user))
+		require.Empty(t, user.OIDCSubject)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcHelper starts a mock issuer and enables OpenID Connect against it
+func oidcHelper(t *testing.T, router *Router) *mocks.OIDCIssuer {
+	t.Helper()
+
+	issuer, err := mocks.NewOIDCIssuer("off-course", "secret")
+	require.NoError(t, err)
+	t.Cleanup(issuer.Close)
+
+	router.config.OIDC = &OIDCConfig{
+		Provider: oidc.New(&oidc.Config{
+			IssuerURL:    issuer.URL(),
+			ClientID:     "off-course",
+			ClientSecret: "secret",
+			RedirectURL:  "http://example.com/api/auth/oidc/callback",
+		}),
+		UsernameClaim: "preferred_username",
+		RoleClaim:     "groups",
+		AdminValue:    "admins",
+	}
+
+	return issuer
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcLoginHelper logs in through the mock issuer and returns the session cookie
+func oidcLoginHelper(t *testing.T, router *Router, issuer *mocks.OIDCIssuer) *http.Cookie {
+	t.Helper()
+
+	loginResp := oidcRequestHelper(t, router, "/api/auth/oidc/login", nil)
+	require.Equal(t, http.StatusFound, loginResp.StatusCode)
+
+	callback := oidcAuthorizeHelper(t, loginResp.Header.Get("Location"))
+
+	resp := oidcRequestHelper(t, router, callback, []*http.Cookie{oidcCookieHelper(t, loginResp)})
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	require.Equal(t, "/", resp.Header.Get("Location"))
+
+	return sessionCookieHelper(t, resp)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcAuthorizeHelper follows the redirect to the issuer and returns the path of the callback
+func oidcAuthorizeHelper(t *testing.T, authURL string) string {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, authURL, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	return location.RequestURI()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcRequestHelper makes a GET request with the cookies
+func oidcRequestHelper(t *testing.T, router *Router, path string, cookies []*http.Cookie) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := router.router.Test(req)
+	require.NoError(t, err)
+
+	return resp
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcCookieHelper returns the login cookie of the response
+func oidcCookieHelper(t *testing.T, resp *http.Response) *http.Cookie {
+	t.Helper()
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oidcCookieName {
+			return cookie
+		}
+	}
+
+	require.FailNow(t, "oidc cookie not found")
+	return nil
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// routePermission defines the role required to access a route. The scope an API token requires is
+// derived from the route (see `tokenScope`)
+type routePermission struct {
+	Method string
+
+	// The path of the route, relative to the API group
+	Path string
+	Role types.UserRole
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// routePermissions declares the role required for every API route, other than the auth routes,
+// which are public, and the shared routes, which are authorized by a share token. Admins manage the
+// library (courses, scans, tags, settings) and the users, and can browse the file system and read
+// the logs. Users browse courses, stream assets, record progress and share assets
+//
+// Every API route must be listed. This is enforced by a test
+var routePermissions = []routePermission{
+	// File system
+	{fiber.MethodGet, "/fileSystem", types.UserRoleAdmin},
+	{fiber.MethodGet, "/fileSystem/:path", types.UserRoleAdmin},
+
+	// Courses
+	{fiber.MethodGet, "/courses", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id", types.UserRoleUser},
+	{fiber.MethodPost, "/courses", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/courses/:id", types.UserRoleAdmin},
+
+	// Course card
+	{fiber.MethodGet, "/courses/:id/card", types.UserRoleUser},
+	{fiber.MethodPut, "/courses/:id/card", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/courses/:id/card", types.UserRoleAdmin},
+
+	// Assets
+	{fiber.MethodGet, "/courses/:id/assets", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/serve", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/stream/:file", types.UserRoleUser},
+	{fiber.MethodPut, "/courses/:id/assets/:asset/progress", types.UserRoleUser},
+
+	// Attachments
+	{fiber.MethodGet, "/courses/:id/assets/:asset/attachments", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/attachments/:attachment", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/attachments/:attachment/serve", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/attachments/:attachment/preview", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/attachments/:attachment/entries", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/attachments/:attachment/entries/*", types.UserRoleUser},
+
+	// Subtitles and chapters
+	{fiber.MethodGet, "/courses/:id/assets/:asset/subtitles/:lang", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/chapters", types.UserRoleUser},
+
+	// EPUB
+	{fiber.MethodGet, "/courses/:id/assets/:asset/epub", types.UserRoleUser},
+	{fiber.MethodPut, "/courses/:id/assets/:asset/epub/progress", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/epub/*", types.UserRoleUser},
+
+	// Slides
+	{fiber.MethodGet, "/courses/:id/assets/:asset/slides", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/slides/:index", types.UserRoleUser},
+
+	// Thumbnails
+	{fiber.MethodGet, "/courses/:id/assets/:asset/thumbnails.vtt", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/thumbnails.jpg", types.UserRoleUser},
+	{fiber.MethodGet, "/courses/:id/assets/:asset/poster.jpg", types.UserRoleUser},
+
+	// Course tags
+	{fiber.MethodGet, "/courses/:id/tags", types.UserRoleUser},
+	{fiber.MethodPost, "/courses/:id/tags", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/courses/:id/tags/:tagId", types.UserRoleAdmin},
+
+	// Scans
+	{fiber.MethodGet, "/scans/:courseId", types.UserRoleUser},
+	{fiber.MethodPost, "/scans", types.UserRoleAdmin},
+
+	// Tags
+	{fiber.MethodGet, "/tags", types.UserRoleUser},
+	{fiber.MethodGet, "/tags/:name", types.UserRoleUser},
+	{fiber.MethodPost, "/tags", types.UserRoleAdmin},
+	{fiber.MethodPut, "/tags/:id", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/tags/:id", types.UserRoleAdmin},
+
+	// Logs
+	{fiber.MethodGet, "/logs/", types.UserRoleAdmin},
+	{fiber.MethodGet, "/logs/types", types.UserRoleAdmin},
+
+	// Settings
+	{fiber.MethodGet, "/settings", types.UserRoleAdmin},
+	{fiber.MethodPut, "/settings", types.UserRoleAdmin},
+
+	// Search
+	{fiber.MethodGet, "/search/transcripts", types.UserRoleUser},
+
+	// Users
+	{fiber.MethodGet, "/users", types.UserRoleAdmin},
+	{fiber.MethodGet, "/users/:id", types.UserRoleAdmin},
+	{fiber.MethodPost, "/users", types.UserRoleAdmin},
+	{fiber.MethodPut, "/users/:id", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/users/:id", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/users/:id/sessions", types.UserRoleAdmin},
+
+	// Login lockouts
+	{fiber.MethodGet, "/lockouts", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/lockouts", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/lockouts/:type/:value", types.UserRoleAdmin},
+
+	// Shares
+	{fiber.MethodGet, "/shares", types.UserRoleAdmin},
+	{fiber.MethodDelete, "/shares/:id", types.UserRoleAdmin},
+	{fiber.MethodGet, "/shares/:id/accesses", types.UserRoleAdmin},
+
+	// Me
+	{fiber.MethodPut, "/me/password", types.UserRoleUser},
+	{fiber.MethodGet, "/me/tokens", types.UserRoleUser},
+	{fiber.MethodPost, "/me/tokens", types.UserRoleUser},
+	{fiber.MethodDelete, "/me/tokens/:id", types.UserRoleUser},
+	{fiber.MethodGet, "/me/sessions", types.UserRoleUser},
+	{fiber.MethodDelete, "/me/sessions/:id", types.UserRoleUser},
+	{fiber.MethodGet, "/me/shares", types.UserRoleUser},
+	{fiber.MethodPost, "/me/shares", types.UserRoleUser},
+	{fiber.MethodDelete, "/me/shares/:id", types.UserRoleUser},
+	{fiber.MethodGet, "/me/shares/:id/accesses", types.UserRoleUser},
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// tokenScope returns the scope an API token requires to access the route. Reading only requires the
+// read-only scope and recording progress requires the progress-write scope. Everything else
+// requires the admin scope
+func (p routePermission) tokenScope() types.TokenScope {
+	if p.Method == fiber.MethodGet {
+		return types.TokenScopeReadOnly
+	}
+
+	if p.Method == fiber.MethodPut && strings.HasSuffix(p.Path, "/progress") {
+		return types.TokenScopeProgressWrite
+	}
+
+	return types.TokenScopeAdmin
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// applyPermissions registers a role check in front of each route in the table. It must be called
+// after the auth middleware and before the routes are initialized, so the check runs first and
+// hands over to the route handler via `c.Next()`
+func (r *Router) applyPermissions(permissions []routePermission) {
+	for _, p := range permissions {
+		if p.Method == fiber.MethodGet {
+			r.api.Get(p.Path, r.requirePermission(p))
+		} else {
+			r.api.Add(p.Method, p.Path, r.requirePermission(p))
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// requirePermission returns a handler that responds with a 403 when the logged in user does not have
+// the required role or, for a request authenticated by an API token, the token does not have the
+// required scope. There is no user while no admin exists (first-run), in which case the request is
+// let through
+func (r *Router) requirePermission(p routePermission) fiber.Handler {
+	scope := p.tokenScope()
+
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals(localsUserKey).(*models.User)
+		if !ok {
+			hasAdmin, err := r.adminExists(c.Context())
+			if err != nil {
+				return errorResponse(c, fiber.StatusInternalServerError, "Error looking up auth status", err)
+			}
+
+			if hasAdmin {
+				return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+			}
+
+			return c.Next()
+		}
+
+		if !user.Role.Allows(p.Role) {
+			return errorResponse(c, fiber.StatusForbidden, "Forbidden", nil)
+		}
+
+		if token, ok := c.Locals(localsTokenKey).(*models.ApiToken); ok && !token.Scope.Allows(scope) {
+			return errorResponse(c, fiber.StatusForbidden, "Token scope does not allow this request", nil)
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/geerew/off-course/utils/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestPermissions_Table(t *testing.T) {
+	t.Run("every route is listed", func(t *testing.T) {
+		router, _ := setup(t)
+
+		listed := map[string]bool{}
+		for _, p := range routePermissions {
+			listed[p.Method+" /api"+p.Path] = true
+		}
+
+		// A listed route is registered twice, once for the role check and once for the handler
+		registered := map[string]int{}
+		for _, route := range router.router.GetRoutes(true) {
+			if !strings.HasPrefix(route.Path, "/api/") || strings.HasPrefix(route.Path, "/api/auth/") || strings.HasPrefix(route.Path, "/api/shared/") {
+				continue
+			}
+
+			// HEAD routes are registered alongside GET routes
+			if route.Method == fiber.MethodHead {
+				continue
+			}
+
+			key := route.Method + " " + route.Path
+			registered[key]++
+			require.True(t, listed[key], "route %s is missing from the permission table", key)
+		}
+
+		for key := range listed {
+			require.Equal(t, 2, registered[key], "permission %s does not match a route", key)
+		}
+	})
+
+	t.Run("valid roles", func(t *testing.T) {
+		for _, p := range routePermissions {
+			require.True(t, p.Role.IsValid(), "%s %s", p.Method, p.Path)
+		}
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestPermissions_Routes(t *testing.T) {
+	router, _ := setup(t)
+
+	createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+	createUserHelper(t, router, "user", "password", types.UserRoleUser)
+
+	adminCookie := loginHelper(t, router, "admin", "password")
+	userCookie := loginHelper(t, router, "user", "password")
+
+	// Group the table by the first path segment (fileSystem, courses, scans, ...)
+	groups := map[string][]routePermission{}
+	for _, p := range routePermissions {
+		group := strings.Split(strings.TrimPrefix(p.Path, "/"), "/")[0]
+		groups[group] = append(groups[group], p)
+	}
+
+	for group, permissions := range groups {
+		t.Run(group, func(t *testing.T) {
+			for _, p := range permissions {
+				path := "/api" + permissionPathHelper(p.Path)
+
+				// Anonymous
+				status, _, err := requestHelper(t, router, httptest.NewRequest(p.Method, path, nil))
+				require.NoError(t, err)
+				require.Equal(t, http.StatusUnauthorized, status, "anonymous %s %s", p.Method, path)
+
+				// User
+				req := httptest.NewRequest(p.Method, path, nil)
+				req.AddCookie(userCookie)
+
+				status, _, err = requestHelper(t, router, req)
+				require.NoError(t, err)
+
+				if p.Role == types.UserRoleAdmin {
+					require.Equal(t, http.StatusForbidden, status, "user %s %s", p.Method, path)
+				} else {
+					require.NotEqual(t, http.StatusForbidden, status, "user %s %s", p.Method, path)
+					require.NotEqual(t, http.StatusUnauthorized, status, "user %s %s", p.Method, path)
+				}
+
+				// Admin
+				req = httptest.NewRequest(p.Method, path, nil)
+				req.AddCookie(adminCookie)
+
+				status, _, err = requestHelper(t, router, req)
+				require.NoError(t, err)
+				require.NotEqual(t, http.StatusForbidden, status, "admin %s %s", p.Method, path)
+				require.NotEqual(t, http.StatusUnauthorized, status, "admin %s %s", p.Method, path)
+			}
+		})
+	}
+
+	t.Run("head", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodHead, "/api/fileSystem", nil)
+		req.AddCookie(userCookie)
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestPermissions_FirstRun(t *testing.T) {
+	router, _ := setup(t)
+
+	// No admin exists, so admin routes are open in order to set up the app
+	status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/logs/", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var permissionParamRegex = regexp.MustCompile(`:[A-Za-z]+`)
+
+// permissionPathHelper fills the params of a route path with placeholder values
+func permissionPathHelper(path string) string {
+	path = permissionParamRegex.ReplaceAllString(path, "x")
+	return strings.ReplaceAll(path, "*", "x")
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ProxyAuthConfig defines the configuration for trusted reverse-proxy header authentication, for
+// when Off Course runs behind an auth proxy such as Authelia or oauth2-proxy. A request from a
+// trusted proxy carrying the user header is authenticated as that user, who is created on first
+// sight
+type ProxyAuthConfig struct {
+	// The header holding the username, such as `Remote-User`
+	UserHeader string
+
+	// The header holding the comma separated groups of the user, such as `Remote-Groups`
+	GroupsHeader string
+
+	// Members of this group are admins. Everyone else is a user
+	AdminGroup string
+
+	// The proxies the headers are trusted from. The headers are ignored from any other address
+	TrustedProxies []netip.Prefix
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ParseTrustedProxies parses a comma separated list of CIDRs. An IP without a prefix length is
+// treated as a single address
+func ParseTrustedProxies(value string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+			}
+
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// isTrusted returns whether the address is one of the trusted proxies
+func (config *ProxyAuthConfig) isTrusted(ip net.IP) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range config.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// role maps the groups header to a role. A member of the admin group is an admin
+func (config *ProxyAuthConfig) role(groups string) types.UserRole {
+	if config.AdminGroup == "" {
+		return types.UserRoleUser
+	}
+
+	members := strings.Split(groups, ",")
+	for i := range members {
+		members[i] = strings.TrimSpace(members[i])
+	}
+
+	if slices.Contains(members, config.AdminGroup) {
+		return types.UserRoleAdmin
+	}
+
+	return types.UserRoleUser
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Router helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// proxyUser returns the user identified by the proxy user header. Nil is returned when proxy auth
+// is not configured, the header is not set or the request did not come from a trusted proxy
+//
+// The user is created on first sight and their role follows the groups header. A disabled user is
+// returned as is, for the caller to reject
+func (r *Router) proxyUser(c *fiber.Ctx) (*models.User, error) {
+	config := r.config.ProxyAuth
+	if config == nil || config.UserHeader == "" {
+		return nil, nil
+	}
+
+	username := strings.TrimSpace(c.Get(config.UserHeader))
+	if username == "" {
+		return nil, nil
+	}
+
+	// Logged at debug level, as every request from the address would be logged
+	if remoteIP := c.Context().RemoteIP(); !config.isTrusted(remoteIP) {
+		r.config.Logger.Debug(
+			"Ignoring proxy auth header from an untrusted address",
+			slog.Any("type", types.LogTypeRequest),
+			slog.String("ip", remoteIP.String()),
+		)
+
+		return nil, nil
+	}
+
+	role := config.role(c.Get(config.GroupsHeader))
+
+	user := &models.User{Username: username}
+	if err := r.dao.GetUserByUsername(c.Context(), user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return r.provisionProxyUser(c.Context(), username, role)
+		}
+
+		return nil, err
+	}
+
+	if err := r.syncRole(c.Context(), user, role); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// provisionProxyUser creates a user seen for the first time in the proxy user header
+func (r *Router) provisionProxyUser(ctx context.Context, username string, role types.UserRole) (*models.User, error) {
+	user := &models.User{Username: username, Role: role}
+
+	if err := r.provisionUser(ctx, user); err != nil {
+		// Another request may have created the user first
+		existing := &models.User{Username: username}
+		if getErr := r.dao.GetUserByUsername(ctx, existing); getErr == nil {
+			return existing, nil
+		}
+
+		return nil, err
+	}
+
+	r.config.Logger.Info(
+		"Created user from the proxy auth header",
+		slog.Any("type", types.LogTypeRequest),
+		slog.String("username", user.Username),
+		slog.String("role", user.Role.String()),
+	)
+
+	return user, nil
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestProxyAuth_ParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		err      bool
+	}{
+		{"", []string{}, false},
+		{"10.0.0.0/8", []string{"10.0.0.0/8"}, false},
+		{"10.1.2.3/8, 192.168.1.10", []string{"10.0.0.0/8", "192.168.1.10/32"}, false},
+		{"::1,fd00::/8", []string{"::1/128", "fd00::/8"}, false},
+		{"10.0.0.0/33", nil, true},
+		{"invalid", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			prefixes, err := ParseTrustedProxies(tt.input)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			result := []string{}
+			for _, prefix := range prefixes {
+				result = append(result, prefix.String())
+			}
+
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestProxyAuth_Role(t *testing.T) {
+	config := &ProxyAuthConfig{AdminGroup: "admins"}
+
+	require.Equal(t, types.UserRoleAdmin, config.role("admins"))
+	require.Equal(t, types.UserRoleAdmin, config.role("dev, admins"))
+	require.Equal(t, types.UserRoleUser, config.role("dev"))
+	require.Equal(t, types.UserRoleUser, config.role("admins-old"))
+	require.Equal(t, types.UserRoleUser, config.role(""))
+
+	config.AdminGroup = ""
+	require.Equal(t, types.UserRoleUser, config.role(""))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestProxyAuth_Middleware(t *testing.T) {
+	t.Run("200 (provisioned)", func(t *testing.T) {
+		router, ctx := setup(t)
+		proxyAuthHelper(t, router, "0.0.0.0/32")
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		for range 2 {
+			status, _ := proxyRequestHelper(t, router, http.MethodGet, "/api/courses", "bob", "dev")
+			require.Equal(t, http.StatusOK, status)
+		}
+
+		user := &models.User{Username: "bob"}
+		require.NoError(t, router.dao.GetUserByUsername(ctx, user))
+		require.Equal(t, types.UserRoleUser, user.Role)
+
+		// The role is enforced
+		status, _ := proxyRequestHelper(t, router, http.MethodGet, "/api/users", "bob", "dev")
+		require.Equal(t, http.StatusForbidden, status)
+
+		// The random password cannot be used to log in
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": ""}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("200 (first admin)", func(t *testing.T) {
+		router, ctx := setup(t)
+		proxyAuthHelper(t, router, "0.0.0.0/32")
+
+		status, _ := proxyRequestHelper(t, router, http.MethodGet, "/api/users", "alice", "dev,admins")
+		require.Equal(t, http.StatusOK, status)
+
+		hasAdmin, err := router.dao.GetHasAdmin(ctx)
+		require.NoError(t, err)
+		require.True(t, hasAdmin)
+
+		// The app has left the first-run state
+		status, _, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/courses", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("200 (role follows groups)", func(t *testing.T) {
+		router, ctx := setup(t)
+		proxyAuthHelper(t, router, "0.0.0.0/32")
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+
+		status, _ := proxyRequestHelper(t, router, http.MethodGet, "/api/users", "bob", "admins")
+		require.Equal(t, http.StatusOK, status)
+
+		user := &models.User{Username: "bob"}
+		require.NoError(t, router.dao.GetUserByUsername(ctx, user))
+		require.Equal(t, types.UserRoleAdmin, user.Role)
+
+		status, _ = proxyRequestHelper(t, router, http.MethodGet, "/api/users", "bob", "")
+		require.Equal(t, http.StatusForbidden, status)
+
+		require.NoError(t, router.dao.GetUserByUsername(ctx, user))
+		require.Equal(t, types.UserRoleUser, user.Role)
+	})
+
+	t.Run("200 (last admin kept)", func(t *testing.T) {
+		router, ctx := setup(t)
+		proxyAuthHelper(t, router, "0.0.0.0/32")
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		status, _ := proxyRequestHelper(t, router, http.MethodGet, "/api/users", "admin", "")
+		require.Equal(t, http.StatusOK, status)
+
+		user := &models.User{Username: "admin"}
+		require.NoError(t, router.dao.GetUserByUsername(ctx, user))
+		require.Equal(t, types.UserRoleAdmin, user.Role)
+	})
+
+	t.Run("401 (untrusted address)", func(t *testing.T) {
+		router, ctx := setup(t)
+		proxyAuthHelper(t, router, "10.0.0.0/8")
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		status, _ := proxyRequestHelper(t, router, http.MethodGet, "/api/courses", "admin", "admins")
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		// The user is not provisioned
+		status, _ = proxyRequestHelper(t, router, http.MethodGet, "/api/courses", "bob", "")
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		count, err := router.dao.Count(ctx, &models.User{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("401 (not configured)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		status, _ := proxyRequestHelper(t, router, http.MethodGet, "/api/courses", "admin", "admins")
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("403 (disabled)", func(t *testing.T) {
+		router, ctx := setup(t)
+		proxyAuthHelper(t, router, "0.0.0.0/32")
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		bob := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+
+		bob.Disabled = true
+		require.NoError(t, router.dao.UpdateUser(ctx, bob))
+
+		status, body := proxyRequestHelper(t, router, http.MethodGet, "/api/courses", "bob", "")
+		require.Equal(t, http.StatusForbidden, status)
+		require.Contains(t, string(body), "Account is disabled")
+	})
+
+	t.Run("status", func(t *testing.T) {
+		router, _ := setup(t)
+		proxyAuthHelper(t, router, "0.0.0.0/32")
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		status, body := proxyRequestHelper(t, router, http.MethodGet, "/api/auth/status", "bob", "")
+		require.Equal(t, http.StatusOK, status)
+
+		var resp authStatusResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.True(t, resp.HasAdmin)
+		require.NotNil(t, resp.User)
+		require.Equal(t, "bob", resp.User.Username)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// proxyAuthHelper enables proxy auth, trusting the CIDR. Requests made by `router.Test` come from
+// 0.0.0.0
+func proxyAuthHelper(t *testing.T, router *Router, cidr string) {
+	t.Helper()
+
+	router.config.ProxyAuth = &ProxyAuthConfig{
+		UserHeader:     "Remote-User",
+		GroupsHeader:   "Remote-Groups",
+		AdminGroup:     "admins",
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix(cidr)},
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// proxyRequestHelper makes a request with the proxy auth headers set
+func proxyRequestHelper(t *testing.T, router *Router, method, path, username, groups string) (int, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Remote-User", username)
+	req.Header.Set("Remote-Groups", groups)
+
+	status, body, err := requestHelper(t, router, req)
+	require.NoError(t, err)
+
+	return status, body
+}
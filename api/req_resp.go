@@ -1,6 +1,10 @@
 package api
 
-import "github.com/geerew/off-course/utils/types"
+import (
+	"time"
+
+	"github.com/geerew/off-course/utils/types"
+)
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
@@ -12,6 +16,13 @@ type fileSystemResponse struct {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+type courseCardRequest struct {
+	// An image file within the course
+	Path string `json:"path"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 type courseProgressResponse struct {
 	Started           bool           `json:"started"`
 	StartedAt         types.DateTime `json:"startedAt"`
@@ -23,19 +34,31 @@ type courseProgressResponse struct {
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 type courseResponse struct {
-	ID        string         `json:"id"`
-	Title     string         `json:"title"`
-	Path      string         `json:"path"`
-	HasCard   bool           `json:"hasCard"`
-	Available bool           `json:"available"`
-	CreatedAt types.DateTime `json:"createdAt"`
-	UpdatedAt types.DateTime `json:"updatedAt"`
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	Path         string         `json:"path"`
+	HasCard      bool           `json:"hasCard"`
+	CardOverride bool           `json:"cardOverride"`
+	Available    bool           `json:"available"`
+	Duration     int            `json:"duration"`
+	CreatedAt    types.DateTime `json:"createdAt"`
+	UpdatedAt    types.DateTime `json:"updatedAt"`
 
 	// Scan status
 	ScanStatus string `json:"scanStatus"`
 
 	// Progress
 	Progress courseProgressResponse `json:"progress"`
+
+	// Chapters
+	Chapters []*courseChapterResponse `json:"chapters,omitempty"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type courseChapterResponse struct {
+	Title    string `json:"title"`
+	Duration int    `json:"duration"`
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -51,6 +74,8 @@ type courseTagResponse struct {
 
 type assetProgressRequest struct {
 	VideoPos  int  `json:"videoPos"`
+	ScrollPos int  `json:"scrollPos"`
+	SlidePos  int  `json:"slidePos"`
 	Completed bool `json:"completed"`
 }
 
@@ -58,23 +83,142 @@ type assetProgressRequest struct {
 
 type assetProgressResponse struct {
 	VideoPos    int            `json:"videoPos"`
+	ScrollPos   int            `json:"scrollPos"`
+	SlidePos    int            `json:"slidePos"`
 	Completed   bool           `json:"completed"`
 	CompletedAt types.DateTime `json:"completedAt"`
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// The types of attachment. A link attachment points to an external resource (parsed from a .url,
+// .webloc or .desktop file)
+const (
+	attachmentTypeFile = "file"
+	attachmentTypeLink = "link"
+)
+
 type attachmentResponse struct {
 	ID        string         `json:"id"`
 	AssetId   string         `json:"assetId"`
 	Title     string         `json:"title"`
 	Path      string         `json:"path"`
+	Type      string         `json:"type"`
+	URL       string         `json:"url,omitempty"`
 	CreatedAt types.DateTime `json:"createdAt"`
 	UpdatedAt types.DateTime `json:"updatedAt"`
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+type attachmentPreviewResponse struct {
+	Kind      string `json:"kind"`
+	Language  string `json:"language,omitempty"`
+	Content   string `json:"content,omitempty"`
+	HTML      string `json:"html,omitempty"`
+	Truncated bool   `json:"truncated"`
+	Size      int64  `json:"size"`
+
+	// The inline URL of an image, otherwise the download URL
+	URL string `json:"url"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type archiveEntryResponse struct {
+	Path           string    `json:"path"`
+	Size           int64     `json:"size"`
+	CompressedSize int64     `json:"compressedSize"`
+	Modified       time.Time `json:"modified"`
+	IsDir          bool      `json:"isDir"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type subtitleResponse struct {
+	ID        string         `json:"id"`
+	AssetId   string         `json:"assetId"`
+	Language  string         `json:"language"`
+	Format    string         `json:"format"`
+	CreatedAt types.DateTime `json:"createdAt"`
+	UpdatedAt types.DateTime `json:"updatedAt"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type epubResponse struct {
+	Title string `json:"title"`
+
+	// The spine index that was read last
+	Position int                      `json:"position"`
+	Spine    []*epubSpineItemResponse `json:"spine"`
+	TOC      []*epubTOCEntryResponse  `json:"toc"`
+}
+
+type epubSpineItemResponse struct {
+	Index     int    `json:"index"`
+	Path      string `json:"path"`
+	Linear    bool   `json:"linear"`
+	URL       string `json:"url"`
+	ScrollPos int    `json:"scrollPos"`
+}
+
+type epubTOCEntryResponse struct {
+	Title      string                  `json:"title"`
+	Path       string                  `json:"path,omitempty"`
+	Fragment   string                  `json:"fragment,omitempty"`
+	SpineIndex int                     `json:"spineIndex"`
+	URL        string                  `json:"url,omitempty"`
+	Children   []*epubTOCEntryResponse `json:"children,omitempty"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type epubProgressRequest struct {
+	SpineIndex int `json:"spineIndex"`
+	ScrollPos  int `json:"scrollPos"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type slidesResponse struct {
+	// The index of the slide that was viewed last
+	Position int              `json:"position"`
+	Slides   []*slideResponse `json:"slides"`
+}
+
+type slideResponse struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type chapterMarkerResponse struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type transcriptMatchResponse struct {
+	CourseID    string `json:"courseId"`
+	CourseTitle string `json:"courseTitle"`
+	AssetID     string `json:"assetId"`
+	AssetTitle  string `json:"assetTitle"`
+	Chapter     string `json:"chapter"`
+	Language    string `json:"language"`
+	Start       int64  `json:"start"`
+	End         int64  `json:"end"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 type assetResponse struct {
 	ID        string         `json:"id"`
 	CourseID  string         `json:"courseId"`
@@ -86,9 +230,24 @@ type assetResponse struct {
 	CreatedAt types.DateTime `json:"createdAt"`
 	UpdatedAt types.DateTime `json:"updatedAt"`
 
+	// The target of a link asset
+	URL string `json:"url,omitempty"`
+
+	// Media
+	Duration    int    `json:"duration"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	VideoCodec  string `json:"videoCodec"`
+	AudioCodec  string `json:"audioCodec"`
+	AudioTracks int    `json:"audioTracks"`
+
+	// How a video should be played. Either `direct` (serve) or `transcode` (stream)
+	Playback string `json:"playback,omitempty"`
+
 	// Relations
 	Progress    *assetProgressResponse `json:"progress"`
 	Attachments []*attachmentResponse  `json:"attachments,omitempty"`
+	Subtitles   []*subtitleResponse    `json:"subtitles,omitempty"`
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -127,3 +286,191 @@ type logResponse struct {
 	Data      types.JsonMap  `json:"data"`
 	CreatedAt types.DateTime `json:"createdAt"`
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type authRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type authStatusResponse struct {
+	// False until the first admin has been created
+	HasAdmin bool `json:"hasAdmin"`
+
+	// Whether users can log in through an OpenID Connect provider
+	OIDC bool `json:"oidc"`
+
+	// The logged in user, if any
+	User *userResponse `json:"user"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type userResponse struct {
+	ID        string         `json:"id"`
+	Username  string         `json:"username"`
+	Role      types.UserRole `json:"role"`
+	Disabled  bool           `json:"disabled"`
+	CreatedAt types.DateTime `json:"createdAt"`
+	UpdatedAt types.DateTime `json:"updatedAt"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type userCreateRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// Defaults to user
+	Role types.UserRole `json:"role"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Each field is unchanged when nil
+type userUpdateRequest struct {
+	Role     *types.UserRole `json:"role"`
+	Password *string         `json:"password"`
+	Disabled *bool           `json:"disabled"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type passwordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type apiTokenCreateRequest struct {
+	Name string `json:"name"`
+
+	// Defaults to read-only
+	Scope types.TokenScope `json:"scope"`
+
+	// The token never expires when empty
+	ExpiresAt types.DateTime `json:"expiresAt"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type apiTokenResponse struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Scope      types.TokenScope `json:"scope"`
+	ExpiresAt  types.DateTime   `json:"expiresAt"`
+	LastUsedAt types.DateTime   `json:"lastUsedAt"`
+	CreatedAt  types.DateTime   `json:"createdAt"`
+
+	// The token itself. This is only returned when the token is created
+	Token string `json:"token,omitempty"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type sessionResponse struct {
+	ID         string         `json:"id"`
+	UserAgent  string         `json:"userAgent"`
+	IPAddress  string         `json:"ipAddress"`
+	ExpiresAt  types.DateTime `json:"expiresAt"`
+	LastSeenAt types.DateTime `json:"lastSeenAt"`
+	CreatedAt  types.DateTime `json:"createdAt"`
+
+	// Whether this is the session making the request
+	Current bool `json:"current"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type lockoutResponse struct {
+	// Either `username` or `ip`
+	Type        string    `json:"type"`
+	Value       string    `json:"value"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"lastFailure"`
+	RetryAt     time.Time `json:"retryAt"`
+
+	// Whether the username or IP address is locked out, rather than backing off
+	Locked bool `json:"locked"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type settingsRequest struct {
+	ProgressMode          types.ProgressMode `json:"progressMode"`
+	ProgressUntimedWeight int                `json:"progressUntimedWeight"`
+
+	// Unchanged when nil. An empty string disables ffmpeg
+	FFmpegPath *string `json:"ffmpegPath"`
+
+	// Unchanged when nil
+	SlidesGroupDirectories *bool `json:"slidesGroupDirectories"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type settingsResponse struct {
+	ProgressMode          types.ProgressMode `json:"progressMode"`
+	ProgressUntimedWeight int                `json:"progressUntimedWeight"`
+	FFmpegPath            string             `json:"ffmpegPath"`
+
+	SlidesGroupDirectories bool `json:"slidesGroupDirectories"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type shareCreateRequest struct {
+	CourseID string `json:"courseId"`
+
+	// The whole course is shared when empty
+	AssetID string `json:"assetId"`
+
+	ExpiresAt types.DateTime `json:"expiresAt"`
+
+	// The number of distinct viewers allowed. 0 allows unlimited viewers
+	MaxViews int `json:"maxViews"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type shareResponse struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"userId"`
+	CourseID  string         `json:"courseId"`
+	AssetID   string         `json:"assetId"`
+	ExpiresAt types.DateTime `json:"expiresAt"`
+	MaxViews  int            `json:"maxViews"`
+	Views     int            `json:"views"`
+	Revoked   bool           `json:"revoked"`
+	Expired   bool           `json:"expired"`
+	CreatedAt types.DateTime `json:"createdAt"`
+
+	// The signed token, used in the share link
+	Token string `json:"token"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type shareAccessResponse struct {
+	ID        string         `json:"id"`
+	IPAddress string         `json:"ipAddress"`
+	UserAgent string         `json:"userAgent"`
+	Path      string         `json:"path"`
+	CreatedAt types.DateTime `json:"createdAt"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type sharedResponse struct {
+	ExpiresAt types.DateTime `json:"expiresAt"`
+
+	// Empty when the whole course is shared
+	AssetID string `json:"assetId"`
+
+	Course *courseResponse  `json:"course"`
+	Assets []*assetResponse `json:"assets"`
+}
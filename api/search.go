@@ -0,0 +1,77 @@
+package api
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/pagination"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type searchAPI struct {
+	logger *slog.Logger
+	dao    *dao.DAO
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initSearchRoutes initializes the search routes
+func (r *Router) initSearchRoutes() {
+	searchAPI := searchAPI{
+		logger: r.config.Logger,
+		dao:    r.dao,
+	}
+
+	searchGroup := r.api.Group("/search")
+	searchGroup.Get("/transcripts", searchAPI.searchTranscripts)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// searchTranscripts searches the subtitle transcripts for the `q` query param. The results can be
+// filtered by course (`courseId`) and subtitle language (`lang`)
+func (api *searchAPI) searchTranscripts(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q", ""))
+	courseID := c.Query("courseId", "")
+	lang := c.Query("lang", "")
+
+	if query == "" {
+		return errorResponse(c, fiber.StatusBadRequest, "A search query is required", nil)
+	}
+
+	options := &database.Options{
+		Pagination: pagination.NewFromApi(c),
+	}
+
+	whereClause := squirrel.And{}
+
+	if courseID != "" {
+		whereClause = append(whereClause, squirrel.Eq{models.COURSE_TABLE + ".id": courseID})
+	}
+
+	if lang != "" {
+		whereClause = append(whereClause, squirrel.Eq{models.SUBTITLE_TABLE + "." + models.SUBTITLE_LANGUAGE: strings.ToLower(lang)})
+	}
+
+	if len(whereClause) > 0 {
+		options.Where = whereClause
+	}
+
+	matches, err := api.dao.SearchTranscripts(c.Context(), query, options)
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error searching transcripts", err)
+	}
+
+	pResult, err := options.Pagination.BuildResult(transcriptMatchResponseHelper(matches))
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error building pagination result", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(pResult)
+}
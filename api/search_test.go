@@ -0,0 +1,161 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSearch_SearchTranscripts(t *testing.T) {
+	t.Run("200 (found)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		courses := []*models.Course{}
+		for i := range 2 {
+			course := &models.Course{Title: fmt.Sprintf("Course %d", i+1), Path: fmt.Sprintf("/course-%d", i+1)}
+			require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    "asset 1",
+				Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+				Chapter:  "01 Intro",
+				Type:     *types.NewAsset("mp4"),
+				Path:     fmt.Sprintf("%s/01 Intro/01 asset.mp4", course.Path),
+				Hash:     security.RandomString(64),
+			}
+			require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+			for _, lang := range []string{"en", "fr"} {
+				subtitle := &models.Subtitle{
+					AssetID:  asset.ID,
+					Language: lang,
+					Format:   "srt",
+					Path:     fmt.Sprintf("%s/01 Intro/01 asset.%s.srt", course.Path, lang),
+				}
+				require.NoError(t, router.dao.CreateSubtitle(ctx, subtitle))
+
+				cues := []*dao.TranscriptCue{
+					{Start: 1000, End: 2000, Text: "install the toolchain"},
+					{Start: 65500, End: 67000, Text: "configure the server"},
+				}
+				require.NoError(t, router.dao.IndexTranscript(ctx, subtitle, cues))
+			}
+
+			courses = append(courses, course)
+		}
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/search/transcripts?q=server", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, matchesResp := unmarshalHelper[transcriptMatchResponse](t, body)
+		require.Equal(t, 4, paginationResp.TotalItems)
+		require.Len(t, matchesResp, 4)
+
+		// Course filter
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/search/transcripts?q=server&courseId="+courses[1].ID, nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, matchesResp = unmarshalHelper[transcriptMatchResponse](t, body)
+		require.Equal(t, 2, paginationResp.TotalItems)
+		require.Equal(t, courses[1].ID, matchesResp[0].CourseID)
+
+		// Course and language filter
+		status, body, err = requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/search/transcripts?q=server&lang=EN&courseId="+courses[1].ID, nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, matchesResp = unmarshalHelper[transcriptMatchResponse](t, body)
+		require.Equal(t, 1, paginationResp.TotalItems)
+
+		match := matchesResp[0]
+		require.Equal(t, courses[1].ID, match.CourseID)
+		require.Equal(t, "Course 2", match.CourseTitle)
+		require.Equal(t, "asset 1", match.AssetTitle)
+		require.Equal(t, "01 Intro", match.Chapter)
+		require.Equal(t, "en", match.Language)
+		require.Equal(t, int64(65500), match.Start)
+		require.Equal(t, int64(67000), match.End)
+		require.Equal(t, "configure the server", match.Text)
+		require.Equal(t, fmt.Sprintf("/course?a=%s&id=%s&t=65", match.AssetID, courses[1].ID), match.Link)
+	})
+
+	t.Run("200 (not found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/search/transcripts?q=server", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, matchesResp := unmarshalHelper[transcriptMatchResponse](t, body)
+		require.Zero(t, paginationResp.TotalItems)
+		require.Empty(t, matchesResp)
+	})
+
+	t.Run("200 (pagination)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		subtitle := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: "/course-1/01 asset.srt"}
+		require.NoError(t, router.dao.CreateSubtitle(ctx, subtitle))
+
+		cues := []*dao.TranscriptCue{}
+		for i := range 15 {
+			cues = append(cues, &dao.TranscriptCue{Start: int64(i * 1000), End: int64(i*1000 + 500), Text: "hello"})
+		}
+		require.NoError(t, router.dao.IndexTranscript(ctx, subtitle, cues))
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/search/transcripts?q=hello&page=2&perPage=10", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, matchesResp := unmarshalHelper[transcriptMatchResponse](t, body)
+		require.Equal(t, 15, paginationResp.TotalItems)
+		require.Equal(t, 2, paginationResp.TotalPages)
+		require.Len(t, matchesResp, 5)
+	})
+
+	t.Run("400 (missing query)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/search/transcripts?q=%20", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "A search query is required")
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS transcripts")
+		require.NoError(t, err)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/search/transcripts?q=server", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
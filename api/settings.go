@@ -0,0 +1,113 @@
+package api
+
+import (
+	"log/slog"
+
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/utils/transcode"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type settingsAPI struct {
+	logger *slog.Logger
+	dao    *dao.DAO
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initSettingsRoutes initializes the settings routes
+func (r *Router) initSettingsRoutes() {
+	settingsAPI := settingsAPI{
+		logger: r.config.Logger,
+		dao:    r.dao,
+	}
+
+	settingsGroup := r.api.Group("/settings")
+	settingsGroup.Get("", settingsAPI.getSettings)
+	settingsGroup.Put("", settingsAPI.updateSettings)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (api *settingsAPI) getSettings(c *fiber.Ctx) error {
+	settings, err := api.dao.GetProgressSettings(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up settings", err)
+	}
+
+	ffmpegPath, err := api.dao.GetFFmpegPath(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up settings", err)
+	}
+
+	slidesGroupDirectories, err := api.dao.GetSlidesGroupDirectories(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up settings", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(settingsResponseHelper(settings, ffmpegPath, slidesGroupDirectories))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// updateSettings updates the settings. As the progress settings affect the percent complete of every
+// course, the progress of all courses is refreshed
+//
+// The ffmpeg path is only updated when set in the request. A non-empty path must point to a working
+// ffmpeg executable. Grouping directories of images into slide decks is also only updated when set,
+// and applies once courses are rescanned
+func (api *settingsAPI) updateSettings(c *fiber.Ctx) error {
+	req := &settingsRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	if !req.ProgressMode.IsValid() {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid progress mode", nil)
+	}
+
+	if req.ProgressUntimedWeight < 0 {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid progress untimed weight", nil)
+	}
+
+	if req.FFmpegPath != nil && *req.FFmpegPath != "" {
+		if _, err := transcode.Version(c.Context(), *req.FFmpegPath); err != nil {
+			return errorResponse(c, fiber.StatusBadRequest, "Invalid ffmpeg path", err)
+		}
+	}
+
+	settings := &dao.ProgressSettings{
+		Mode:          req.ProgressMode,
+		UntimedWeight: req.ProgressUntimedWeight,
+	}
+
+	if err := api.dao.UpdateProgressSettings(c.Context(), settings); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error updating settings", err)
+	}
+
+	if req.FFmpegPath != nil {
+		if err := api.dao.UpdateFFmpegPath(c.Context(), *req.FFmpegPath); err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error updating settings", err)
+		}
+	}
+
+	if req.SlidesGroupDirectories != nil {
+		if err := api.dao.UpdateSlidesGroupDirectories(c.Context(), *req.SlidesGroupDirectories); err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error updating settings", err)
+		}
+	}
+
+	ffmpegPath, err := api.dao.GetFFmpegPath(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up settings", err)
+	}
+
+	slidesGroupDirectories, err := api.dao.GetSlidesGroupDirectories(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up settings", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(settingsResponseHelper(settings, ffmpegPath, slidesGroupDirectories))
+}
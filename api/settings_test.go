@@ -0,0 +1,222 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/mocks"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSettings_GetSettings(t *testing.T) {
+	t.Run("200 (found)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, body, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/settings", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var settingsResp settingsResponse
+		err = json.Unmarshal(body, &settingsResp)
+		require.NoError(t, err)
+		require.Equal(t, types.ProgressModeCount, settingsResp.ProgressMode)
+		require.Equal(t, 60, settingsResp.ProgressUntimedWeight)
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.PARAM_TABLE)
+		require.NoError(t, err)
+
+		status, _, err := requestHelper(t, router, httptest.NewRequest(http.MethodGet, "/api/settings", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSettings_UpdateSettings(t *testing.T) {
+	t.Run("200 (updated)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		assets := []*models.Asset{}
+		for i, duration := range []int{300, 100} {
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    fmt.Sprintf("asset %d", i+1),
+				Prefix:   sql.NullInt16{Int16: int16(i + 1), Valid: true},
+				Type:     *types.NewAsset("mp4"),
+				Path:     fmt.Sprintf("/course-1/%02d asset.mp4", i+1),
+				Hash:     security.RandomString(64),
+				Duration: duration,
+			}
+			require.NoError(t, router.dao.CreateAsset(ctx, asset))
+			assets = append(assets, asset)
+		}
+
+		require.NoError(t, router.dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[1].ID, Completed: true}))
+
+		require.NoError(t, router.dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 50, course.Progress.Percent)
+
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"progressMode":"duration","progressUntimedWeight":30}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var settingsResp settingsResponse
+		err = json.Unmarshal(body, &settingsResp)
+		require.NoError(t, err)
+		require.Equal(t, types.ProgressModeDuration, settingsResp.ProgressMode)
+		require.Equal(t, 30, settingsResp.ProgressUntimedWeight)
+
+		// The course progress is refreshed
+		require.NoError(t, router.dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 25, course.Progress.Percent)
+	})
+
+	t.Run("200 (ffmpeg path)", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("ffmpeg stub requires a unix shell")
+		}
+
+		router, ctx := setup(t)
+
+		ffmpegPath, err := mocks.StubFFmpeg(t.TempDir())
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"progressMode":"count","ffmpegPath":"`+ffmpegPath+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var settingsResp settingsResponse
+		require.NoError(t, json.Unmarshal(body, &settingsResp))
+		require.Equal(t, ffmpegPath, settingsResp.FFmpegPath)
+
+		// Unchanged when not set
+		req = httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"progressMode":"count"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, _, err = requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		path, err := router.dao.GetFFmpegPath(ctx)
+		require.NoError(t, err)
+		require.Equal(t, ffmpegPath, path)
+
+		// Cleared
+		req = httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"progressMode":"count","ffmpegPath":""}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, body, err = requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		require.NoError(t, json.Unmarshal(body, &settingsResp))
+		require.Empty(t, settingsResp.FFmpegPath)
+	})
+
+	t.Run("200 (slides group directories)", func(t *testing.T) {
+		router, ctx := setup(t)
+
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"progressMode":"count","slidesGroupDirectories":true}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		var settingsResp settingsResponse
+		require.NoError(t, json.Unmarshal(body, &settingsResp))
+		require.True(t, settingsResp.SlidesGroupDirectories)
+
+		// Unchanged when not set
+		req = httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"progressMode":"count"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, _, err = requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		group, err := router.dao.GetSlidesGroupDirectories(ctx)
+		require.NoError(t, err)
+		require.True(t, group)
+
+		// Disabled
+		req = httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"progressMode":"count","slidesGroupDirectories":false}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, body, err = requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, status)
+
+		require.NoError(t, json.Unmarshal(body, &settingsResp))
+		require.False(t, settingsResp.SlidesGroupDirectories)
+	})
+
+	t.Run("400 (bind error)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`bob`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, body, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Error parsing data")
+	})
+
+	t.Run("400 (invalid data)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		for body, msg := range map[string]string{
+			`{"progressMode":"bob"}`:                                  "Invalid progress mode",
+			`{"progressMode":"count","progressUntimedWeight":-1}`:     "Invalid progress untimed weight",
+			`{"progressMode":"count","ffmpegPath":"/invalid/ffmpeg"}`: "Invalid ffmpeg path",
+		} {
+			req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			status, respBody, err := requestHelper(t, router, req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, status)
+			require.Contains(t, string(respBody), msg)
+		}
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.PARAM_TABLE)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"progressMode":"duration"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		status, _, err := requestHelper(t, router, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
@@ -0,0 +1,484 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/pagination"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The fiber locals key holding the share of the request, when accessed through a share link
+const localsShareKey = "share"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type sharesAPI struct {
+	logger *slog.Logger
+	dao    *dao.DAO
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initShareRoutes initializes the share routes. Users manage their own shares through the `/me`
+// routes, while the `/shares` routes allow an admin to manage the shares of every user
+func (r *Router) initShareRoutes() {
+	sharesAPI := sharesAPI{
+		logger: r.config.Logger,
+		dao:    r.dao,
+	}
+
+	shareGroup := r.api.Group("/shares")
+	shareGroup.Get("", sharesAPI.getShares)
+	shareGroup.Delete("/:id", sharesAPI.revokeShare)
+	shareGroup.Get("/:id/accesses", sharesAPI.getShareAccesses)
+
+	meGroup := r.api.Group("/me/shares")
+	meGroup.Get("", sharesAPI.getMyShares)
+	meGroup.Post("", sharesAPI.createShare)
+	meGroup.Delete("/:id", sharesAPI.revokeMyShare)
+	meGroup.Get("/:id/accesses", sharesAPI.getMyShareAccesses)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initSharedRoutes initializes the routes accessed through a share link. These routes are
+// registered before the auth middleware, as the share token is the only credential. They are
+// read-only and limited to viewing an asset and its attachments
+func (r *Router) initSharedRoutes() {
+	sharesAPI := sharesAPI{
+		logger: r.config.Logger,
+		dao:    r.dao,
+	}
+
+	coursesAPI := newCoursesAPI(r)
+
+	sharedGroup := r.api.Group("/shared/:token")
+	sharedGroup.Get("", sharesAPI.shareView, sharesAPI.getShared)
+
+	// Asset
+	assetGroup := sharedGroup.Group("/courses/:id/assets/:asset")
+	assetGroup.Get("/serve", sharesAPI.shareView, coursesAPI.serveAsset)
+	assetGroup.Get("/stream/:file", sharesAPI.shareAccess, coursesAPI.streamAsset)
+	assetGroup.Get("/subtitles/:lang", sharesAPI.shareAccess, coursesAPI.serveSubtitle)
+	assetGroup.Get("/chapters", sharesAPI.shareAccess, coursesAPI.getChapterMarkers)
+	assetGroup.Get("/thumbnails.vtt", sharesAPI.shareAccess, coursesAPI.getThumbnails)
+	assetGroup.Get("/thumbnails.jpg", sharesAPI.shareAccess, coursesAPI.serveSprite)
+	assetGroup.Get("/poster.jpg", sharesAPI.shareAccess, coursesAPI.servePoster)
+
+	// Asset EPUB and slides. The reading progress is not updated through a share
+	assetGroup.Get("/epub", sharesAPI.shareView, coursesAPI.getEPUB)
+	assetGroup.Get("/epub/*", sharesAPI.shareAccess, coursesAPI.serveEPUBResource)
+	assetGroup.Get("/slides", sharesAPI.shareView, coursesAPI.getSlides)
+	assetGroup.Get("/slides/:index", sharesAPI.shareAccess, coursesAPI.serveSlide)
+
+	// Asset attachments
+	assetGroup.Get("/attachments", sharesAPI.shareAccess, coursesAPI.getAttachments)
+	assetGroup.Get("/attachments/:attachment", sharesAPI.shareAccess, coursesAPI.getAttachment)
+	assetGroup.Get("/attachments/:attachment/serve", sharesAPI.shareView, coursesAPI.serveAttachment)
+	assetGroup.Get("/attachments/:attachment/preview", sharesAPI.shareAccess, coursesAPI.previewAttachment)
+	assetGroup.Get("/attachments/:attachment/entries", sharesAPI.shareAccess, coursesAPI.getAttachmentEntries)
+	assetGroup.Get("/attachments/:attachment/entries/*", sharesAPI.shareView, coursesAPI.serveAttachmentEntry)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getShares lists the shares of every user
+func (api *sharesAPI) getShares(c *fiber.Ctx) error {
+	return api.listShares(c, nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getMyShares lists the shares of the logged in user
+func (api *sharesAPI) getMyShares(c *fiber.Ctx) error {
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	return api.listShares(c, squirrel.Eq{models.SHARE_TABLE + "." + models.SHARE_USER_ID: user.ID})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// createShare creates a share of a course, or a single asset of the course, for the logged in
+// user. A share must expire
+func (api *sharesAPI) createShare(c *fiber.Ctx) error {
+	req := &shareCreateRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	if req.CourseID == "" {
+		return errorResponse(c, fiber.StatusBadRequest, "A course ID is required", nil)
+	}
+
+	if req.ExpiresAt.IsZero() {
+		return errorResponse(c, fiber.StatusBadRequest, "An expiry is required", nil)
+	}
+
+	if req.ExpiresAt.Time().Before(time.Now()) {
+		return errorResponse(c, fiber.StatusBadRequest, "The expiry must be in the future", nil)
+	}
+
+	if req.MaxViews < 0 {
+		return errorResponse(c, fiber.StatusBadRequest, "The max views cannot be negative", nil)
+	}
+
+	course := &models.Course{Base: models.Base{ID: req.CourseID}}
+	if err := api.dao.GetById(c.Context(), course); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errorResponse(c, fiber.StatusBadRequest, "Course not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course", err)
+	}
+
+	share := &models.Share{
+		UserID:    user.ID,
+		CourseID:  req.CourseID,
+		AssetID:   req.AssetID,
+		ExpiresAt: req.ExpiresAt,
+		MaxViews:  req.MaxViews,
+	}
+
+	if err := api.dao.CreateShare(c.Context(), share); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errorResponse(c, fiber.StatusBadRequest, "Asset not found", nil)
+		}
+
+		if errors.Is(err, dao.ErrShareAssetCourse) {
+			return errorResponse(c, fiber.StatusBadRequest, "Asset does not belong to course", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error creating share", err)
+	}
+
+	secret, err := api.dao.GetShareSecret(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up share secret", err)
+	}
+
+	api.logger.Info(
+		"Created share",
+		slog.Any("type", types.LogTypeRequest),
+		slog.String("share_id", share.ID),
+		slog.String("course_id", share.CourseID),
+		slog.String("asset_id", share.AssetID),
+		slog.String("by", user.Username),
+	)
+
+	return c.Status(fiber.StatusCreated).JSON(shareResponseHelper([]*models.Share{share}, secret)[0])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// revokeShare revokes a share of any user
+func (api *sharesAPI) revokeShare(c *fiber.Ctx) error {
+	return api.revoke(c, "")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// revokeMyShare revokes a share of the logged in user
+func (api *sharesAPI) revokeMyShare(c *fiber.Ctx) error {
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	return api.revoke(c, user.ID)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getShareAccesses lists the audit log of a share of any user, most recent first
+func (api *sharesAPI) getShareAccesses(c *fiber.Ctx) error {
+	return api.listAccesses(c, "")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getMyShareAccesses lists the audit log of a share of the logged in user, most recent first
+func (api *sharesAPI) getMyShareAccesses(c *fiber.Ctx) error {
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	return api.listAccesses(c, user.ID)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getShared returns what a share link grants access to. For a course, this is the course and its
+// assets. For an asset, this is the course and the single asset. Paths on disk are not included
+func (api *sharesAPI) getShared(c *fiber.Ctx) error {
+	share := c.Locals(localsShareKey).(*models.Share)
+
+	course := &models.Course{Base: models.Base{ID: share.CourseID}}
+	if err := api.dao.GetById(c.Context(), course); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up course", err)
+	}
+
+	where := squirrel.Eq{models.ASSET_TABLE + ".course_id": share.CourseID}
+	if share.AssetID != "" {
+		where[models.ASSET_TABLE+"."+models.BASE_ID] = share.AssetID
+	}
+
+	assets := []*models.Asset{}
+	options := &database.Options{
+		OrderBy: []string{"chapter asc", "prefix asc"},
+		Where:   where,
+	}
+
+	if err := api.dao.List(c.Context(), &assets, options); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up assets", err)
+	}
+
+	resp := &sharedResponse{
+		ExpiresAt: share.ExpiresAt,
+		AssetID:   share.AssetID,
+		Course:    courseResponseHelper([]*models.Course{course})[0],
+		Assets:    assetResponseHelper(assets),
+	}
+
+	resp.Course.Path = ""
+	for _, asset := range resp.Assets {
+		asset.Path = ""
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// shareView is the middleware of the shared routes that view a share, being the share itself, the
+// opening of an asset and the download of an attachment. The access is recorded in the audit log
+// (see `share`)
+//
+// A request with a `Range` header continues a view, such as a video seeking, so it is only checked
+// like any other shared request
+func (api *sharesAPI) shareView(c *fiber.Ctx) error {
+	return api.share(c, c.Get(fiber.HeaderRange) == "")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// shareAccess is the middleware of the remaining shared routes, such as the HLS segments,
+// thumbnails, subtitles and attachment listings. A single view makes many of these requests, so
+// they are checked against the share but not recorded in the audit log, unless the IP address is
+// new (see `share`)
+func (api *sharesAPI) shareAccess(c *fiber.Ctx) error {
+	return api.share(c, false)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// share verifies the share token of a shared request, checks the share is usable and the request is
+// within its scope. When view is true the access is recorded in the audit log, otherwise it is only
+// checked against the view limit. Either way, the first access of an IP address counts as a view
+//
+// A token that is not signed, or whose share no longer exists, responds with a 404. A revoked or
+// expired share, one whose owner is disabled or one whose view limit is reached, responds with a
+// 410. A course or asset outside of the share responds with a 403
+func (api *sharesAPI) share(c *fiber.Ctx, view bool) error {
+	secret, err := api.dao.GetShareSecret(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up share secret", err)
+	}
+
+	id, ok := security.VerifySignedToken(secret, c.Params("token"))
+	if !ok {
+		return errorResponse(c, fiber.StatusNotFound, "Share not found", nil)
+	}
+
+	share := &models.Share{Base: models.Base{ID: id}}
+	if err := api.dao.GetById(c.Context(), share); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errorResponse(c, fiber.StatusNotFound, "Share not found", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up share", err)
+	}
+
+	if share.Revoked {
+		return errorResponse(c, fiber.StatusGone, "Share has been revoked", nil)
+	}
+
+	if share.IsExpired() {
+		return errorResponse(c, fiber.StatusGone, "Share has expired", nil)
+	}
+
+	// A share is only usable while its owner is
+	owner := &models.User{Base: models.Base{ID: share.UserID}}
+	if err := api.dao.GetById(c.Context(), owner); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error looking up share owner", err)
+		}
+
+		return errorResponse(c, fiber.StatusGone, "Share is no longer available", nil)
+	}
+
+	if owner.Disabled {
+		return errorResponse(c, fiber.StatusGone, "Share is no longer available", nil)
+	}
+
+	if courseId := c.Params("id"); courseId != "" && courseId != share.CourseID {
+		return errorResponse(c, fiber.StatusForbidden, "Course is not shared", nil)
+	}
+
+	if assetId := c.Params("asset"); share.AssetID != "" && assetId != "" && assetId != share.AssetID {
+		return errorResponse(c, fiber.StatusForbidden, "Asset is not shared", nil)
+	}
+
+	access := &models.ShareAccess{
+		IPAddress: clientIP(c),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Path:      c.Path(),
+	}
+
+	record := api.dao.CheckShareAccess
+	if view {
+		record = api.dao.RecordShareAccess
+	}
+
+	if err := record(c.Context(), share, access); err != nil {
+		if errors.Is(err, dao.ErrShareViewLimit) {
+			return errorResponse(c, fiber.StatusGone, "Share has reached its view limit", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error recording share access", err)
+	}
+
+	// The shared handlers order by the query, which is not accepted from an anonymous request
+	c.Request().URI().QueryArgs().Del("orderBy")
+
+	c.Locals(localsShareKey, share)
+
+	return c.Next()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// listShares lists the shares matching the where clause, most recent first
+func (api *sharesAPI) listShares(c *fiber.Ctx, where squirrel.Sqlizer) error {
+	orderBy := c.Query("orderBy", models.SHARE_TABLE+"."+models.BASE_CREATED_AT+" desc")
+
+	options := &database.Options{
+		OrderBy:    strings.Split(orderBy, ","),
+		Where:      where,
+		Pagination: pagination.NewFromApi(c),
+	}
+
+	shares := []*models.Share{}
+	if err := api.dao.List(c.Context(), &shares, options); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up shares", err)
+	}
+
+	secret, err := api.dao.GetShareSecret(c.Context())
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up share secret", err)
+	}
+
+	pResult, err := options.Pagination.BuildResult(shareResponseHelper(shares, secret))
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error building pagination result", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(pResult)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// revoke revokes the share with the ID in the path. When the user ID is not empty, the share must
+// belong to the user
+func (api *sharesAPI) revoke(c *fiber.Ctx, userId string) error {
+	share, err := api.lookupShare(c, userId)
+	if share == nil {
+		return err
+	}
+
+	if err := api.dao.RevokeShare(c.Context(), share); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error revoking share", err)
+	}
+
+	api.logger.Info(
+		"Revoked share",
+		slog.Any("type", types.LogTypeRequest),
+		slog.String("share_id", share.ID),
+		slog.String("by", currentUsername(c)),
+	)
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// listAccesses lists the audit log of the share with the ID in the path, most recent first. When the
+// user ID is not empty, the share must belong to the user
+func (api *sharesAPI) listAccesses(c *fiber.Ctx, userId string) error {
+	share, err := api.lookupShare(c, userId)
+	if share == nil {
+		return err
+	}
+
+	options := &database.Options{
+		OrderBy:    []string{models.SHARE_ACCESS_TABLE + "." + models.BASE_CREATED_AT + " desc"},
+		Where:      squirrel.Eq{models.SHARE_ACCESS_TABLE + "." + models.SHARE_ACCESS_SHARE_ID: share.ID},
+		Pagination: pagination.NewFromApi(c),
+	}
+
+	accesses := []*models.ShareAccess{}
+	if err := api.dao.List(c.Context(), &accesses, options); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up share accesses", err)
+	}
+
+	pResult, err := options.Pagination.BuildResult(shareAccessResponseHelper(accesses))
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error building pagination result", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(pResult)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// lookupShare looks up the share with the ID in the path. When the user ID is not empty, the share
+// must belong to the user. When the share is nil, an error response has been written
+func (api *sharesAPI) lookupShare(c *fiber.Ctx, userId string) (*models.Share, error) {
+	where := squirrel.Eq{models.SHARE_TABLE + "." + models.BASE_ID: c.Params("id")}
+	if userId != "" {
+		where[models.SHARE_TABLE+"."+models.SHARE_USER_ID] = userId
+	}
+
+	share := &models.Share{}
+	if err := api.dao.Get(c.Context(), share, &database.Options{Where: where}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errorResponse(c, fiber.StatusNotFound, "Share not found", nil)
+		}
+
+		return nil, errorResponse(c, fiber.StatusInternalServerError, "Error looking up share", err)
+	}
+
+	return share, nil
+}
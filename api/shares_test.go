@@ -0,0 +1,580 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestShares_CreateShare(t *testing.T) {
+	t.Run("201 (course)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "bob", "password")
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z", "maxViews": 3}`)
+		require.Equal(t, course.ID, share.CourseID)
+		require.Empty(t, share.AssetID)
+		require.Equal(t, 3, share.MaxViews)
+		require.Zero(t, share.Views)
+		require.False(t, share.Revoked)
+		require.False(t, share.Expired)
+		require.True(t, strings.HasPrefix(share.Token, share.ID+"."))
+	})
+
+	t.Run("201 (asset)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course, asset, _ := sharedCourseHelper(t, router, ctx)
+
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "assetId": "`+asset.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+		require.Equal(t, asset.ID, share.AssetID)
+		require.Zero(t, share.MaxViews)
+	})
+
+	t.Run("400 (invalid)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+
+		other := &models.Course{Title: "Course 2", Path: "/Course 2"}
+		require.NoError(t, router.dao.CreateCourse(ctx, other))
+
+		otherAsset := &models.Asset{
+			CourseID: other.ID,
+			Title:    "asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 2/01 asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, otherAsset))
+
+		for _, body := range []string{
+			`{"expiresAt": "2999-01-01 00:00:00.000Z"}`,
+			`{"courseId": "` + course.ID + `"}`,
+			`{"courseId": "` + course.ID + `", "expiresAt": "2000-01-01 00:00:00.000Z"}`,
+			`{"courseId": "` + course.ID + `", "expiresAt": "2999-01-01 00:00:00.000Z", "maxViews": -1}`,
+			`{"courseId": "invalid", "expiresAt": "2999-01-01 00:00:00.000Z"}`,
+			`{"courseId": "` + course.ID + `", "assetId": "invalid", "expiresAt": "2999-01-01 00:00:00.000Z"}`,
+			`{"courseId": "` + course.ID + `", "assetId": "` + otherAsset.ID + `", "expiresAt": "2999-01-01 00:00:00.000Z"}`,
+		} {
+			status, _ := userRequestHelper(t, router, http.MethodPost, "/api/me/shares", body, cookie)
+			require.Equal(t, http.StatusBadRequest, status, body)
+		}
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestShares_GetShares(t *testing.T) {
+	t.Run("200 (own and all)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		adminCookie := loginHelper(t, router, "admin", "password")
+		bobCookie := loginHelper(t, router, "bob", "password")
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+		body := `{"courseId": "` + course.ID + `", "expiresAt": "2999-01-01 00:00:00.000Z"}`
+
+		adminShare := shareHelper(t, router, adminCookie, body)
+		bobShare := shareHelper(t, router, bobCookie, body)
+
+		status, respBody := userRequestHelper(t, router, http.MethodGet, "/api/me/shares", "", bobCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, sharesResp := unmarshalHelper[shareResponse](t, respBody)
+		require.Equal(t, 1, int(paginationResp.TotalItems))
+		require.Equal(t, bobShare.ID, sharesResp[0].ID)
+		require.Equal(t, bobShare.Token, sharesResp[0].Token)
+
+		status, respBody = userRequestHelper(t, router, http.MethodGet, "/api/shares", "", adminCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, sharesResp = unmarshalHelper[shareResponse](t, respBody)
+		require.Equal(t, 2, int(paginationResp.TotalItems))
+		require.ElementsMatch(t, []string{adminShare.ID, bobShare.ID}, []string{sharesResp[0].ID, sharesResp[1].ID})
+	})
+
+	t.Run("403 (user)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "bob", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/shares", "", cookie)
+		require.Equal(t, http.StatusForbidden, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestShares_RevokeShare(t *testing.T) {
+	t.Run("204 (own)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "bob", "password")
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/me/shares/"+share.ID, "", cookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token, "", nil)
+		require.Equal(t, http.StatusGone, status)
+	})
+
+	t.Run("204 (admin)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		adminCookie := loginHelper(t, router, "admin", "password")
+		bobCookie := loginHelper(t, router, "bob", "password")
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, bobCookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/shares/"+share.ID, "", adminCookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, respBody := userRequestHelper(t, router, http.MethodGet, "/api/me/shares", "", bobCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		_, sharesResp := unmarshalHelper[shareResponse](t, respBody)
+		require.True(t, sharesResp[0].Revoked)
+	})
+
+	t.Run("404 (another user)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		adminCookie := loginHelper(t, router, "admin", "password")
+		bobCookie := loginHelper(t, router, "bob", "password")
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, adminCookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/me/shares/"+share.ID, "", bobCookie)
+		require.Equal(t, http.StatusNotFound, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/me/shares/"+share.ID+"/accesses", "", bobCookie)
+		require.Equal(t, http.StatusNotFound, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token, "", nil)
+		require.Equal(t, http.StatusOK, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestShares_Shared(t *testing.T) {
+	t.Run("200 (course)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course, asset, attachment := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+
+		// No session is needed
+		status, body := userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token, "", nil)
+		require.Equal(t, http.StatusOK, status)
+
+		var shared sharedResponse
+		require.NoError(t, json.Unmarshal(body, &shared))
+		require.Equal(t, course.ID, shared.Course.ID)
+		require.Empty(t, shared.Course.Path)
+		require.Empty(t, shared.AssetID)
+		require.Len(t, shared.Assets, 2)
+		require.Equal(t, asset.ID, shared.Assets[0].ID)
+		require.Empty(t, shared.Assets[0].Path)
+
+		base := "/api/shared/" + share.Token + "/courses/" + course.ID + "/assets/" + asset.ID
+
+		status, body = userRequestHelper(t, router, http.MethodGet, base+"/serve", "", nil)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "video", string(body))
+
+		status, body = userRequestHelper(t, router, http.MethodGet, base+"/attachments/"+attachment.ID+"/serve", "", nil)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "notes", string(body))
+
+		// The shared routes are read-only, so progress still needs a session
+		status, _ = userRequestHelper(t, router, http.MethodPut, base+"/progress", `{"videoPos": 10}`, nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		// Other routes still need a session
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/courses/"+course.ID+"/assets/"+asset.ID+"/serve", "", nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("200 (asset)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course, asset, _ := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "assetId": "`+asset.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+
+		status, body := userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token, "", nil)
+		require.Equal(t, http.StatusOK, status)
+
+		var shared sharedResponse
+		require.NoError(t, json.Unmarshal(body, &shared))
+		require.Equal(t, asset.ID, shared.AssetID)
+		require.Len(t, shared.Assets, 1)
+		require.Equal(t, asset.ID, shared.Assets[0].ID)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token+"/courses/"+course.ID+"/assets/"+asset.ID+"/serve", "", nil)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("200 (epub)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "book",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("epub"),
+			Path:     "/Course 1/01 book.epub",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+
+		for name, content := range map[string]string{
+			"mimetype":               "application/epub+zip",
+			"META-INF/container.xml": `<container><rootfiles><rootfile full-path="content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`,
+			"content.opf": `<package><metadata><title>The Book</title></metadata><manifest>
+				<item id="c1" href="chapter1.xhtml" media-type="application/xhtml+xml"/>
+				</manifest><spine><itemref idref="c1"/></spine></package>`,
+			"chapter1.xhtml": "<html><body>one</body></html>",
+		} {
+			f, err := w.Create(name)
+			require.NoError(t, err)
+
+			_, err = f.Write([]byte(content))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, w.Close())
+		require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, asset.Path, buf.Bytes(), os.ModePerm))
+
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "assetId": "`+asset.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+		base := "/api/shared/" + share.Token + "/courses/" + course.ID + "/assets/" + asset.ID
+
+		status, body := userRequestHelper(t, router, http.MethodGet, base+"/epub", "", nil)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp epubResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Equal(t, "The Book", resp.Title)
+		require.Len(t, resp.Spine, 1)
+
+		status, body = userRequestHelper(t, router, http.MethodGet, base+"/epub/chapter1.xhtml", "", nil)
+		require.Equal(t, http.StatusOK, status)
+		require.Contains(t, string(body), "one")
+
+		// The reading progress still needs a session
+		status, _ = userRequestHelper(t, router, http.MethodPut, base+"/epub/progress", `{"spineIndex": 0, "scrollPos": 50}`, nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("200 (slides)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+		require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "deck",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("png"),
+			Path:     "/Course 1/01 deck",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+
+		for _, name := range []string{"slide1.png", "slide2.png"} {
+			require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, asset.Path+"/"+name, []byte(name), os.ModePerm))
+		}
+
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "assetId": "`+asset.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+		base := "/api/shared/" + share.Token + "/courses/" + course.ID + "/assets/" + asset.ID
+
+		status, body := userRequestHelper(t, router, http.MethodGet, base+"/slides", "", nil)
+		require.Equal(t, http.StatusOK, status)
+
+		var resp slidesResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Len(t, resp.Slides, 2)
+
+		status, body = userRequestHelper(t, router, http.MethodGet, base+"/slides/1", "", nil)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "slide2.png", string(body))
+	})
+
+	t.Run("403 (out of scope)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course, asset, _ := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "assetId": "`+asset.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+
+		assets := []*models.Asset{}
+		require.NoError(t, router.dao.List(ctx, &assets, nil))
+
+		var otherAsset *models.Asset
+		for _, a := range assets {
+			if a.ID != asset.ID {
+				otherAsset = a
+			}
+		}
+
+		other := &models.Course{Title: "Course 2", Path: "/Course 2"}
+		require.NoError(t, router.dao.CreateCourse(ctx, other))
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token+"/courses/"+course.ID+"/assets/"+otherAsset.ID+"/serve", "", nil)
+		require.Equal(t, http.StatusForbidden, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token+"/courses/"+other.ID+"/assets/"+asset.ID+"/serve", "", nil)
+		require.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("404 (invalid token)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+
+		_, signature, _ := strings.Cut(share.Token, ".")
+
+		for _, token := range []string{"invalid", share.ID, share.ID + ".invalid", "abc." + signature} {
+			status, _ := userRequestHelper(t, router, http.MethodGet, "/api/shared/"+token, "", nil)
+			require.Equal(t, http.StatusNotFound, status, token)
+		}
+
+		// A signed token of a deleted share
+		secret, err := router.dao.GetShareSecret(ctx)
+		require.NoError(t, err)
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/shared/"+security.SignToken(secret, "deleted"), "", nil)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("410 (expired)", func(t *testing.T) {
+		router, ctx := setup(t)
+		admin := createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+
+		expiresAt, err := types.ParseDateTime(time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+
+		share := &models.Share{UserID: admin.ID, CourseID: course.ID, ExpiresAt: expiresAt}
+		require.NoError(t, router.dao.CreateShare(ctx, share))
+
+		secret, err := router.dao.GetShareSecret(ctx)
+		require.NoError(t, err)
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/shared/"+security.SignToken(secret, share.ID), "", nil)
+		require.Equal(t, http.StatusGone, status)
+	})
+
+	t.Run("410 (owner disabled)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		bob := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		adminCookie := loginHelper(t, router, "admin", "password")
+		bobCookie := loginHelper(t, router, "bob", "password")
+
+		course, _, _ := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, bobCookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z"}`)
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token, "", nil)
+		require.Equal(t, http.StatusOK, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodPut, "/api/users/"+bob.ID, `{"disabled": true}`, adminCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token, "", nil)
+		require.Equal(t, http.StatusGone, status)
+
+		// Enabling the owner restores the share
+		status, _ = userRequestHelper(t, router, http.MethodPut, "/api/users/"+bob.ID, `{"disabled": false}`, adminCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token, "", nil)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("410 (view limit)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course, asset, _ := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z", "maxViews": 1}`)
+
+		// Another viewer uses the only view
+		shareModel := &models.Share{Base: models.Base{ID: share.ID}}
+		require.NoError(t, router.dao.GetById(ctx, shareModel))
+		require.NoError(t, router.dao.RecordShareAccess(ctx, shareModel, &models.ShareAccess{IPAddress: "10.0.0.1"}))
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token, "", nil)
+		require.Equal(t, http.StatusGone, status)
+
+		// Requests that are not recorded are still limited
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/shared/"+share.Token+"/courses/"+course.ID+"/assets/"+asset.ID+"/attachments", "", nil)
+		require.Equal(t, http.StatusGone, status)
+	})
+
+	t.Run("200 (audit log)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		course, asset, attachment := sharedCourseHelper(t, router, ctx)
+		share := shareHelper(t, router, cookie, `{"courseId": "`+course.ID+`", "expiresAt": "2999-01-01 00:00:00.000Z", "maxViews": 1}`)
+
+		// The same viewer may use the share many times
+		paths := []string{
+			"/api/shared/" + share.Token,
+			"/api/shared/" + share.Token + "/courses/" + course.ID + "/assets/" + asset.ID + "/serve",
+			"/api/shared/" + share.Token + "/courses/" + course.ID + "/assets/" + asset.ID + "/attachments/" + attachment.ID + "/serve",
+		}
+
+		for _, path := range paths {
+			status, _ := userRequestHelper(t, router, http.MethodGet, path, "", nil)
+			require.Equal(t, http.StatusOK, status)
+		}
+
+		// The requests made during a view are not recorded
+		base := "/api/shared/" + share.Token + "/courses/" + course.ID + "/assets/" + asset.ID
+
+		req := httptest.NewRequest(http.MethodGet, base+"/serve", nil)
+		req.Header.Set("Range", "bytes=0-1")
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, base+"/attachments", "", nil)
+		require.Equal(t, http.StatusOK, status)
+
+		status, body := userRequestHelper(t, router, http.MethodGet, "/api/me/shares/"+share.ID+"/accesses", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, accessesResp := unmarshalHelper[shareAccessResponse](t, body)
+		require.Equal(t, 3, int(paginationResp.TotalItems))
+		require.ElementsMatch(t, paths, []string{accessesResp[0].Path, accessesResp[1].Path, accessesResp[2].Path})
+		require.Equal(t, "0.0.0.0", accessesResp[0].IPAddress)
+
+		status, body = userRequestHelper(t, router, http.MethodGet, "/api/shares/"+share.ID+"/accesses", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, _ = unmarshalHelper[shareAccessResponse](t, body)
+		require.Equal(t, 3, int(paginationResp.TotalItems))
+
+		// One view is counted for the viewer
+		status, body = userRequestHelper(t, router, http.MethodGet, "/api/me/shares", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		_, sharesResp := unmarshalHelper[shareResponse](t, body)
+		require.Equal(t, 1, sharesResp[0].Views)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// shareHelper creates a share for the logged in user
+func shareHelper(t *testing.T, router *Router, cookie *http.Cookie, body string) *shareResponse {
+	t.Helper()
+
+	status, respBody := userRequestHelper(t, router, http.MethodPost, "/api/me/shares", body, cookie)
+	require.Equal(t, http.StatusCreated, status, string(respBody))
+
+	share := &shareResponse{}
+	require.NoError(t, json.Unmarshal(respBody, share))
+
+	return share
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// sharedCourseHelper creates a course with 2 assets, the first of which has an attachment. The
+// first asset and the attachment exist on disk
+func sharedCourseHelper(t *testing.T, router *Router, ctx context.Context) (*models.Course, *models.Asset, *models.Attachment) {
+	t.Helper()
+
+	course := &models.Course{Title: "Course 1", Path: "/Course 1"}
+	require.NoError(t, router.dao.CreateCourse(ctx, course))
+
+	var assets []*models.Asset
+	for i := range 2 {
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "asset",
+			Prefix:   sql.NullInt16{Int16: int16(i + 1), Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/Course 1/0" + string(rune('1'+i)) + " asset.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, router.dao.CreateAsset(ctx, asset))
+		assets = append(assets, asset)
+	}
+
+	attachment := &models.Attachment{
+		AssetID: assets[0].ID,
+		Title:   "notes.txt",
+		Path:    "/Course 1/01 notes.txt",
+	}
+	require.NoError(t, router.dao.CreateAttachment(ctx, attachment))
+
+	require.Nil(t, router.config.AppFs.Fs.MkdirAll("/Course 1", os.ModePerm))
+	require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, assets[0].Path, []byte("video"), os.ModePerm))
+	require.Nil(t, afero.WriteFile(router.config.AppFs.Fs, attachment.Path, []byte("notes"), os.ModePerm))
+
+	return course, assets[0], attachment
+}
@@ -0,0 +1,454 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/pagination"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+type usersAPI struct {
+	logger *slog.Logger
+	dao    *dao.DAO
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initUserRoutes initializes the user routes. The `/users` routes are for admins to manage users
+// while the `/me` routes are for the logged in user, including their API tokens and sessions
+func (r *Router) initUserRoutes() {
+	usersAPI := usersAPI{
+		logger: r.config.Logger,
+		dao:    r.dao,
+	}
+
+	userGroup := r.api.Group("/users")
+	userGroup.Get("", usersAPI.getUsers)
+	userGroup.Get("/:id", usersAPI.getUser)
+	userGroup.Post("", usersAPI.createUser)
+	userGroup.Put("/:id", usersAPI.updateUser)
+	userGroup.Delete("/:id", usersAPI.deleteUser)
+	userGroup.Delete("/:id/sessions", usersAPI.deleteUserSessions)
+
+	meGroup := r.api.Group("/me")
+	meGroup.Put("/password", usersAPI.updatePassword)
+	meGroup.Get("/tokens", usersAPI.getTokens)
+	meGroup.Post("/tokens", usersAPI.createToken)
+	meGroup.Delete("/tokens/:id", usersAPI.deleteToken)
+	meGroup.Get("/sessions", usersAPI.getSessions)
+	meGroup.Delete("/sessions/:id", usersAPI.deleteSession)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (api *usersAPI) getUsers(c *fiber.Ctx) error {
+	orderBy := c.Query("orderBy", models.USER_TABLE+"."+models.USER_USERNAME+" asc")
+
+	options := &database.Options{
+		OrderBy:    strings.Split(orderBy, ","),
+		Pagination: pagination.NewFromApi(c),
+	}
+
+	users := []*models.User{}
+	if err := api.dao.List(c.Context(), &users, options); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up users", err)
+	}
+
+	pResult, err := options.Pagination.BuildResult(userResponseHelper(users))
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error building pagination result", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(pResult)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (api *usersAPI) getUser(c *fiber.Ctx) error {
+	user, err := api.lookupUser(c, c.Params("id"))
+	if user == nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(userResponseHelper([]*models.User{user})[0])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (api *usersAPI) createUser(c *fiber.Ctx) error {
+	req := &userCreateRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		return errorResponse(c, fiber.StatusBadRequest, "A username is required", nil)
+	}
+
+	if req.Role == "" {
+		req.Role = types.UserRoleUser
+	}
+
+	// A form body is not validated when parsed, unlike JSON
+	if !req.Role.IsValid() {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid role", nil)
+	}
+
+	hash, err := security.HashPassword(req.Password)
+	if err != nil {
+		return passwordErrorResponse(c, err)
+	}
+
+	user := &models.User{Username: req.Username, PasswordHash: hash, Role: req.Role}
+	if err := api.dao.CreateUser(c.Context(), user); err != nil {
+		if errors.Is(err, dao.ErrUserExists) {
+			return errorResponse(c, fiber.StatusConflict, "Username already exists", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error creating user", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(userResponseHelper([]*models.User{user})[0])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// updateUser changes the role of a user, resets their password and/or disables (or enables) their
+// account. The last active admin cannot be demoted or disabled
+func (api *usersAPI) updateUser(c *fiber.Ctx) error {
+	req := &userUpdateRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	if req.Role != nil && !req.Role.IsValid() {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid role", nil)
+	}
+
+	user, err := api.lookupUser(c, c.Params("id"))
+	if user == nil {
+		return err
+	}
+
+	if req.Role != nil {
+		user.Role = *req.Role
+	}
+
+	if req.Password != nil {
+		hash, err := security.HashPassword(*req.Password)
+		if err != nil {
+			return passwordErrorResponse(c, err)
+		}
+
+		user.PasswordHash = hash
+	}
+
+	if req.Disabled != nil {
+		user.Disabled = *req.Disabled
+	}
+
+	if err := api.dao.UpdateUser(c.Context(), user); err != nil {
+		if errors.Is(err, dao.ErrLastAdmin) {
+			return errorResponse(c, fiber.StatusBadRequest, "Cannot demote or disable the last admin", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error updating user", err)
+	}
+
+	// A password reset or a disabled account logs the user out everywhere
+	if req.Password != nil || user.Disabled {
+		if err := api.dao.DeleteUserSessions(c.Context(), user.ID); err != nil {
+			return errorResponse(c, fiber.StatusInternalServerError, "Error deleting sessions", err)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(userResponseHelper([]*models.User{user})[0])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (api *usersAPI) deleteUser(c *fiber.Ctx) error {
+	user := &models.User{Base: models.Base{ID: c.Params("id")}}
+	if err := api.dao.DeleteUser(c.Context(), user); err != nil {
+		if errors.Is(err, dao.ErrLastAdmin) {
+			return errorResponse(c, fiber.StatusBadRequest, "Cannot delete the last admin", nil)
+		}
+
+		return errorResponse(c, fiber.StatusInternalServerError, "Error deleting user", err)
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// deleteUserSessions revokes all the sessions of a user, logging them out everywhere. API tokens
+// are not revoked
+func (api *usersAPI) deleteUserSessions(c *fiber.Ctx) error {
+	user, err := api.lookupUser(c, c.Params("id"))
+	if user == nil {
+		return err
+	}
+
+	if err := api.dao.DeleteUserSessions(c.Context(), user.ID); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error deleting sessions", err)
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// updatePassword changes the password of the logged in user. The current password is required
+func (api *usersAPI) updatePassword(c *fiber.Ctx) error {
+	req := &passwordRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	if !security.ComparePassword(user.PasswordHash, req.CurrentPassword) {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid current password", nil)
+	}
+
+	hash, err := security.HashPassword(req.NewPassword)
+	if err != nil {
+		return passwordErrorResponse(c, err)
+	}
+
+	user.PasswordHash = hash
+	if err := api.dao.UpdateUser(c.Context(), user); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error updating password", err)
+	}
+
+	// Log the user out everywhere else. A request authenticated with an API token has no session to
+	// keep, so every session is deleted
+	if session, ok := c.Locals(localsSessionKey).(*models.Session); ok {
+		err = api.dao.DeleteOtherSessions(c.Context(), session)
+	} else {
+		err = api.dao.DeleteUserSessions(c.Context(), user.ID)
+	}
+
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error deleting sessions", err)
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getTokens lists the API tokens of the logged in user. The tokens themselves are not returned
+func (api *usersAPI) getTokens(c *fiber.Ctx) error {
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	orderBy := c.Query("orderBy", models.API_TOKEN_TABLE+"."+models.BASE_CREATED_AT+" desc")
+
+	options := &database.Options{
+		OrderBy:    strings.Split(orderBy, ","),
+		Where:      squirrel.Eq{models.API_TOKEN_TABLE + "." + models.API_TOKEN_USER_ID: user.ID},
+		Pagination: pagination.NewFromApi(c),
+	}
+
+	tokens := []*models.ApiToken{}
+	if err := api.dao.List(c.Context(), &tokens, options); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up tokens", err)
+	}
+
+	pResult, err := options.Pagination.BuildResult(apiTokenResponseHelper(tokens))
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error building pagination result", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(pResult)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// createToken creates an API token for the logged in user. The token is only returned in this
+// response, as only its hash is stored. Only an admin can create a token with the admin scope
+func (api *usersAPI) createToken(c *fiber.Ctx) error {
+	req := &apiTokenCreateRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, "Error parsing data", err)
+	}
+
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		return errorResponse(c, fiber.StatusBadRequest, "A name is required", nil)
+	}
+
+	if req.Scope == "" {
+		req.Scope = types.TokenScopeReadOnly
+	}
+
+	// A form body is not validated when parsed, unlike JSON
+	if !req.Scope.IsValid() {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid scope", nil)
+	}
+
+	if req.Scope == types.TokenScopeAdmin && user.Role != types.UserRoleAdmin {
+		return errorResponse(c, fiber.StatusBadRequest, "Only an admin can create a token with the admin scope", nil)
+	}
+
+	if !req.ExpiresAt.IsZero() && req.ExpiresAt.Time().Before(time.Now()) {
+		return errorResponse(c, fiber.StatusBadRequest, "The expiry must be in the future", nil)
+	}
+
+	secret := security.GenerateToken()
+
+	token := &models.ApiToken{
+		UserID:    user.ID,
+		Name:      req.Name,
+		TokenHash: security.HashToken(secret),
+		Scope:     req.Scope,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := api.dao.CreateApiToken(c.Context(), token); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error creating token", err)
+	}
+
+	resp := apiTokenResponseHelper([]*models.ApiToken{token})[0]
+	resp.Token = secret
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// deleteToken revokes an API token of the logged in user
+func (api *usersAPI) deleteToken(c *fiber.Ctx) error {
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{
+			models.API_TOKEN_TABLE + "." + models.BASE_ID:           c.Params("id"),
+			models.API_TOKEN_TABLE + "." + models.API_TOKEN_USER_ID: user.ID,
+		},
+	}
+
+	if err := api.dao.Delete(c.Context(), &models.ApiToken{}, options); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error deleting token", err)
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getSessions lists the active sessions of the logged in user. The session making the request is
+// marked as current
+func (api *usersAPI) getSessions(c *fiber.Ctx) error {
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	orderBy := c.Query("orderBy", models.SESSION_TABLE+"."+models.SESSION_LAST_SEEN_AT+" desc")
+
+	options := &database.Options{
+		OrderBy: strings.Split(orderBy, ","),
+		Where: squirrel.And{
+			squirrel.Eq{models.SESSION_TABLE + "." + models.SESSION_USER_ID: user.ID},
+			squirrel.Gt{models.SESSION_TABLE + "." + models.SESSION_EXPIRES_AT: types.NowDateTime()},
+		},
+		Pagination: pagination.NewFromApi(c),
+	}
+
+	sessions := []*models.Session{}
+	if err := api.dao.List(c.Context(), &sessions, options); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error looking up sessions", err)
+	}
+
+	pResult, err := options.Pagination.BuildResult(sessionResponseHelper(sessions, currentSessionId(c)))
+	if err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error building pagination result", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(pResult)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// deleteSession revokes a session of the logged in user. Revoking the current session logs out
+func (api *usersAPI) deleteSession(c *fiber.Ctx) error {
+	user, ok := c.Locals(localsUserKey).(*models.User)
+	if !ok {
+		return errorResponse(c, fiber.StatusUnauthorized, "Unauthorized", nil)
+	}
+
+	id := c.Params("id")
+
+	options := &database.Options{
+		Where: squirrel.Eq{
+			models.SESSION_TABLE + "." + models.BASE_ID:         id,
+			models.SESSION_TABLE + "." + models.SESSION_USER_ID: user.ID,
+		},
+	}
+
+	if err := api.dao.Delete(c.Context(), &models.Session{}, options); err != nil {
+		return errorResponse(c, fiber.StatusInternalServerError, "Error deleting session", err)
+	}
+
+	if id == currentSessionId(c) {
+		clearSessionCookie(c)
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// lookupUser gets a user by ID. When the user cannot be found (or an error occurs), a response is
+// written and nil is returned
+func (api *usersAPI) lookupUser(c *fiber.Ctx, id string) (*models.User, error) {
+	user := &models.User{Base: models.Base{ID: id}}
+	if err := api.dao.GetById(c.Context(), user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errorResponse(c, fiber.StatusNotFound, "User not found", nil)
+		}
+
+		return nil, errorResponse(c, fiber.StatusInternalServerError, "Error looking up user", err)
+	}
+
+	return user, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// passwordErrorResponse writes the response for an error returned when hashing a password
+func passwordErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, security.ErrPasswordTooShort) || errors.Is(err, security.ErrPasswordTooLong) {
+		return errorResponse(c, fiber.StatusBadRequest, "Invalid password", err)
+	}
+
+	return errorResponse(c, fiber.StatusInternalServerError, "Error hashing password", err)
+}
@@ -0,0 +1,780 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestUsers_GetUsers(t *testing.T) {
+	t.Run("200 (found)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodGet, "/api/users", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, usersResp := unmarshalHelper[userResponse](t, body)
+		require.Equal(t, 2, int(paginationResp.TotalItems))
+		require.Equal(t, "admin", usersResp[0].Username)
+		require.Equal(t, types.UserRoleAdmin, usersResp[0].Role)
+		require.Equal(t, "bob", usersResp[1].Username)
+		require.Equal(t, types.UserRoleUser, usersResp[1].Role)
+
+		// The password hash is never returned
+		require.NotContains(t, string(body), "password")
+	})
+
+	t.Run("500 (internal error)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		_, err := router.config.DbManager.DataDb.Exec("DROP TABLE IF EXISTS " + models.USER_TABLE)
+		require.NoError(t, err)
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/users", "", nil)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestUsers_GetUser(t *testing.T) {
+	t.Run("200 (found)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		user := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodGet, "/api/users/"+user.ID, "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		var userResp userResponse
+		require.NoError(t, json.Unmarshal(body, &userResp))
+		require.Equal(t, user.ID, userResp.ID)
+		require.Equal(t, "bob", userResp.Username)
+		require.False(t, userResp.Disabled)
+	})
+
+	t.Run("404 (not found)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/users/invalid", "", cookie)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestUsers_CreateUser(t *testing.T) {
+	t.Run("201 (created)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPost, "/api/users", `{"username": " bob ", "password": "password"}`, cookie)
+		require.Equal(t, http.StatusCreated, status)
+
+		var userResp userResponse
+		require.NoError(t, json.Unmarshal(body, &userResp))
+		require.Equal(t, "bob", userResp.Username)
+		require.Equal(t, types.UserRoleUser, userResp.Role)
+
+		status, body = userRequestHelper(t, router, http.MethodPost, "/api/users", `{"username": "alice", "password": "password", "role": "admin"}`, cookie)
+		require.Equal(t, http.StatusCreated, status)
+
+		require.NoError(t, json.Unmarshal(body, &userResp))
+		require.Equal(t, types.UserRoleAdmin, userResp.Role)
+
+		// The new users can log in
+		loginHelper(t, router, "bob", "password")
+		loginHelper(t, router, "alice", "password")
+	})
+
+	t.Run("400 (invalid data)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		for _, body := range []string{
+			`bob`,
+			`{"username": " ", "password": "password"}`,
+			`{"username": "bob", "password": "short"}`,
+			`{"username": "bob", "password": "password", "role": "superuser"}`,
+		} {
+			status, _ := userRequestHelper(t, router, http.MethodPost, "/api/users", body, cookie)
+			require.Equal(t, http.StatusBadRequest, status, body)
+		}
+
+		// A form body is not validated by the parser
+		req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader("username=bob&password=password&role=root"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookie)
+
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("409 (existing)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPost, "/api/users", `{"username": "admin", "password": "password"}`, cookie)
+		require.Equal(t, http.StatusConflict, status)
+		require.Contains(t, string(body), "Username already exists")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestUsers_UpdateUser(t *testing.T) {
+	t.Run("200 (role)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		user := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPut, "/api/users/"+user.ID, `{"role": "admin"}`, cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		var userResp userResponse
+		require.NoError(t, json.Unmarshal(body, &userResp))
+		require.Equal(t, types.UserRoleAdmin, userResp.Role)
+
+		userResult := &models.User{Base: models.Base{ID: user.ID}}
+		require.NoError(t, router.dao.GetById(ctx, userResult))
+		require.Equal(t, types.UserRoleAdmin, userResult.Role)
+
+		// The password is unchanged
+		require.True(t, security.ComparePassword(userResult.PasswordHash, "password"))
+	})
+
+	t.Run("200 (password)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		user := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "admin", "password")
+		userCookie := loginHelper(t, router, "bob", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodPut, "/api/users/"+user.ID, `{"password": "new password"}`, cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		// The existing session of the user is revoked
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", userCookie)
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		// The session of the admin is unaffected
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": "password"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		loginHelper(t, router, "bob", "new password")
+	})
+
+	t.Run("200 (disabled)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		user := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		adminCookie := loginHelper(t, router, "admin", "password")
+		userCookie := loginHelper(t, router, "bob", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPut, "/api/users/"+user.ID, `{"disabled": true}`, adminCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		var userResp userResponse
+		require.NoError(t, json.Unmarshal(body, &userResp))
+		require.True(t, userResp.Disabled)
+
+		// The existing session is no longer valid
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/courses", "", userCookie)
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		// And it has been deleted
+		count, err := router.dao.Count(ctx, &models.Session{}, &database.Options{
+			Where: squirrel.Eq{models.SESSION_TABLE + "." + models.SESSION_USER_ID: user.ID},
+		})
+		require.NoError(t, err)
+		require.Zero(t, count)
+
+		// And the user cannot log in
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": "password"}`)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// Enable
+		status, _ = userRequestHelper(t, router, http.MethodPut, "/api/users/"+user.ID, `{"disabled": false}`, adminCookie)
+		require.Equal(t, http.StatusOK, status)
+
+		loginHelper(t, router, "bob", "password")
+	})
+
+	t.Run("400 (invalid data)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		user := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		for _, body := range []string{
+			`bob`,
+			`{"role": "superuser"}`,
+			`{"password": "short"}`,
+		} {
+			status, _ := userRequestHelper(t, router, http.MethodPut, "/api/users/"+user.ID, body, cookie)
+			require.Equal(t, http.StatusBadRequest, status, body)
+		}
+
+		// A form body is not validated by the parser
+		req := httptest.NewRequest(http.MethodPut, "/api/users/"+user.ID, strings.NewReader("role=root"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookie)
+
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		result := &models.User{Base: models.Base{ID: user.ID}}
+		require.NoError(t, router.dao.GetById(context.Background(), result))
+		require.Equal(t, types.UserRoleUser, result.Role)
+	})
+
+	t.Run("400 (last admin)", func(t *testing.T) {
+		router, _ := setup(t)
+		admin := createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPut, "/api/users/"+admin.ID, `{"role": "user"}`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "last admin")
+
+		status, _ = userRequestHelper(t, router, http.MethodPut, "/api/users/"+admin.ID, `{"disabled": true}`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		// Another admin allows the demotion
+		createUserHelper(t, router, "alice", "password", types.UserRoleAdmin)
+
+		status, _ = userRequestHelper(t, router, http.MethodPut, "/api/users/"+admin.ID, `{"role": "user"}`, cookie)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("404 (not found)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodPut, "/api/users/invalid", `{"role": "user"}`, cookie)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestUsers_DeleteUser(t *testing.T) {
+	t.Run("204 (deleted)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		user := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/users/"+user.ID, "", cookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		count, err := router.dao.Count(ctx, &models.User{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("204 (not found)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/users/invalid", "", cookie)
+		require.Equal(t, http.StatusNoContent, status)
+	})
+
+	t.Run("400 (last admin)", func(t *testing.T) {
+		router, _ := setup(t)
+		admin := createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodDelete, "/api/users/"+admin.ID, "", cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "last admin")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestUsers_UpdatePassword(t *testing.T) {
+	t.Run("204 (updated)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		otherCookie := loginHelper(t, router, "bob", "password")
+		cookie := loginHelper(t, router, "bob", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodPut, "/api/me/password", `{"currentPassword": "password", "newPassword": "new password"}`, cookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		// The other sessions are revoked, but the current one is kept
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", otherCookie)
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		resp := authRequestHelper(t, router, "/api/auth/login", `{"username": "bob", "password": "password"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		loginHelper(t, router, "bob", "new password")
+	})
+
+	t.Run("400 (invalid current password)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPut, "/api/me/password", `{"currentPassword": "wrong password", "newPassword": "new password"}`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "Invalid current password")
+
+		loginHelper(t, router, "admin", "password")
+	})
+
+	t.Run("400 (invalid new password)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodPut, "/api/me/password", `{"currentPassword": "password", "newPassword": "short"}`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodPut, "/api/me/password", `bob`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("401 (not logged in)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		// No admin exists so the request is let through, but there is no user
+		status, _ := userRequestHelper(t, router, http.MethodPut, "/api/me/password", `{"currentPassword": "password", "newPassword": "new password"}`, nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestUsers_Tokens(t *testing.T) {
+	t.Run("201 (created)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "bob", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPost, "/api/me/tokens", `{"name": "script", "scope": "progress-write"}`, cookie)
+		require.Equal(t, http.StatusCreated, status)
+
+		var created apiTokenResponse
+		require.NoError(t, json.Unmarshal(body, &created))
+		require.Equal(t, "script", created.Name)
+		require.Equal(t, types.TokenScopeProgressWrite, created.Scope)
+		require.True(t, strings.HasPrefix(created.Token, security.TokenPrefix))
+		require.True(t, created.ExpiresAt.IsZero())
+
+		// Only the hash is stored
+		token := &models.ApiToken{Base: models.Base{ID: created.ID}}
+		require.NoError(t, router.dao.GetById(context.Background(), token))
+		require.Equal(t, security.HashToken(created.Token), token.TokenHash)
+
+		// The token is not listed
+		status, body = userRequestHelper(t, router, http.MethodGet, "/api/me/tokens", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+		require.NotContains(t, string(body), created.Token)
+
+		paginationResp, tokensResp := unmarshalHelper[apiTokenResponse](t, body)
+		require.Equal(t, 1, int(paginationResp.TotalItems))
+		require.Equal(t, created.ID, tokensResp[0].ID)
+		require.Empty(t, tokensResp[0].Token)
+	})
+
+	t.Run("201 (defaults to read-only)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPost, "/api/me/tokens", `{"name": "script", "expiresAt": "2999-01-01 00:00:00.000Z"}`, cookie)
+		require.Equal(t, http.StatusCreated, status)
+
+		var created apiTokenResponse
+		require.NoError(t, json.Unmarshal(body, &created))
+		require.Equal(t, types.TokenScopeReadOnly, created.Scope)
+		require.Equal(t, 2999, created.ExpiresAt.Time().Year())
+	})
+
+	t.Run("400 (invalid data)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "bob", "password")
+
+		status, body := userRequestHelper(t, router, http.MethodPost, "/api/me/tokens", `{"name": " "}`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "A name is required")
+
+		status, _ = userRequestHelper(t, router, http.MethodPost, "/api/me/tokens", `{"name": "script", "scope": "invalid"}`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		// A form body is not validated by the parser
+		req := httptest.NewRequest(http.MethodPost, "/api/me/tokens", strings.NewReader("name=script&scope=root"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookie)
+
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		status, body = userRequestHelper(t, router, http.MethodPost, "/api/me/tokens", `{"name": "script", "scope": "admin"}`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "admin scope")
+
+		status, body = userRequestHelper(t, router, http.MethodPost, "/api/me/tokens", `{"name": "script", "expiresAt": "2000-01-01 00:00:00.000Z"}`, cookie)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, string(body), "expiry must be in the future")
+	})
+
+	t.Run("bearer", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "bob", "password")
+
+		readOnly := tokenHelper(t, router, cookie, `{"name": "read", "scope": "read-only"}`)
+		progressWrite := tokenHelper(t, router, cookie, `{"name": "progress", "scope": "progress-write"}`)
+
+		// Reading is allowed and the last used time is recorded
+		status, _ := tokenRequestHelper(t, router, http.MethodGet, "/api/courses", "", readOnly.Token)
+		require.Equal(t, http.StatusOK, status)
+
+		token := &models.ApiToken{Base: models.Base{ID: readOnly.ID}}
+		require.NoError(t, router.dao.GetById(context.Background(), token))
+		require.False(t, token.LastUsedAt.IsZero())
+
+		// Recording progress requires the progress-write scope
+		status, body := tokenRequestHelper(t, router, http.MethodPut, "/api/courses/x/assets/x/progress", `{"videoPos": 10}`, readOnly.Token)
+		require.Equal(t, http.StatusForbidden, status)
+		require.Contains(t, string(body), "Token scope")
+
+		status, _ = tokenRequestHelper(t, router, http.MethodPut, "/api/courses/x/assets/x/progress", `{"videoPos": 10}`, progressWrite.Token)
+		require.Equal(t, http.StatusNotFound, status)
+
+		// Managing tokens requires the admin scope
+		status, _ = tokenRequestHelper(t, router, http.MethodPost, "/api/me/tokens", `{"name": "other"}`, progressWrite.Token)
+		require.Equal(t, http.StatusForbidden, status)
+
+		// The role still applies
+		status, _ = tokenRequestHelper(t, router, http.MethodGet, "/api/users", "", readOnly.Token)
+		require.Equal(t, http.StatusForbidden, status)
+
+		// Unknown token
+		status, body = tokenRequestHelper(t, router, http.MethodGet, "/api/courses", "", "invalid")
+		require.Equal(t, http.StatusUnauthorized, status)
+		require.Contains(t, string(body), "Invalid token")
+	})
+
+	t.Run("bearer (admin scope)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		admin := tokenHelper(t, router, cookie, `{"name": "admin", "scope": "admin"}`)
+
+		status, _ := tokenRequestHelper(t, router, http.MethodPost, "/api/users", `{"username": "bob", "password": "password"}`, admin.Token)
+		require.Equal(t, http.StatusCreated, status)
+	})
+
+	t.Run("401 (expired)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		bob := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+
+		expiresAt, err := types.ParseDateTime(time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+
+		secret := security.GenerateToken()
+		require.NoError(t, router.dao.CreateApiToken(context.Background(), &models.ApiToken{
+			UserID:    bob.ID,
+			Name:      "script",
+			TokenHash: security.HashToken(secret),
+			Scope:     types.TokenScopeReadOnly,
+			ExpiresAt: expiresAt,
+		}))
+
+		status, _ := tokenRequestHelper(t, router, http.MethodGet, "/api/courses", "", secret)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("401 (disabled user)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		bob := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		cookie := loginHelper(t, router, "bob", "password")
+
+		token := tokenHelper(t, router, cookie, `{"name": "script"}`)
+
+		bob.Disabled = true
+		require.NoError(t, router.dao.UpdateUser(context.Background(), bob))
+
+		status, _ := tokenRequestHelper(t, router, http.MethodGet, "/api/courses", "", token.Token)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("204 (revoked)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		adminCookie := loginHelper(t, router, "admin", "password")
+		bobCookie := loginHelper(t, router, "bob", "password")
+
+		token := tokenHelper(t, router, bobCookie, `{"name": "script"}`)
+
+		// Another user cannot revoke the token
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/me/tokens/"+token.ID, "", adminCookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, _ = tokenRequestHelper(t, router, http.MethodGet, "/api/courses", "", token.Token)
+		require.Equal(t, http.StatusOK, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodDelete, "/api/me/tokens/"+token.ID, "", bobCookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, _ = tokenRequestHelper(t, router, http.MethodGet, "/api/courses", "", token.Token)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("401 (not logged in)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		// No admin exists so the request is let through, but there is no user
+		status, _ := userRequestHelper(t, router, http.MethodPost, "/api/me/tokens", `{"name": "script"}`, nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestUsers_Sessions(t *testing.T) {
+	t.Run("200 (found)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+
+		// Log in from a second device
+		body, err := json.Marshal(&authRequest{Username: "bob", Password: "password"})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "phone")
+
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		phoneCookie := sessionCookieHelper(t, resp)
+
+		cookie := loginHelper(t, router, "bob", "password")
+		loginHelper(t, router, "admin", "password")
+
+		status, respBody := userRequestHelper(t, router, http.MethodGet, "/api/me/sessions", "", cookie)
+		require.Equal(t, http.StatusOK, status)
+
+		paginationResp, sessionsResp := unmarshalHelper[sessionResponse](t, respBody)
+		require.Equal(t, 2, int(paginationResp.TotalItems))
+
+		phone := &models.Session{TokenHash: security.HashToken(phoneCookie.Value)}
+		require.NoError(t, router.dao.GetSessionByHash(context.Background(), phone))
+
+		for _, sessionResp := range sessionsResp {
+			if sessionResp.ID == phone.ID {
+				require.Equal(t, "phone", sessionResp.UserAgent)
+				require.False(t, sessionResp.Current)
+			} else {
+				require.True(t, sessionResp.Current)
+			}
+
+			require.NotEmpty(t, sessionResp.IPAddress)
+			require.False(t, sessionResp.LastSeenAt.IsZero())
+			require.False(t, sessionResp.ExpiresAt.IsZero())
+		}
+	})
+
+	t.Run("204 (revoked)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		adminCookie := loginHelper(t, router, "admin", "password")
+		cookie1 := loginHelper(t, router, "bob", "password")
+		cookie2 := loginHelper(t, router, "bob", "password")
+
+		session2 := &models.Session{TokenHash: security.HashToken(cookie2.Value)}
+		require.NoError(t, router.dao.GetSessionByHash(ctx, session2))
+
+		// Another user cannot revoke the session
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/me/sessions/"+session2.ID, "", adminCookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", cookie2)
+		require.Equal(t, http.StatusOK, status)
+
+		// Revoke another session
+		status, _ = userRequestHelper(t, router, http.MethodDelete, "/api/me/sessions/"+session2.ID, "", cookie1)
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", cookie2)
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", cookie1)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("204 (revoked current)", func(t *testing.T) {
+		router, ctx := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		cookie := loginHelper(t, router, "admin", "password")
+
+		session := &models.Session{TokenHash: security.HashToken(cookie.Value)}
+		require.NoError(t, router.dao.GetSessionByHash(ctx, session))
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/me/sessions/"+session.ID, nil)
+		req.AddCookie(cookie)
+
+		resp, err := router.router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		// The cookie is cleared
+		require.Empty(t, sessionCookieHelper(t, resp).Value)
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/tags", "", cookie)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("204 (admin revokes all)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		bob := createUserHelper(t, router, "bob", "password", types.UserRoleUser)
+		adminCookie := loginHelper(t, router, "admin", "password")
+		cookie1 := loginHelper(t, router, "bob", "password")
+		cookie2 := loginHelper(t, router, "bob", "password")
+
+		// A user cannot revoke the sessions of another user
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/users/"+bob.ID+"/sessions", "", cookie1)
+		require.Equal(t, http.StatusForbidden, status)
+
+		status, _ = userRequestHelper(t, router, http.MethodDelete, "/api/users/"+bob.ID+"/sessions", "", adminCookie)
+		require.Equal(t, http.StatusNoContent, status)
+
+		for _, cookie := range []*http.Cookie{cookie1, cookie2} {
+			status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", cookie)
+			require.Equal(t, http.StatusUnauthorized, status)
+		}
+
+		// The admin is still logged in
+		status, _ = userRequestHelper(t, router, http.MethodGet, "/api/tags", "", adminCookie)
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("404 (admin revokes all, not found)", func(t *testing.T) {
+		router, _ := setup(t)
+		createUserHelper(t, router, "admin", "password", types.UserRoleAdmin)
+		adminCookie := loginHelper(t, router, "admin", "password")
+
+		status, _ := userRequestHelper(t, router, http.MethodDelete, "/api/users/invalid/sessions", "", adminCookie)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("401 (not logged in)", func(t *testing.T) {
+		router, _ := setup(t)
+
+		status, _ := userRequestHelper(t, router, http.MethodGet, "/api/me/sessions", "", nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// userRequestHelper makes a request with an optional JSON body and session cookie
+func userRequestHelper(t *testing.T, router *Router, method, path, body string, cookie *http.Cookie) (int, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := router.router.Test(req)
+	require.NoError(t, err)
+
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// tokenHelper creates an API token for the logged in user
+func tokenHelper(t *testing.T, router *Router, cookie *http.Cookie, body string) *apiTokenResponse {
+	t.Helper()
+
+	status, respBody := userRequestHelper(t, router, http.MethodPost, "/api/me/tokens", body, cookie)
+	require.Equal(t, http.StatusCreated, status, string(respBody))
+
+	token := &apiTokenResponse{}
+	require.NoError(t, json.Unmarshal(respBody, token))
+
+	return token
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// tokenRequestHelper makes a request with an optional JSON body, authenticated by an API token
+func tokenRequestHelper(t *testing.T, router *Router, method, path, body, token string) (int, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := router.router.Test(req)
+	require.NoError(t, err)
+
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/geerew/off-course/dao"
 	"github.com/geerew/off-course/database"
 	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/transcode"
 	"github.com/geerew/off-course/utils/types"
 	"github.com/robfig/cron/v3"
 )
@@ -18,9 +19,10 @@ var loggerType = slog.Any("type", types.LogTypeCron)
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 type CronConfig struct {
-	Db     database.Database
-	AppFs  *appFs.AppFs
-	Logger *slog.Logger
+	Db         database.Database
+	AppFs      *appFs.AppFs
+	Transcoder *transcode.Transcoder
+	Logger     *slog.Logger
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -41,5 +43,27 @@ func InitCron(config *CronConfig) {
 	go func() { ca.run() }()
 	c.AddFunc("@every 5m", func() { ca.run() })
 
+	// Thumbnails
+	if config.Transcoder != nil {
+		th := &thumbnails{
+			dao:        dao.NewDAO(config.Db),
+			appFs:      config.AppFs,
+			transcoder: config.Transcoder,
+			logger:     config.Logger,
+		}
+
+		go func() { th.run() }()
+		c.AddFunc("@every 15m", func() { th.run() })
+	}
+
+	// Sessions
+	se := &sessions{
+		dao:    dao.NewDAO(config.Db),
+		logger: config.Logger,
+	}
+
+	go func() { se.run() }()
+	c.AddFunc("@every 1h", func() { se.run() })
+
 	c.Start()
 }
@@ -0,0 +1,31 @@
+package cron
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/geerew/off-course/dao"
+)
+
+// sessions purges the sessions that have expired. Expired sessions are already rejected when used,
+// so this only keeps the table from growing
+type sessions struct {
+	dao    *dao.DAO
+	logger *slog.Logger
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (s *sessions) run() error {
+	deleted, err := s.dao.DeleteExpiredSessions(context.Background())
+	if err != nil {
+		s.logger.Error("Failed to purge expired sessions", loggerType, slog.String("error", err.Error()))
+		return err
+	}
+
+	if deleted > 0 {
+		s.logger.Debug("Purged expired sessions", loggerType, slog.Int64("count", deleted))
+	}
+
+	return nil
+}
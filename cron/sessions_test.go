@@ -0,0 +1,64 @@
+package cron
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSessions_Run(t *testing.T) {
+	t.Run("purge expired", func(t *testing.T) {
+		db, _, logger, _ := setup(t)
+
+		dao := dao.NewDAO(db)
+		ctx := context.Background()
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		created := []*models.Session{}
+		for _, expiration := range []time.Duration{-time.Hour, time.Hour} {
+			expiresAt, err := types.ParseDateTime(time.Now().Add(expiration))
+			require.NoError(t, err)
+
+			session := &models.Session{
+				UserID:     user.ID,
+				TokenHash:  security.HashToken(security.RandomString(64)),
+				ExpiresAt:  expiresAt,
+				LastSeenAt: types.NowDateTime(),
+			}
+			require.NoError(t, dao.CreateSession(ctx, session))
+			created = append(created, session)
+		}
+
+		se := &sessions{dao: dao, logger: logger}
+		require.NoError(t, se.run())
+
+		count, err := dao.Count(ctx, &models.Session{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		require.NoError(t, dao.GetById(ctx, created[1]))
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db, _, logger, logs := setup(t)
+
+		_, err := db.Exec("DROP TABLE IF EXISTS " + models.SESSION_TABLE)
+		require.NoError(t, err)
+
+		se := &sessions{dao: dao.NewDAO(db), logger: logger}
+		require.ErrorContains(t, se.run(), "no such table: "+models.SESSION_TABLE)
+
+		// Check the logger
+		require.Equal(t, "Failed to purge expired sessions", (*logs)[len(*logs)-1].Message)
+	})
+}
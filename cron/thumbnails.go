@@ -0,0 +1,143 @@
+package cron
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/pagination"
+	"github.com/geerew/off-course/utils/transcode"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/spf13/afero"
+)
+
+// thumbnails generates the poster and sprite sheet of video assets that are missing them, or
+// whose thumbnails were generated for a different hash. Thumbnails of assets that no longer exist
+// are removed
+type thumbnails struct {
+	dao        *dao.DAO
+	appFs      *appFs.AppFs
+	transcoder *transcode.Transcoder
+	logger     *slog.Logger
+
+	// Prevents overlapping runs, as generating thumbnails may take longer than the schedule
+	running atomic.Bool
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (th *thumbnails) run() error {
+	if !th.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer th.running.Store(false)
+
+	ctx := context.Background()
+
+	ffmpegPath, err := th.dao.GetFFmpegPath(ctx)
+	if err != nil {
+		th.logger.Error("Failed to get ffmpeg path", loggerType, slog.String("error", err.Error()))
+		return err
+	}
+
+	if ffmpegPath == "" {
+		return nil
+	}
+
+	th.logger.Debug("Generating thumbnails", loggerType)
+
+	perPage := 100
+	page := 1
+	totalPages := 1
+
+	// The assets with a video track
+	options := &database.Options{
+		OrderBy: []string{models.ASSET_TABLE + ".created_at asc"},
+		Where: squirrel.And{
+			squirrel.Eq{models.ASSET_TABLE + "." + models.ASSET_TYPE: types.AssetVideo},
+			squirrel.Gt{models.ASSET_TABLE + "." + models.ASSET_DURATION: 0},
+			squirrel.Gt{models.ASSET_TABLE + "." + models.ASSET_WIDTH: 0},
+		},
+	}
+
+	seen := map[string]bool{}
+
+	for page <= totalPages {
+		p := pagination.New(page, perPage)
+		options.Pagination = p
+
+		assets := []*models.Asset{}
+		if err := th.dao.List(ctx, &assets, options); err != nil {
+			th.logger.Error("Failed to fetch assets", loggerType, slog.String("error", err.Error()))
+			return err
+		}
+
+		if page == 1 {
+			totalPages = p.TotalPages()
+		}
+
+		for _, asset := range assets {
+			seen[asset.ID] = true
+			th.generate(ctx, ffmpegPath, asset)
+		}
+
+		page++
+	}
+
+	// Remove the thumbnails of deleted assets
+	ids, err := th.transcoder.ThumbnailIDs()
+	if err != nil {
+		th.logger.Error("Failed to list thumbnails", loggerType, slog.String("error", err.Error()))
+		return err
+	}
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+
+		if err := th.transcoder.RemoveThumbnails(id); err != nil {
+			th.logger.Error("Failed to remove thumbnails", loggerType, slog.String("asset", id), slog.String("error", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// generate generates the thumbnails of an asset when they are missing or stale. Failures are
+// logged and the asset is retried on the next run
+func (th *thumbnails) generate(ctx context.Context, ffmpegPath string, asset *models.Asset) {
+	if _, err := th.transcoder.Thumbnails(asset.ID, asset.Hash); err == nil {
+		return
+	}
+
+	// The course may be unavailable
+	if exists, err := afero.Exists(th.appFs.Fs, asset.Path); err != nil || !exists {
+		return
+	}
+
+	src := &transcode.Source{
+		Path:     asset.Path,
+		Key:      asset.Hash,
+		Duration: asset.Duration,
+		Width:    asset.Width,
+		Height:   asset.Height,
+	}
+
+	if _, err := th.transcoder.GenerateThumbnails(ctx, ffmpegPath, asset.ID, src); err != nil {
+		th.logger.Error(
+			"Failed to generate thumbnails",
+			loggerType,
+			slog.String("asset", asset.ID),
+			slog.String("path", asset.Path),
+			slog.String("error", err.Error()),
+		)
+	}
+}
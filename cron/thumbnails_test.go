@@ -0,0 +1,140 @@
+package cron
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/geerew/off-course/dao"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils/mocks"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/transcode"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestThumbnails_Run(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ffmpeg stub requires a unix shell")
+	}
+
+	t.Run("generate", func(t *testing.T) {
+		db, appFs, logger, _ := setup(t)
+
+		dao := dao.NewDAO(db)
+		ctx := context.Background()
+
+		ffmpegPath, err := mocks.StubFFmpeg(t.TempDir())
+		require.NoError(t, err)
+		require.NoError(t, dao.UpdateFFmpegPath(ctx, ffmpegPath))
+
+		course := &models.Course{Title: "course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		// Video, audio only and missing from disk
+		assets := []*models.Asset{}
+		for i, width := range []int{1280, 0, 1280} {
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    fmt.Sprintf("asset %d", i+1),
+				Prefix:   sql.NullInt16{Int16: int16(i + 1), Valid: true},
+				Type:     *types.NewAsset("mkv"),
+				Path:     fmt.Sprintf("/course-1/%02d asset.mkv", i+1),
+				Hash:     security.RandomString(64),
+				Duration: 60,
+				Width:    width,
+				Height:   width * 9 / 16,
+			}
+			require.NoError(t, dao.CreateAsset(ctx, asset))
+			assets = append(assets, asset)
+
+			if i < 2 {
+				require.NoError(t, afero.WriteFile(appFs.Fs, asset.Path, []byte("video"), os.ModePerm))
+			}
+		}
+
+		transcoder := transcode.New(&transcode.Config{
+			CacheDir:      filepath.Join(t.TempDir(), "transcode"),
+			ThumbnailsDir: filepath.Join(t.TempDir(), "thumbnails"),
+			Logger:        logger,
+		})
+
+		th := &thumbnails{dao: dao, appFs: appFs, transcoder: transcoder, logger: logger}
+		require.NoError(t, th.run())
+
+		thumbnails, err := transcoder.Thumbnails(assets[0].ID, assets[0].Hash)
+		require.NoError(t, err)
+		require.Equal(t, 6, thumbnails.Count)
+
+		ids, err := transcoder.ThumbnailIDs()
+		require.NoError(t, err)
+		require.Equal(t, []string{assets[0].ID}, ids)
+
+		// Changing the hash regenerates the thumbnails
+		assets[0].Hash = security.RandomString(64)
+		require.NoError(t, dao.UpdateAsset(ctx, assets[0]))
+
+		_, err = transcoder.Thumbnails(assets[0].ID, assets[0].Hash)
+		require.ErrorIs(t, err, transcode.ErrNoThumbnails)
+
+		require.NoError(t, th.run())
+
+		_, err = transcoder.Thumbnails(assets[0].ID, assets[0].Hash)
+		require.NoError(t, err)
+
+		// Deleting the asset removes the thumbnails
+		require.NoError(t, dao.Delete(ctx, assets[0], nil))
+		require.NoError(t, th.run())
+
+		ids, err = transcoder.ThumbnailIDs()
+		require.NoError(t, err)
+		require.Empty(t, ids)
+	})
+
+	t.Run("ffmpeg not configured", func(t *testing.T) {
+		db, appFs, logger, _ := setup(t)
+
+		transcoder := transcode.New(&transcode.Config{
+			ThumbnailsDir: filepath.Join(t.TempDir(), "thumbnails"),
+			Logger:        logger,
+		})
+
+		th := &thumbnails{dao: dao.NewDAO(db), appFs: appFs, transcoder: transcoder, logger: logger}
+		require.NoError(t, th.run())
+
+		ids, err := transcoder.ThumbnailIDs()
+		require.NoError(t, err)
+		require.Empty(t, ids)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db, appFs, logger, logs := setup(t)
+
+		dao := dao.NewDAO(db)
+		require.NoError(t, dao.UpdateFFmpegPath(context.Background(), "/usr/bin/ffmpeg"))
+
+		_, err := db.Exec("DROP TABLE IF EXISTS " + models.ASSET_TABLE)
+		require.NoError(t, err)
+
+		transcoder := transcode.New(&transcode.Config{
+			ThumbnailsDir: filepath.Join(t.TempDir(), "thumbnails"),
+			Logger:        logger,
+		})
+
+		th := &thumbnails{dao: dao, appFs: appFs, transcoder: transcoder, logger: logger}
+
+		err = th.run()
+		require.ErrorContains(t, err, "no such table: "+models.ASSET_TABLE)
+
+		// Check the logger
+		require.Equal(t, "Failed to fetch assets", (*logs)[len(*logs)-1].Message)
+	})
+}
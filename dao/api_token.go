@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// CreateApiToken creates an API token. The name is trimmed and cannot be empty
+func (dao *DAO) CreateApiToken(ctx context.Context, token *models.ApiToken) error {
+	if token == nil {
+		return utils.ErrNilPtr
+	}
+
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		return ErrInvalidTokenName
+	}
+
+	return dao.Create(ctx, token)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetApiTokenByHash gets an API token by the hash of the token
+func (dao *DAO) GetApiTokenByHash(ctx context.Context, token *models.ApiToken) error {
+	if token == nil {
+		return utils.ErrNilPtr
+	}
+
+	if token.TokenHash == "" {
+		return utils.ErrInvalidValue
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{models.API_TOKEN_TABLE + "." + models.API_TOKEN_TOKEN_HASH: token.TokenHash},
+	}
+
+	return dao.Get(ctx, token, options)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TouchApiToken records that an API token has been used
+func (dao *DAO) TouchApiToken(ctx context.Context, token *models.ApiToken) error {
+	if token == nil {
+		return utils.ErrNilPtr
+	}
+
+	if token.ID == "" {
+		return utils.ErrInvalidId
+	}
+
+	token.LastUsedAt = types.NowDateTime()
+
+	_, err := dao.Update(ctx, token)
+	return err
+}
@@ -0,0 +1,120 @@
+package dao
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_CreateApiToken(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		token := &models.ApiToken{
+			UserID:    user.ID,
+			Name:      " script ",
+			TokenHash: security.HashToken(security.GenerateToken()),
+			Scope:     types.TokenScopeReadOnly,
+		}
+		require.NoError(t, dao.CreateApiToken(ctx, token))
+		require.Equal(t, "script", token.Name)
+
+		// Deleting the user deletes the token
+		require.NoError(t, dao.DeleteUser(ctx, user))
+		require.ErrorIs(t, dao.GetById(ctx, token), sql.ErrNoRows)
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CreateApiToken(ctx, &models.ApiToken{Name: " "}), ErrInvalidTokenName)
+	})
+
+	t.Run("invalid user", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		token := &models.ApiToken{UserID: "invalid", Name: "script", TokenHash: "hash", Scope: types.TokenScopeReadOnly}
+		require.ErrorContains(t, dao.CreateApiToken(ctx, token), "FOREIGN KEY constraint failed")
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CreateApiToken(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_GetApiTokenByHash(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		hash := security.HashToken(security.GenerateToken())
+		token := &models.ApiToken{UserID: user.ID, Name: "script", TokenHash: hash, Scope: types.TokenScopeAdmin}
+		require.NoError(t, dao.CreateApiToken(ctx, token))
+
+		tokenResult := &models.ApiToken{TokenHash: hash}
+		require.NoError(t, dao.GetApiTokenByHash(ctx, tokenResult))
+		require.Equal(t, token.ID, tokenResult.ID)
+		require.Equal(t, user.ID, tokenResult.UserID)
+		require.Equal(t, types.TokenScopeAdmin, tokenResult.Scope)
+		require.True(t, tokenResult.ExpiresAt.IsZero())
+		require.True(t, tokenResult.LastUsedAt.IsZero())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetApiTokenByHash(ctx, &models.ApiToken{TokenHash: "hash"}), sql.ErrNoRows)
+	})
+
+	t.Run("empty hash", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetApiTokenByHash(ctx, &models.ApiToken{}), utils.ErrInvalidValue)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetApiTokenByHash(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_TouchApiToken(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		token := &models.ApiToken{UserID: user.ID, Name: "script", TokenHash: "hash", Scope: types.TokenScopeReadOnly}
+		require.NoError(t, dao.CreateApiToken(ctx, token))
+		require.NoError(t, dao.TouchApiToken(ctx, token))
+
+		tokenResult := &models.ApiToken{Base: models.Base{ID: token.ID}}
+		require.NoError(t, dao.GetById(ctx, tokenResult))
+		require.False(t, tokenResult.LastUsedAt.IsZero())
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.TouchApiToken(ctx, &models.ApiToken{}), utils.ErrInvalidId)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.TouchApiToken(ctx, nil), utils.ErrNilPtr)
+	})
+}
@@ -3,13 +3,15 @@ package dao
 import (
 	"context"
 
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
 	"github.com/geerew/off-course/models"
 	"github.com/geerew/off-course/utils"
 )
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// CreateAsset creates an asset and refreshes course progress
+// CreateAsset creates an asset and refreshes the course progress of every user with progress
 func (dao *DAO) CreateAsset(ctx context.Context, asset *models.Asset) error {
 	if asset == nil {
 		return utils.ErrNilPtr
@@ -21,7 +23,7 @@ func (dao *DAO) CreateAsset(ctx context.Context, asset *models.Asset) error {
 			return err
 		}
 
-		return dao.RefreshCourseProgress(txCtx, asset.CourseID)
+		return dao.refreshCourseProgressForAllUsers(txCtx, asset.CourseID)
 	})
 }
 
@@ -36,3 +38,68 @@ func (dao *DAO) UpdateAsset(ctx context.Context, asset *models.Asset) error {
 	_, err := dao.Update(ctx, asset)
 	return err
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SumDurationsByCourse returns the total duration of the assets of each course, keyed by course
+// ID. Courses without assets are not included
+func (dao *DAO) SumDurationsByCourse(ctx context.Context, courseIDs []string) (map[string]int, error) {
+	if len(courseIDs) == 0 {
+		return map[string]int{}, nil
+	}
+
+	return dao.sumDurations(ctx, models.ASSET_COURSE_ID, squirrel.Eq{models.ASSET_TABLE + ".course_id": courseIDs})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SumDurationsByChapter returns the total duration of the assets in each chapter of a course, keyed
+// by chapter. Assets in the root of the course are keyed by an empty string
+func (dao *DAO) SumDurationsByChapter(ctx context.Context, courseID string) (map[string]int, error) {
+	if courseID == "" {
+		return nil, utils.ErrInvalidId
+	}
+
+	return dao.sumDurations(ctx, models.ASSET_CHAPTER, squirrel.Eq{models.ASSET_TABLE + ".course_id": courseID})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// sumDurations sums the asset durations, grouped by the given asset column
+func (dao *DAO) sumDurations(ctx context.Context, groupBy string, where squirrel.Sqlizer) (map[string]int, error) {
+	column := models.ASSET_TABLE + "." + groupBy
+
+	query, args, _ := squirrel.
+		StatementBuilder.
+		PlaceholderFormat(squirrel.Question).
+		Select("COALESCE("+column+", '')", "SUM("+models.ASSET_TABLE+"."+models.ASSET_DURATION+")").
+		From(models.ASSET_TABLE).
+		Where(where).
+		GroupBy(column).
+		ToSql()
+
+	q := database.QuerierFromContext(ctx, dao.db)
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[string]int{}
+	for rows.Next() {
+		var key string
+		var duration int
+
+		if err := rows.Scan(&key, &duration); err != nil {
+			return nil, err
+		}
+
+		results[key] += duration
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -2,6 +2,8 @@ package dao
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/geerew/off-course/database"
@@ -12,7 +14,8 @@ import (
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// CreateOrUpdateAssetProgress creates/updates an asset progress and refreshes course progress
+// CreateOrUpdateAssetProgress creates/updates the progress of a user through an asset and refreshes
+// the progress of the user through the course
 func (dao *DAO) CreateOrUpdateAssetProgress(ctx context.Context, assetProgress *models.AssetProgress) error {
 	if assetProgress == nil {
 		return utils.ErrNilPtr
@@ -23,6 +26,13 @@ func (dao *DAO) CreateOrUpdateAssetProgress(ctx context.Context, assetProgress *
 			assetProgress.VideoPos = 0
 		}
 
+		// The scroll position is a percentage
+		assetProgress.ScrollPos = max(0, min(assetProgress.ScrollPos, 100))
+
+		if assetProgress.SlidePos < 0 {
+			assetProgress.SlidePos = 0
+		}
+
 		asset := &models.Asset{}
 		err := dao.Get(
 			txCtx,
@@ -34,7 +44,12 @@ func (dao *DAO) CreateOrUpdateAssetProgress(ctx context.Context, assetProgress *
 			return err
 		}
 
-		if asset.Progress == nil {
+		existing, err := dao.getAssetProgress(txCtx, assetProgress.AssetID, assetProgress.UserID)
+		if err != nil {
+			return err
+		}
+
+		if existing == nil {
 			// Create
 			if assetProgress.Completed {
 				assetProgress.CompletedAt = types.NowDateTime()
@@ -46,10 +61,10 @@ func (dao *DAO) CreateOrUpdateAssetProgress(ctx context.Context, assetProgress *
 			}
 		} else {
 			// Update
-			assetProgress.ID = asset.Progress.ID
+			assetProgress.ID = existing.ID
 			if assetProgress.Completed {
-				if asset.Progress.Completed {
-					assetProgress.CompletedAt = asset.Progress.CompletedAt
+				if existing.Completed {
+					assetProgress.CompletedAt = existing.CompletedAt
 				} else {
 					assetProgress.CompletedAt = types.NowDateTime()
 				}
@@ -64,6 +79,63 @@ func (dao *DAO) CreateOrUpdateAssetProgress(ctx context.Context, assetProgress *
 		}
 
 		// Refresh course progress
-		return dao.RefreshCourseProgress(txCtx, asset.CourseID)
+		return dao.RefreshCourseProgress(txCtx, asset.CourseID, assetProgress.UserID)
 	})
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// LoadAssetProgress populates the progress of a user through each of the given assets. Assets the
+// user has not started are left with a nil progress
+func (dao *DAO) LoadAssetProgress(ctx context.Context, assets []*models.Asset, userID string) error {
+	if len(assets) == 0 {
+		return nil
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{
+			models.ASSET_PROGRESS_TABLE + "." + models.ASSET_PROGRESS_ASSET_ID: utils.Map(assets, func(a *models.Asset) string { return a.ID }),
+			models.ASSET_PROGRESS_TABLE + "." + models.ASSET_PROGRESS_USER_ID:  userID,
+		},
+	}
+
+	progress := []*models.AssetProgress{}
+	if err := dao.List(ctx, &progress, options); err != nil {
+		return err
+	}
+
+	byAsset := make(map[string]*models.AssetProgress, len(progress))
+	for _, p := range progress {
+		byAsset[p.AssetID] = p
+	}
+
+	for _, asset := range assets {
+		asset.Progress = byAsset[asset.ID]
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getAssetProgress gets the progress of a user through an asset. Nil is returned when the user has
+// no progress
+func (dao *DAO) getAssetProgress(ctx context.Context, assetID string, userID string) (*models.AssetProgress, error) {
+	assetProgress := &models.AssetProgress{}
+	err := dao.Get(ctx, assetProgress, &database.Options{
+		Where: squirrel.Eq{
+			models.ASSET_PROGRESS_TABLE + "." + models.ASSET_PROGRESS_ASSET_ID: assetID,
+			models.ASSET_PROGRESS_TABLE + "." + models.ASSET_PROGRESS_USER_ID:  userID,
+		},
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return assetProgress, nil
+}
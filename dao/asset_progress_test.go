@@ -63,6 +63,44 @@ func Test_CreateOrUpdateAssetProgress(t *testing.T) {
 		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
 	})
 
+	t.Run("scroll position", func(t *testing.T) {
+		dao, ctx := setup(t)
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("md"),
+			Path:     "/course-1/01 asset.md",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		assetProgress := &models.AssetProgress{AssetID: asset.ID, ScrollPos: 40}
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Equal(t, 40, asset.Progress.ScrollPos)
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.True(t, course.Progress.Started)
+
+		// Clamped to a percentage
+		assetProgress.ScrollPos = 150
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Equal(t, 100, asset.Progress.ScrollPos)
+
+		assetProgress.ScrollPos = -5
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Equal(t, 0, asset.Progress.ScrollPos)
+	})
+
 	t.Run("nil", func(t *testing.T) {
 		dao, ctx := setup(t)
 		require.ErrorIs(t, dao.CreateOrUpdateAssetProgress(ctx, nil), utils.ErrNilPtr)
@@ -2,6 +2,7 @@ package dao
 
 import (
 	"database/sql"
+	"fmt"
 	"testing"
 	"time"
 
@@ -61,13 +62,19 @@ func Test_UpdateAsset(t *testing.T) {
 		time.Sleep(1 * time.Millisecond)
 
 		newAsset := &models.Asset{
-			Base:    originalAsset.Base,
-			Title:   "Asset 2",                            // Mutable
-			Prefix:  sql.NullInt16{Int16: 2, Valid: true}, // Mutable
-			Chapter: "Chapter 2",                          // Mutable
-			Type:    *types.NewAsset("html"),              // Mutable
-			Path:    "/course-1/02 asset.html",            // Mutable
-			Hash:    "5678",                               // Mutable
+			Base:        originalAsset.Base,
+			Title:       "Asset 2",                            // Mutable
+			Prefix:      sql.NullInt16{Int16: 2, Valid: true}, // Mutable
+			Chapter:     "Chapter 2",                          // Mutable
+			Type:        *types.NewAsset("html"),              // Mutable
+			Path:        "/course-1/02 asset.html",            // Mutable
+			Hash:        "5678",                               // Mutable
+			Duration:    90,                                   // Mutable
+			Width:       1920,                                 // Mutable
+			Height:      1080,                                 // Mutable
+			VideoCodec:  "h264",                               // Mutable
+			AudioCodec:  "aac",                                // Mutable
+			AudioTracks: 1,                                    // Mutable
 		}
 		require.NoError(t, dao.UpdateAsset(ctx, newAsset))
 
@@ -81,6 +88,12 @@ func Test_UpdateAsset(t *testing.T) {
 		require.Equal(t, newAsset.Type, assertResult.Type)                      // Changed
 		require.Equal(t, newAsset.Path, assertResult.Path)                      // Changed
 		require.Equal(t, newAsset.Hash, assertResult.Hash)                      // Changed
+		require.Equal(t, newAsset.Duration, assertResult.Duration)              // Changed
+		require.Equal(t, newAsset.Width, assertResult.Width)                    // Changed
+		require.Equal(t, newAsset.Height, assertResult.Height)                  // Changed
+		require.Equal(t, newAsset.VideoCodec, assertResult.VideoCodec)          // Changed
+		require.Equal(t, newAsset.AudioCodec, assertResult.AudioCodec)          // Changed
+		require.Equal(t, newAsset.AudioTracks, assertResult.AudioTracks)        // Changed
 		require.False(t, assertResult.UpdatedAt.Equal(originalAsset.UpdatedAt)) // Changed
 	})
 
@@ -112,6 +125,70 @@ func Test_UpdateAsset(t *testing.T) {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+func Test_SumDurations(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		courses := []*models.Course{}
+		for i := range 3 {
+			course := &models.Course{Title: fmt.Sprintf("Course %d", i), Path: fmt.Sprintf("/course-%d", i)}
+			require.NoError(t, dao.CreateCourse(ctx, course))
+			courses = append(courses, course)
+		}
+
+		// Course 1 has 2 chapters and a root asset, course 2 has 1 chapter and course 3 has no
+		// assets
+		assets := []struct {
+			course   *models.Course
+			chapter  string
+			duration int
+		}{
+			{courses[0], "", 10},
+			{courses[0], "Chapter 1", 60},
+			{courses[0], "Chapter 1", 120},
+			{courses[0], "Chapter 2", 300},
+			{courses[1], "Chapter 1", 45},
+		}
+
+		for i, a := range assets {
+			asset := &models.Asset{
+				CourseID: a.course.ID,
+				Title:    fmt.Sprintf("Asset %d", i),
+				Prefix:   sql.NullInt16{Int16: int16(i), Valid: true},
+				Chapter:  a.chapter,
+				Type:     *types.NewAsset("mp4"),
+				Path:     fmt.Sprintf("%s/%s/%02d asset.mp4", a.course.Path, a.chapter, i),
+				Hash:     fmt.Sprintf("%d", i),
+				Duration: a.duration,
+			}
+			require.NoError(t, dao.CreateAsset(ctx, asset))
+		}
+
+		byCourse, err := dao.SumDurationsByCourse(ctx, []string{courses[0].ID, courses[1].ID, courses[2].ID})
+		require.NoError(t, err)
+		require.Len(t, byCourse, 2)
+		require.Equal(t, 490, byCourse[courses[0].ID])
+		require.Equal(t, 45, byCourse[courses[1].ID])
+
+		byChapter, err := dao.SumDurationsByChapter(ctx, courses[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{"": 10, "Chapter 1": 180, "Chapter 2": 300}, byChapter)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		byCourse, err := dao.SumDurationsByCourse(ctx, nil)
+		require.NoError(t, err)
+		require.Empty(t, byCourse)
+
+		_, err = dao.SumDurationsByChapter(ctx, "")
+		require.ErrorIs(t, err, utils.ErrInvalidId)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 func Test_AssetDeleteCascade(t *testing.T) {
 	dao, ctx := setup(t)
 
@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// CreateChapterMarker creates a chapter marker
+func (dao *DAO) CreateChapterMarker(ctx context.Context, marker *models.ChapterMarker) error {
+	if marker == nil {
+		return utils.ErrNilPtr
+	}
+
+	return dao.Create(ctx, marker)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ListChapterMarkers lists the chapter markers of an asset, ordered by start
+func (dao *DAO) ListChapterMarkers(ctx context.Context, assetID string) ([]*models.ChapterMarker, error) {
+	if assetID == "" {
+		return nil, utils.ErrInvalidId
+	}
+
+	options := &database.Options{
+		OrderBy: []string{models.CHAPTER_MARKER_TABLE + "." + models.CHAPTER_MARKER_START + " asc"},
+		Where:   squirrel.Eq{models.CHAPTER_MARKER_TABLE + "." + models.CHAPTER_MARKER_ASSET_ID: assetID},
+	}
+
+	markers := []*models.ChapterMarker{}
+	err := dao.List(ctx, &markers, options)
+	return markers, err
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ReplaceChapterMarkers replaces the chapter markers of an asset. Nothing is changed when the
+// existing markers have the same titles and start times
+func (dao *DAO) ReplaceChapterMarkers(ctx context.Context, assetID string, markers []*models.ChapterMarker) error {
+	if assetID == "" {
+		return utils.ErrInvalidId
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := dao.ListChapterMarkers(txCtx, assetID)
+		if err != nil {
+			return err
+		}
+
+		if sameChapterMarkers(existing, markers) {
+			return nil
+		}
+
+		options := &database.Options{
+			Where: squirrel.Eq{models.CHAPTER_MARKER_TABLE + "." + models.CHAPTER_MARKER_ASSET_ID: assetID},
+		}
+
+		if err := dao.Delete(txCtx, &models.ChapterMarker{}, options); err != nil {
+			return err
+		}
+
+		for _, marker := range markers {
+			marker.AssetID = assetID
+
+			if err := dao.CreateChapterMarker(txCtx, marker); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// sameChapterMarkers returns true when both slices have the same titles and start times, in the
+// same order
+func sameChapterMarkers(a, b []*models.ChapterMarker) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Title != b[i].Title || a[i].Start != b[i].Start {
+			return false
+		}
+	}
+
+	return true
+}
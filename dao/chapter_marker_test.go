@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_CreateChapterMarker(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		marker := &models.ChapterMarker{AssetID: asset.ID, Title: "Intro", Start: 0}
+		require.NoError(t, dao.CreateChapterMarker(ctx, marker))
+
+		markers, err := dao.ListChapterMarkers(ctx, asset.ID)
+		require.NoError(t, err)
+		require.Len(t, markers, 1)
+		require.Equal(t, "Intro", markers[0].Title)
+		require.Zero(t, markers[0].Start)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CreateChapterMarker(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_ReplaceChapterMarkers(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		require.NoError(t, dao.ReplaceChapterMarkers(ctx, asset.ID, []*models.ChapterMarker{
+			{Title: "Intro", Start: 0},
+			{Title: "Install", Start: 30000},
+		}))
+
+		markers, err := dao.ListChapterMarkers(ctx, asset.ID)
+		require.NoError(t, err)
+		require.Len(t, markers, 2)
+		require.Equal(t, "Install", markers[1].Title)
+		require.Equal(t, 30000, markers[1].Start)
+
+		// Unchanged markers are kept
+		require.NoError(t, dao.ReplaceChapterMarkers(ctx, asset.ID, []*models.ChapterMarker{
+			{Title: "Intro", Start: 0},
+			{Title: "Install", Start: 30000},
+		}))
+
+		unchanged, err := dao.ListChapterMarkers(ctx, asset.ID)
+		require.NoError(t, err)
+		require.Equal(t, markers[0].ID, unchanged[0].ID)
+
+		// Changed markers are replaced
+		require.NoError(t, dao.ReplaceChapterMarkers(ctx, asset.ID, []*models.ChapterMarker{
+			{Title: "Setup", Start: 5000},
+		}))
+
+		markers, err = dao.ListChapterMarkers(ctx, asset.ID)
+		require.NoError(t, err)
+		require.Len(t, markers, 1)
+		require.Equal(t, "Setup", markers[0].Title)
+
+		// No markers
+		require.NoError(t, dao.ReplaceChapterMarkers(ctx, asset.ID, nil))
+
+		markers, err = dao.ListChapterMarkers(ctx, asset.ID)
+		require.NoError(t, err)
+		require.Empty(t, markers)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.ReplaceChapterMarkers(ctx, "", nil), utils.ErrInvalidId)
+
+		_, err := dao.ListChapterMarkers(ctx, "")
+		require.ErrorIs(t, err, utils.ErrInvalidId)
+	})
+}
@@ -14,21 +14,14 @@ import (
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// CreateCourse creates a course and course progress
+// CreateCourse creates a course. Course progress is per user, so it is created once a user makes
+// progress through the course
 func (dao *DAO) CreateCourse(ctx context.Context, course *models.Course) error {
 	if course == nil {
 		return utils.ErrNilPtr
 	}
 
-	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
-		err := dao.Create(txCtx, course)
-		if err != nil {
-			return err
-		}
-
-		courseProgress := &models.CourseProgress{CourseID: course.Id()}
-		return dao.CreateCourseProgress(txCtx, courseProgress)
-	})
+	return dao.Create(ctx, course)
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"math"
 
 	"github.com/Masterminds/squirrel"
@@ -35,55 +36,95 @@ func (dao *DAO) UpdateCourseProgress(ctx context.Context, courseProgress *models
 	return err
 }
 
-// Refresh refreshes the current course progress for the given ID
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// RefreshCourseProgress refreshes the progress of a user through a course. The course progress is
+// created when it does not exist
+//
+// It calculates the number of assets, number of completed assets and number of started assets (a
+// video with a position or a document with a scroll position), then calculates the percent complete
+// and whether the course has been started
 //
-// It calculates the number of assets, number of completed assets and number of started video assets,
-// then calculates the percent complete and whether the course has been started
+// The percent complete depends upon the progress mode. In count mode, each asset is weighted equally.
+// In duration mode, each asset is weighted by its duration and partially watched videos contribute
+// their position. Assets without a duration are weighted as the configured untimed weight, and partially
+// read documents contribute their scroll position. When the total weight is 0, count mode is used
 //
 // Based upon this calculation,
 //   - If the course has been started and `started_at` is null, `started_at` will be set to NOW
 //   - If the course is not started, `started_at` is set to null
 //   - If the course is complete and `completed_at` is null, `completed_at` is set to NOW
 //   - If the course is not complete, `completed_at` is set to null
-func (dao *DAO) RefreshCourseProgress(ctx context.Context, courseID string) error {
+func (dao *DAO) RefreshCourseProgress(ctx context.Context, courseID string, userID string) error {
 	if courseID == "" {
 		return utils.ErrInvalidId
 	}
 
-	// Count the number of assets, number of completed assets and number of video assets started for
-	// this course
+	settings, err := dao.GetProgressSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The weight of an asset and the watched weight of an asset, in duration mode
+	weight := "(CASE WHEN " + models.ASSET_TABLE + ".duration > 0 THEN " + models.ASSET_TABLE + ".duration ELSE ? END)"
+	watched := "(CASE WHEN " + models.ASSET_PROGRESS_TABLE + ".completed THEN " + weight +
+		" WHEN " + models.ASSET_TABLE + ".duration > 0 THEN MIN(COALESCE(" + models.ASSET_PROGRESS_TABLE + ".video_pos, 0), " + models.ASSET_TABLE + ".duration)" +
+		" ELSE ? * MIN(COALESCE(" + models.ASSET_PROGRESS_TABLE + ".scroll_pos, 0), 100) / 100 END)"
+
+	// Count the number of assets, number of completed assets and number of assets started by the
+	// user for this course, along with the total and watched weights
 	query, args, _ := squirrel.
 		StatementBuilder.
 		PlaceholderFormat(squirrel.Question).
 		Select(
 			"COUNT(DISTINCT "+models.ASSET_TABLE+".id) AS total_count",
 			"SUM(CASE WHEN "+models.ASSET_PROGRESS_TABLE+".completed THEN 1 ELSE 0 END) AS completed_count",
-			"SUM(CASE WHEN "+models.ASSET_PROGRESS_TABLE+".video_pos > 0 THEN 1 ELSE 0 END) AS started_count").
+			"SUM(CASE WHEN "+models.ASSET_PROGRESS_TABLE+".video_pos > 0 OR "+models.ASSET_PROGRESS_TABLE+".scroll_pos > 0 THEN 1 ELSE 0 END) AS started_count").
+		Column("SUM("+weight+") AS total_weight", settings.UntimedWeight).
+		Column("SUM("+watched+") AS watched_weight", settings.UntimedWeight, settings.UntimedWeight).
 		From(models.ASSET_TABLE).
-		LeftJoin(models.ASSET_PROGRESS_TABLE + " ON " + models.ASSET_TABLE + ".id = " + models.ASSET_PROGRESS_TABLE + ".asset_id").
+		LeftJoin(
+			models.ASSET_PROGRESS_TABLE+" ON "+models.ASSET_TABLE+".id = "+models.ASSET_PROGRESS_TABLE+".asset_id AND "+
+				models.ASSET_PROGRESS_TABLE+".user_id = ?", userID).
 		Where(squirrel.And{squirrel.Eq{models.ASSET_TABLE + ".course_id": courseID}}).
 		ToSql()
 
 	var totalAssetCount sql.NullInt32
 	var completedAssetCount sql.NullInt32
 	var startedAssetCount sql.NullInt32
+	var totalWeight sql.NullInt64
+	var watchedWeight sql.NullInt64
 
 	q := database.QuerierFromContext(ctx, dao.db)
-	err := q.QueryRow(query, args...).Scan(&totalAssetCount, &completedAssetCount, &startedAssetCount)
+	err = q.QueryRow(query, args...).Scan(&totalAssetCount, &completedAssetCount, &startedAssetCount, &totalWeight, &watchedWeight)
 	if err != nil {
 		return err
 	}
 
 	// Get the course progress
 	courseProgress := &models.CourseProgress{}
-	err = dao.Get(ctx, courseProgress, &database.Options{Where: squirrel.Eq{courseProgress.Table() + ".course_id": courseID}})
-	if err != nil {
+	err = dao.Get(ctx, courseProgress, &database.Options{
+		Where: squirrel.Eq{
+			models.COURSE_PROGRESS_TABLE + "." + models.COURSE_PROGRESS_COURSE_ID: courseID,
+			models.COURSE_PROGRESS_TABLE + "." + models.COURSE_PROGRESS_USER_ID:   userID,
+		},
+	})
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return err
 	}
 
+	exists := err == nil
+	courseProgress.CourseID = courseID
+	courseProgress.UserID = userID
+
 	now := types.NowDateTime()
 
-	courseProgress.Percent = int(math.Abs((float64(completedAssetCount.Int32) * float64(100)) / float64(totalAssetCount.Int32)))
+	if settings.Mode == types.ProgressModeDuration && totalWeight.Int64 > 0 {
+		courseProgress.Percent = int(math.Abs((float64(watchedWeight.Int64) * float64(100)) / float64(totalWeight.Int64)))
+	} else {
+		courseProgress.Percent = int(math.Abs((float64(completedAssetCount.Int32) * float64(100)) / float64(totalAssetCount.Int32)))
+	}
 
 	if startedAssetCount.Int32 > 0 || courseProgress.Percent > 0 && courseProgress.Percent <= 100 {
 		courseProgress.Started = true
@@ -99,6 +140,10 @@ func (dao *DAO) RefreshCourseProgress(ctx context.Context, courseID string) erro
 		courseProgress.CompletedAt = types.DateTime{}
 	}
 
+	if !exists {
+		return dao.Create(ctx, courseProgress)
+	}
+
 	// Update the course progress
 	_, err = dao.Update(ctx, courseProgress)
 	return err
@@ -106,14 +151,78 @@ func (dao *DAO) RefreshCourseProgress(ctx context.Context, courseID string) erro
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// refreshCourseProgressForAllUsers refreshes the progress of every user who has progress for the
+// given course. This is used when something other than a user changes the progress of a course,
+// such as a new asset or a change to the progress settings
+func (dao *DAO) refreshCourseProgressForAllUsers(ctx context.Context, courseID string) error {
+	userIDs, err := dao.ListPluck(
+		ctx,
+		&models.CourseProgress{},
+		&database.Options{Where: squirrel.Eq{models.COURSE_PROGRESS_TABLE + "." + models.COURSE_PROGRESS_COURSE_ID: courseID}},
+		models.COURSE_PROGRESS_USER_ID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := dao.RefreshCourseProgress(ctx, courseID, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// LoadCourseProgress populates the progress of a user through each of the given courses. Courses
+// the user has not started are left with an empty progress
+func (dao *DAO) LoadCourseProgress(ctx context.Context, courses []*models.Course, userID string) error {
+	if len(courses) == 0 {
+		return nil
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{
+			models.COURSE_PROGRESS_TABLE + "." + models.COURSE_PROGRESS_COURSE_ID: utils.Map(courses, func(c *models.Course) string { return c.ID }),
+			models.COURSE_PROGRESS_TABLE + "." + models.COURSE_PROGRESS_USER_ID:   userID,
+		},
+	}
+
+	progress := []*models.CourseProgress{}
+	if err := dao.List(ctx, &progress, options); err != nil {
+		return err
+	}
+
+	byCourse := make(map[string]*models.CourseProgress, len(progress))
+	for _, p := range progress {
+		byCourse[p.CourseID] = p
+	}
+
+	for _, course := range courses {
+		if p, ok := byCourse[course.ID]; ok {
+			course.Progress = *p
+		} else {
+			course.Progress = models.CourseProgress{}
+		}
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 // PluckIDsForStartedCourses returns a list of course IDs for courses that have been started but not
-// completed
-func (dao *DAO) PluckIDsForStartedCourses(ctx context.Context, options *database.Options) ([]string, error) {
+// completed by the user
+func (dao *DAO) PluckIDsForStartedCourses(ctx context.Context, userID string, options *database.Options) ([]string, error) {
 	if options == nil {
 		options = &database.Options{}
 	}
 
 	options.Where = squirrel.And{
+		squirrel.Eq{models.COURSE_PROGRESS_TABLE + ".user_id": userID},
 		squirrel.Eq{models.COURSE_PROGRESS_TABLE + ".started": true},
 		squirrel.NotEq{models.COURSE_PROGRESS_TABLE + ".percent": 100},
 	}
@@ -123,24 +232,36 @@ func (dao *DAO) PluckIDsForStartedCourses(ctx context.Context, options *database
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// PluckIDsForCompletedCourses returns a list of course IDs for courses that have been completed
-func (dao *DAO) PluckIDsForCompletedCourses(ctx context.Context, options *database.Options) ([]string, error) {
+// PluckIDsForCompletedCourses returns a list of course IDs for courses that have been completed by
+// the user
+func (dao *DAO) PluckIDsForCompletedCourses(ctx context.Context, userID string, options *database.Options) ([]string, error) {
 	if options == nil {
 		options = &database.Options{}
 	}
 
-	options.Where = squirrel.Eq{models.COURSE_PROGRESS_TABLE + ".percent": 100}
+	options.Where = squirrel.And{
+		squirrel.Eq{models.COURSE_PROGRESS_TABLE + ".user_id": userID},
+		squirrel.Eq{models.COURSE_PROGRESS_TABLE + ".percent": 100},
+	}
+
 	return dao.ListPluck(ctx, &models.CourseProgress{}, options, models.COURSE_PROGRESS_COURSE_ID)
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// PluckNotStartedCourses returns a list of course IDs for courses that have not been started
-func (dao *DAO) PluckIDsForNotStartedCourses(ctx context.Context, options *database.Options) ([]string, error) {
+// PluckNotStartedCourses returns a list of course IDs for courses that have not been started by the
+// user. A course the user has never opened has no course progress, so this is every course without
+// a started course progress for the user
+func (dao *DAO) PluckIDsForNotStartedCourses(ctx context.Context, userID string, options *database.Options) ([]string, error) {
 	if options == nil {
 		options = &database.Options{}
 	}
 
-	options.Where = squirrel.Eq{models.COURSE_PROGRESS_TABLE + ".started": false}
-	return dao.ListPluck(ctx, &models.CourseProgress{}, options, models.COURSE_PROGRESS_COURSE_ID)
+	options.Where = squirrel.Expr(
+		models.COURSE_TABLE+".id NOT IN (SELECT "+models.COURSE_PROGRESS_COURSE_ID+" FROM "+models.COURSE_PROGRESS_TABLE+
+			" WHERE "+models.COURSE_PROGRESS_USER_ID+" = ? AND "+models.COURSE_PROGRESS_STARTED+" = TRUE)",
+		userID,
+	)
+
+	return dao.ListPluck(ctx, &models.Course{}, options, models.BASE_ID)
 }
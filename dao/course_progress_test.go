@@ -44,6 +44,7 @@ func Test_RefreshCourseProgress(t *testing.T) {
 
 		course := &models.Course{Title: "Course 1", Path: "/course-1"}
 		require.NoError(t, dao.CreateCourse(ctx, course))
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
 		require.False(t, course.Progress.Started)
 		require.True(t, course.Progress.StartedAt.IsZero())
 		require.Zero(t, course.Progress.Percent)
@@ -65,7 +66,7 @@ func Test_RefreshCourseProgress(t *testing.T) {
 		assetProgress := &models.AssetProgress{AssetID: asset1.ID, VideoPos: 1}
 		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
 
-		require.NoError(t, dao.GetById(ctx, course))
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
 		require.True(t, course.Progress.Started)
 		require.False(t, course.Progress.StartedAt.IsZero())
 		require.Zero(t, 0, course.Progress.Percent)
@@ -75,7 +76,7 @@ func Test_RefreshCourseProgress(t *testing.T) {
 		assetProgress.VideoPos = 0
 		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
 
-		require.NoError(t, dao.GetById(ctx, course))
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
 		require.False(t, course.Progress.Started)
 		require.True(t, course.Progress.StartedAt.IsZero())
 		require.Zero(t, 0, course.Progress.Percent)
@@ -85,7 +86,7 @@ func Test_RefreshCourseProgress(t *testing.T) {
 		assetProgress.Completed = true
 		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
 
-		require.NoError(t, dao.GetById(ctx, course))
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
 		require.True(t, course.Progress.Started)
 		require.False(t, course.Progress.StartedAt.IsZero())
 		require.Equal(t, 100, course.Progress.Percent)
@@ -104,7 +105,7 @@ func Test_RefreshCourseProgress(t *testing.T) {
 		require.NoError(t, dao.CreateAsset(ctx, asset2))
 
 		// Check course progress
-		require.NoError(t, dao.GetById(ctx, course))
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
 		require.True(t, course.Progress.Started)
 		require.False(t, course.Progress.StartedAt.IsZero())
 		require.Equal(t, 50, course.Progress.Percent)
@@ -113,8 +114,179 @@ func Test_RefreshCourseProgress(t *testing.T) {
 
 	t.Run("invalid course id", func(t *testing.T) {
 		dao, ctx := setup(t)
-		require.ErrorIs(t, dao.RefreshCourseProgress(ctx, ""), utils.ErrInvalidId)
+		require.ErrorIs(t, dao.RefreshCourseProgress(ctx, "", ""), utils.ErrInvalidId)
 	})
+
+	t.Run("duration mode", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.NoError(t, dao.UpdateProgressSettings(ctx, &ProgressSettings{Mode: types.ProgressModeDuration, UntimedWeight: 100}))
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		// A 600 second video, a 300 second video and a PDF (weighted as 100 seconds)
+		assets := []*models.Asset{}
+		for i, a := range []struct {
+			ext      string
+			duration int
+		}{{"mp4", 600}, {"mp4", 300}, {"pdf", 0}} {
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    fmt.Sprintf("Asset %d", i+1),
+				Prefix:   sql.NullInt16{Int16: int16(i + 1), Valid: true},
+				Type:     *types.NewAsset(a.ext),
+				Path:     fmt.Sprintf("/course-1/%02d asset.%s", i+1, a.ext),
+				Hash:     fmt.Sprintf("hash %d", i+1),
+				Duration: a.duration,
+			}
+			require.NoError(t, dao.CreateAsset(ctx, asset))
+			assets = append(assets, asset)
+		}
+
+		// Partially watch asset 1 (300 / 1000)
+		assetProgress := &models.AssetProgress{AssetID: assets[0].ID, VideoPos: 300}
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.True(t, course.Progress.Started)
+		require.Equal(t, 30, course.Progress.Percent)
+
+		// A position beyond the duration is capped (600 / 1000)
+		assetProgress.VideoPos = 900
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, assetProgress))
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 60, course.Progress.Percent)
+
+		// Scroll half way through the PDF (650 / 1000)
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[2].ID, ScrollPos: 50}))
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 65, course.Progress.Percent)
+
+		// Complete the PDF (700 / 1000)
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[2].ID, Completed: true}))
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 70, course.Progress.Percent)
+
+		// Switching to count mode refreshes the progress (1 / 3)
+		require.NoError(t, dao.UpdateProgressSettings(ctx, &ProgressSettings{Mode: types.ProgressModeCount}))
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 33, course.Progress.Percent)
+
+		// Complete everything in duration mode
+		require.NoError(t, dao.UpdateProgressSettings(ctx, &ProgressSettings{Mode: types.ProgressModeDuration, UntimedWeight: 100}))
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[0].ID, Completed: true}))
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[1].ID, Completed: true}))
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 100, course.Progress.Percent)
+		require.False(t, course.Progress.CompletedAt.IsZero())
+	})
+
+	t.Run("duration mode without durations", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.NoError(t, dao.UpdateProgressSettings(ctx, &ProgressSettings{Mode: types.ProgressModeDuration, UntimedWeight: 0}))
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		assets := []*models.Asset{}
+		for i := range 2 {
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    fmt.Sprintf("Asset %d", i+1),
+				Prefix:   sql.NullInt16{Int16: int16(i + 1), Valid: true},
+				Type:     *types.NewAsset("pdf"),
+				Path:     fmt.Sprintf("/course-1/%02d asset.pdf", i+1),
+				Hash:     fmt.Sprintf("hash %d", i+1),
+			}
+			require.NoError(t, dao.CreateAsset(ctx, asset))
+			assets = append(assets, asset)
+		}
+
+		// The total weight is 0 so count mode is used
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[0].ID, Completed: true}))
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 50, course.Progress.Percent)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_RefreshCourseProgressPerUser(t *testing.T) {
+	dao, ctx := setup(t)
+
+	user1 := &models.User{Username: "user1", PasswordHash: "hash", Role: types.UserRoleUser}
+	require.NoError(t, dao.CreateUser(ctx, user1))
+
+	user2 := &models.User{Username: "user2", PasswordHash: "hash", Role: types.UserRoleUser}
+	require.NoError(t, dao.CreateUser(ctx, user2))
+
+	course := &models.Course{Title: "Course 1", Path: "/course-1"}
+	require.NoError(t, dao.CreateCourse(ctx, course))
+
+	assets := []*models.Asset{}
+	for i := range 2 {
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    fmt.Sprintf("Asset %d", i+1),
+			Prefix:   sql.NullInt16{Int16: int16(i + 1), Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     fmt.Sprintf("/course-1/%02d asset.mp4", i+1),
+			Hash:     fmt.Sprintf("hash %d", i+1),
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+		assets = append(assets, asset)
+	}
+
+	// User 1 completes both assets while user 2 starts the first
+	require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[0].ID, UserID: user1.ID, Completed: true}))
+	require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[1].ID, UserID: user1.ID, Completed: true}))
+	require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[0].ID, UserID: user2.ID, VideoPos: 10}))
+
+	require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, user1.ID))
+	require.Equal(t, user1.ID, course.Progress.UserID)
+	require.Equal(t, 100, course.Progress.Percent)
+
+	require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, user2.ID))
+	require.Equal(t, user2.ID, course.Progress.UserID)
+	require.True(t, course.Progress.Started)
+	require.Zero(t, course.Progress.Percent)
+
+	require.NoError(t, dao.LoadAssetProgress(ctx, assets, user2.ID))
+	require.NotNil(t, assets[0].Progress)
+	require.False(t, assets[0].Progress.Completed)
+	require.Equal(t, 10, assets[0].Progress.VideoPos)
+	require.Nil(t, assets[1].Progress)
+
+	// No progress for a user who has not started the course
+	require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, "invalid"))
+	require.False(t, course.Progress.Started)
+	require.Empty(t, course.Progress.ID)
+
+	// A new asset refreshes the progress of both users
+	asset3 := &models.Asset{
+		CourseID: course.ID,
+		Title:    "Asset 3",
+		Prefix:   sql.NullInt16{Int16: 3, Valid: true},
+		Type:     *types.NewAsset("mp4"),
+		Path:     "/course-1/03 asset.mp4",
+		Hash:     "hash 3",
+	}
+	require.NoError(t, dao.CreateAsset(ctx, asset3))
+
+	require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, user1.ID))
+	require.Equal(t, 66, course.Progress.Percent)
+
+	count, err := dao.Count(ctx, &models.CourseProgress{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -152,22 +324,38 @@ func Test_PluckIDsForStartedCourses(t *testing.T) {
 	require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: assets[1].ID, VideoPos: 10, Completed: true}))
 
 	// Find started courses
-	ids, err := dao.PluckIDsForStartedCourses(ctx, nil)
+	ids, err := dao.PluckIDsForStartedCourses(ctx, "", nil)
 	require.NoError(t, err)
 	require.Len(t, ids, 1)
 	require.Equal(t, courses[0].ID, ids[0])
 
 	// Find completed courses
-	ids, err = dao.PluckIDsForCompletedCourses(ctx, nil)
+	ids, err = dao.PluckIDsForCompletedCourses(ctx, "", nil)
 	require.NoError(t, err)
 	require.Len(t, ids, 1)
 	require.Equal(t, courses[1].ID, ids[0])
 
 	// Find not started courses
-	ids, err = dao.PluckIDsForNotStartedCourses(ctx, nil)
+	ids, err = dao.PluckIDsForNotStartedCourses(ctx, "", nil)
 	require.NoError(t, err)
 	require.Len(t, ids, 1)
 	require.Equal(t, courses[2].ID, ids[0])
+
+	// Another user has not started any course
+	user := &models.User{Username: "user", PasswordHash: "hash", Role: types.UserRoleUser}
+	require.NoError(t, dao.CreateUser(ctx, user))
+
+	ids, err = dao.PluckIDsForStartedCourses(ctx, user.ID, nil)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	ids, err = dao.PluckIDsForCompletedCourses(ctx, user.ID, nil)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	ids, err = dao.PluckIDsForNotStartedCourses(ctx, user.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, ids, 3)
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -177,6 +365,7 @@ func Test_CourseProgressDeleteCascade(t *testing.T) {
 
 	course := &models.Course{Title: "Course", Path: "/course"}
 	require.NoError(t, dao.CreateCourse(ctx, course))
+	require.NoError(t, dao.CreateCourseProgress(ctx, &models.CourseProgress{CourseID: course.ID}))
 
 	courseProgress := &models.CourseProgress{CourseID: course.ID}
 	require.NoError(t, dao.Get(ctx, courseProgress, &database.Options{Where: squirrel.Eq{courseProgress.Table() + ".course_id": course.ID}}))
@@ -0,0 +1,15 @@
+package dao
+
+import "errors"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	ErrAdminExists      = errors.New("an admin already exists")
+	ErrInvalidUsername  = errors.New("username cannot be empty")
+	ErrInvalidTokenName = errors.New("token name cannot be empty")
+	ErrLastAdmin        = errors.New("cannot remove, demote or disable the last admin")
+	ErrShareAssetCourse = errors.New("the shared asset does not belong to the course")
+	ErrShareViewLimit   = errors.New("the share has reached its view limit")
+	ErrUserExists       = errors.New("username already exists")
+)
@@ -2,11 +2,14 @@ package dao
 
 import (
 	"context"
+	"database/sql"
+	"strconv"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/geerew/off-course/database"
 	"github.com/geerew/off-course/models"
 	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
 )
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -50,3 +53,154 @@ func (dao *DAO) UpdateParam(ctx context.Context, param *models.Param) error {
 	_, err := dao.Update(ctx, param)
 	return err
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ProgressSettings defines the settings used when calculating the percent complete of a course
+type ProgressSettings struct {
+	Mode types.ProgressMode
+
+	// The weight, in seconds, of an asset without a duration when the mode is duration
+	UntimedWeight int
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetProgressSettings gets the progress settings from the params table. Missing or invalid params
+// fallback to the defaults
+func (dao *DAO) GetProgressSettings(ctx context.Context) (*ProgressSettings, error) {
+	settings := &ProgressSettings{
+		Mode:          types.ProgressModeCount,
+		UntimedWeight: 60,
+	}
+
+	mode := &models.Param{Key: models.PARAM_KEY_PROGRESS_MODE}
+	if err := dao.GetParamByKey(ctx, mode); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if types.ProgressMode(mode.Value).IsValid() {
+		settings.Mode = types.ProgressMode(mode.Value)
+	}
+
+	weight := &models.Param{Key: models.PARAM_KEY_PROGRESS_UNTIMED_WEIGHT}
+	if err := dao.GetParamByKey(ctx, weight); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if w, err := strconv.Atoi(weight.Value); err == nil && w >= 0 {
+		settings.UntimedWeight = w
+	}
+
+	return settings, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// UpdateProgressSettings updates the progress settings and refreshes the progress of every user,
+// as the percent complete depends upon the settings
+func (dao *DAO) UpdateProgressSettings(ctx context.Context, settings *ProgressSettings) error {
+	if settings == nil {
+		return utils.ErrNilPtr
+	}
+
+	if !settings.Mode.IsValid() || settings.UntimedWeight < 0 {
+		return utils.ErrInvalidValue
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		values := map[string]string{
+			models.PARAM_KEY_PROGRESS_MODE:           settings.Mode.String(),
+			models.PARAM_KEY_PROGRESS_UNTIMED_WEIGHT: strconv.Itoa(settings.UntimedWeight),
+		}
+
+		for key, value := range values {
+			if err := dao.setParam(txCtx, key, value); err != nil {
+				return err
+			}
+		}
+
+		courseIDs, err := dao.ListPluck(txCtx, &models.Course{}, nil, models.BASE_ID)
+		if err != nil {
+			return err
+		}
+
+		for _, courseID := range courseIDs {
+			if err := dao.refreshCourseProgressForAllUsers(txCtx, courseID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetFFmpegPath gets the path of the ffmpeg executable from the params table. An empty path is
+// returned when ffmpeg is not configured
+func (dao *DAO) GetFFmpegPath(ctx context.Context) (string, error) {
+	param := &models.Param{Key: models.PARAM_KEY_FFMPEG_PATH}
+	if err := dao.GetParamByKey(ctx, param); err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	return param.Value, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// UpdateFFmpegPath updates the path of the ffmpeg executable. An empty path disables ffmpeg by
+// removing the param, as a param value cannot be empty
+func (dao *DAO) UpdateFFmpegPath(ctx context.Context, path string) error {
+	if path == "" {
+		options := &database.Options{
+			Where: squirrel.Eq{models.PARAM_TABLE + "." + models.PARAM_KEY: models.PARAM_KEY_FFMPEG_PATH},
+		}
+
+		return dao.Delete(ctx, &models.Param{}, options)
+	}
+
+	return dao.setParam(ctx, models.PARAM_KEY_FFMPEG_PATH, path)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetSlidesGroupDirectories gets whether a prefixed directory of images is grouped into a single
+// slides asset when scanning. It defaults to false
+func (dao *DAO) GetSlidesGroupDirectories(ctx context.Context) (bool, error) {
+	param := &models.Param{Key: models.PARAM_KEY_SLIDES_GROUP_DIRS}
+	if err := dao.GetParamByKey(ctx, param); err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+
+	group, _ := strconv.ParseBool(param.Value)
+	return group, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// UpdateSlidesGroupDirectories updates whether a prefixed directory of images is grouped into a
+// single slides asset. Courses need to be rescanned for the change to apply
+func (dao *DAO) UpdateSlidesGroupDirectories(ctx context.Context, group bool) error {
+	return dao.setParam(ctx, models.PARAM_KEY_SLIDES_GROUP_DIRS, strconv.FormatBool(group))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// setParam sets the value of a parameter, creating it when it does not exist
+func (dao *DAO) setParam(ctx context.Context, key, value string) error {
+	param := &models.Param{Key: key}
+	err := dao.GetParamByKey(ctx, param)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	param.Value = value
+
+	if err == sql.ErrNoRows {
+		return dao.CreateParam(ctx, param)
+	}
+
+	return dao.UpdateParam(ctx, param)
+}
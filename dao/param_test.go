@@ -6,6 +6,7 @@ import (
 
 	"github.com/geerew/off-course/models"
 	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
 	"github.com/stretchr/testify/require"
 )
 
@@ -107,6 +108,104 @@ func Test_UpdateParam(t *testing.T) {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+func Test_ProgressSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		settings, err := dao.GetProgressSettings(ctx)
+		require.NoError(t, err)
+		require.Equal(t, types.ProgressModeCount, settings.Mode)
+		require.Equal(t, 60, settings.UntimedWeight)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.NoError(t, dao.UpdateProgressSettings(ctx, &ProgressSettings{Mode: types.ProgressModeDuration, UntimedWeight: 120}))
+
+		settings, err := dao.GetProgressSettings(ctx)
+		require.NoError(t, err)
+		require.Equal(t, types.ProgressModeDuration, settings.Mode)
+		require.Equal(t, 120, settings.UntimedWeight)
+	})
+
+	t.Run("missing params", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		_, err := dao.db.Exec("DELETE FROM " + models.PARAM_TABLE)
+		require.NoError(t, err)
+
+		settings, err := dao.GetProgressSettings(ctx)
+		require.NoError(t, err)
+		require.Equal(t, types.ProgressModeCount, settings.Mode)
+
+		require.NoError(t, dao.UpdateProgressSettings(ctx, &ProgressSettings{Mode: types.ProgressModeDuration, UntimedWeight: 0}))
+
+		settings, err = dao.GetProgressSettings(ctx)
+		require.NoError(t, err)
+		require.Equal(t, types.ProgressModeDuration, settings.Mode)
+		require.Zero(t, settings.UntimedWeight)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.ErrorIs(t, dao.UpdateProgressSettings(ctx, nil), utils.ErrNilPtr)
+		require.ErrorIs(t, dao.UpdateProgressSettings(ctx, &ProgressSettings{Mode: "invalid"}), utils.ErrInvalidValue)
+		require.ErrorIs(t, dao.UpdateProgressSettings(ctx, &ProgressSettings{Mode: types.ProgressModeCount, UntimedWeight: -1}), utils.ErrInvalidValue)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_FFmpegPath(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		path, err := dao.GetFFmpegPath(ctx)
+		require.NoError(t, err)
+		require.Empty(t, path)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.NoError(t, dao.UpdateFFmpegPath(ctx, "/usr/bin/ffmpeg"))
+
+		path, err := dao.GetFFmpegPath(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "/usr/bin/ffmpeg", path)
+
+		require.NoError(t, dao.UpdateFFmpegPath(ctx, "/usr/local/bin/ffmpeg"))
+
+		path, err = dao.GetFFmpegPath(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "/usr/local/bin/ffmpeg", path)
+
+		// Clear
+		require.NoError(t, dao.UpdateFFmpegPath(ctx, ""))
+
+		path, err = dao.GetFFmpegPath(ctx)
+		require.NoError(t, err)
+		require.Empty(t, path)
+
+		// Clearing again is a no-op
+		require.NoError(t, dao.UpdateFFmpegPath(ctx, ""))
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		_, err := dao.db.Exec("DROP TABLE IF EXISTS " + models.PARAM_TABLE)
+		require.NoError(t, err)
+
+		_, err = dao.GetFFmpegPath(ctx)
+		require.ErrorContains(t, err, "no such table: "+models.PARAM_TABLE)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 // func TestParam_Get(t *testing.T) {
 // 	t.Run("found", func(t *testing.T) {
 // 		dao, _ := paramSetup(t)
@@ -193,3 +292,41 @@ func Test_UpdateParam(t *testing.T) {
 // 		require.ErrorContains(t, err, "no such table: "+dao.Table())
 // 	})
 // }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_SlidesGroupDirectories(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		group, err := dao.GetSlidesGroupDirectories(ctx)
+		require.NoError(t, err)
+		require.False(t, group)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.NoError(t, dao.UpdateSlidesGroupDirectories(ctx, true))
+
+		group, err := dao.GetSlidesGroupDirectories(ctx)
+		require.NoError(t, err)
+		require.True(t, group)
+
+		require.NoError(t, dao.UpdateSlidesGroupDirectories(ctx, false))
+
+		group, err = dao.GetSlidesGroupDirectories(ctx)
+		require.NoError(t, err)
+		require.False(t, group)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		_, err := dao.db.Exec("DROP TABLE IF EXISTS " + models.PARAM_TABLE)
+		require.NoError(t, err)
+
+		_, err = dao.GetSlidesGroupDirectories(ctx)
+		require.ErrorContains(t, err, "no such table: "+models.PARAM_TABLE)
+	})
+}
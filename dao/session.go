@@ -0,0 +1,128 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// CreateSession creates a session
+func (dao *DAO) CreateSession(ctx context.Context, session *models.Session) error {
+	if session == nil {
+		return utils.ErrNilPtr
+	}
+
+	return dao.Create(ctx, session)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetSessionByHash gets a session by the hash of the session token
+func (dao *DAO) GetSessionByHash(ctx context.Context, session *models.Session) error {
+	if session == nil {
+		return utils.ErrNilPtr
+	}
+
+	if session.TokenHash == "" {
+		return utils.ErrInvalidValue
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{models.SESSION_TABLE + "." + models.SESSION_TOKEN_HASH: session.TokenHash},
+	}
+
+	return dao.Get(ctx, session, options)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TouchSession records that a session has been seen and slides its expiry forward by the
+// expiration
+func (dao *DAO) TouchSession(ctx context.Context, session *models.Session, expiration time.Duration) error {
+	if session == nil {
+		return utils.ErrNilPtr
+	}
+
+	if session.ID == "" {
+		return utils.ErrInvalidId
+	}
+
+	now := types.NowDateTime()
+
+	expiresAt, err := types.ParseDateTime(now.Time().Add(expiration))
+	if err != nil {
+		return err
+	}
+
+	session.LastSeenAt = now
+	session.ExpiresAt = expiresAt
+
+	_, err = dao.Update(ctx, session)
+	return err
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DeleteUserSessions deletes all the sessions of a user, logging them out everywhere
+func (dao *DAO) DeleteUserSessions(ctx context.Context, userID string) error {
+	if userID == "" {
+		return utils.ErrInvalidId
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{models.SESSION_TABLE + "." + models.SESSION_USER_ID: userID},
+	}
+
+	return dao.Delete(ctx, &models.Session{}, options)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DeleteOtherSessions deletes all the sessions of the session's user except the session itself,
+// logging the user out everywhere else
+func (dao *DAO) DeleteOtherSessions(ctx context.Context, session *models.Session) error {
+	if session == nil {
+		return utils.ErrNilPtr
+	}
+
+	if session.ID == "" || session.UserID == "" {
+		return utils.ErrInvalidId
+	}
+
+	options := &database.Options{
+		Where: squirrel.And{
+			squirrel.Eq{models.SESSION_TABLE + "." + models.SESSION_USER_ID: session.UserID},
+			squirrel.NotEq{models.SESSION_TABLE + "." + models.BASE_ID: session.ID},
+		},
+	}
+
+	return dao.Delete(ctx, &models.Session{}, options)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DeleteExpiredSessions deletes the sessions that have expired and returns how many were deleted
+func (dao *DAO) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	query, args, _ := squirrel.
+		StatementBuilder.
+		PlaceholderFormat(squirrel.Question).
+		Delete(models.SESSION_TABLE).
+		Where(squirrel.LtOrEq{models.SESSION_EXPIRES_AT: types.NowDateTime()}).
+		ToSql()
+
+	q := database.QuerierFromContext(ctx, dao.db)
+
+	result, err := q.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
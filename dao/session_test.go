@@ -0,0 +1,231 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_CreateSession(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		session := createSessionHelper(t, dao, ctx, user.ID, time.Hour)
+
+		sessionResult := &models.Session{Base: models.Base{ID: session.ID}}
+		require.NoError(t, dao.GetById(ctx, sessionResult))
+		require.Equal(t, user.ID, sessionResult.UserID)
+		require.Equal(t, "agent", sessionResult.UserAgent)
+		require.Equal(t, "127.0.0.1", sessionResult.IPAddress)
+		require.False(t, sessionResult.IsExpired())
+
+		// Deleting the user deletes the session
+		require.NoError(t, dao.DeleteUser(ctx, user))
+		require.ErrorIs(t, dao.GetById(ctx, session), sql.ErrNoRows)
+	})
+
+	t.Run("invalid user", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		session := &models.Session{
+			UserID:     "invalid",
+			TokenHash:  "hash",
+			ExpiresAt:  types.NowDateTime(),
+			LastSeenAt: types.NowDateTime(),
+		}
+		require.ErrorContains(t, dao.CreateSession(ctx, session), "FOREIGN KEY constraint failed")
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CreateSession(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_GetSessionByHash(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		session := createSessionHelper(t, dao, ctx, user.ID, time.Hour)
+
+		sessionResult := &models.Session{TokenHash: session.TokenHash}
+		require.NoError(t, dao.GetSessionByHash(ctx, sessionResult))
+		require.Equal(t, session.ID, sessionResult.ID)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetSessionByHash(ctx, &models.Session{TokenHash: "hash"}), sql.ErrNoRows)
+	})
+
+	t.Run("empty hash", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetSessionByHash(ctx, &models.Session{}), utils.ErrInvalidValue)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetSessionByHash(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_TouchSession(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		session := createSessionHelper(t, dao, ctx, user.ID, time.Minute)
+		originalExpiresAt := session.ExpiresAt
+
+		require.NoError(t, dao.TouchSession(ctx, session, time.Hour))
+
+		sessionResult := &models.Session{Base: models.Base{ID: session.ID}}
+		require.NoError(t, dao.GetById(ctx, sessionResult))
+		require.True(t, sessionResult.ExpiresAt.Time().After(originalExpiresAt.Time().Add(50*time.Minute)))
+		require.True(t, sessionResult.LastSeenAt.Equal(session.LastSeenAt))
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.TouchSession(ctx, &models.Session{}, time.Hour), utils.ErrInvalidId)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.TouchSession(ctx, nil, time.Hour), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_DeleteUserSessions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user1 := &models.User{Username: "user1", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user1))
+
+		user2 := &models.User{Username: "user2", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user2))
+
+		createSessionHelper(t, dao, ctx, user1.ID, time.Hour)
+		createSessionHelper(t, dao, ctx, user1.ID, time.Hour)
+		createSessionHelper(t, dao, ctx, user2.ID, time.Hour)
+
+		require.NoError(t, dao.DeleteUserSessions(ctx, user1.ID))
+
+		count, err := dao.Count(ctx, &models.Session{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.DeleteUserSessions(ctx, ""), utils.ErrInvalidId)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_DeleteOtherSessions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user1 := &models.User{Username: "user1", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user1))
+
+		user2 := &models.User{Username: "user2", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user2))
+
+		current := createSessionHelper(t, dao, ctx, user1.ID, time.Hour)
+		createSessionHelper(t, dao, ctx, user1.ID, time.Hour)
+		createSessionHelper(t, dao, ctx, user2.ID, time.Hour)
+
+		require.NoError(t, dao.DeleteOtherSessions(ctx, current))
+
+		count, err := dao.Count(ctx, &models.Session{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		require.NoError(t, dao.GetById(ctx, current))
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.DeleteOtherSessions(ctx, &models.Session{}), utils.ErrInvalidId)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.DeleteOtherSessions(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_DeleteExpiredSessions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		createSessionHelper(t, dao, ctx, user.ID, -time.Hour)
+		createSessionHelper(t, dao, ctx, user.ID, -time.Minute)
+		active := createSessionHelper(t, dao, ctx, user.ID, time.Hour)
+
+		deleted, err := dao.DeleteExpiredSessions(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), deleted)
+
+		require.NoError(t, dao.GetById(ctx, active))
+
+		deleted, err = dao.DeleteExpiredSessions(ctx)
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// createSessionHelper creates a session for the user that expires after the expiration. A negative
+// expiration creates an expired session
+func createSessionHelper(t *testing.T, dao *DAO, ctx context.Context, userID string, expiration time.Duration) *models.Session {
+	t.Helper()
+
+	expiresAt, err := types.ParseDateTime(time.Now().Add(expiration))
+	require.NoError(t, err)
+
+	session := &models.Session{
+		UserID:     userID,
+		TokenHash:  security.HashToken(security.RandomString(64)),
+		UserAgent:  "agent",
+		IPAddress:  "127.0.0.1",
+		ExpiresAt:  expiresAt,
+		LastSeenAt: types.NowDateTime(),
+	}
+	require.NoError(t, dao.CreateSession(ctx, session))
+
+	return session
+}
@@ -0,0 +1,198 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/types"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// CreateShare creates a share. When an asset is shared, it must belong to the course
+func (dao *DAO) CreateShare(ctx context.Context, share *models.Share) error {
+	if share == nil {
+		return utils.ErrNilPtr
+	}
+
+	if share.AssetID == "" {
+		return dao.Create(ctx, share)
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		asset := &models.Asset{Base: models.Base{ID: share.AssetID}}
+		if err := dao.GetById(txCtx, asset); err != nil {
+			return err
+		}
+
+		if asset.CourseID != share.CourseID {
+			return ErrShareAssetCourse
+		}
+
+		return dao.Create(txCtx, share)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// RevokeShare revokes a share so it can no longer be used. The share, and its audit log, are kept
+func (dao *DAO) RevokeShare(ctx context.Context, share *models.Share) error {
+	if share == nil {
+		return utils.ErrNilPtr
+	}
+
+	if share.ID == "" {
+		return utils.ErrInvalidId
+	}
+
+	// Reload the share so the views are not overwritten with a stale count
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if err := dao.GetById(txCtx, share); err != nil {
+			return err
+		}
+
+		share.Revoked = true
+
+		_, err := dao.Update(txCtx, share)
+		return err
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// RecordShareAccess records a use of a share in the audit log. A view is counted the first time an
+// IP address uses the share. When the share has a max views and it has been reached, the access is
+// not recorded and `ErrShareViewLimit` is returned to a new IP address
+//
+// The share is updated with the current number of views
+func (dao *DAO) RecordShareAccess(ctx context.Context, share *models.Share, access *models.ShareAccess) error {
+	if share == nil || access == nil {
+		return utils.ErrNilPtr
+	}
+
+	if share.ID == "" {
+		return utils.ErrInvalidId
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		q := database.QuerierFromContext(txCtx, dao.db)
+
+		seen, err := dao.Count(txCtx, &models.ShareAccess{}, &database.Options{
+			Where: squirrel.Eq{
+				models.SHARE_ACCESS_TABLE + "." + models.SHARE_ACCESS_SHARE_ID:   share.ID,
+				models.SHARE_ACCESS_TABLE + "." + models.SHARE_ACCESS_IP_ADDRESS: access.IPAddress,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if seen == 0 {
+			// Only count the view when the limit has not been reached, in a single statement so
+			// concurrent viewers cannot exceed it
+			query, args, _ := squirrel.
+				StatementBuilder.
+				PlaceholderFormat(squirrel.Question).
+				Update(models.SHARE_TABLE).
+				Set(models.SHARE_VIEWS, squirrel.Expr(models.SHARE_VIEWS+" + 1")).
+				Set(models.BASE_UPDATED_AT, types.NowDateTime()).
+				Where(squirrel.Eq{models.BASE_ID: share.ID}).
+				Where(squirrel.Or{
+					squirrel.Eq{models.SHARE_MAX_VIEWS: 0},
+					squirrel.Expr(models.SHARE_VIEWS + " < " + models.SHARE_MAX_VIEWS),
+				}).
+				ToSql()
+
+			result, err := q.Exec(query, args...)
+			if err != nil {
+				return err
+			}
+
+			updated, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			if updated == 0 {
+				return ErrShareViewLimit
+			}
+		}
+
+		access.ShareID = share.ID
+		if err := dao.Create(txCtx, access); err != nil {
+			return err
+		}
+
+		return dao.GetById(txCtx, share)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// CheckShareAccess checks a use of a share against its view limit, without recording it in the
+// audit log. It is used for the many requests a single view makes, such as video ranges and
+// segments. The first time an IP address uses the share, the access is recorded and counted as a
+// view (see `RecordShareAccess`)
+func (dao *DAO) CheckShareAccess(ctx context.Context, share *models.Share, access *models.ShareAccess) error {
+	if share == nil || access == nil {
+		return utils.ErrNilPtr
+	}
+
+	if share.ID == "" {
+		return utils.ErrInvalidId
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		seen, err := dao.Count(txCtx, &models.ShareAccess{}, &database.Options{
+			Where: squirrel.Eq{
+				models.SHARE_ACCESS_TABLE + "." + models.SHARE_ACCESS_SHARE_ID:   share.ID,
+				models.SHARE_ACCESS_TABLE + "." + models.SHARE_ACCESS_IP_ADDRESS: access.IPAddress,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if seen > 0 {
+			return nil
+		}
+
+		return dao.RecordShareAccess(txCtx, share, access)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetShareSecret gets the secret used to sign share tokens. It is generated the first time it is
+// needed, so changing the param invalidates every share token
+func (dao *DAO) GetShareSecret(ctx context.Context) (string, error) {
+	var secret string
+
+	err := dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		param := &models.Param{Key: models.PARAM_KEY_SHARE_SECRET}
+		err := dao.GetParamByKey(txCtx, param)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if err == nil {
+			secret = param.Value
+			return nil
+		}
+
+		param.Value = security.RandomString(64)
+		if err := dao.CreateParam(txCtx, param); err != nil {
+			return err
+		}
+
+		secret = param.Value
+		return nil
+	})
+
+	return secret, err
+}
@@ -0,0 +1,265 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_CreateShare(t *testing.T) {
+	t.Run("course", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, _, course := createShareHelper(t, dao, ctx, false, 0)
+
+		shareResult := &models.Share{Base: models.Base{ID: share.ID}}
+		require.NoError(t, dao.GetById(ctx, shareResult))
+		require.Equal(t, course.ID, shareResult.CourseID)
+		require.Empty(t, shareResult.AssetID)
+		require.Zero(t, shareResult.Views)
+		require.False(t, shareResult.Revoked)
+		require.False(t, shareResult.IsExpired())
+
+		// Deleting the course deletes the share
+		require.NoError(t, dao.Delete(ctx, course, nil))
+		require.ErrorIs(t, dao.GetById(ctx, share), sql.ErrNoRows)
+	})
+
+	t.Run("asset", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, asset, _ := createShareHelper(t, dao, ctx, true, 0)
+		require.Equal(t, asset.ID, share.AssetID)
+
+		// Deleting the asset deletes the share
+		require.NoError(t, dao.Delete(ctx, asset, nil))
+		require.ErrorIs(t, dao.GetById(ctx, share), sql.ErrNoRows)
+	})
+
+	t.Run("asset of another course", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, _, _ := createShareHelper(t, dao, ctx, true, 0)
+
+		other := &models.Course{Title: "Course 2", Path: "/course-2"}
+		require.NoError(t, dao.CreateCourse(ctx, other))
+
+		invalid := &models.Share{UserID: share.UserID, CourseID: other.ID, AssetID: share.AssetID, ExpiresAt: share.ExpiresAt}
+		require.ErrorIs(t, dao.CreateShare(ctx, invalid), ErrShareAssetCourse)
+	})
+
+	t.Run("invalid asset", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, _, _ := createShareHelper(t, dao, ctx, false, 0)
+
+		invalid := &models.Share{UserID: share.UserID, CourseID: share.CourseID, AssetID: "invalid", ExpiresAt: share.ExpiresAt}
+		require.ErrorIs(t, dao.CreateShare(ctx, invalid), sql.ErrNoRows)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CreateShare(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_RevokeShare(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, _, _ := createShareHelper(t, dao, ctx, false, 0)
+		require.NoError(t, dao.RecordShareAccess(ctx, share, &models.ShareAccess{IPAddress: "127.0.0.1"}))
+
+		// A stale share does not reset the views
+		stale := &models.Share{Base: models.Base{ID: share.ID}}
+		require.NoError(t, dao.RevokeShare(ctx, stale))
+
+		shareResult := &models.Share{Base: models.Base{ID: share.ID}}
+		require.NoError(t, dao.GetById(ctx, shareResult))
+		require.True(t, shareResult.Revoked)
+		require.Equal(t, 1, shareResult.Views)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.RevokeShare(ctx, &models.Share{}), utils.ErrInvalidId)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.RevokeShare(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_RecordShareAccess(t *testing.T) {
+	t.Run("unlimited", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, _, _ := createShareHelper(t, dao, ctx, false, 0)
+
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1", "10.0.0.3"} {
+			access := &models.ShareAccess{IPAddress: ip, UserAgent: "agent", Path: "/api/shared"}
+			require.NoError(t, dao.RecordShareAccess(ctx, share, access))
+			require.Equal(t, share.ID, access.ShareID)
+		}
+
+		// Views are counted per IP address, but every access is recorded
+		require.Equal(t, 3, share.Views)
+
+		accesses := []*models.ShareAccess{}
+		require.NoError(t, dao.List(ctx, &accesses, &database.Options{
+			Where: shareAccessesWhere(share.ID),
+		}))
+		require.Len(t, accesses, 4)
+	})
+
+	t.Run("view limit", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, _, _ := createShareHelper(t, dao, ctx, false, 2)
+
+		require.NoError(t, dao.RecordShareAccess(ctx, share, &models.ShareAccess{IPAddress: "10.0.0.1"}))
+		require.NoError(t, dao.RecordShareAccess(ctx, share, &models.ShareAccess{IPAddress: "10.0.0.2"}))
+		require.ErrorIs(t, dao.RecordShareAccess(ctx, share, &models.ShareAccess{IPAddress: "10.0.0.3"}), ErrShareViewLimit)
+
+		// Existing viewers may continue
+		require.NoError(t, dao.RecordShareAccess(ctx, share, &models.ShareAccess{IPAddress: "10.0.0.1"}))
+		require.Equal(t, 2, share.Views)
+
+		count, err := dao.Count(ctx, &models.ShareAccess{}, &database.Options{Where: shareAccessesWhere(share.ID)})
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.RecordShareAccess(ctx, &models.Share{}, &models.ShareAccess{}), utils.ErrInvalidId)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.RecordShareAccess(ctx, nil, &models.ShareAccess{}), utils.ErrNilPtr)
+		require.ErrorIs(t, dao.RecordShareAccess(ctx, &models.Share{}, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_CheckShareAccess(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, _, _ := createShareHelper(t, dao, ctx, false, 0)
+
+		for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2", "10.0.0.1"} {
+			require.NoError(t, dao.CheckShareAccess(ctx, share, &models.ShareAccess{IPAddress: ip}))
+		}
+
+		// Only the first access of each IP address is recorded and counted
+		require.Equal(t, 2, share.Views)
+
+		count, err := dao.Count(ctx, &models.ShareAccess{}, &database.Options{Where: shareAccessesWhere(share.ID)})
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("view limit", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		share, _, _ := createShareHelper(t, dao, ctx, false, 1)
+
+		require.NoError(t, dao.RecordShareAccess(ctx, share, &models.ShareAccess{IPAddress: "10.0.0.1"}))
+		require.NoError(t, dao.CheckShareAccess(ctx, share, &models.ShareAccess{IPAddress: "10.0.0.1"}))
+		require.ErrorIs(t, dao.CheckShareAccess(ctx, share, &models.ShareAccess{IPAddress: "10.0.0.2"}), ErrShareViewLimit)
+
+		count, err := dao.Count(ctx, &models.ShareAccess{}, &database.Options{Where: shareAccessesWhere(share.ID)})
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CheckShareAccess(ctx, &models.Share{}, &models.ShareAccess{}), utils.ErrInvalidId)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CheckShareAccess(ctx, nil, &models.ShareAccess{}), utils.ErrNilPtr)
+		require.ErrorIs(t, dao.CheckShareAccess(ctx, &models.Share{}, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_GetShareSecret(t *testing.T) {
+	dao, ctx := setup(t)
+
+	secret, err := dao.GetShareSecret(ctx)
+	require.NoError(t, err)
+	require.Len(t, secret, 64)
+
+	// The secret is only generated once
+	again, err := dao.GetShareSecret(ctx)
+	require.NoError(t, err)
+	require.Equal(t, secret, again)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// createShareHelper creates a user, a course with an asset and a share of the course, or of the
+// asset, expiring in an hour
+func createShareHelper(t *testing.T, dao *DAO, ctx context.Context, shareAsset bool, maxViews int) (*models.Share, *models.Asset, *models.Course) {
+	t.Helper()
+
+	user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+	require.NoError(t, dao.CreateUser(ctx, user))
+
+	course := &models.Course{Title: "Course 1", Path: "/course-1"}
+	require.NoError(t, dao.CreateCourse(ctx, course))
+
+	asset := &models.Asset{
+		CourseID: course.ID,
+		Title:    "Asset 1",
+		Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+		Chapter:  "Chapter 1",
+		Type:     *types.NewAsset("mp4"),
+		Path:     "/course-1/01 asset.mp4",
+		Hash:     "1234",
+	}
+	require.NoError(t, dao.CreateAsset(ctx, asset))
+
+	expiresAt, err := types.ParseDateTime(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	share := &models.Share{UserID: user.ID, CourseID: course.ID, ExpiresAt: expiresAt, MaxViews: maxViews}
+	if shareAsset {
+		share.AssetID = asset.ID
+	}
+
+	require.NoError(t, dao.CreateShare(ctx, share))
+
+	return share, asset, course
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// shareAccessesWhere returns the where clause selecting the accesses of a share
+func shareAccessesWhere(shareID string) squirrel.Eq {
+	return squirrel.Eq{models.SHARE_ACCESS_TABLE + "." + models.SHARE_ACCESS_SHARE_ID: shareID}
+}
@@ -0,0 +1,110 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ListSpineProgress lists the spine progress of a user through an EPUB asset, ordered by spine index
+func (dao *DAO) ListSpineProgress(ctx context.Context, assetID string, userID string) ([]*models.SpineProgress, error) {
+	if assetID == "" {
+		return nil, utils.ErrInvalidId
+	}
+
+	options := &database.Options{
+		OrderBy: []string{models.SPINE_PROGRESS_TABLE + "." + models.SPINE_PROGRESS_SPINE_INDEX + " asc"},
+		Where: squirrel.Eq{
+			models.SPINE_PROGRESS_TABLE + "." + models.SPINE_PROGRESS_ASSET_ID: assetID,
+			models.SPINE_PROGRESS_TABLE + "." + models.SPINE_PROGRESS_USER_ID:  userID,
+		},
+	}
+
+	progress := []*models.SpineProgress{}
+	err := dao.List(ctx, &progress, options)
+	return progress, err
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// CreateOrUpdateSpineProgress creates/updates the progress of a user through a spine item, then
+// updates the asset progress of the user. The scroll position of the asset is the average scroll
+// position of its spine items, where spineCount is the number of spine items in the reading
+// order. The asset is marked as completed once every spine item has been read
+func (dao *DAO) CreateOrUpdateSpineProgress(ctx context.Context, spineProgress *models.SpineProgress, spineCount int) error {
+	if spineProgress == nil {
+		return utils.ErrNilPtr
+	}
+
+	if spineCount <= 0 {
+		return utils.ErrInvalidValue
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		// The scroll position is a percentage
+		spineProgress.ScrollPos = max(0, min(spineProgress.ScrollPos, 100))
+
+		existing := &models.SpineProgress{}
+		err := dao.Get(txCtx, existing, &database.Options{
+			Where: squirrel.Eq{
+				models.SPINE_PROGRESS_TABLE + "." + models.SPINE_PROGRESS_ASSET_ID:    spineProgress.AssetID,
+				models.SPINE_PROGRESS_TABLE + "." + models.SPINE_PROGRESS_USER_ID:     spineProgress.UserID,
+				models.SPINE_PROGRESS_TABLE + "." + models.SPINE_PROGRESS_SPINE_INDEX: spineProgress.SpineIndex,
+			},
+		})
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := dao.Create(txCtx, spineProgress); err != nil {
+				return err
+			}
+		} else {
+			spineProgress.ID = existing.ID
+			if _, err := dao.Update(txCtx, spineProgress); err != nil {
+				return err
+			}
+		}
+
+		progress, err := dao.ListSpineProgress(txCtx, spineProgress.AssetID, spineProgress.UserID)
+		if err != nil {
+			return err
+		}
+
+		total := 0
+		for _, p := range progress {
+			total += p.ScrollPos
+		}
+
+		existingAssetProgress, err := dao.getAssetProgress(txCtx, spineProgress.AssetID, spineProgress.UserID)
+		if err != nil {
+			return err
+		}
+
+		assetProgress := &models.AssetProgress{
+			AssetID:   spineProgress.AssetID,
+			UserID:    spineProgress.UserID,
+			ScrollPos: min(total/spineCount, 100),
+		}
+
+		if existingAssetProgress != nil {
+			assetProgress.VideoPos = existingAssetProgress.VideoPos
+			assetProgress.Completed = existingAssetProgress.Completed
+		}
+
+		if total >= spineCount*100 {
+			assetProgress.Completed = true
+		}
+
+		return dao.CreateOrUpdateAssetProgress(txCtx, assetProgress)
+	})
+}
@@ -0,0 +1,128 @@
+package dao
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_CreateOrUpdateSpineProgress(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("epub"),
+			Path:     "/course-1/01 book.epub",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		// Read half of the first of 2 spine items
+		spineProgress := &models.SpineProgress{AssetID: asset.ID, SpineIndex: 0, ScrollPos: 50}
+		require.NoError(t, dao.CreateOrUpdateSpineProgress(ctx, spineProgress, 2))
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Equal(t, 25, asset.Progress.ScrollPos)
+		require.False(t, asset.Progress.Completed)
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.True(t, course.Progress.Started)
+
+		// Finish the first spine item (clamped to 100)
+		require.NoError(t, dao.CreateOrUpdateSpineProgress(ctx, &models.SpineProgress{AssetID: asset.ID, SpineIndex: 0, ScrollPos: 120}, 2))
+
+		progress, err := dao.ListSpineProgress(ctx, asset.ID, "")
+		require.NoError(t, err)
+		require.Len(t, progress, 1)
+		require.Equal(t, 100, progress[0].ScrollPos)
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Equal(t, 50, asset.Progress.ScrollPos)
+		require.False(t, asset.Progress.Completed)
+
+		// Finish the second spine item
+		require.NoError(t, dao.CreateOrUpdateSpineProgress(ctx, &models.SpineProgress{AssetID: asset.ID, SpineIndex: 1, ScrollPos: 100}, 2))
+
+		progress, err = dao.ListSpineProgress(ctx, asset.ID, "")
+		require.NoError(t, err)
+		require.Len(t, progress, 2)
+		require.Equal(t, 1, progress[1].SpineIndex)
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Equal(t, 100, asset.Progress.ScrollPos)
+		require.True(t, asset.Progress.Completed)
+		require.False(t, asset.Progress.CompletedAt.IsZero())
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, ""))
+		require.Equal(t, 100, course.Progress.Percent)
+
+		// Scrolling back keeps the asset completed
+		require.NoError(t, dao.CreateOrUpdateSpineProgress(ctx, &models.SpineProgress{AssetID: asset.ID, SpineIndex: 1, ScrollPos: 10}, 2))
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Equal(t, 55, asset.Progress.ScrollPos)
+		require.True(t, asset.Progress.Completed)
+
+		// Another user has no progress
+		user := &models.User{Username: "user", PasswordHash: "hash", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		progress, err = dao.ListSpineProgress(ctx, asset.ID, user.ID)
+		require.NoError(t, err)
+		require.Empty(t, progress)
+
+		require.NoError(t, dao.CreateOrUpdateSpineProgress(ctx, &models.SpineProgress{AssetID: asset.ID, UserID: user.ID, SpineIndex: 0, ScrollPos: 40}, 2))
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, user.ID))
+		require.Equal(t, 20, asset.Progress.ScrollPos)
+		require.False(t, asset.Progress.Completed)
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Equal(t, 55, asset.Progress.ScrollPos)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CreateOrUpdateSpineProgress(ctx, nil, 1), utils.ErrNilPtr)
+		require.ErrorIs(t, dao.CreateOrUpdateSpineProgress(ctx, &models.SpineProgress{AssetID: "1234"}, 0), utils.ErrInvalidValue)
+
+		_, err := dao.ListSpineProgress(ctx, "", "")
+		require.ErrorIs(t, err, utils.ErrInvalidId)
+	})
+
+	t.Run("delete cascade", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("epub"),
+			Path:     "/course-1/01 book.epub",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+		require.NoError(t, dao.CreateOrUpdateSpineProgress(ctx, &models.SpineProgress{AssetID: asset.ID, ScrollPos: 10}, 1))
+
+		require.NoError(t, dao.Delete(ctx, asset, nil))
+
+		progress, err := dao.ListSpineProgress(ctx, asset.ID, "")
+		require.NoError(t, err)
+		require.Empty(t, progress)
+	})
+}
@@ -0,0 +1,43 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// CreateSubtitle creates a subtitle
+func (dao *DAO) CreateSubtitle(ctx context.Context, subtitle *models.Subtitle) error {
+	if subtitle == nil {
+		return utils.ErrNilPtr
+	}
+
+	return dao.Create(ctx, subtitle)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetSubtitleByLanguage gets the subtitle of an asset for the given language
+func (dao *DAO) GetSubtitleByLanguage(ctx context.Context, subtitle *models.Subtitle) error {
+	if subtitle == nil {
+		return utils.ErrNilPtr
+	}
+
+	if subtitle.AssetID == "" {
+		return utils.ErrInvalidId
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{
+			subtitle.Table() + "." + models.SUBTITLE_ASSET_ID: subtitle.AssetID,
+			subtitle.Table() + "." + models.SUBTITLE_LANGUAGE: subtitle.Language,
+		},
+	}
+
+	return dao.Get(ctx, subtitle, options)
+}
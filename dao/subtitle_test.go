@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_CreateSubtitle(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		subtitle := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: "/course-1/01 asset.en.srt"}
+		require.NoError(t, dao.CreateSubtitle(ctx, subtitle))
+
+		// The subtitle is loaded as a relation of the asset
+		require.NoError(t, dao.GetById(ctx, asset))
+		require.Len(t, asset.Subtitles, 1)
+		require.Equal(t, subtitle.ID, asset.Subtitles[0].ID)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.CreateSubtitle(ctx, nil), utils.ErrNilPtr)
+	})
+
+	t.Run("duplicate language", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		require.NoError(t, dao.CreateSubtitle(ctx, &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: "/course-1/01 asset.en.srt"}))
+		require.ErrorContains(t, dao.CreateSubtitle(ctx, &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "vtt", Path: "/course-1/01 asset.en.vtt"}), "UNIQUE constraint failed")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_GetSubtitleByLanguage(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		for _, lang := range []string{"en", "fr"} {
+			require.NoError(t, dao.CreateSubtitle(ctx, &models.Subtitle{AssetID: asset.ID, Language: lang, Format: "srt", Path: "/course-1/01 asset." + lang + ".srt"}))
+		}
+
+		subtitle := &models.Subtitle{AssetID: asset.ID, Language: "fr"}
+		require.NoError(t, dao.GetSubtitleByLanguage(ctx, subtitle))
+		require.Equal(t, "/course-1/01 asset.fr.srt", subtitle.Path)
+
+		subtitle = &models.Subtitle{AssetID: asset.ID, Language: "de"}
+		require.ErrorIs(t, dao.GetSubtitleByLanguage(ctx, subtitle), sql.ErrNoRows)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.ErrorIs(t, dao.GetSubtitleByLanguage(ctx, nil), utils.ErrNilPtr)
+		require.ErrorIs(t, dao.GetSubtitleByLanguage(ctx, &models.Subtitle{Language: "en"}), utils.ErrInvalidId)
+	})
+}
@@ -0,0 +1,212 @@
+package dao
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const transcriptTable = "transcripts"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TranscriptCue defines a subtitle cue to index. Start and End are in milliseconds
+type TranscriptCue struct {
+	Start int64
+	End   int64
+	Text  string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TranscriptMatch defines a cue matching a transcript search. Start and End are in milliseconds
+type TranscriptMatch struct {
+	CourseID    string
+	CourseTitle string
+	AssetID     string
+	AssetTitle  string
+	Chapter     string
+	SubtitleID  string
+	Language    string
+	Start       int64
+	End         int64
+	Text        string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IndexTranscript replaces the indexed cues of a subtitle with the given cues
+func (dao *DAO) IndexTranscript(ctx context.Context, subtitle *models.Subtitle, cues []*TranscriptCue) error {
+	if subtitle == nil {
+		return utils.ErrNilPtr
+	}
+
+	if subtitle.ID == "" || subtitle.AssetID == "" {
+		return utils.ErrInvalidId
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		q := database.QuerierFromContext(txCtx, dao.db)
+
+		if _, err := q.Exec("DELETE FROM "+transcriptTable+" WHERE subtitle_id = ?", subtitle.ID); err != nil {
+			return err
+		}
+
+		for _, cue := range cues {
+			if cue == nil || strings.TrimSpace(cue.Text) == "" {
+				continue
+			}
+
+			query, args, _ := squirrel.
+				StatementBuilder.
+				PlaceholderFormat(squirrel.Question).
+				Insert(transcriptTable).
+				Columns("text", "subtitle_id", "asset_id", "start_ms", "end_ms").
+				Values(cue.Text, subtitle.ID, subtitle.AssetID, cue.Start, cue.End).
+				ToSql()
+
+			if _, err := q.Exec(query, args...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ListUnindexedSubtitles lists the subtitles of the given assets that have no indexed cues
+func (dao *DAO) ListUnindexedSubtitles(ctx context.Context, assetIDs []string) ([]*models.Subtitle, error) {
+	subtitles := []*models.Subtitle{}
+
+	if len(assetIDs) == 0 {
+		return subtitles, nil
+	}
+
+	options := &database.Options{
+		Where: squirrel.And{
+			squirrel.Eq{models.SUBTITLE_TABLE + "." + models.SUBTITLE_ASSET_ID: assetIDs},
+			squirrel.Expr(models.SUBTITLE_TABLE + ".id NOT IN (SELECT subtitle_id FROM " + transcriptTable + ")"),
+		},
+	}
+
+	err := dao.List(ctx, &subtitles, options)
+	return subtitles, err
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SearchTranscripts searches the indexed cues for the given query, ordered by relevance
+//
+// Each whitespace separated term must match. A query wrapped in double quotes is matched as a
+// phrase. The options WHERE clause may reference the assets and courses tables and the pagination,
+// when set, is applied
+func (dao *DAO) SearchTranscripts(ctx context.Context, query string, options *database.Options) ([]*TranscriptMatch, error) {
+	matches := []*TranscriptMatch{}
+
+	match := transcriptMatchExpr(query)
+	if match == "" {
+		return matches, nil
+	}
+
+	where := squirrel.And{squirrel.Expr(transcriptTable+" MATCH ?", match)}
+	if options != nil && options.Where != nil {
+		where = append(where, options.Where)
+	}
+
+	builder := squirrel.
+		StatementBuilder.
+		PlaceholderFormat(squirrel.Question).
+		Select().
+		From(transcriptTable).
+		Join(models.ASSET_TABLE + " ON " + models.ASSET_TABLE + ".id = " + transcriptTable + ".asset_id").
+		Join(models.COURSE_TABLE + " ON " + models.COURSE_TABLE + ".id = " + models.ASSET_TABLE + ".course_id").
+		Join(models.SUBTITLE_TABLE + " ON " + models.SUBTITLE_TABLE + ".id = " + transcriptTable + ".subtitle_id").
+		Where(where)
+
+	q := database.QuerierFromContext(ctx, dao.db)
+
+	if options != nil && options.Pagination != nil {
+		countQuery, countArgs, _ := builder.Column("COUNT(*)").ToSql()
+
+		var count int
+		if err := q.QueryRow(countQuery, countArgs...).Scan(&count); err != nil {
+			return nil, err
+		}
+
+		options.Pagination.SetCount(count)
+		builder = options.Pagination.Apply(builder)
+	}
+
+	selectQuery, args, _ := builder.
+		Columns(
+			models.COURSE_TABLE+".id",
+			models.COURSE_TABLE+".title",
+			models.ASSET_TABLE+".id",
+			models.ASSET_TABLE+".title",
+			"COALESCE("+models.ASSET_TABLE+".chapter, '')",
+			models.SUBTITLE_TABLE+".id",
+			models.SUBTITLE_TABLE+".language",
+			transcriptTable+".start_ms",
+			transcriptTable+".end_ms",
+			transcriptTable+".text",
+		).
+		OrderBy(transcriptTable+".rank", transcriptTable+".start_ms").
+		ToSql()
+
+	rows, err := q.Query(selectQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := &TranscriptMatch{}
+		if err := rows.Scan(
+			&m.CourseID, &m.CourseTitle, &m.AssetID, &m.AssetTitle, &m.Chapter,
+			&m.SubtitleID, &m.Language, &m.Start, &m.End, &m.Text,
+		); err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, m)
+	}
+
+	return matches, rows.Err()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// transcriptMatchExpr builds a FTS5 match expression from a user query. Each term is quoted so
+// FTS5 operators and punctuation in the query are matched literally
+func transcriptMatchExpr(query string) string {
+	query = strings.TrimSpace(query)
+
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+
+	// Phrase
+	if len(query) >= 2 && strings.HasPrefix(query, `"`) && strings.HasSuffix(query, `"`) {
+		phrase := strings.TrimSpace(query[1 : len(query)-1])
+		if phrase == "" {
+			return ""
+		}
+
+		return quote(phrase)
+	}
+
+	terms := []string{}
+	for _, term := range strings.Fields(query) {
+		terms = append(terms, quote(term))
+	}
+
+	return strings.Join(terms, " ")
+}
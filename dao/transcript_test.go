@@ -0,0 +1,285 @@
+package dao
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
+	"github.com/geerew/off-course/models"
+	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/pagination"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_IndexTranscript(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		subtitle := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: "/course-1/01 asset.en.srt"}
+		require.NoError(t, dao.CreateSubtitle(ctx, subtitle))
+
+		cues := []*TranscriptCue{
+			{Start: 1000, End: 2000, Text: "hello world"},
+			{Start: 3000, End: 4000, Text: "goodbye world"},
+			{Start: 5000, End: 6000, Text: "  "},
+		}
+		require.NoError(t, dao.IndexTranscript(ctx, subtitle, cues))
+
+		matches, err := dao.SearchTranscripts(ctx, "world", nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+
+		// Reindexing replaces the existing cues
+		require.NoError(t, dao.IndexTranscript(ctx, subtitle, cues[:1]))
+
+		matches, err = dao.SearchTranscripts(ctx, "world", nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		require.Equal(t, "hello world", matches[0].Text)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.IndexTranscript(ctx, nil, nil), utils.ErrNilPtr)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.IndexTranscript(ctx, &models.Subtitle{}, nil), utils.ErrInvalidId)
+	})
+
+	t.Run("delete cascades", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		assets := []*models.Asset{}
+		for i := range 2 {
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    "Asset",
+				Prefix:   sql.NullInt16{Int16: int16(i + 1), Valid: true},
+				Type:     *types.NewAsset("mp4"),
+				Path:     "/course-1/0" + string(rune('1'+i)) + " asset.mp4",
+				Hash:     "1234",
+			}
+			require.NoError(t, dao.CreateAsset(ctx, asset))
+
+			subtitle := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: asset.Path + ".srt"}
+			require.NoError(t, dao.CreateSubtitle(ctx, subtitle))
+			require.NoError(t, dao.IndexTranscript(ctx, subtitle, []*TranscriptCue{{Start: 0, End: 1000, Text: "hello"}}))
+
+			assets = append(assets, asset)
+		}
+
+		matches, err := dao.SearchTranscripts(ctx, "hello", nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+
+		// Delete the subtitle of the first asset
+		require.NoError(t, dao.Delete(ctx, &models.Subtitle{}, &database.Options{Where: squirrel.Eq{models.SUBTITLE_TABLE + "." + models.SUBTITLE_ASSET_ID: assets[0].ID}}))
+
+		matches, err = dao.SearchTranscripts(ctx, "hello", nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		require.Equal(t, assets[1].ID, matches[0].AssetID)
+
+		// Delete the course
+		require.NoError(t, dao.Delete(ctx, course, nil))
+
+		matches, err = dao.SearchTranscripts(ctx, "hello", nil)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_ListUnindexedSubtitles(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		en := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: "/course-1/01 asset.en.srt"}
+		require.NoError(t, dao.CreateSubtitle(ctx, en))
+
+		fr := &models.Subtitle{AssetID: asset.ID, Language: "fr", Format: "srt", Path: "/course-1/01 asset.fr.srt"}
+		require.NoError(t, dao.CreateSubtitle(ctx, fr))
+
+		subtitles, err := dao.ListUnindexedSubtitles(ctx, []string{asset.ID})
+		require.NoError(t, err)
+		require.Len(t, subtitles, 2)
+
+		require.NoError(t, dao.IndexTranscript(ctx, en, []*TranscriptCue{{Start: 0, End: 1000, Text: "hello"}}))
+
+		subtitles, err = dao.ListUnindexedSubtitles(ctx, []string{asset.ID})
+		require.NoError(t, err)
+		require.Len(t, subtitles, 1)
+		require.Equal(t, fr.ID, subtitles[0].ID)
+	})
+
+	t.Run("no assets", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		subtitles, err := dao.ListUnindexedSubtitles(ctx, nil)
+		require.NoError(t, err)
+		require.Empty(t, subtitles)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_SearchTranscripts(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		courses := []*models.Course{}
+		for i := range 2 {
+			course := &models.Course{Title: "Course " + string(rune('1'+i)), Path: "/course-" + string(rune('1'+i))}
+			require.NoError(t, dao.CreateCourse(ctx, course))
+
+			asset := &models.Asset{
+				CourseID: course.ID,
+				Title:    "Asset 1",
+				Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+				Chapter:  "01 Intro",
+				Type:     *types.NewAsset("mp4"),
+				Path:     course.Path + "/01 Intro/01 asset.mp4",
+				Hash:     "1234",
+			}
+			require.NoError(t, dao.CreateAsset(ctx, asset))
+
+			subtitle := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: course.Path + "/01 Intro/01 asset.en.srt"}
+			require.NoError(t, dao.CreateSubtitle(ctx, subtitle))
+
+			cues := []*TranscriptCue{
+				{Start: 1000, End: 2000, Text: "Install the package manager"},
+				{Start: 65000, End: 67000, Text: "Then configure the café server"},
+			}
+			require.NoError(t, dao.IndexTranscript(ctx, subtitle, cues))
+
+			courses = append(courses, course)
+		}
+
+		// All terms must match
+		matches, err := dao.SearchTranscripts(ctx, "configure server", nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+		require.Equal(t, int64(65000), matches[0].Start)
+		require.Equal(t, int64(67000), matches[0].End)
+		require.Equal(t, "01 Intro", matches[0].Chapter)
+		require.Equal(t, "Asset 1", matches[0].AssetTitle)
+		require.Equal(t, "en", matches[0].Language)
+
+		matches, err = dao.SearchTranscripts(ctx, "configure package", nil)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+
+		// Diacritics are ignored
+		matches, err = dao.SearchTranscripts(ctx, "cafe", nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+
+		// Phrase
+		matches, err = dao.SearchTranscripts(ctx, `"the package"`, nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+
+		matches, err = dao.SearchTranscripts(ctx, `"package the"`, nil)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+
+		// FTS5 syntax is matched literally
+		matches, err = dao.SearchTranscripts(ctx, `install OR "NEAR(`, nil)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+
+		// Where
+		matches, err = dao.SearchTranscripts(ctx, "install", &database.Options{Where: squirrel.Eq{models.COURSE_TABLE + ".id": courses[1].ID}})
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		require.Equal(t, courses[1].ID, matches[0].CourseID)
+		require.Equal(t, "Course 2", matches[0].CourseTitle)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		subtitle := &models.Subtitle{AssetID: asset.ID, Language: "en", Format: "srt", Path: "/course-1/01 asset.en.srt"}
+		require.NoError(t, dao.CreateSubtitle(ctx, subtitle))
+
+		cues := []*TranscriptCue{}
+		for i := range 15 {
+			cues = append(cues, &TranscriptCue{Start: int64(i * 1000), End: int64(i*1000 + 500), Text: "hello"})
+		}
+		require.NoError(t, dao.IndexTranscript(ctx, subtitle, cues))
+
+		p := pagination.New(2, 10)
+		matches, err := dao.SearchTranscripts(ctx, "hello", &database.Options{Pagination: p})
+		require.NoError(t, err)
+		require.Len(t, matches, 5)
+		require.Equal(t, 15, p.TotalItems())
+		require.Equal(t, 2, p.TotalPages())
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		matches, err := dao.SearchTranscripts(ctx, `  "" `, nil)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		_, err := dao.db.Exec("DROP TABLE IF EXISTS transcripts")
+		require.NoError(t, err)
+
+		_, err = dao.SearchTranscripts(ctx, "hello", nil)
+		require.ErrorContains(t, err, "no such table: transcripts")
+	})
+}
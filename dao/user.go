@@ -2,18 +2,260 @@ package dao
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
 
+	"github.com/Masterminds/squirrel"
+	"github.com/geerew/off-course/database"
 	"github.com/geerew/off-course/models"
 	"github.com/geerew/off-course/utils"
+	"github.com/geerew/off-course/utils/types"
 )
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// CreateUser creates a user
+// CreateUser creates a user. It returns `ErrUserExists` when the username is already taken
 func (dao *DAO) CreateUser(ctx context.Context, user *models.User) error {
 	if user == nil {
 		return utils.ErrNilPtr
 	}
 
-	return dao.Create(ctx, user)
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		existing := &models.User{Username: user.Username}
+		err := dao.GetUserByUsername(txCtx, existing)
+		if err == nil {
+			return ErrUserExists
+		}
+
+		if !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, ErrInvalidUsername) {
+			return err
+		}
+
+		return dao.Create(txCtx, user)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetUserByUsername gets a user by their username. The username is trimmed before the lookup
+func (dao *DAO) GetUserByUsername(ctx context.Context, user *models.User) error {
+	if user == nil {
+		return utils.ErrNilPtr
+	}
+
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Username == "" {
+		return ErrInvalidUsername
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{models.USER_TABLE + "." + models.USER_USERNAME: user.Username},
+	}
+
+	return dao.Get(ctx, user, options)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetUserByOIDCSubject gets a user by their OpenID Connect subject
+func (dao *DAO) GetUserByOIDCSubject(ctx context.Context, user *models.User) error {
+	if user == nil {
+		return utils.ErrNilPtr
+	}
+
+	if user.OIDCSubject == "" {
+		return utils.ErrInvalidValue
+	}
+
+	options := &database.Options{
+		Where: squirrel.Eq{models.USER_TABLE + "." + models.USER_OIDC_SUBJECT: user.OIDCSubject},
+	}
+
+	return dao.Get(ctx, user, options)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GetHasAdmin gets whether an admin user has been created. Until then, the app is in its first-run
+// state and the API is open so the admin can be bootstrapped
+func (dao *DAO) GetHasAdmin(ctx context.Context) (bool, error) {
+	param := &models.Param{Key: models.PARAM_KEY_HAS_ADMIN}
+	if err := dao.GetParamByKey(ctx, param); err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+
+	hasAdmin, _ := strconv.ParseBool(param.Value)
+	return hasAdmin, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// BootstrapAdmin creates the first admin user and sets the `hasAdmin` param. The role of the user is
+// forced to admin. Progress recorded before the admin existed (first-run) is given to the admin.
+// ErrAdminExists is returned when an admin has already been created
+func (dao *DAO) BootstrapAdmin(ctx context.Context, user *models.User) error {
+	if user == nil {
+		return utils.ErrNilPtr
+	}
+
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Username == "" {
+		return ErrInvalidUsername
+	}
+
+	user.Role = types.UserRoleAdmin
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		hasAdmin, err := dao.GetHasAdmin(txCtx)
+		if err != nil {
+			return err
+		}
+
+		if hasAdmin {
+			return ErrAdminExists
+		}
+
+		// Guard against the param being out of sync with the users table
+		count, err := dao.Count(txCtx, &models.User{}, &database.Options{
+			Where: squirrel.Eq{models.USER_TABLE + "." + models.USER_ROLE: types.UserRoleAdmin},
+		})
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return ErrAdminExists
+		}
+
+		if err := dao.CreateUser(txCtx, user); err != nil {
+			return err
+		}
+
+		if err := dao.claimUnownedProgress(txCtx, user.ID); err != nil {
+			return err
+		}
+
+		return dao.setParam(txCtx, models.PARAM_KEY_HAS_ADMIN, strconv.FormatBool(true))
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// UpdateUser updates a user. ErrLastAdmin is returned when the update would demote or disable the
+// last active admin
+func (dao *DAO) UpdateUser(ctx context.Context, user *models.User) error {
+	if user == nil {
+		return utils.ErrNilPtr
+	}
+
+	if user.ID == "" {
+		return utils.ErrInvalidId
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if user.Role != types.UserRoleAdmin || user.Disabled {
+			if err := dao.guardLastAdmin(txCtx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		_, err := dao.Update(txCtx, user)
+		return err
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DeleteUser deletes a user along with their progress. ErrLastAdmin is returned when the user is the
+// last active admin
+func (dao *DAO) DeleteUser(ctx context.Context, user *models.User) error {
+	if user == nil {
+		return utils.ErrNilPtr
+	}
+
+	if user.ID == "" {
+		return utils.ErrInvalidId
+	}
+
+	return dao.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if err := dao.guardLastAdmin(txCtx, user.ID); err != nil {
+			return err
+		}
+
+		for _, model := range progressModels() {
+			err := dao.Delete(txCtx, model, &database.Options{Where: squirrel.Eq{model.Table() + ".user_id": user.ID}})
+			if err != nil {
+				return err
+			}
+		}
+
+		return dao.Delete(txCtx, user, nil)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// guardLastAdmin returns ErrLastAdmin when the user (as currently stored) is the only active admin.
+// An active admin is an admin that is not disabled
+func (dao *DAO) guardLastAdmin(ctx context.Context, id string) error {
+	existing := &models.User{Base: models.Base{ID: id}}
+	if err := dao.GetById(ctx, existing); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		return err
+	}
+
+	if existing.Role != types.UserRoleAdmin || existing.Disabled {
+		return nil
+	}
+
+	count, err := dao.Count(ctx, &models.User{}, &database.Options{
+		Where: squirrel.Eq{
+			models.USER_TABLE + "." + models.USER_ROLE:     types.UserRoleAdmin,
+			models.USER_TABLE + "." + models.USER_DISABLED: false,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if count <= 1 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// progressModels returns the models that hold the progress of a user
+func progressModels() []models.Modeler {
+	return []models.Modeler{&models.CourseProgress{}, &models.AssetProgress{}, &models.SpineProgress{}}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// claimUnownedProgress gives the progress recorded without a user (first-run) to the user
+func (dao *DAO) claimUnownedProgress(ctx context.Context, userID string) error {
+	q := database.QuerierFromContext(ctx, dao.db)
+
+	for _, model := range progressModels() {
+		query, args, _ := squirrel.
+			StatementBuilder.
+			PlaceholderFormat(squirrel.Question).
+			Update(model.Table()).
+			Set("user_id", userID).
+			Where(squirrel.Eq{"user_id": ""}).
+			ToSql()
+
+		if _, err := q.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
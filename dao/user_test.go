@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"database/sql"
 	"testing"
 
 	"github.com/geerew/off-course/models"
@@ -18,8 +19,333 @@ func Test_CreateUser(t *testing.T) {
 		require.NoError(t, dao.CreateUser(ctx, user))
 	})
 
+	t.Run("duplicate", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.NoError(t, dao.CreateUser(ctx, &models.User{Username: "admin", PasswordHash: "password", Role: types.UserRoleAdmin}))
+
+		user := &models.User{Username: "admin", PasswordHash: "password", Role: types.UserRoleUser}
+		require.ErrorIs(t, dao.CreateUser(ctx, user), ErrUserExists)
+	})
+
 	t.Run("nil", func(t *testing.T) {
 		dao, ctx := setup(t)
 		require.ErrorIs(t, dao.CreateUser(ctx, nil), utils.ErrNilPtr)
 	})
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_GetUserByUsername(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "admin", PasswordHash: "password", Role: types.UserRoleAdmin}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		userResult := &models.User{Username: " admin "}
+		require.NoError(t, dao.GetUserByUsername(ctx, userResult))
+		require.Equal(t, user.ID, userResult.ID)
+		require.Equal(t, "password", userResult.PasswordHash)
+		require.Equal(t, types.UserRoleAdmin, userResult.Role)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetUserByUsername(ctx, &models.User{Username: "admin"}), sql.ErrNoRows)
+	})
+
+	t.Run("empty username", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetUserByUsername(ctx, &models.User{Username: " "}), ErrInvalidUsername)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetUserByUsername(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_GetUserByOIDCSubject(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "bob", PasswordHash: "password", Role: types.UserRoleUser, OIDCSubject: "subject"}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		userResult := &models.User{OIDCSubject: "subject"}
+		require.NoError(t, dao.GetUserByOIDCSubject(ctx, userResult))
+		require.Equal(t, user.ID, userResult.ID)
+		require.Equal(t, "bob", userResult.Username)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetUserByOIDCSubject(ctx, &models.User{OIDCSubject: "subject"}), sql.ErrNoRows)
+	})
+
+	t.Run("duplicate subject", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.NoError(t, dao.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "password", Role: types.UserRoleUser, OIDCSubject: "subject"}))
+		require.ErrorContains(t, dao.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "password", Role: types.UserRoleUser, OIDCSubject: "subject"}), "UNIQUE constraint failed")
+
+		// Local users have no subject
+		require.NoError(t, dao.CreateUser(ctx, &models.User{Username: "carol", PasswordHash: "password", Role: types.UserRoleUser}))
+		require.NoError(t, dao.CreateUser(ctx, &models.User{Username: "dave", PasswordHash: "password", Role: types.UserRoleUser}))
+	})
+
+	t.Run("empty subject", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetUserByOIDCSubject(ctx, &models.User{}), utils.ErrInvalidValue)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.GetUserByOIDCSubject(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_GetHasAdmin(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		hasAdmin, err := dao.GetHasAdmin(ctx)
+		require.NoError(t, err)
+		require.False(t, hasAdmin)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		_, err := dao.db.Exec("DROP TABLE IF EXISTS " + models.PARAM_TABLE)
+		require.NoError(t, err)
+
+		_, err = dao.GetHasAdmin(ctx)
+		require.ErrorContains(t, err, "no such table: "+models.PARAM_TABLE)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_BootstrapAdmin(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: " admin ", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.BootstrapAdmin(ctx, user))
+		require.Equal(t, "admin", user.Username)
+		require.Equal(t, types.UserRoleAdmin, user.Role)
+
+		hasAdmin, err := dao.GetHasAdmin(ctx)
+		require.NoError(t, err)
+		require.True(t, hasAdmin)
+
+		userResult := &models.User{Username: "admin"}
+		require.NoError(t, dao.GetUserByUsername(ctx, userResult))
+		require.Equal(t, types.UserRoleAdmin, userResult.Role)
+	})
+
+	t.Run("claims progress", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/01 asset.mp4",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		// Progress recorded during first-run has no user
+		require.NoError(t, dao.CreateOrUpdateAssetProgress(ctx, &models.AssetProgress{AssetID: asset.ID, Completed: true}))
+
+		user := &models.User{Username: "admin", PasswordHash: "password"}
+		require.NoError(t, dao.BootstrapAdmin(ctx, user))
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, user.ID))
+		require.NotNil(t, asset.Progress)
+		require.True(t, asset.Progress.Completed)
+
+		require.NoError(t, dao.LoadCourseProgress(ctx, []*models.Course{course}, user.ID))
+		require.Equal(t, 100, course.Progress.Percent)
+
+		require.NoError(t, dao.LoadAssetProgress(ctx, []*models.Asset{asset}, ""))
+		require.Nil(t, asset.Progress)
+	})
+
+	t.Run("admin exists", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		require.NoError(t, dao.BootstrapAdmin(ctx, &models.User{Username: "admin", PasswordHash: "password"}))
+		require.ErrorIs(t, dao.BootstrapAdmin(ctx, &models.User{Username: "admin2", PasswordHash: "password"}), ErrAdminExists)
+
+		count, err := dao.Count(ctx, &models.User{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("admin exists without param", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "admin", PasswordHash: "password", Role: types.UserRoleAdmin}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		require.ErrorIs(t, dao.BootstrapAdmin(ctx, &models.User{Username: "admin2", PasswordHash: "password"}), ErrAdminExists)
+	})
+
+	t.Run("empty username", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.BootstrapAdmin(ctx, &models.User{PasswordHash: "password"}), ErrInvalidUsername)
+
+		hasAdmin, err := dao.GetHasAdmin(ctx)
+		require.NoError(t, err)
+		require.False(t, hasAdmin)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.BootstrapAdmin(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_UpdateUser(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		user.Role = types.UserRoleAdmin
+		user.PasswordHash = "new password"
+		user.Disabled = true
+		require.NoError(t, dao.UpdateUser(ctx, user))
+
+		userResult := &models.User{Base: models.Base{ID: user.ID}}
+		require.NoError(t, dao.GetById(ctx, userResult))
+		require.Equal(t, types.UserRoleAdmin, userResult.Role)
+		require.Equal(t, "new password", userResult.PasswordHash)
+		require.True(t, userResult.Disabled)
+	})
+
+	t.Run("last admin", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		admin := &models.User{Username: "admin", PasswordHash: "password", Role: types.UserRoleAdmin}
+		require.NoError(t, dao.CreateUser(ctx, admin))
+
+		// Demote
+		admin.Role = types.UserRoleUser
+		require.ErrorIs(t, dao.UpdateUser(ctx, admin), ErrLastAdmin)
+
+		// Disable
+		admin.Role = types.UserRoleAdmin
+		admin.Disabled = true
+		require.ErrorIs(t, dao.UpdateUser(ctx, admin), ErrLastAdmin)
+
+		// A disabled admin does not count
+		admin2 := &models.User{Username: "admin2", PasswordHash: "password", Role: types.UserRoleAdmin, Disabled: true}
+		require.NoError(t, dao.CreateUser(ctx, admin2))
+		require.ErrorIs(t, dao.UpdateUser(ctx, admin), ErrLastAdmin)
+
+		// An active admin does
+		admin2.Disabled = false
+		require.NoError(t, dao.UpdateUser(ctx, admin2))
+		require.NoError(t, dao.UpdateUser(ctx, admin))
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.UpdateUser(ctx, &models.User{}), utils.ErrInvalidId)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.UpdateUser(ctx, nil), utils.ErrNilPtr)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_DeleteUser(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		admin := &models.User{Username: "admin", PasswordHash: "password", Role: types.UserRoleAdmin}
+		require.NoError(t, dao.CreateUser(ctx, admin))
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		require.NoError(t, dao.DeleteUser(ctx, user))
+		require.ErrorIs(t, dao.GetById(ctx, user), sql.ErrNoRows)
+	})
+
+	t.Run("deletes progress", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		admin := &models.User{Username: "admin", PasswordHash: "password", Role: types.UserRoleAdmin}
+		require.NoError(t, dao.CreateUser(ctx, admin))
+
+		user := &models.User{Username: "user", PasswordHash: "password", Role: types.UserRoleUser}
+		require.NoError(t, dao.CreateUser(ctx, user))
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("epub"),
+			Path:     "/course-1/01 book.epub",
+			Hash:     "1234",
+		}
+		require.NoError(t, dao.CreateAsset(ctx, asset))
+
+		for _, u := range []*models.User{admin, user} {
+			require.NoError(t, dao.CreateOrUpdateSpineProgress(ctx, &models.SpineProgress{AssetID: asset.ID, UserID: u.ID, ScrollPos: 50}, 1))
+		}
+
+		require.NoError(t, dao.DeleteUser(ctx, user))
+
+		// Only the progress of the admin remains
+		for _, model := range progressModels() {
+			count, err := dao.Count(ctx, model, nil)
+			require.NoError(t, err)
+			require.Equal(t, 1, count, model.Table())
+		}
+	})
+
+	t.Run("last admin", func(t *testing.T) {
+		dao, ctx := setup(t)
+
+		admin := &models.User{Username: "admin", PasswordHash: "password", Role: types.UserRoleAdmin}
+		require.NoError(t, dao.CreateUser(ctx, admin))
+		require.ErrorIs(t, dao.DeleteUser(ctx, admin), ErrLastAdmin)
+
+		admin2 := &models.User{Username: "admin2", PasswordHash: "password", Role: types.UserRoleAdmin}
+		require.NoError(t, dao.CreateUser(ctx, admin2))
+		require.NoError(t, dao.DeleteUser(ctx, admin))
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.DeleteUser(ctx, &models.User{}), utils.ErrInvalidId)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		dao, ctx := setup(t)
+		require.ErrorIs(t, dao.DeleteUser(ctx, nil), utils.ErrNilPtr)
+	})
+}
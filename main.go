@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/geerew/off-course/api"
 	"github.com/geerew/off-course/cron"
@@ -17,9 +19,12 @@ import (
 	"github.com/geerew/off-course/database"
 	"github.com/geerew/off-course/models"
 	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/cardimage"
 	"github.com/geerew/off-course/utils/coursescan"
 	"github.com/geerew/off-course/utils/logger"
+	"github.com/geerew/off-course/utils/oidc"
 	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/transcode"
 	"github.com/spf13/afero"
 )
 
@@ -33,8 +38,45 @@ func main() {
 	// Flags
 	port := flag.String("port", ":9081", "server port")
 	isDebug := flag.Bool("debug", false, "verbose")
+	transcodeCache := flag.Int64("transcode-cache", 5120, "max size of the transcode cache in MB")
+	transcodeJobs := flag.Int("transcode-jobs", 2, "max number of videos transcoded at once")
+	proxyAuthHeader := flag.String("auth-proxy-header", "", "header identifying the user when behind an auth proxy, such as Remote-User (disabled when empty)")
+	proxyAuthGroupsHeader := flag.String("auth-proxy-groups-header", "Remote-Groups", "header holding the comma separated groups of the user")
+	proxyAuthAdminGroup := flag.String("auth-proxy-admin-group", "admins", "group whose members are admins")
+	proxyAuthTrusted := flag.String("auth-proxy-trusted", "", "comma separated CIDRs of the proxies the auth headers are trusted from")
+	trustedProxies := flag.String("trusted-proxies", "", "comma separated CIDRs of the reverse proxies the client IP header is trusted from (defaults to -auth-proxy-trusted)")
+	clientIPHeader := flag.String("client-ip-header", "X-Forwarded-For", "header holding the client IP address of requests from a trusted proxy")
+	oidcIssuer := flag.String("oidc-issuer", "", "OpenID Connect issuer URL to log in with (disabled when empty)")
+	oidcClientID := flag.String("oidc-client-id", "", "OpenID Connect client ID")
+	oidcClientSecret := flag.String("oidc-client-secret", "", "OpenID Connect client secret (empty for a public client)")
+	oidcRedirectURL := flag.String("oidc-redirect-url", "", "the URL of /api/auth/oidc/callback as seen by the browser, such as https://courses.example.com/api/auth/oidc/callback")
+	oidcScopes := flag.String("oidc-scopes", "openid,profile,email", "comma separated OpenID Connect scopes to request")
+	oidcUsernameClaim := flag.String("oidc-username-claim", "preferred_username", "claim used as the username of new users")
+	oidcRoleClaim := flag.String("oidc-role-claim", "", "claim holding the groups of the user, such as groups (roles are managed in the app when empty)")
+	oidcAdminValue := flag.String("oidc-admin-value", "admins", "value of the role claim whose users are admins")
+	loginMaxFailures := flag.Int("login-max-failures", 5, "failed logins after which a username is locked out (an IP address after 4 times as many)")
+	loginLockout := flag.Duration("login-lockout", 15*time.Minute, "how long a username or IP address is locked out after too many failed logins")
 	flag.Parse()
 
+	proxyAuth, err := proxyAuthConfig(*proxyAuthHeader, *proxyAuthGroupsHeader, *proxyAuthAdminGroup, *proxyAuthTrusted)
+	if err != nil {
+		log.Fatal("Invalid proxy auth configuration: ", err)
+	}
+
+	if *trustedProxies == "" {
+		*trustedProxies = *proxyAuthTrusted
+	}
+
+	trustedPrefixes, err := api.ParseTrustedProxies(*trustedProxies)
+	if err != nil {
+		log.Fatal("Invalid trusted proxies: ", err)
+	}
+
+	oidcConfig, err := oidcConfig(*oidcIssuer, *oidcClientID, *oidcClientSecret, *oidcRedirectURL, *oidcScopes, *oidcUsernameClaim, *oidcRoleClaim, *oidcAdminValue)
+	if err != nil {
+		log.Fatal("Invalid OpenID Connect configuration: ", err)
+	}
+
 	ctx := context.Background()
 
 	// Create app filesystem
@@ -76,21 +118,46 @@ func main() {
 	// Start the worker (pass in the func that will process the job)
 	go courseScan.Worker(ctx, coursescan.Processor, nil)
 
+	// Transcoder (ffmpeg is configured through the settings)
+	transcoder := transcode.New(&transcode.Config{
+		CacheDir:      "./oc_data/transcode",
+		ThumbnailsDir: "./oc_data/thumbnails",
+		MaxCacheSize:  *transcodeCache << 20,
+		MaxJobs:       *transcodeJobs,
+		Logger:        logger,
+	})
+
+	// Resized course cards
+	cards := cardimage.New(&cardimage.Config{
+		CacheDir:   "./oc_data/cards",
+		UploadsDir: "./oc_data/card_uploads",
+		AppFs:      appFs,
+		Logger:     logger,
+	})
+
 	// Initialize cron jobs
 	cron.InitCron(&cron.CronConfig{
-		Db:     dbManager.DataDb,
-		AppFs:  appFs,
-		Logger: logger,
+		Db:         dbManager.DataDb,
+		AppFs:      appFs,
+		Transcoder: transcoder,
+		Logger:     logger,
 	})
 
 	// Create router
 	router := api.NewRouter(&api.RouterConfig{
-		DbManager:    dbManager,
-		Logger:       logger,
-		AppFs:        appFs,
-		CourseScan:   courseScan,
-		Port:         *port,
-		IsProduction: isProduction,
+		DbManager:      dbManager,
+		Logger:         logger,
+		AppFs:          appFs,
+		CourseScan:     courseScan,
+		Transcoder:     transcoder,
+		Cards:          cards,
+		Port:           *port,
+		IsProduction:   isProduction,
+		ProxyAuth:      proxyAuth,
+		OIDC:           oidcConfig,
+		LoginThrottle:  loginThrottleConfig(*loginMaxFailures, *loginLockout),
+		TrustedProxies: trustedPrefixes,
+		ClientIPHeader: strings.TrimSpace(*clientIPHeader),
 	})
 
 	var wg sync.WaitGroup
@@ -181,3 +248,90 @@ func loggerWriteFn(ctx context.Context, db database.Database) logger.WriteFn {
 		return nil
 	}
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// proxyAuthConfig builds the trusted reverse-proxy header authentication config from the flags. Nil
+// is returned when no user header is set. At least one trusted proxy is required, as otherwise
+// anyone could set the header
+func proxyAuthConfig(userHeader, groupsHeader, adminGroup, trusted string) (*api.ProxyAuthConfig, error) {
+	userHeader = strings.TrimSpace(userHeader)
+	if userHeader == "" {
+		return nil, nil
+	}
+
+	trustedProxies, err := api.ParseTrustedProxies(trusted)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(trustedProxies) == 0 {
+		return nil, errors.New("-auth-proxy-trusted is required when -auth-proxy-header is set")
+	}
+
+	return &api.ProxyAuthConfig{
+		UserHeader:     userHeader,
+		GroupsHeader:   strings.TrimSpace(groupsHeader),
+		AdminGroup:     strings.TrimSpace(adminGroup),
+		TrustedProxies: trustedProxies,
+	}, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// oidcConfig builds the OpenID Connect login config from the flags. Nil is returned when no issuer
+// is set. The provider is contacted on the first login, so the app starts when it is unreachable
+func oidcConfig(issuer, clientID, clientSecret, redirectURL, scopes, usernameClaim, roleClaim, adminValue string) (*api.OIDCConfig, error) {
+	issuer = strings.TrimSpace(issuer)
+	if issuer == "" {
+		return nil, nil
+	}
+
+	if strings.TrimSpace(clientID) == "" {
+		return nil, errors.New("-oidc-client-id is required when -oidc-issuer is set")
+	}
+
+	if strings.TrimSpace(redirectURL) == "" {
+		return nil, errors.New("-oidc-redirect-url is required when -oidc-issuer is set")
+	}
+
+	scopeList := []string{}
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopeList = append(scopeList, scope)
+		}
+	}
+
+	return &api.OIDCConfig{
+		Provider: oidc.New(&oidc.Config{
+			IssuerURL:    issuer,
+			ClientID:     strings.TrimSpace(clientID),
+			ClientSecret: clientSecret,
+			RedirectURL:  strings.TrimSpace(redirectURL),
+			Scopes:       scopeList,
+		}),
+		UsernameClaim: strings.TrimSpace(usernameClaim),
+		RoleClaim:     strings.TrimSpace(roleClaim),
+		AdminValue:    strings.TrimSpace(adminValue),
+	}, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// loginThrottleConfig builds the login throttling config from the flags. An IP address, which may
+// be shared by several users, is allowed 4 times as many failures as a username
+func loginThrottleConfig(maxFailures int, lockout time.Duration) *api.LoginThrottleConfig {
+	config := api.DefaultLoginThrottleConfig()
+
+	if maxFailures > 0 {
+		config.Username.MaxFailures = maxFailures
+		config.IP.MaxFailures = 4 * maxFailures
+	}
+
+	if lockout > 0 {
+		config.Username.LockoutDuration = lockout
+		config.IP.LockoutDuration = lockout
+	}
+
+	return config
+}
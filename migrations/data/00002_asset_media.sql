@@ -0,0 +1,9 @@
+-- +goose Up
+
+--- Media information extracted from the asset container
+ALTER TABLE assets ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE assets ADD COLUMN width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE assets ADD COLUMN height INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE assets ADD COLUMN video_codec TEXT NOT NULL DEFAULT '';
+ALTER TABLE assets ADD COLUMN audio_codec TEXT NOT NULL DEFAULT '';
+ALTER TABLE assets ADD COLUMN audio_tracks INTEGER NOT NULL DEFAULT 0;
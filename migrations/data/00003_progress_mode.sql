@@ -0,0 +1,15 @@
+-- +goose Up
+
+--- Course progress calculation. The mode is either `count` or `duration`. In duration mode, assets
+--- without a duration are weighted as this many seconds
+INSERT INTO params (id, key, value) VALUES (
+    'Jx2YbN8qTk',
+    'progressMode',
+    'count'
+);
+
+INSERT INTO params (id, key, value) VALUES (
+    'pQ7mWc3vLd',
+    'progressUntimedWeight',
+    '60'
+);
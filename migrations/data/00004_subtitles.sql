@@ -0,0 +1,15 @@
+-- +goose Up
+
+--- Subtitle tracks of an asset
+CREATE TABLE subtitles (
+	id          TEXT PRIMARY KEY NOT NULL,
+	asset_id    TEXT NOT NULL,
+	language    TEXT NOT NULL,
+	format      TEXT NOT NULL,
+	path        TEXT UNIQUE NOT NULL,
+	created_at  TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at  TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (asset_id) REFERENCES assets (id) ON DELETE CASCADE,
+	UNIQUE (asset_id, language)
+);
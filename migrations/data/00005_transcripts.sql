@@ -0,0 +1,19 @@
+-- +goose Up
+
+--- Full-text index of subtitle cues. Start and end are in milliseconds
+CREATE VIRTUAL TABLE transcripts USING fts5(
+	text,
+	subtitle_id UNINDEXED,
+	asset_id UNINDEXED,
+	start_ms UNINDEXED,
+	end_ms UNINDEXED,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+--- FTS5 tables do not support foreign keys, so remove the cues of a subtitle when it is deleted
+-- +goose StatementBegin
+CREATE TRIGGER transcripts_subtitle_delete AFTER DELETE ON subtitles
+BEGIN
+	DELETE FROM transcripts WHERE subtitle_id = OLD.id;
+END;
+-- +goose StatementEnd
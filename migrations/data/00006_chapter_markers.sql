@@ -0,0 +1,14 @@
+-- +goose Up
+
+--- Chapter markers embedded in a video asset. The start is in milliseconds
+CREATE TABLE chapter_markers (
+	id          TEXT PRIMARY KEY NOT NULL,
+	asset_id    TEXT NOT NULL,
+	title       TEXT NOT NULL,
+	start       INTEGER NOT NULL DEFAULT 0,
+	created_at  TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at  TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (asset_id) REFERENCES assets (id) ON DELETE CASCADE,
+	UNIQUE (asset_id, start)
+);
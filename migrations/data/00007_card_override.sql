@@ -0,0 +1,4 @@
+-- +goose Up
+
+--- When true, the card was set through the API and is kept when the course is scanned
+ALTER TABLE courses ADD COLUMN card_override BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- +goose Up
+
+--- The scroll position of a document asset (markdown, text or notebook), as a percentage
+ALTER TABLE assets_progress ADD COLUMN scroll_pos INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+-- +goose Up
+
+--- The reading progress of each spine item (chapter) of an EPUB asset. The scroll position is a
+--- percentage
+CREATE TABLE spine_progress (
+	id           TEXT PRIMARY KEY NOT NULL,
+	asset_id     TEXT NOT NULL,
+	spine_index  INTEGER NOT NULL DEFAULT 0,
+	scroll_pos   INTEGER NOT NULL DEFAULT 0,
+	created_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (asset_id) REFERENCES assets (id) ON DELETE CASCADE,
+	UNIQUE (asset_id, spine_index)
+);
@@ -0,0 +1,4 @@
+-- +goose Up
+
+--- The last slide viewed of a slides asset
+ALTER TABLE assets_progress ADD COLUMN slide_pos INTEGER NOT NULL DEFAULT 0;
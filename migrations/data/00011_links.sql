@@ -0,0 +1,5 @@
+-- +goose Up
+
+--- The target of a link asset or attachment (parsed from a .url, .webloc or .desktop file)
+ALTER TABLE assets ADD COLUMN url TEXT NOT NULL DEFAULT '';
+ALTER TABLE attachments ADD COLUMN url TEXT NOT NULL DEFAULT '';
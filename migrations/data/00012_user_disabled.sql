@@ -0,0 +1,4 @@
+-- +goose Up
+
+--- A disabled user cannot log in
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,77 @@
+-- +goose Up
+
+--- Progress is tracked per user. The tables are rebuilt, as SQLite cannot change a unique
+--- constraint in place, and existing progress is given to the first admin. While no admin exists
+--- (first-run), progress is recorded against an empty user ID and claimed by the admin once created
+
+CREATE TABLE courses_progress_new (
+	id           TEXT PRIMARY KEY NOT NULL,
+	course_id    TEXT NOT NULL,
+	user_id      TEXT NOT NULL DEFAULT '',
+	started      BOOLEAN NOT NULL DEFAULT FALSE,
+	started_at   TEXT,
+	percent      INTEGER NOT NULL DEFAULT 0,
+	completed_at TEXT,
+	created_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE CASCADE,
+	UNIQUE (course_id, user_id)
+);
+
+INSERT INTO courses_progress_new (id, course_id, user_id, started, started_at, percent, completed_at, created_at, updated_at)
+	SELECT id, course_id, COALESCE((SELECT id FROM users WHERE role = 'admin' ORDER BY created_at LIMIT 1), ''),
+		started, started_at, percent, completed_at, created_at, updated_at
+	FROM courses_progress;
+
+DROP TABLE courses_progress;
+ALTER TABLE courses_progress_new RENAME TO courses_progress;
+
+---
+
+CREATE TABLE assets_progress_new (
+	id           TEXT PRIMARY KEY NOT NULL,
+	asset_id     TEXT NOT NULL,
+	user_id      TEXT NOT NULL DEFAULT '',
+	video_pos    INTEGER NOT NULL DEFAULT 0,
+	scroll_pos   INTEGER NOT NULL DEFAULT 0,
+	slide_pos    INTEGER NOT NULL DEFAULT 0,
+	completed    BOOLEAN NOT NULL DEFAULT FALSE,
+	completed_at TEXT,
+	created_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (asset_id) REFERENCES assets (id) ON DELETE CASCADE,
+	UNIQUE (asset_id, user_id)
+);
+
+INSERT INTO assets_progress_new (id, asset_id, user_id, video_pos, scroll_pos, slide_pos, completed, completed_at, created_at, updated_at)
+	SELECT id, asset_id, COALESCE((SELECT id FROM users WHERE role = 'admin' ORDER BY created_at LIMIT 1), ''),
+		video_pos, scroll_pos, slide_pos, completed, completed_at, created_at, updated_at
+	FROM assets_progress;
+
+DROP TABLE assets_progress;
+ALTER TABLE assets_progress_new RENAME TO assets_progress;
+
+---
+
+CREATE TABLE spine_progress_new (
+	id           TEXT PRIMARY KEY NOT NULL,
+	asset_id     TEXT NOT NULL,
+	user_id      TEXT NOT NULL DEFAULT '',
+	spine_index  INTEGER NOT NULL DEFAULT 0,
+	scroll_pos   INTEGER NOT NULL DEFAULT 0,
+	created_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (asset_id) REFERENCES assets (id) ON DELETE CASCADE,
+	UNIQUE (asset_id, user_id, spine_index)
+);
+
+INSERT INTO spine_progress_new (id, asset_id, user_id, spine_index, scroll_pos, created_at, updated_at)
+	SELECT id, asset_id, COALESCE((SELECT id FROM users WHERE role = 'admin' ORDER BY created_at LIMIT 1), ''),
+		spine_index, scroll_pos, created_at, updated_at
+	FROM spine_progress;
+
+DROP TABLE spine_progress;
+ALTER TABLE spine_progress_new RENAME TO spine_progress;
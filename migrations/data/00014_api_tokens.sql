@@ -0,0 +1,16 @@
+-- +goose Up
+
+--- Personal API tokens. Only the hash of a token is stored
+CREATE TABLE api_tokens (
+	id           TEXT PRIMARY KEY NOT NULL,
+	user_id      TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	token_hash   TEXT NOT NULL UNIQUE,
+	scope        TEXT NOT NULL CHECK(scope IN ('read-only', 'progress-write', 'admin')),
+	expires_at   TEXT,
+	last_used_at TEXT,
+	created_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
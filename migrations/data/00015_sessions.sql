@@ -0,0 +1,16 @@
+-- +goose Up
+
+--- Login sessions. The cookie holds the session token and only its hash is stored
+CREATE TABLE sessions (
+	id           TEXT PRIMARY KEY NOT NULL,
+	user_id      TEXT NOT NULL,
+	token_hash   TEXT NOT NULL UNIQUE,
+	user_agent   TEXT NOT NULL DEFAULT '',
+	ip_address   TEXT NOT NULL DEFAULT '',
+	expires_at   TEXT NOT NULL,
+	last_seen_at TEXT NOT NULL,
+	created_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at   TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+-- +goose Up
+
+--- The subject of a user signed in through OpenID Connect. Empty for local users
+ALTER TABLE users ADD COLUMN oidc_subject TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX idx_users_oidc_subject ON users (oidc_subject) WHERE oidc_subject != '';
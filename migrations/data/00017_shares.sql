@@ -0,0 +1,43 @@
+-- +goose Up
+
+--- Share links. A share grants read-only access to a course or, when the asset ID is set, a single
+--- asset of the course. A max views of 0 allows unlimited views
+CREATE TABLE shares (
+	id         TEXT PRIMARY KEY NOT NULL,
+	user_id    TEXT NOT NULL,
+	course_id  TEXT NOT NULL,
+	asset_id   TEXT NOT NULL DEFAULT '',
+	expires_at TEXT NOT NULL,
+	max_views  INTEGER NOT NULL DEFAULT 0,
+	views      INTEGER NOT NULL DEFAULT 0,
+	revoked    BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+	FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE CASCADE
+);
+
+--- The asset ID is empty for a course share, so it cannot be a foreign key. Remove the shares of an
+--- asset when it is deleted
+-- +goose StatementBegin
+CREATE TRIGGER shares_asset_delete AFTER DELETE ON assets
+BEGIN
+	DELETE FROM shares WHERE asset_id = OLD.id;
+END;
+-- +goose StatementEnd
+
+--- Audit log of every use of a share
+CREATE TABLE share_accesses (
+	id         TEXT PRIMARY KEY NOT NULL,
+	share_id   TEXT NOT NULL,
+	ip_address TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	path       TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	updated_at TEXT NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f', 'NOW')),
+	---
+	FOREIGN KEY (share_id) REFERENCES shares (id) ON DELETE CASCADE
+);
+
+CREATE INDEX share_accesses_share_ip_idx ON share_accesses (share_id, ip_address);
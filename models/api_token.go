@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/geerew/off-course/utils/schema"
+	"github.com/geerew/off-course/utils/types"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ApiToken defines the model for a personal API token. Only the hash of the token is stored
+type ApiToken struct {
+	Base
+
+	UserID     string
+	Name       string
+	TokenHash  string
+	Scope      types.TokenScope
+	ExpiresAt  types.DateTime
+	LastUsedAt types.DateTime
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	API_TOKEN_TABLE        = "api_tokens"
+	API_TOKEN_USER_ID      = "user_id"
+	API_TOKEN_NAME         = "name"
+	API_TOKEN_TOKEN_HASH   = "token_hash"
+	API_TOKEN_SCOPE        = "scope"
+	API_TOKEN_EXPIRES_AT   = "expires_at"
+	API_TOKEN_LAST_USED_AT = "last_used_at"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Table implements the `schema.Modeler` interface by returning the table name
+func (t *ApiToken) Table() string {
+	return API_TOKEN_TABLE
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fields implements the `schema.Modeler` interface by defining the model fields
+func (t *ApiToken) Define(s *schema.ModelConfig) {
+	s.Embedded("Base")
+
+	// Common fields
+	s.Field("UserID").Column(API_TOKEN_USER_ID).NotNull()
+	s.Field("Name").Column(API_TOKEN_NAME).NotNull()
+	s.Field("TokenHash").Column(API_TOKEN_TOKEN_HASH).NotNull()
+	s.Field("Scope").Column(API_TOKEN_SCOPE).NotNull()
+	s.Field("ExpiresAt").Column(API_TOKEN_EXPIRES_AT)
+	s.Field("LastUsedAt").Column(API_TOKEN_LAST_USED_AT).Mutable()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsExpired returns whether the token has an expiry in the past. A token without an expiry never
+// expires
+func (t *ApiToken) IsExpired() bool {
+	return !t.ExpiresAt.IsZero() && t.ExpiresAt.Time().Before(types.NowDateTime().Time())
+}
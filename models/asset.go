@@ -22,9 +22,24 @@ type Asset struct {
 	Path     string
 	Hash     string
 
+	// The target of a link asset
+	URL string
+
+	// Media information
+	Duration    int
+	Width       int
+	Height      int
+	VideoCodec  string
+	AudioCodec  string
+	AudioTracks int
+
+	// The progress of a user through the asset. Progress is per user, so it is not a relation and
+	// is loaded through `dao.LoadAssetProgress`
+	Progress *AssetProgress
+
 	// Relations
-	Progress    *AssetProgress
 	Attachments []*Attachment
+	Subtitles   []*Subtitle
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -38,6 +53,13 @@ var (
 	ASSET_TYPE           = "type"
 	ASSET_PATH           = "path"
 	ASSET_HASH           = "hash"
+	ASSET_URL            = "url"
+	ASSET_DURATION       = "duration"
+	ASSET_WIDTH          = "width"
+	ASSET_HEIGHT         = "height"
+	ASSET_VIDEO_CODEC    = "video_codec"
+	ASSET_AUDIO_CODEC    = "audio_codec"
+	ASSET_AUDIO_TRACKS   = "audio_tracks"
 	ASSET_VIDEO_POSITION = "video_pos"
 	ASSET_COMPLETED      = "completed"
 	ASSET_COMPLETED_AT   = "completed_at"
@@ -64,8 +86,17 @@ func (a *Asset) Define(s *schema.ModelConfig) {
 	s.Field("Type").Column(ASSET_TYPE).NotNull().Mutable()
 	s.Field("Path").Column(ASSET_PATH).NotNull().Mutable()
 	s.Field("Hash").Column(ASSET_HASH).NotNull().Mutable()
+	s.Field("URL").Column(ASSET_URL).Mutable()
+
+	// Media fields
+	s.Field("Duration").Column(ASSET_DURATION).Mutable()
+	s.Field("Width").Column(ASSET_WIDTH).Mutable()
+	s.Field("Height").Column(ASSET_HEIGHT).Mutable()
+	s.Field("VideoCodec").Column(ASSET_VIDEO_CODEC).Mutable()
+	s.Field("AudioCodec").Column(ASSET_AUDIO_CODEC).Mutable()
+	s.Field("AudioTracks").Column(ASSET_AUDIO_TRACKS).Mutable()
 
 	// Relation fields
-	s.Relation("Progress").MatchOn(ASSET_PROGRESS_ASSET_ID)
 	s.Relation("Attachments").MatchOn(ATTACHMENT_ASSET_ID)
+	s.Relation("Subtitles").MatchOn(SUBTITLE_ASSET_ID)
 }
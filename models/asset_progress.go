@@ -7,11 +7,14 @@ import (
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// AssetProgress defines the model for an asset progress
+// AssetProgress defines the model for the progress of a user through an asset
 type AssetProgress struct {
 	Base
 	AssetID     string
+	UserID      string
 	VideoPos    int
+	ScrollPos   int
+	SlidePos    int
 	Completed   bool
 	CompletedAt types.DateTime
 }
@@ -21,7 +24,10 @@ type AssetProgress struct {
 var (
 	ASSET_PROGRESS_TABLE        = "assets_progress"
 	ASSET_PROGRESS_ASSET_ID     = "asset_id"
+	ASSET_PROGRESS_USER_ID      = "user_id"
 	ASSET_PROGRESS_VIDEO_POS    = "video_pos"
+	ASSET_PROGRESS_SCROLL_POS   = "scroll_pos"
+	ASSET_PROGRESS_SLIDE_POS    = "slide_pos"
 	ASSET_PROGRESS_COMPLETED    = "completed"
 	ASSET_PROGRESS_COMPLETED_AT = "completed_at"
 )
@@ -41,7 +47,10 @@ func (a *AssetProgress) Define(s *schema.ModelConfig) {
 
 	// Common fields
 	s.Field("AssetID").Column(ASSET_PROGRESS_ASSET_ID).NotNull()
+	s.Field("UserID").Column(ASSET_PROGRESS_USER_ID)
 	s.Field("VideoPos").Column(ASSET_PROGRESS_VIDEO_POS).Mutable()
+	s.Field("ScrollPos").Column(ASSET_PROGRESS_SCROLL_POS).Mutable()
+	s.Field("SlidePos").Column(ASSET_PROGRESS_SLIDE_POS).Mutable()
 	s.Field("Completed").Column(ASSET_PROGRESS_COMPLETED).Mutable()
 	s.Field("CompletedAt").Column(ASSET_PROGRESS_COMPLETED_AT).Mutable()
 }
@@ -10,6 +10,9 @@ type Attachment struct {
 	AssetID string
 	Title   string
 	Path    string
+
+	// The target of a link attachment. Empty for a file
+	URL string
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -19,6 +22,7 @@ var (
 	ATTACHMENT_ASSET_ID = "asset_id"
 	ATTACHMENT_TITLE    = "title"
 	ATTACHMENT_PATH     = "path"
+	ATTACHMENT_URL      = "url"
 )
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -37,4 +41,5 @@ func (a *Attachment) Define(s *schema.ModelConfig) {
 	s.Field("AssetID").Column(ATTACHMENT_ASSET_ID).NotNull()
 	s.Field("Title").Column(ATTACHMENT_TITLE).NotNull().Mutable()
 	s.Field("Path").Column(ATTACHMENT_PATH).NotNull().Mutable()
+	s.Field("URL").Column(ATTACHMENT_URL).Mutable()
 }
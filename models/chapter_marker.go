@@ -0,0 +1,41 @@
+package models
+
+import "github.com/geerew/off-course/utils/schema"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ChapterMarker defines the model for a chapter marker embedded in a video asset. The start is in
+// milliseconds
+type ChapterMarker struct {
+	Base
+	AssetID string
+	Title   string
+	Start   int
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	CHAPTER_MARKER_TABLE    = "chapter_markers"
+	CHAPTER_MARKER_ASSET_ID = "asset_id"
+	CHAPTER_MARKER_TITLE    = "title"
+	CHAPTER_MARKER_START    = "start"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Table implements the `schema.Modeler` interface by returning the table name
+func (m *ChapterMarker) Table() string {
+	return CHAPTER_MARKER_TABLE
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fields implements the `schema.Modeler` interface by defining the model fields
+func (m *ChapterMarker) Define(c *schema.ModelConfig) {
+	c.Embedded("Base")
+
+	c.Field("AssetID").Column(CHAPTER_MARKER_ASSET_ID).NotNull()
+	c.Field("Title").Column(CHAPTER_MARKER_TITLE).NotNull().Mutable()
+	c.Field("Start").Column(CHAPTER_MARKER_START).Mutable()
+}
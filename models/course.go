@@ -17,22 +17,27 @@ type Course struct {
 	CardPath  string
 	Available bool
 
+	// True when the card was set through the API, rather than detected by the scanner
+	CardOverride bool
+
 	// Joins
 	ScanStatus types.ScanStatus
 
-	// Relations
+	// The progress of a user through the course. Progress is per user, so it is not a relation and
+	// is loaded through `dao.LoadCourseProgress`
 	Progress CourseProgress
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 var (
-	COURSE_TABLE       = "courses"
-	COURSE_TITLE       = "title"
-	COURSE_PATH        = "path"
-	COURSE_CARD_PATH   = "card_path"
-	COURSE_AVAILABLE   = "available"
-	COURSE_SCAN_STATUS = "status"
+	COURSE_TABLE         = "courses"
+	COURSE_TITLE         = "title"
+	COURSE_PATH          = "path"
+	COURSE_CARD_PATH     = "card_path"
+	COURSE_AVAILABLE     = "available"
+	COURSE_CARD_OVERRIDE = "card_override"
+	COURSE_SCAN_STATUS   = "status"
 )
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -53,13 +58,11 @@ func (c *Course) Define(s *schema.ModelConfig) {
 	s.Field("Path").Column(COURSE_PATH).NotNull()
 	s.Field("CardPath").Column(COURSE_CARD_PATH).Mutable()
 	s.Field("Available").Column(COURSE_AVAILABLE).Mutable()
+	s.Field("CardOverride").Column(COURSE_CARD_OVERRIDE).Mutable()
 
 	// Join fields
 	s.Field("ScanStatus").JoinTable(SCAN_TABLE).Column(COURSE_SCAN_STATUS).Alias("scan_status")
 
-	// Relation fields
-	s.Relation("Progress").MatchOn(COURSE_PROGRESS_COURSE_ID)
-
 	// Joins
 	s.LeftJoin(SCAN_TABLE).On(fmt.Sprintf("%s.%s = %s.%s", COURSE_TABLE, BASE_ID, SCAN_TABLE, SCAN_COURSE_ID))
 }
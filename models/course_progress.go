@@ -7,10 +7,11 @@ import (
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-// CourseProgress defines the model for a course progress
+// CourseProgress defines the model for the progress of a user through a course
 type CourseProgress struct {
 	Base
 	CourseID    string
+	UserID      string
 	Started     bool
 	StartedAt   types.DateTime
 	Percent     int
@@ -22,6 +23,7 @@ type CourseProgress struct {
 var (
 	COURSE_PROGRESS_TABLE        = "courses_progress"
 	COURSE_PROGRESS_COURSE_ID    = "course_id"
+	COURSE_PROGRESS_USER_ID      = "user_id"
 	COURSE_PROGRESS_STARTED      = "started"
 	COURSE_PROGRESS_STARTED_AT   = "started_at"
 	COURSE_PROGRESS_PERCENT      = "percent"
@@ -43,6 +45,7 @@ func (cp *CourseProgress) Define(s *schema.ModelConfig) {
 
 	// Common fields
 	s.Field("CourseID").Column(COURSE_PROGRESS_COURSE_ID).NotNull()
+	s.Field("UserID").Column(COURSE_PROGRESS_USER_ID)
 	s.Field("Started").Column(COURSE_PROGRESS_STARTED).Mutable()
 	s.Field("StartedAt").Column(COURSE_PROGRESS_STARTED_AT).Mutable()
 	s.Field("Percent").Column(COURSE_PROGRESS_PERCENT).Mutable()
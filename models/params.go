@@ -21,6 +21,18 @@ var (
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// Param keys
+var (
+	PARAM_KEY_PROGRESS_MODE           = "progressMode"
+	PARAM_KEY_PROGRESS_UNTIMED_WEIGHT = "progressUntimedWeight"
+	PARAM_KEY_FFMPEG_PATH             = "ffmpegPath"
+	PARAM_KEY_SLIDES_GROUP_DIRS       = "slidesGroupDirectories"
+	PARAM_KEY_HAS_ADMIN               = "hasAdmin"
+	PARAM_KEY_SHARE_SECRET            = "shareSecret"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 // Table implements the `schema.Modeler` interface by returning the table name
 func (p *Param) Table() string {
 	return PARAM_TABLE
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/geerew/off-course/utils/schema"
+	"github.com/geerew/off-course/utils/types"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Session defines the model for a login session. The session token is held in a cookie and only
+// its hash is stored, along with the device that created the session
+type Session struct {
+	Base
+
+	UserID     string
+	TokenHash  string
+	UserAgent  string
+	IPAddress  string
+	ExpiresAt  types.DateTime
+	LastSeenAt types.DateTime
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	SESSION_TABLE        = "sessions"
+	SESSION_USER_ID      = "user_id"
+	SESSION_TOKEN_HASH   = "token_hash"
+	SESSION_USER_AGENT   = "user_agent"
+	SESSION_IP_ADDRESS   = "ip_address"
+	SESSION_EXPIRES_AT   = "expires_at"
+	SESSION_LAST_SEEN_AT = "last_seen_at"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Table implements the `schema.Modeler` interface by returning the table name
+func (s *Session) Table() string {
+	return SESSION_TABLE
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fields implements the `schema.Modeler` interface by defining the model fields
+func (s *Session) Define(c *schema.ModelConfig) {
+	c.Embedded("Base")
+
+	// Common fields
+	c.Field("UserID").Column(SESSION_USER_ID).NotNull()
+	c.Field("TokenHash").Column(SESSION_TOKEN_HASH).NotNull()
+	c.Field("UserAgent").Column(SESSION_USER_AGENT)
+	c.Field("IPAddress").Column(SESSION_IP_ADDRESS)
+	c.Field("ExpiresAt").Column(SESSION_EXPIRES_AT).NotNull().Mutable()
+	c.Field("LastSeenAt").Column(SESSION_LAST_SEEN_AT).NotNull().Mutable()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsExpired returns whether the session has expired
+func (s *Session) IsExpired() bool {
+	return !s.ExpiresAt.Time().After(types.NowDateTime().Time())
+}
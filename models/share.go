@@ -0,0 +1,113 @@
+package models
+
+import (
+	"github.com/geerew/off-course/utils/schema"
+	"github.com/geerew/off-course/utils/types"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Share defines the model for a share link, which grants read-only access to a course, or a single
+// asset of the course, without an account
+type Share struct {
+	Base
+
+	UserID   string
+	CourseID string
+
+	// Empty when the whole course is shared
+	AssetID string
+
+	ExpiresAt types.DateTime
+
+	// The number of distinct viewers allowed. 0 allows unlimited viewers
+	MaxViews int
+	Views    int
+
+	Revoked bool
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	SHARE_TABLE      = "shares"
+	SHARE_USER_ID    = "user_id"
+	SHARE_COURSE_ID  = "course_id"
+	SHARE_ASSET_ID   = "asset_id"
+	SHARE_EXPIRES_AT = "expires_at"
+	SHARE_MAX_VIEWS  = "max_views"
+	SHARE_VIEWS      = "views"
+	SHARE_REVOKED    = "revoked"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Table implements the `schema.Modeler` interface by returning the table name
+func (s *Share) Table() string {
+	return SHARE_TABLE
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fields implements the `schema.Modeler` interface by defining the model fields
+func (s *Share) Define(c *schema.ModelConfig) {
+	c.Embedded("Base")
+
+	// Common fields
+	c.Field("UserID").Column(SHARE_USER_ID).NotNull()
+	c.Field("CourseID").Column(SHARE_COURSE_ID).NotNull()
+	c.Field("AssetID").Column(SHARE_ASSET_ID)
+	c.Field("ExpiresAt").Column(SHARE_EXPIRES_AT).NotNull()
+	c.Field("MaxViews").Column(SHARE_MAX_VIEWS)
+	c.Field("Views").Column(SHARE_VIEWS).Mutable()
+	c.Field("Revoked").Column(SHARE_REVOKED).Mutable()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsExpired returns whether the share has expired
+func (s *Share) IsExpired() bool {
+	return !s.ExpiresAt.Time().After(types.NowDateTime().Time())
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ShareAccess defines the model for a use of a share, recorded as an audit log
+type ShareAccess struct {
+	Base
+
+	ShareID   string
+	IPAddress string
+	UserAgent string
+	Path      string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	SHARE_ACCESS_TABLE      = "share_accesses"
+	SHARE_ACCESS_SHARE_ID   = "share_id"
+	SHARE_ACCESS_IP_ADDRESS = "ip_address"
+	SHARE_ACCESS_USER_AGENT = "user_agent"
+	SHARE_ACCESS_PATH       = "path"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Table implements the `schema.Modeler` interface by returning the table name
+func (a *ShareAccess) Table() string {
+	return SHARE_ACCESS_TABLE
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fields implements the `schema.Modeler` interface by defining the model fields
+func (a *ShareAccess) Define(c *schema.ModelConfig) {
+	c.Embedded("Base")
+
+	// Common fields
+	c.Field("ShareID").Column(SHARE_ACCESS_SHARE_ID).NotNull()
+	c.Field("IPAddress").Column(SHARE_ACCESS_IP_ADDRESS)
+	c.Field("UserAgent").Column(SHARE_ACCESS_USER_AGENT)
+	c.Field("Path").Column(SHARE_ACCESS_PATH)
+}
@@ -0,0 +1,44 @@
+package models
+
+import "github.com/geerew/off-course/utils/schema"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SpineProgress defines the model for the reading progress of a user through a spine item (chapter)
+// of an EPUB asset. The scroll position is a percentage
+type SpineProgress struct {
+	Base
+	AssetID    string
+	UserID     string
+	SpineIndex int
+	ScrollPos  int
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	SPINE_PROGRESS_TABLE       = "spine_progress"
+	SPINE_PROGRESS_ASSET_ID    = "asset_id"
+	SPINE_PROGRESS_USER_ID     = "user_id"
+	SPINE_PROGRESS_SPINE_INDEX = "spine_index"
+	SPINE_PROGRESS_SCROLL_POS  = "scroll_pos"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Table implements the `schema.Modeler` interface by returning the table name
+func (s *SpineProgress) Table() string {
+	return SPINE_PROGRESS_TABLE
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fields implements the `schema.Modeler` interface by defining the model fields
+func (s *SpineProgress) Define(c *schema.ModelConfig) {
+	c.Embedded("Base")
+
+	c.Field("AssetID").Column(SPINE_PROGRESS_ASSET_ID).NotNull()
+	c.Field("UserID").Column(SPINE_PROGRESS_USER_ID)
+	c.Field("SpineIndex").Column(SPINE_PROGRESS_SPINE_INDEX)
+	c.Field("ScrollPos").Column(SPINE_PROGRESS_SCROLL_POS).Mutable()
+}
@@ -0,0 +1,43 @@
+package models
+
+import "github.com/geerew/off-course/utils/schema"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Subtitle defines the model for a subtitle track of an asset
+type Subtitle struct {
+	Base
+	AssetID  string
+	Language string
+	Format   string
+	Path     string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	SUBTITLE_TABLE    = "subtitles"
+	SUBTITLE_ASSET_ID = "asset_id"
+	SUBTITLE_LANGUAGE = "language"
+	SUBTITLE_FORMAT   = "format"
+	SUBTITLE_PATH     = "path"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Table implements the `schema.Modeler` interface by returning the table name
+func (s *Subtitle) Table() string {
+	return SUBTITLE_TABLE
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fields implements the `schema.Modeler` interface by defining the model fields
+func (s *Subtitle) Define(c *schema.ModelConfig) {
+	c.Embedded("Base")
+
+	c.Field("AssetID").Column(SUBTITLE_ASSET_ID).NotNull()
+	c.Field("Language").Column(SUBTITLE_LANGUAGE).NotNull().Mutable()
+	c.Field("Format").Column(SUBTITLE_FORMAT).NotNull().Mutable()
+	c.Field("Path").Column(SUBTITLE_PATH).NotNull().Mutable()
+}
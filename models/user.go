@@ -14,6 +14,10 @@ type User struct {
 	Username     string
 	PasswordHash string
 	Role         types.UserRole
+	Disabled     bool
+
+	// The subject of the user at the OpenID Connect provider. Empty for local users
+	OIDCSubject string
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -23,6 +27,8 @@ var (
 	USER_USERNAME      = "username"
 	USER_PASSWORD_HASH = "password_hash"
 	USER_ROLE          = "role"
+	USER_DISABLED      = "disabled"
+	USER_OIDC_SUBJECT  = "oidc_subject"
 )
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -40,6 +46,8 @@ func (u *User) Define(s *schema.ModelConfig) {
 
 	// Common fields
 	s.Field("Username").Column(USER_USERNAME).NotNull()
-	s.Field("PasswordHash").Column(USER_PASSWORD_HASH).NotNull()
-	s.Field("Role").Column(USER_ROLE).NotNull()
+	s.Field("PasswordHash").Column(USER_PASSWORD_HASH).NotNull().Mutable()
+	s.Field("Role").Column(USER_ROLE).NotNull().Mutable()
+	s.Field("Disabled").Column(USER_DISABLED).Mutable()
+	s.Field("OIDCSubject").Column(USER_OIDC_SUBJECT)
 }
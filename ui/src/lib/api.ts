@@ -1,22 +1,37 @@
 import { FileSystemSchema, type FileSystem } from '$lib/types/fileSystem';
 import {
+	ApiTokenSchema,
 	AssetSchema,
+	AuthStatusSchema,
 	CourseSchema,
 	CourseTagSchema,
+	LockoutSchema,
 	ScanSchema,
+	ShareSchema,
+	SharedSchema,
 	TagSchema,
+	UserSchema,
+	type ApiToken,
+	type ApiTokenCreateParams,
 	type Asset,
 	type AssetsGetParams,
+	type AuthStatus,
 	type Course,
 	type CourseTag,
 	type CoursesGetParams,
+	type Lockout,
+	type LockoutType,
 	type LogsGetParams,
 	type Scan,
+	type Share,
+	type ShareCreateParams,
+	type Shared,
 	type Tag,
 	type TagGetParams,
-	type TagsGetParams
+	type TagsGetParams,
+	type User
 } from '$lib/types/models';
-import { PaginationSchema, type Pagination } from '$lib/types/pagination';
+import { PaginationSchema, type Pagination, type PaginationParams } from '$lib/types/pagination';
 import axios from 'axios';
 import { array, safeParse, string } from 'valibot';
 
@@ -33,6 +48,12 @@ export const ATTACHMENT_API = '/api/attachments';
 export const TAGS_API = '/api/tags';
 export const SCAN_API = '/api/scans';
 export const LOG_API = '/api/logs';
+export const AUTH_API = '/api/auth';
+export const ME_API = '/api/me';
+export const USERS_API = '/api/users';
+export const LOCKOUTS_API = '/api/lockouts';
+export const SHARES_API = '/api/shares';
+export const SHARED_API = '/api/shared';
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
@@ -608,3 +629,369 @@ export async function GetLogTypes(): Promise<string[]> {
 		}
 	}
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Auth
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - Get whether an admin exists and the logged in user (if any)
+export async function GetAuthStatus(): Promise<AuthStatus> {
+	try {
+		const response = await axios.get<AuthStatus>(`${GetBackendUrl(AUTH_API)}/status`);
+		const result = safeParse(AuthStatusSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to retrieve auth status: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// POST - Create the first admin. Only available while no admin exists. The admin is logged in
+export async function BootstrapAdmin(username: string, password: string): Promise<User> {
+	try {
+		const response = await axios.post<User>(`${GetBackendUrl(AUTH_API)}/bootstrap`, {
+			username,
+			password
+		});
+		const result = safeParse(UserSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to create admin: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// POST - Log in with a username and password
+export async function Login(username: string, password: string): Promise<User> {
+	try {
+		const response = await axios.post<User>(`${GetBackendUrl(AUTH_API)}/login`, {
+			username,
+			password
+		});
+		const result = safeParse(UserSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to log in: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - The URL to navigate to in order to log in through the OpenID Connect provider. The backend
+// redirects back to the app once logged in
+export function OIDCLoginUrl(): string {
+	return `${GetBackendUrl(AUTH_API)}/oidc/login`;
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// POST - Log out
+export async function Logout(): Promise<boolean> {
+	try {
+		await axios.post(`${GetBackendUrl(AUTH_API)}/logout`);
+		return true;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to log out: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// API Tokens
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - Get a paginated list of the logged in user's API tokens
+export async function GetApiTokens(params?: PaginationParams): Promise<Pagination> {
+	try {
+		const response = await axios.get<Pagination>(`${GetBackendUrl(ME_API)}/tokens`, { params });
+		const result = safeParse(PaginationSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to retrieve API tokens: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// POST - Create an API token. The token is only returned in this response
+export async function CreateApiToken(params: ApiTokenCreateParams): Promise<ApiToken> {
+	try {
+		const response = await axios.post<ApiToken>(`${GetBackendUrl(ME_API)}/tokens`, params);
+		const result = safeParse(ApiTokenSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to create API token: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DELETE - Revoke an API token
+export async function DeleteApiToken(tokenId: string): Promise<boolean> {
+	try {
+		await axios.delete(`${GetBackendUrl(ME_API)}/tokens/${tokenId}`);
+		return true;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to revoke API token: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Sessions
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - Get a paginated list of the logged in user's active sessions
+export async function GetSessions(params?: PaginationParams): Promise<Pagination> {
+	try {
+		const response = await axios.get<Pagination>(`${GetBackendUrl(ME_API)}/sessions`, { params });
+		const result = safeParse(PaginationSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to retrieve sessions: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DELETE - Revoke a session of the logged in user. Revoking the current session logs out
+export async function DeleteSession(sessionId: string): Promise<boolean> {
+	try {
+		await axios.delete(`${GetBackendUrl(ME_API)}/sessions/${sessionId}`);
+		return true;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to revoke session: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DELETE - Revoke all the sessions of a user (admin only)
+export async function DeleteUserSessions(userId: string): Promise<boolean> {
+	try {
+		await axios.delete(`${GetBackendUrl(USERS_API)}/${userId}/sessions`);
+		return true;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to revoke sessions: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Lockouts
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - Get the usernames and IP addresses with failed logins (admin)
+export async function GetLockouts(): Promise<Lockout[]> {
+	try {
+		const response = await axios.get<Lockout[]>(GetBackendUrl(LOCKOUTS_API));
+		const result = safeParse(array(LockoutSchema), response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to retrieve lockouts: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DELETE - Clear the failed logins of a username or IP address (admin)
+export async function DeleteLockout(type: LockoutType, value: string): Promise<boolean> {
+	try {
+		await axios.delete(`${GetBackendUrl(LOCKOUTS_API)}/${type}/${encodeURIComponent(value)}`);
+		return true;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to clear lockout: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DELETE - Clear the failed logins of every username and IP address (admin)
+export async function DeleteLockouts(): Promise<boolean> {
+	try {
+		await axios.delete(GetBackendUrl(LOCKOUTS_API));
+		return true;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to clear lockouts: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Shares
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - Get a paginated list of the logged in user's shares
+export async function GetShares(params?: PaginationParams): Promise<Pagination> {
+	try {
+		const response = await axios.get<Pagination>(`${GetBackendUrl(ME_API)}/shares`, { params });
+		const result = safeParse(PaginationSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to retrieve shares: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - Get a paginated list of the shares of every user (admin)
+export async function GetAllShares(params?: PaginationParams): Promise<Pagination> {
+	try {
+		const response = await axios.get<Pagination>(GetBackendUrl(SHARES_API), { params });
+		const result = safeParse(PaginationSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to retrieve shares: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// POST - Share a course, or a single asset of the course
+export async function CreateShare(params: ShareCreateParams): Promise<Share> {
+	try {
+		const response = await axios.post<Share>(`${GetBackendUrl(ME_API)}/shares`, params);
+		const result = safeParse(ShareSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to create share: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// DELETE - Revoke a share of the logged in user
+export async function RevokeShare(shareId: string): Promise<boolean> {
+	try {
+		await axios.delete(`${GetBackendUrl(ME_API)}/shares/${shareId}`);
+		return true;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to revoke share: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - Get a paginated list of the uses of a share of the logged in user
+export async function GetShareAccesses(shareId: string, params?: PaginationParams): Promise<Pagination> {
+	try {
+		const response = await axios.get<Pagination>(`${GetBackendUrl(ME_API)}/shares/${shareId}/accesses`, {
+			params
+		});
+		const result = safeParse(PaginationSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to retrieve share accesses: ${error}`);
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GET - Get what a share link grants access to. No login is needed
+export async function GetShared(token: string): Promise<Shared> {
+	try {
+		const response = await axios.get<Shared>(`${GetBackendUrl(SHARED_API)}/${token}`);
+		const result = safeParse(SharedSchema, response.data);
+
+		if (!result.success) throw new Error('Invalid response from server');
+		return result.output;
+	} catch (error) {
+		if (axios.isAxiosError(error)) {
+			throw error;
+		} else {
+			throw new Error(`Failed to retrieve share: ${error}`);
+		}
+	}
+}
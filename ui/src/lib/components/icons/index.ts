@@ -3,6 +3,7 @@ import ArrowCounterClockwise from 'phosphor-svelte/lib/ArrowCounterClockwise';
 import ArrowDown from 'phosphor-svelte/lib/ArrowDown';
 import ArrowLeft from 'phosphor-svelte/lib/ArrowLeft';
 import ArrowRight from 'phosphor-svelte/lib/ArrowRight';
+import ArrowSquareOut from 'phosphor-svelte/lib/ArrowSquareOut';
 import ArrowUp from 'phosphor-svelte/lib/ArrowUp';
 import ArrowsClockwise from 'phosphor-svelte/lib/ArrowsClockwise';
 import ArrowsDownUp from 'phosphor-svelte/lib/ArrowsDownUp';
@@ -90,6 +91,7 @@ export const Icons = {
 	Download: DownloadSimple,
 	Dot: DotOutline,
 	Edit: PencilSimple,
+	ExternalLink: ArrowSquareOut,
 	EyeSlash,
 	FileCode,
 	FileVideo,
@@ -2,6 +2,7 @@ import {
 	any,
 	array,
 	boolean,
+	nullable,
 	number,
 	object,
 	optional,
@@ -36,7 +37,9 @@ export const AttachmentSchema = object({
 		courseId: string(),
 		assetId: string(),
 		title: string(),
-		path: string()
+		path: string(),
+		type: picklist(['file', 'link']),
+		url: optional(string())
 	}).entries
 });
 
@@ -46,7 +49,7 @@ export type Attachment = InferOutput<typeof AttachmentSchema>;
 // Asset
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
-const AssetTypeSchema = picklist(['video', 'html', 'pdf']);
+const AssetTypeSchema = picklist(['video', 'html', 'epub', 'notebook', 'markdown', 'pdf', 'slides', 'text', 'link']);
 export type AssetType = InferOutput<typeof AssetTypeSchema>;
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -60,9 +63,12 @@ export const AssetSchema = object({
 		chapter: string(),
 		path: string(),
 		assetType: AssetTypeSchema,
+		url: optional(string()),
 
 		// Progress
 		videoPos: number(),
+		scrollPos: number(),
+		slidePos: number(),
 		completed: boolean(),
 		completedAt: string(),
 
@@ -92,6 +98,7 @@ export const CourseSchema = object({
 		title: string(),
 		path: string(),
 		hasCard: boolean(),
+		cardOverride: boolean(),
 		available: boolean(),
 
 		// Scan status
@@ -251,3 +258,145 @@ export const LogLevelMapping: { [key in LogLevel]: number } = {
 	[LogLevel.WARN]: 4,
 	[LogLevel.ERROR]: 8
 };
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Users
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const UserRoleSchema = picklist(['admin', 'user']);
+export type UserRole = InferOutput<typeof UserRoleSchema>;
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+export const UserSchema = object({
+	...BaseSchema.entries,
+	...object({
+		username: string(),
+		role: UserRoleSchema,
+		disabled: boolean()
+	}).entries
+});
+
+export type User = InferOutput<typeof UserSchema>;
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+export const AuthStatusSchema = object({
+	hasAdmin: boolean(),
+	oidc: boolean(),
+	user: nullable(UserSchema)
+});
+
+export type AuthStatus = InferOutput<typeof AuthStatusSchema>;
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// API Tokens
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const TokenScopeSchema = picklist(['read-only', 'progress-write', 'admin']);
+export type TokenScope = InferOutput<typeof TokenScopeSchema>;
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+export const ApiTokenSchema = object({
+	id: string(),
+	name: string(),
+	scope: TokenScopeSchema,
+	expiresAt: string(),
+	lastUsedAt: string(),
+	createdAt: string(),
+	token: optional(string())
+});
+
+export type ApiToken = InferOutput<typeof ApiTokenSchema>;
+
+export type ApiTokenCreateParams = {
+	name: string;
+	scope?: TokenScope;
+	expiresAt?: string;
+};
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Sessions
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+export const SessionSchema = object({
+	id: string(),
+	userAgent: string(),
+	ipAddress: string(),
+	expiresAt: string(),
+	lastSeenAt: string(),
+	createdAt: string(),
+	current: boolean()
+});
+
+export type Session = InferOutput<typeof SessionSchema>;
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Lockouts
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const LockoutTypeSchema = picklist(['username', 'ip']);
+
+export type LockoutType = InferOutput<typeof LockoutTypeSchema>;
+
+export const LockoutSchema = object({
+	type: LockoutTypeSchema,
+	value: string(),
+	failures: number(),
+	lastFailure: string(),
+	retryAt: string(),
+	locked: boolean()
+});
+
+export type Lockout = InferOutput<typeof LockoutSchema>;
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Shares
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+export const ShareSchema = object({
+	id: string(),
+	userId: string(),
+	courseId: string(),
+	assetId: string(),
+	expiresAt: string(),
+	maxViews: number(),
+	views: number(),
+	revoked: boolean(),
+	expired: boolean(),
+	createdAt: string(),
+	token: string()
+});
+
+export type Share = InferOutput<typeof ShareSchema>;
+
+export type ShareCreateParams = {
+	courseId: string;
+	assetId?: string;
+	expiresAt: string;
+	maxViews?: number;
+};
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+export const ShareAccessSchema = object({
+	id: string(),
+	ipAddress: string(),
+	userAgent: string(),
+	path: string(),
+	createdAt: string()
+});
+
+export type ShareAccess = InferOutput<typeof ShareAccessSchema>;
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+export const SharedSchema = object({
+	expiresAt: string(),
+	assetId: string(),
+	course: CourseSchema,
+	assets: array(AssetSchema)
+});
+
+export type Shared = InferOutput<typeof SharedSchema>;
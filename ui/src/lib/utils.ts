@@ -189,3 +189,16 @@ export function UpdateQueryParam(key: string, value: string, replaceState: boole
 
 	goto(url.toString(), { replaceState, keepFocus: true, noScroll: true });
 }
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+export function RemoveQueryParam(key: string, replaceState: boolean) {
+	if (typeof window === 'undefined') return;
+
+	const url = new URL(window.location.href);
+	if (!url.searchParams.has(key)) return;
+
+	url.searchParams.delete(key);
+
+	goto(url.toString(), { replaceState, keepFocus: true, noScroll: true });
+}
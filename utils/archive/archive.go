@@ -0,0 +1,210 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Entry defines a file or directory within an archive
+type Entry struct {
+	// The cleaned, slash separated path of the entry
+	Path string
+
+	// The uncompressed size
+	Size           int64
+	CompressedSize int64
+
+	Modified time.Time
+	IsDir    bool
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Archive is a ZIP archive read from an afero.Fs. Nothing is extracted to disk; entries are
+// decompressed as they are read
+type Archive struct {
+	file   afero.File
+	reader *zip.Reader
+
+	// The files of the archive by cleaned path. Entries with unsafe names are left out
+	files map[string]*zip.File
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsArchive returns true when the file at path is a ZIP archive, based on its extension
+func IsArchive(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".zip")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Open opens the ZIP archive at path. The archive must be closed once done
+func Open(fs afero.Fs, path string) (*Archive, error) {
+	file, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Insecure names are handled below, so the error is ignored when the reader is valid
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil && (reader == nil || !errors.Is(err, zip.ErrInsecurePath)) {
+		file.Close()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	a := &Archive{file: file, reader: reader, files: map[string]*zip.File{}}
+
+	for _, f := range reader.File {
+		name, ok := CleanName(f.Name)
+		if !ok {
+			continue
+		}
+
+		// The first entry wins when names collide after cleaning
+		if _, exists := a.files[name]; !exists {
+			a.files[name] = f
+		}
+	}
+
+	return a, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Close closes the archive
+func (a *Archive) Close() error {
+	return a.file.Close()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Entries returns the entries of the archive, sorted by path
+func (a *Archive) Entries() []*Entry {
+	entries := make([]*Entry, 0, len(a.files))
+
+	for name, f := range a.files {
+		entries = append(entries, &Entry{
+			Path:           name,
+			Size:           int64(f.UncompressedSize64),
+			CompressedSize: int64(f.CompressedSize64),
+			Modified:       f.Modified,
+			IsDir:          f.FileInfo().IsDir(),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Entry returns the file entry with the given name. ErrEntryNotFound is returned when there is no
+// such entry or it is a directory
+func (a *Archive) Entry(name string) (*Entry, error) {
+	name, ok := CleanName(name)
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+
+	f, exists := a.files[name]
+	if !exists || f.FileInfo().IsDir() {
+		return nil, ErrEntryNotFound
+	}
+
+	return &Entry{
+		Path:           name,
+		Size:           int64(f.UncompressedSize64),
+		CompressedSize: int64(f.CompressedSize64),
+		Modified:       f.Modified,
+	}, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// OpenEntry opens the file entry with the given name, positioned at offset. Stored (uncompressed)
+// entries are read directly from the archive. Compressed entries are decompressed from the start,
+// skipping up to offset
+func (a *Archive) OpenEntry(name string, offset int64) (io.ReadCloser, error) {
+	entry, err := a.Entry(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f := a.files[entry.Path]
+
+	if offset < 0 || offset > entry.Size {
+		return nil, fmt.Errorf("%w: invalid offset", ErrInvalidArchive)
+	}
+
+	if f.Method == zip.Store {
+		raw, err := f.OpenRaw()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+
+		if seeker, ok := raw.(io.ReadSeeker); ok {
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+
+			return io.NopCloser(seeker), nil
+		}
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	if _, err := io.CopyN(io.Discard, rc, offset); err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	return rc, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// CleanName cleans the name of an entry into a slash separated relative path. False is returned
+// when the name is empty, absolute or escapes the archive
+func CleanName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, `\`, "/")
+
+	if name == "" || strings.ContainsRune(name, 0) || strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" {
+		return "", false
+	}
+
+	// Drive letters are checked on all systems, as archives are often created on Windows
+	if len(name) >= 2 && name[1] == ':' {
+		return "", false
+	}
+
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+
+	return name, true
+}
@@ -0,0 +1,172 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// setup writes a ZIP archive to an in-memory fs. Entries are deflated, apart from those with a
+// `.stored` suffix
+func setup(t *testing.T, entries map[string]string) (afero.Fs, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for name, content := range entries {
+		method := zip.Deflate
+		if strings.HasSuffix(name, ".stored") {
+			method = zip.Store
+		}
+
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/course/01 Resources.zip", buf.Bytes(), os.ModePerm))
+
+	return fs, "/course/01 Resources.zip"
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestArchive_IsArchive(t *testing.T) {
+	require.True(t, IsArchive("/a/01 Resources.zip"))
+	require.True(t, IsArchive("files.ZIP"))
+	require.False(t, IsArchive("files.7z"))
+	require.False(t, IsArchive("zip"))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestArchive_Open(t *testing.T) {
+	t.Run("entries", func(t *testing.T) {
+		fs, path := setup(t, map[string]string{
+			"src/":           "",
+			"src/main.go":    "package main",
+			`docs\notes.md`:  "# notes",
+			"./readme.txt":   "readme",
+			"../evil.sh":     "evil",
+			"/etc/passwd":    "root",
+			"C:/windows.ini": "win",
+		})
+
+		a, err := Open(fs, path)
+		require.NoError(t, err)
+		defer a.Close()
+
+		entries := a.Entries()
+		require.Len(t, entries, 4)
+
+		require.Equal(t, "docs/notes.md", entries[0].Path)
+		require.Equal(t, "readme.txt", entries[1].Path)
+		require.Equal(t, "src", entries[2].Path)
+		require.True(t, entries[2].IsDir)
+		require.Equal(t, "src/main.go", entries[3].Path)
+		require.False(t, entries[3].IsDir)
+		require.EqualValues(t, 12, entries[3].Size)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/a.zip", []byte("not a zip"), os.ModePerm))
+
+		_, err := Open(fs, "/a.zip")
+		require.ErrorIs(t, err, ErrInvalidArchive)
+
+		_, err = Open(fs, "/missing.zip")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestArchive_OpenEntry(t *testing.T) {
+	fs, path := setup(t, map[string]string{
+		"src/":              "",
+		"deflated.txt":      "0123456789",
+		"data/plain.stored": "abcdefghij",
+	})
+
+	a, err := Open(fs, path)
+	require.NoError(t, err)
+	defer a.Close()
+
+	read := func(name string, offset int64) string {
+		rc, err := a.OpenEntry(name, offset)
+		require.NoError(t, err)
+		defer rc.Close()
+
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+
+		return string(data)
+	}
+
+	require.Equal(t, "0123456789", read("deflated.txt", 0))
+	require.Equal(t, "56789", read("deflated.txt", 5))
+	require.Equal(t, "abcdefghij", read("data/plain.stored", 0))
+	require.Equal(t, "hij", read("data/plain.stored", 7))
+	require.Equal(t, "", read("data/plain.stored", 10))
+
+	// Names are cleaned
+	require.Equal(t, "abcdefghij", read(`data\..\data\plain.stored`, 0))
+
+	_, err = a.OpenEntry("src", 0)
+	require.ErrorIs(t, err, ErrEntryNotFound)
+
+	_, err = a.OpenEntry("missing.txt", 0)
+	require.ErrorIs(t, err, ErrEntryNotFound)
+
+	_, err = a.OpenEntry("../deflated.txt", 0)
+	require.ErrorIs(t, err, ErrEntryNotFound)
+
+	_, err = a.OpenEntry("deflated.txt", 11)
+	require.ErrorIs(t, err, ErrInvalidArchive)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestArchive_CleanName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"a/b.txt", "a/b.txt", true},
+		{`a\b.txt`, "a/b.txt", true},
+		{"./a//b/../c.txt", "a/c.txt", true},
+		{"a/", "a", true},
+		{"", "", false},
+		{".", "", false},
+		{"..", "", false},
+		{"../a", "", false},
+		{"a/../../b", "", false},
+		{`..\a`, "", false},
+		{"/a", "", false},
+		{"C:/a", "", false},
+		{`C:\a`, "", false},
+		{"a\x00b", "", false},
+	}
+
+	for _, tt := range tests {
+		name, ok := CleanName(tt.name)
+		require.Equal(t, tt.ok, ok, tt.name)
+		require.Equal(t, tt.expected, name, tt.name)
+	}
+}
@@ -0,0 +1,8 @@
+package archive
+
+import "errors"
+
+var (
+	ErrInvalidArchive = errors.New("invalid archive")
+	ErrEntryNotFound  = errors.New("entry not found")
+)
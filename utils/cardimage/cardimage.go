@@ -0,0 +1,444 @@
+package cardimage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"image"
+	"image/jpeg"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	// Registers the decoders of the supported formats
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/types"
+	"github.com/spf13/afero"
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/singleflight"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var loggerType = slog.Any("type", types.LogTypeFileSystem)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Sizes maps the supported card sizes to their width in pixels. The height follows the aspect
+// ratio of the source
+var Sizes = map[string]int{
+	"sm": 320,
+	"md": 640,
+	"lg": 1280,
+}
+
+// The extensions of the images that can be decoded
+var imageExtensions = map[string]bool{
+	"jpg":  true,
+	"jpeg": true,
+	"png":  true,
+	"gif":  true,
+	"webp": true,
+	"bmp":  true,
+	"tiff": true,
+	"tif":  true,
+}
+
+// The quality of the generated JPEGs
+const jpegQuality = 85
+
+// The background colors of the placeholders
+var placeholderColors = []string{
+	"#2563eb", "#7c3aed", "#db2777", "#dc2626", "#ea580c",
+	"#ca8a04", "#16a34a", "#0d9488", "#0891b2", "#4f46e5",
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Config defines the configuration for a Cache
+type Config struct {
+	// The directory to write the derivatives to
+	CacheDir string
+
+	// The directory to write the uploaded cards to. Unlike the derivatives, uploads are written
+	// through AppFs, as they are served like any other card
+	UploadsDir string
+
+	AppFs  *appFs.AppFs
+	Logger *slog.Logger
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Cache generates resized JPEG derivatives of course cards and caches them on disk. A derivative
+// is keyed by the path of the source, its mtime and the size, so a changed card is regenerated.
+//
+// The images that failed to decode and the fallback card of each course directory are kept in
+// memory, keyed by mtime, as cards are requested on every listing of the courses
+type Cache struct {
+	cacheDir   string
+	uploadsDir string
+	appFs      *appFs.AppFs
+	logger     *slog.Logger
+
+	// Concurrent requests for the same derivative share a single generation
+	group singleflight.Group
+
+	mu        sync.Mutex
+	failed    map[string]time.Time
+	fallbacks map[string]fallback
+}
+
+// fallback is the image found in a course directory, as of the mtime of the directory
+type fallback struct {
+	modTime time.Time
+	path    string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// New creates a new Cache
+func New(config *Config) *Cache {
+	return &Cache{
+		cacheDir:   config.CacheDir,
+		uploadsDir: config.UploadsDir,
+		appFs:      config.AppFs,
+		logger:     config.Logger,
+		failed:     map[string]time.Time{},
+		fallbacks:  map[string]fallback{},
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsImage returns true when the file at path is an image that can be resized
+func IsImage(path string) bool {
+	return imageExtensions[strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))]
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Derivative returns the path of a JPEG of the image at path, resized to the given size, when not
+// already cached. Images narrower than the size are not upscaled. Older derivatives of the same
+// image and size are removed. Concurrent calls for the same derivative wait on a single
+// generation, and ErrInvalidImage is returned without decoding while a failed image is unchanged
+func (c *Cache) Derivative(path, size string) (string, error) {
+	if _, ok := Sizes[size]; !ok {
+		return "", ErrInvalidSize
+	}
+
+	info, err := c.appFs.Fs.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := cacheKey(path, size)
+	out := filepath.Join(c.cacheDir, fmt.Sprintf("%s-%d.jpg", prefix, info.ModTime().UnixNano()))
+
+	if _, err := os.Stat(out); err == nil {
+		return out, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	// An image that failed to decode is not decoded again until it changes
+	c.mu.Lock()
+	failedAt, failed := c.failed[path]
+	c.mu.Unlock()
+
+	if failed && failedAt.Equal(info.ModTime()) {
+		return "", ErrInvalidImage
+	}
+
+	_, err, _ = c.group.Do(out, func() (any, error) {
+		return nil, c.generate(path, size, out, info.ModTime())
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return out, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fallback returns the first image, sorted by path, within the course directory and its
+// immediate subdirectories. An empty string is returned when there is no image. The result is
+// cached until the mtime of the directory changes or the image is removed
+func (c *Cache) Fallback(dir string) string {
+	info, err := c.appFs.Fs.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return ""
+	}
+
+	c.mu.Lock()
+	cached, ok := c.fallbacks[dir]
+	c.mu.Unlock()
+
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		if cached.path == "" {
+			return ""
+		}
+
+		if exists, err := afero.Exists(c.appFs.Fs, cached.path); err == nil && exists {
+			return cached.path
+		}
+	}
+
+	files, err := c.appFs.ReadDirFlat(dir, 2)
+	if err != nil {
+		return ""
+	}
+
+	images := slices.DeleteFunc(files, func(path string) bool { return !IsImage(path) })
+	slices.Sort(images)
+
+	path := ""
+	if len(images) > 0 {
+		path = images[0]
+	}
+
+	c.mu.Lock()
+	c.fallbacks[dir] = fallback{modTime: info.ModTime(), path: path}
+	c.mu.Unlock()
+
+	return path
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SaveUpload writes an uploaded card of the course to the uploads directory, replacing any
+// previous upload, and returns its path. ErrInvalidImage is returned when the upload is not an
+// image that can be decoded
+func (c *Cache) SaveUpload(courseID, filename string, r io.Reader) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if courseID == "" || !IsImage(ext) {
+		return "", ErrInvalidImage
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+
+	if err := c.appFs.Fs.MkdirAll(c.uploadsDir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if err := c.RemoveUpload(courseID); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(c.uploadsDir, courseID+ext)
+	if err := afero.WriteFile(c.appFs.Fs, path, data, 0o644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// RemoveUpload removes the uploaded card of the course, if any
+func (c *Cache) RemoveUpload(courseID string) error {
+	if courseID == "" {
+		return nil
+	}
+
+	matches, err := afero.Glob(c.appFs.Fs, filepath.Join(c.uploadsDir, courseID+".*"))
+	if err != nil {
+		return err
+	}
+
+	for _, match := range matches {
+		if err := c.appFs.Fs.Remove(match); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsUpload returns true when path is in the uploads directory
+func (c *Cache) IsUpload(path string) bool {
+	return filepath.Dir(path) == filepath.Clean(c.uploadsDir)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Placeholder generates an SVG card showing the initials of the title on a background color
+// derived from the title
+func Placeholder(title string) []byte {
+	h := fnv.New32a()
+	h.Write([]byte(title))
+	color := placeholderColors[h.Sum32()%uint32(len(placeholderColors))]
+
+	return []byte(fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="640" height="360" viewBox="0 0 640 360">`+
+			`<rect width="640" height="360" fill="%s"/>`+
+			`<text x="50%%" y="50%%" dy=".35em" fill="#ffffff" font-family="sans-serif" font-size="140" font-weight="600" text-anchor="middle">%s</text>`+
+			`</svg>`,
+		color,
+		html.EscapeString(initials(title)),
+	))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// decode decodes the image at path
+func (c *Cache) decode(path string) (image.Image, error) {
+	f, err := c.appFs.Fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		c.logger.Warn("Failed to decode card", loggerType, slog.String("path", path), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+
+	return img, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// generate writes the derivative of the image at path to out. A failure to decode is recorded
+// against the mtime of the image
+func (c *Cache) generate(path, size, out string, modTime time.Time) error {
+	start := time.Now()
+
+	src, err := c.decode(path)
+	if err != nil {
+		if errors.Is(err, ErrInvalidImage) {
+			c.mu.Lock()
+			c.failed[path] = modTime
+			c.mu.Unlock()
+		}
+
+		return err
+	}
+
+	if err := os.MkdirAll(c.cacheDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// Write to a temp file first, so a partial file is never served
+	tmp, err := os.CreateTemp(c.cacheDir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := jpeg.Encode(tmp, resize(src, Sizes[size]), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Remove the derivatives of a previous version of the image
+	if stale, err := filepath.Glob(filepath.Join(c.cacheDir, cacheKey(path, size)+"-*.jpg")); err == nil {
+		for _, s := range stale {
+			os.Remove(s)
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), out); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.failed, path)
+	c.mu.Unlock()
+
+	c.logger.Debug(
+		"Generated card",
+		loggerType,
+		slog.String("path", path),
+		slog.String("size", size),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// resize scales the image down to the given width. The image is drawn onto an RGBA canvas either
+// way, which also flattens formats JPEG cannot encode directly
+func resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > width {
+		h = max(1, h*width/w)
+		w = width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	return dst
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// cacheKey returns the key of the derivatives of an image and size
+func cacheKey(path, size string) string {
+	sum := sha256.Sum256([]byte(path + "\x00" + size))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// initials returns up to 2 initials of the words of the title, in upper case
+func initials(title string) string {
+	letters := []rune{}
+
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, word := range words {
+		letters = append(letters, unicode.ToUpper([]rune(word)[0]))
+		if len(letters) == 2 {
+			break
+		}
+	}
+
+	if len(letters) == 0 {
+		return "?"
+	}
+
+	return string(letters)
+}
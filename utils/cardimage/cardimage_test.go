@@ -0,0 +1,386 @@
+package cardimage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func setup(t *testing.T) *Cache {
+	t.Helper()
+
+	// Logger
+	var logs []*logger.Log
+	var logsMux sync.Mutex
+	logger, _, err := logger.InitLogger(&logger.BatchOptions{
+		BatchSize: 1,
+		WriteFn:   logger.TestWriteFn(&logs, &logsMux),
+	})
+	require.NoError(t, err, "Failed to initialize logger")
+
+	return New(&Config{
+		CacheDir: filepath.Join(t.TempDir(), "cards"),
+		AppFs:    appFs.NewAppFs(afero.NewMemMapFs(), logger),
+		Logger:   logger,
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func writePNG(t *testing.T, fs afero.Fs, path string, width, height int) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	require.NoError(t, afero.WriteFile(fs, path, buf.Bytes(), os.ModePerm))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCardImage_IsImage(t *testing.T) {
+	for _, path := range []string{"card.jpg", "card.JPEG", "/a/b.png", "c.webp", "d.tiff", "e.tif", "f.bmp", "g.gif"} {
+		require.True(t, IsImage(path), path)
+	}
+
+	for _, path := range []string{"card", "card.svg", "video.mp4", "notes.txt"} {
+		require.False(t, IsImage(path), path)
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCardImage_Derivative(t *testing.T) {
+	t.Run("resize", func(t *testing.T) {
+		cache := setup(t)
+		writePNG(t, cache.appFs.Fs, "/course/card.png", 800, 400)
+
+		out, err := cache.Derivative("/course/card.png", "sm")
+		require.NoError(t, err)
+
+		f, err := os.Open(out)
+		require.NoError(t, err)
+		defer f.Close()
+
+		img, err := jpeg.Decode(f)
+		require.NoError(t, err)
+		require.Equal(t, 320, img.Bounds().Dx())
+		require.Equal(t, 160, img.Bounds().Dy())
+	})
+
+	t.Run("no upscale", func(t *testing.T) {
+		cache := setup(t)
+		writePNG(t, cache.appFs.Fs, "/course/card.png", 200, 100)
+
+		out, err := cache.Derivative("/course/card.png", "lg")
+		require.NoError(t, err)
+
+		f, err := os.Open(out)
+		require.NoError(t, err)
+		defer f.Close()
+
+		cfg, err := jpeg.DecodeConfig(f)
+		require.NoError(t, err)
+		require.Equal(t, 200, cfg.Width)
+		require.Equal(t, 100, cfg.Height)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		cache := setup(t)
+		writePNG(t, cache.appFs.Fs, "/course/card.png", 800, 400)
+
+		out, err := cache.Derivative("/course/card.png", "sm")
+		require.NoError(t, err)
+
+		info, err := os.Stat(out)
+		require.NoError(t, err)
+
+		out2, err := cache.Derivative("/course/card.png", "sm")
+		require.NoError(t, err)
+		require.Equal(t, out, out2)
+
+		info2, err := os.Stat(out2)
+		require.NoError(t, err)
+		require.Equal(t, info.ModTime(), info2.ModTime())
+
+		// Another size is a separate derivative
+		out3, err := cache.Derivative("/course/card.png", "md")
+		require.NoError(t, err)
+		require.NotEqual(t, out, out3)
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		cache := setup(t)
+		writePNG(t, cache.appFs.Fs, "/course/card.png", 800, 400)
+
+		out, err := cache.Derivative("/course/card.png", "sm")
+		require.NoError(t, err)
+
+		writePNG(t, cache.appFs.Fs, "/course/card.png", 400, 400)
+		mtime := time.Now().Add(time.Minute)
+		require.NoError(t, cache.appFs.Fs.Chtimes("/course/card.png", mtime, mtime))
+
+		out2, err := cache.Derivative("/course/card.png", "sm")
+		require.NoError(t, err)
+		require.NotEqual(t, out, out2)
+
+		// The stale derivative is removed
+		require.NoFileExists(t, out)
+		require.FileExists(t, out2)
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		cache := setup(t)
+		writePNG(t, cache.appFs.Fs, "/course/card.png", 10, 10)
+
+		_, err := cache.Derivative("/course/card.png", "xl")
+		require.ErrorIs(t, err, ErrInvalidSize)
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		cache := setup(t)
+		require.NoError(t, afero.WriteFile(cache.appFs.Fs, "/course/card.jpg", []byte("test"), os.ModePerm))
+
+		_, err := cache.Derivative("/course/card.jpg", "sm")
+		require.ErrorIs(t, err, ErrInvalidImage)
+	})
+
+	t.Run("invalid image cached", func(t *testing.T) {
+		cache := setup(t)
+		require.NoError(t, afero.WriteFile(cache.appFs.Fs, "/course/card.png", []byte("test"), os.ModePerm))
+
+		info, err := cache.appFs.Fs.Stat("/course/card.png")
+		require.NoError(t, err)
+		modTime := info.ModTime()
+
+		_, err = cache.Derivative("/course/card.png", "sm")
+		require.ErrorIs(t, err, ErrInvalidImage)
+
+		// A valid image with the same mtime is not decoded
+		writePNG(t, cache.appFs.Fs, "/course/card.png", 100, 100)
+		require.NoError(t, cache.appFs.Fs.Chtimes("/course/card.png", modTime, modTime))
+
+		_, err = cache.Derivative("/course/card.png", "sm")
+		require.ErrorIs(t, err, ErrInvalidImage)
+
+		// Another size fails without decoding either
+		_, err = cache.Derivative("/course/card.png", "md")
+		require.ErrorIs(t, err, ErrInvalidImage)
+
+		// Modifying the image clears the failure
+		mtime := modTime.Add(time.Minute)
+		require.NoError(t, cache.appFs.Fs.Chtimes("/course/card.png", mtime, mtime))
+
+		out, err := cache.Derivative("/course/card.png", "sm")
+		require.NoError(t, err)
+		require.FileExists(t, out)
+		require.Empty(t, cache.failed)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		cache := setup(t)
+		writePNG(t, cache.appFs.Fs, "/course/card.png", 800, 400)
+
+		var wg sync.WaitGroup
+		outs := make([]string, 10)
+		errs := make([]error, 10)
+
+		for i := range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				outs[i], errs[i] = cache.Derivative("/course/card.png", "sm")
+			}()
+		}
+
+		wg.Wait()
+
+		for i := range 10 {
+			require.NoError(t, errs[i])
+			require.Equal(t, outs[0], outs[i])
+		}
+
+		matches, err := filepath.Glob(filepath.Join(cache.cacheDir, "*"))
+		require.NoError(t, err)
+		require.Equal(t, []string{outs[0]}, matches)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		cache := setup(t)
+
+		_, err := cache.Derivative("/course/card.jpg", "sm")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCardImage_Fallback(t *testing.T) {
+	t.Run("first image", func(t *testing.T) {
+		cache := setup(t)
+		require.NoError(t, afero.WriteFile(cache.appFs.Fs, "/course/01 video.mp4", []byte("video"), os.ModePerm))
+		writePNG(t, cache.appFs.Fs, "/course/chapter 2/diagram.png", 10, 10)
+		writePNG(t, cache.appFs.Fs, "/course/chapter 1/slide.png", 10, 10)
+
+		require.Equal(t, "/course/chapter 1/slide.png", cache.Fallback("/course"))
+	})
+
+	t.Run("no image", func(t *testing.T) {
+		cache := setup(t)
+		require.NoError(t, afero.WriteFile(cache.appFs.Fs, "/course/01 video.mp4", []byte("video"), os.ModePerm))
+
+		require.Empty(t, cache.Fallback("/course"))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		cache := setup(t)
+
+		require.Empty(t, cache.Fallback("/course"))
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		cache := setup(t)
+		writePNG(t, cache.appFs.Fs, "/course/chapter 2/diagram.png", 10, 10)
+
+		info, err := cache.appFs.Fs.Stat("/course")
+		require.NoError(t, err)
+		modTime := info.ModTime()
+
+		require.Equal(t, "/course/chapter 2/diagram.png", cache.Fallback("/course"))
+
+		// A new image is not found while the mtime of the directory is unchanged
+		writePNG(t, cache.appFs.Fs, "/course/chapter 1/slide.png", 10, 10)
+		require.NoError(t, cache.appFs.Fs.Chtimes("/course", modTime, modTime))
+		require.Equal(t, "/course/chapter 2/diagram.png", cache.Fallback("/course"))
+
+		// Modifying the directory clears the cache
+		mtime := modTime.Add(time.Minute)
+		require.NoError(t, cache.appFs.Fs.Chtimes("/course", mtime, mtime))
+		require.Equal(t, "/course/chapter 1/slide.png", cache.Fallback("/course"))
+	})
+
+	t.Run("cached image removed", func(t *testing.T) {
+		cache := setup(t)
+		writePNG(t, cache.appFs.Fs, "/course/chapter 1/slide.png", 10, 10)
+		writePNG(t, cache.appFs.Fs, "/course/chapter 2/diagram.png", 10, 10)
+
+		info, err := cache.appFs.Fs.Stat("/course")
+		require.NoError(t, err)
+		modTime := info.ModTime()
+
+		require.Equal(t, "/course/chapter 1/slide.png", cache.Fallback("/course"))
+
+		require.NoError(t, cache.appFs.Fs.Remove("/course/chapter 1/slide.png"))
+		require.NoError(t, cache.appFs.Fs.Chtimes("/course", modTime, modTime))
+		require.Equal(t, "/course/chapter 2/diagram.png", cache.Fallback("/course"))
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCardImage_Uploads(t *testing.T) {
+	t.Run("save", func(t *testing.T) {
+		cache := setup(t)
+		cache.uploadsDir = "/uploads"
+
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+		path, err := cache.SaveUpload("course1", "Card.PNG", bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, "/uploads/course1.png", path)
+		require.True(t, cache.IsUpload(path))
+		require.False(t, cache.IsUpload("/course1/card.png"))
+
+		data, err := afero.ReadFile(cache.appFs.Fs, path)
+		require.NoError(t, err)
+		require.Equal(t, buf.Bytes(), data)
+
+		// Replaces a previous upload with another extension
+		buf.Reset()
+		require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil))
+
+		path, err = cache.SaveUpload("course1", "card.jpg", bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, "/uploads/course1.jpg", path)
+
+		matches, err := afero.Glob(cache.appFs.Fs, "/uploads/*")
+		require.NoError(t, err)
+		require.Equal(t, []string{"/uploads/course1.jpg"}, matches)
+
+		require.NoError(t, cache.RemoveUpload("course1"))
+
+		matches, err = afero.Glob(cache.appFs.Fs, "/uploads/*")
+		require.NoError(t, err)
+		require.Empty(t, matches)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cache := setup(t)
+		cache.uploadsDir = "/uploads"
+
+		_, err := cache.SaveUpload("course1", "card.txt", strings.NewReader("text"))
+		require.ErrorIs(t, err, ErrInvalidImage)
+
+		_, err = cache.SaveUpload("course1", "card.png", strings.NewReader("not a png"))
+		require.ErrorIs(t, err, ErrInvalidImage)
+
+		_, err = cache.SaveUpload("", "card.png", strings.NewReader("not a png"))
+		require.ErrorIs(t, err, ErrInvalidImage)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCardImage_Placeholder(t *testing.T) {
+	svg := string(Placeholder("Go for Beginners"))
+	require.True(t, strings.HasPrefix(svg, "<svg"))
+	require.Contains(t, svg, ">GF</text>")
+
+	// The color is stable
+	require.Equal(t, svg, string(Placeholder("Go for Beginners")))
+
+	// Only letters and numbers are used
+	require.Contains(t, string(Placeholder("<script>")), ">S</text>")
+	require.Contains(t, string(Placeholder("& more")), ">M</text>")
+	require.Contains(t, string(Placeholder("")), ">?</text>")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestCardImage_Initials(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{"course", "C"},
+		{"learn go fast", "LG"},
+		{"  über (2024) edition", "Ü2"},
+		{"---", "?"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, initials(tt.title), tt.title)
+	}
+}
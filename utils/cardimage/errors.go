@@ -0,0 +1,8 @@
+package cardimage
+
+import "errors"
+
+var (
+	ErrInvalidSize  = errors.New("invalid card size")
+	ErrInvalidImage = errors.New("invalid image")
+)
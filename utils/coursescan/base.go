@@ -7,7 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/geerew/off-course/dao"
@@ -15,8 +18,13 @@ import (
 	"github.com/geerew/off-course/models"
 	"github.com/geerew/off-course/utils"
 	"github.com/geerew/off-course/utils/appFs"
+	"github.com/geerew/off-course/utils/media"
 	"github.com/geerew/off-course/utils/security"
+	"github.com/geerew/off-course/utils/slides"
+	"github.com/geerew/off-course/utils/subtitles"
 	"github.com/geerew/off-course/utils/types"
+	"github.com/geerew/off-course/utils/weblink"
+	"github.com/spf13/afero"
 )
 
 var (
@@ -192,6 +200,7 @@ func (s *CourseScan) Worker(ctx context.Context, processorFn CourseScanProcessor
 
 type assetMap map[string]map[int]*models.Asset
 type attachmentMap map[string]map[int][]*models.Attachment
+type subtitleMap map[string]map[int][]*models.Subtitle
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
@@ -266,15 +275,37 @@ func Processor(ctx context.Context, s *CourseScan, scan *models.Scan) error {
 
 	cardPath := ""
 
-	// Get all files down to a depth of 2
-	files, err := s.appFs.ReadDirFlat(course.Path, 2)
+	groupSlides, err := s.dao.GetSlidesGroupDirectories(ctx)
 	if err != nil {
 		return err
 	}
 
-	// Maps to hold assets and attachments by [chapter][prefix]
+	// Get all files down to a depth of 2. When grouping slide decks, a deck can be within a chapter
+	// so read down to a depth of 3
+	depth := 2
+	if groupSlides {
+		depth = 3
+	}
+
+	files, err := s.appFs.ReadDirFlat(course.Path, depth)
+	if err != nil {
+		return err
+	}
+
+	// Find the slide decks and remove their images from the files, along with any other files at a
+	// depth of 3
+	decks := map[string][]string{}
+	if groupSlides {
+		decks, files, err = findSlideDecks(s.appFs.Fs, utils.NormalizeWindowsDrive(course.Path), files)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Maps to hold assets, attachments and subtitles by [chapter][prefix]
 	assetsMap := assetMap{}
 	attachmentsMap := attachmentMap{}
+	subtitlesMap := subtitleMap{}
 
 	for _, fp := range files {
 		normalizedPath := utils.NormalizeWindowsDrive(fp)
@@ -312,6 +343,24 @@ func Processor(ctx context.Context, s *CourseScan, scan *models.Scan) error {
 			attachmentsMap[chapter] = make(map[int][]*models.Attachment)
 		}
 
+		if _, exists := subtitlesMap[chapter]; !exists {
+			subtitlesMap[chapter] = make(map[int][]*models.Subtitle)
+		}
+
+		// Add subtitle. These are matched to a video asset once all the files have been processed
+		if psf := parseSubtitleFilename(filename); psf != nil {
+			subtitlesMap[chapter][psf.prefix] = append(
+				subtitlesMap[chapter][psf.prefix],
+				&models.Subtitle{
+					Language: psf.language,
+					Format:   psf.format,
+					Path:     normalizedPath,
+				},
+			)
+
+			continue
+		}
+
 		pfn := parseFilename(filename)
 
 		// Ignore files that are neither assets nor attachments
@@ -326,6 +375,16 @@ func Processor(ctx context.Context, s *CourseScan, scan *models.Scan) error {
 			continue
 		}
 
+		// A link file that cannot be parsed is added as a (file) attachment
+		var link *weblink.Link
+		if pfn.asset != nil && pfn.asset.IsLink() {
+			link = s.parseLink(normalizedPath)
+			if link == nil {
+				pfn.title = pfn.title + filepath.Ext(normalizedPath)
+				pfn.asset = nil
+			}
+		}
+
 		// Add attachment
 		if pfn.asset == nil {
 			attachmentsMap[chapter][pfn.prefix] = append(
@@ -348,6 +407,10 @@ func Processor(ctx context.Context, s *CourseScan, scan *models.Scan) error {
 			Type:     *pfn.asset,
 		}
 
+		if link != nil {
+			newAsset.URL = link.URL
+		}
+
 		existing, exists := assetsMap[chapter][pfn.prefix]
 
 		if !exists {
@@ -361,9 +424,8 @@ func Processor(ctx context.Context, s *CourseScan, scan *models.Scan) error {
 			assetsMap[chapter][pfn.prefix] = newAsset
 		} else {
 			// Check if this new asset has a higher priority than the existing asset. The priority
-			// is video > html > pdf
-			if newAsset.Type.IsVideo() && !existing.Type.IsVideo() ||
-				newAsset.Type.IsHTML() && existing.Type.IsPDF() {
+			// is video > html > epub > notebook > markdown > pdf > slides > text > link
+			if newAsset.Type.Priority() > existing.Type.Priority() {
 
 				// Demote the existing asset to an attachment and add the new asset
 				s.logger.Debug(
@@ -401,16 +463,165 @@ func Processor(ctx context.Context, s *CourseScan, scan *models.Scan) error {
 		}
 	}
 
-	course.CardPath = cardPath
+	// Add the slide decks. As a deck is a directory, it cannot be demoted to an attachment, so it
+	// is ignored when there is an asset of the same or a higher priority
+	deckDirs := make([]string, 0, len(decks))
+	for dir := range decks {
+		deckDirs = append(deckDirs, dir)
+	}
+	sort.Strings(deckDirs)
+
+	for _, dir := range deckDirs {
+		pdn := parseDirname(filepath.Base(dir))
 
-	return s.db.RunInTransaction(ctx, func(txCtx context.Context) error {
-		// Convert the assets map to a slice
-		assets := make([]*models.Asset, 0, len(files))
-		for _, chapterMap := range assetsMap {
-			for _, asset := range chapterMap {
-				assets = append(assets, asset)
+		chapter := ""
+		if parentDir := filepath.Dir(dir); parentDir != utils.NormalizeWindowsDrive(course.Path) {
+			chapter = filepath.Base(parentDir)
+		}
+
+		if _, exists := assetsMap[chapter]; !exists {
+			assetsMap[chapter] = make(map[int]*models.Asset)
+		}
+
+		if _, exists := attachmentsMap[chapter]; !exists {
+			attachmentsMap[chapter] = make(map[int][]*models.Attachment)
+		}
+
+		if _, exists := subtitlesMap[chapter]; !exists {
+			subtitlesMap[chapter] = make(map[int][]*models.Subtitle)
+		}
+
+		newAsset := &models.Asset{
+			Title:    pdn.title,
+			Prefix:   sql.NullInt16{Int16: int16(pdn.prefix), Valid: true},
+			CourseID: course.ID,
+			Chapter:  chapter,
+			Path:     dir,
+		}
+		newAsset.Type.SetSlides()
+
+		existing, exists := assetsMap[chapter][pdn.prefix]
+		if exists && newAsset.Type.Priority() <= existing.Type.Priority() {
+			s.logger.Debug(
+				"Found a slide deck with the same prefix as an asset of the same or a higher priority. Ignoring",
+				loggerType,
+				slog.String("path", scan.CoursePath),
+				slog.String("dir", dir),
+			)
+
+			continue
+		}
+
+		hash, err := slides.Hash(s.appFs.Fs, decks[dir])
+		if err != nil {
+			return err
+		}
+		newAsset.Hash = hash
+
+		assetsMap[chapter][pdn.prefix] = newAsset
+
+		// Demote the existing asset to an attachment
+		if exists {
+			attachmentsMap[chapter][pdn.prefix] = append(
+				attachmentsMap[chapter][pdn.prefix],
+				&models.Attachment{
+					Title: existing.Title + filepath.Ext(existing.Path),
+					Path:  existing.Path,
+				},
+			)
+		}
+	}
+
+	// Set the target of link attachments. The title of the link is used when it has one
+	for _, chapterAttachments := range attachmentsMap {
+		for _, attachments := range chapterAttachments {
+			for _, attachment := range attachments {
+				if !weblink.IsLink(attachment.Path) {
+					continue
+				}
+
+				link := s.parseLink(attachment.Path)
+				if link == nil {
+					continue
+				}
+
+				attachment.URL = link.URL
+				attachment.Title = strings.TrimSuffix(attachment.Title, filepath.Ext(attachment.Path))
+				if link.Title != "" {
+					attachment.Title = link.Title
+				}
+			}
+		}
+	}
+
+	// Keep a card set through the API, unless it has since been removed
+	if course.CardOverride {
+		if exists, err := afero.Exists(s.appFs.Fs, course.CardPath); err != nil || !exists {
+			s.logger.Debug(
+				"Course card override no longer exists. Reverting to detection",
+				loggerType,
+				slog.String("card", course.CardPath),
+				slog.String("path", scan.CoursePath),
+			)
+
+			course.CardOverride = false
+		}
+	}
+
+	if !course.CardOverride {
+		course.CardPath = cardPath
+	}
+
+	// Subtitles can only be added to a video asset and each language can only be added once. When
+	// this is not the case, add the subtitle as an attachment
+	for chapter, chapterSubtitles := range subtitlesMap {
+		for prefix, potentialSubtitles := range chapterSubtitles {
+			asset, exists := assetsMap[chapter][prefix]
+			languages := map[string]bool{}
+			subtitles := []*models.Subtitle{}
+
+			for _, subtitle := range potentialSubtitles {
+				if exists && asset.Type.IsVideo() && !languages[subtitle.Language] {
+					languages[subtitle.Language] = true
+					subtitles = append(subtitles, subtitle)
+					continue
+				}
+
+				s.logger.Debug(
+					"Subtitle does not match a video asset or is a duplicate language. Adding as attachment",
+					loggerType,
+					slog.String("path", scan.CoursePath),
+					slog.String("file", subtitle.Path),
+				)
+
+				attachmentsMap[chapter][prefix] = append(
+					attachmentsMap[chapter][prefix],
+					&models.Attachment{
+						Title: parseSubtitleFilename(filepath.Base(subtitle.Path)).title,
+						Path:  subtitle.Path,
+					},
+				)
+			}
+
+			chapterSubtitles[prefix] = subtitles
+		}
+	}
+
+	// Convert the assets map to a slice and extract the media information and chapter markers for
+	// video assets
+	assets := make([]*models.Asset, 0, len(files))
+	markersMap := map[*models.Asset][]*models.ChapterMarker{}
+	for _, chapterMap := range assetsMap {
+		for _, asset := range chapterMap {
+			if asset.Type.IsVideo() {
+				markersMap[asset] = s.probeMedia(asset)
 			}
+
+			assets = append(assets, asset)
 		}
+	}
+
+	return s.db.RunInTransaction(ctx, func(txCtx context.Context) error {
 
 		// Update the assets in DB
 		if len(assets) > 0 {
@@ -447,6 +658,44 @@ func Processor(ctx context.Context, s *CourseScan, scan *models.Scan) error {
 			}
 		}
 
+		// Convert the subtitles map to a slice
+		subtitles := []*models.Subtitle{}
+		for chapter, chapterSubtitles := range subtitlesMap {
+			for prefix, assetSubtitles := range chapterSubtitles {
+				for _, subtitle := range assetSubtitles {
+					subtitle.AssetID = assetsMap[chapter][prefix].ID
+					subtitles = append(subtitles, subtitle)
+				}
+			}
+		}
+
+		// Update the subtitles in DB. This also runs when there are no subtitles on disk, so that
+		// removed subtitles are deleted
+		if len(ids) > 0 {
+			err = updateSubtitles(txCtx, s.dao, ids, subtitles)
+			if err != nil {
+				return err
+			}
+
+			err = s.indexTranscripts(txCtx, ids)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Update the chapter markers in DB. The assets have been created or updated, so their IDs
+		// are set
+		for asset, markers := range markersMap {
+			if asset.ID == "" {
+				continue
+			}
+
+			err = s.dao.ReplaceChapterMarkers(txCtx, asset.ID, markers)
+			if err != nil {
+				return err
+			}
+		}
+
 		err = s.dao.UpdateCourse(txCtx, course)
 		if err != nil {
 			return err
@@ -460,6 +709,123 @@ func Processor(ctx context.Context, s *CourseScan, scan *models.Scan) error {
 // PRIVATE
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// probeMedia parses the container headers of an asset and sets the duration, resolution, codecs
+// and audio track count. The embedded chapter markers are returned. Failures are logged and the
+// asset is left without media information, as an unparsable file should not fail the scan
+func (s *CourseScan) probeMedia(asset *models.Asset) []*models.ChapterMarker {
+	file, err := s.appFs.Fs.Open(asset.Path)
+	if err != nil {
+		s.logger.Debug(
+			"Failed to open asset for media probing",
+			loggerType,
+			slog.String("file", asset.Path),
+			slog.String("error", err.Error()),
+		)
+
+		return nil
+	}
+	defer file.Close()
+
+	meta, err := media.Probe(file)
+	if err != nil {
+		s.logger.Debug(
+			"Unable to extract media information",
+			loggerType,
+			slog.String("file", asset.Path),
+			slog.String("error", err.Error()),
+		)
+
+		return nil
+	}
+
+	asset.Duration = meta.Seconds()
+	asset.Width = meta.Width
+	asset.Height = meta.Height
+	asset.VideoCodec = meta.VideoCodec
+	asset.AudioCodec = meta.AudioCodec
+	asset.AudioTracks = meta.AudioTracks
+
+	markers := make([]*models.ChapterMarker, 0, len(meta.Chapters))
+	for _, chapter := range meta.Chapters {
+		markers = append(markers, &models.ChapterMarker{Title: chapter.Title, Start: int(chapter.Start)})
+	}
+
+	return markers
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// indexTranscripts parses the subtitles of the given assets that have not been indexed and adds
+// their cues to the transcript index. Subtitles that cannot be read or parsed are logged and
+// skipped, as a broken subtitle should not fail the scan
+func (s *CourseScan) indexTranscripts(ctx context.Context, assetIDs []string) error {
+	unindexed, err := s.dao.ListUnindexedSubtitles(ctx, assetIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, subtitle := range unindexed {
+		file, err := s.appFs.Fs.Open(subtitle.Path)
+		if err != nil {
+			s.logger.Debug(
+				"Failed to open subtitle for indexing",
+				loggerType,
+				slog.String("file", subtitle.Path),
+				slog.String("error", err.Error()),
+			)
+
+			continue
+		}
+
+		cues, err := subtitles.Parse(file, subtitle.Format)
+		file.Close()
+
+		if err != nil {
+			s.logger.Debug(
+				"Unable to parse subtitle for indexing",
+				loggerType,
+				slog.String("file", subtitle.Path),
+				slog.String("error", err.Error()),
+			)
+
+			continue
+		}
+
+		transcript := make([]*dao.TranscriptCue, 0, len(cues))
+		for _, cue := range cues {
+			transcript = append(transcript, &dao.TranscriptCue{Start: cue.Start, End: cue.End, Text: cue.PlainText()})
+		}
+
+		if err := s.dao.IndexTranscript(ctx, subtitle, transcript); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseLink parses a link file (.url, .webloc or .desktop). Failures are logged and nil is
+// returned, as an unparsable link file should not fail the scan
+func (s *CourseScan) parseLink(path string) *weblink.Link {
+	link, err := weblink.Open(s.appFs.Fs, path)
+	if err != nil {
+		s.logger.Debug(
+			"Unable to parse link file",
+			loggerType,
+			slog.String("file", path),
+			slog.String("error", err.Error()),
+		)
+
+		return nil
+	}
+
+	return link
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 // parsedFilename that holds information following a filename being parsed
 type parsedFilename struct {
 	prefix int
@@ -538,6 +904,211 @@ func parseFilename(filename string) *parsedFilename {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// parsedSubtitleFilename holds information following a subtitle filename being parsed
+type parsedSubtitleFilename struct {
+	prefix   int
+	title    string
+	language string
+	format   string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The language of a subtitle file without a language code in its name
+const defaultSubtitleLanguage = "und"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// A regex for parsing a subtitle file name into a prefix, title, language and extension
+//
+// Valid patterns:
+//
+//	 `<prefix>.<ext>`
+//	 `<prefix>.<lang>.<ext>`
+//	 `<prefix> <title>.<ext>`
+//	 `<prefix> <title>.<lang>.<ext>`
+//
+//	- <prefix> is required and must be a number
+//	- <title> is optional and follows the same rules as `filenameRegex`
+//	- <lang> is optional and is a 2 or 3 letter language code with an optional region, such as
+//	  `en`, `fre` or `pt-BR`
+//	- <ext> is required and must be a supported subtitle format
+var subtitleFilenameRegex = regexp.MustCompile(`(?i)^\s*(?P<Prefix>[0-9]+)(?:(?:\s+-+\s+|\s+-+|\s+|-+\s*)(?P<Title>[^.][^.]*))?(?:\.(?P<Lang>[a-z]{2,3}(?:[-_][a-z0-9]{2,4})?))?\.(?P<Ext>srt|vtt|ass|ssa)$`)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseSubtitleFilename parses a file name and determines if it represents a subtitle. When the
+// file name does not include a language, the language is `und` (undetermined). The title is the
+// file name without the prefix, which is used when the subtitle is added as an attachment
+//
+// When the file is not a subtitle, nil is returned
+func parseSubtitleFilename(filename string) *parsedSubtitleFilename {
+	matches := subtitleFilenameRegex.FindStringSubmatchIndex(filename)
+	if matches == nil {
+		return nil
+	}
+
+	group := func(name string) string {
+		idx := subtitleFilenameRegex.SubexpIndex(name)
+		if matches[2*idx] < 0 {
+			return ""
+		}
+
+		return filename[matches[2*idx]:matches[2*idx+1]]
+	}
+
+	prefix, err := strconv.Atoi(group("Prefix"))
+	if err != nil {
+		return nil
+	}
+
+	psf := &parsedSubtitleFilename{
+		prefix:   prefix,
+		title:    filename,
+		language: strings.ReplaceAll(strings.ToLower(group("Lang")), "_", "-"),
+		format:   strings.ToLower(group("Ext")),
+	}
+
+	// The title runs from the start of <title> to the end of the file name
+	if group("Title") != "" {
+		psf.title = filename[matches[2*subtitleFilenameRegex.SubexpIndex("Title")]:]
+	}
+
+	if psf.language == "" {
+		psf.language = defaultSubtitleLanguage
+	}
+
+	return psf
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parsedDirname holds information following a slide deck directory name being parsed
+type parsedDirname struct {
+	prefix int
+	title  string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// A regex for parsing a slide deck directory name into a prefix and title. It follows the same
+// rules as `filenameRegex`, except there is no extension so the title can include dots
+var dirnameRegex = regexp.MustCompile(`^\s*(?P<Prefix>[0-9]+)(?:(?:\s+-+\s+|\s+-+|\s+|-+\s*)(?P<Title>.+))?$`)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseDirname parses a directory name into a prefix and title. When there is no title, the
+// directory name is used. When the directory name has no prefix, nil is returned
+func parseDirname(dirname string) *parsedDirname {
+	matches := dirnameRegex.FindStringSubmatch(dirname)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	prefix, err := strconv.Atoi(matches[dirnameRegex.SubexpIndex("Prefix")])
+	if err != nil {
+		return nil
+	}
+
+	pdn := &parsedDirname{
+		prefix: prefix,
+		title:  strings.TrimSpace(matches[dirnameRegex.SubexpIndex("Title")]),
+	}
+
+	if pdn.title == "" {
+		pdn.title = dirname
+	}
+
+	return pdn
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// findSlideDecks finds the slide decks in a course. A slide deck is a prefixed directory, in the
+// root of the course or within a chapter, with no subdirectories and whose (non-hidden) files are
+// all images
+//
+// The decks are returned as a map of directory to ordered slides. The remaining files are also
+// returned, which excludes the slides and any other files deeper than a chapter
+func findSlideDecks(fs afero.Fs, coursePath string, files []string) (map[string][]string, []string, error) {
+	filesByDir := map[string][]string{}
+	nonDecks := map[string]bool{}
+
+	for _, fp := range files {
+		normalizedPath := utils.NormalizeWindowsDrive(fp)
+		dir := filepath.Dir(normalizedPath)
+
+		if dir == coursePath {
+			continue
+		}
+
+		filesByDir[dir] = append(filesByDir[dir], normalizedPath)
+
+		if !slides.IsHidden(normalizedPath) && !slides.IsImage(normalizedPath) {
+			nonDecks[dir] = true
+		}
+	}
+
+	decks := map[string][]string{}
+	for dir, dirFiles := range filesByDir {
+		if nonDecks[dir] || parseDirname(filepath.Base(dir)) == nil {
+			continue
+		}
+
+		images := []string{}
+		for _, fp := range dirFiles {
+			if !slides.IsHidden(fp) {
+				images = append(images, fp)
+			}
+		}
+
+		if len(images) == 0 {
+			continue
+		}
+
+		// A directory with a subdirectory is not a deck
+		entries, err := afero.ReadDir(fs, dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if slices.ContainsFunc(entries, func(entry os.FileInfo) bool { return entry.IsDir() }) {
+			continue
+		}
+
+		slides.Sort(images)
+		decks[dir] = images
+	}
+
+	remaining := make([]string, 0, len(files))
+	for _, fp := range files {
+		dir := filepath.Dir(utils.NormalizeWindowsDrive(fp))
+
+		if _, isDeck := decks[dir]; isDeck || dirDepth(coursePath, dir) > 1 {
+			continue
+		}
+
+		remaining = append(remaining, fp)
+	}
+
+	return decks, remaining, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// dirDepth returns the depth of a directory within the course, where the course directory is 0
+// and a chapter is 1
+func dirDepth(coursePath, dir string) int {
+	rel, err := filepath.Rel(coursePath, dir)
+	if err != nil || rel == "." {
+		return 0
+	}
+
+	return len(strings.Split(rel, string(filepath.Separator)))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 // isCard determines if a given file name represents a card based on its name and extension
 func isCard(filename string) bool {
 	// Get the extension. If there is no extension, return false
@@ -584,18 +1155,19 @@ func updateAssets(ctx context.Context, dao *dao.DAO, courseId string, assets []*
 		return err
 	}
 
-	// Add assets
-	// TODO: This could be optimized by using a bulk insert
-	for _, asset := range toAdd {
-		if err := dao.CreateAsset(ctx, asset); err != nil {
+	// Delete assets. This happens before adding assets, as a changed asset (such as a slide deck
+	// with a new slide) has a new hash but the same path
+	for _, deleteAsset := range toDelete {
+		err := dao.Delete(ctx, deleteAsset, nil)
+		if err != nil {
 			return err
 		}
 	}
 
-	// Delete assets
-	for _, deleteAsset := range toDelete {
-		err := dao.Delete(ctx, deleteAsset, nil)
-		if err != nil {
+	// Add assets
+	// TODO: This could be optimized by using a bulk insert
+	for _, asset := range toAdd {
+		if err := dao.CreateAsset(ctx, asset); err != nil {
 			return err
 		}
 	}
@@ -675,5 +1247,63 @@ func updateAttachments(ctx context.Context, dao *dao.DAO, assetIDs []string, att
 		}
 	}
 
+	// Update the attachments whose information has changed, such as the target of a link
+	existingAttachmentsMap := make(map[string]*models.Attachment)
+	for _, existingAttachment := range existingAttachments {
+		existingAttachmentsMap[existingAttachment.Path] = existingAttachment
+	}
+
+	for _, attachment := range attachments {
+		existingAttachment, exists := existingAttachmentsMap[attachment.Path]
+		if !exists {
+			continue
+		}
+
+		if existingAttachment.Title == attachment.Title && existingAttachment.URL == attachment.URL {
+			continue
+		}
+
+		attachment.ID = existingAttachment.ID
+		if err := dao.UpdateAttachment(ctx, attachment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// updateSubtitles updates the subtitles in the database based on the subtitles found on disk. It
+// compares the existing subtitles in the database with the subtitles found on disk, and performs the
+// necessary additions and deletions
+func updateSubtitles(ctx context.Context, dao *dao.DAO, assetIDs []string, subtitles []*models.Subtitle) error {
+	existingSubtitles := []*models.Subtitle{}
+	err := dao.List(ctx, &existingSubtitles, &database.Options{Where: squirrel.Eq{models.SUBTITLE_TABLE + ".asset_id": assetIDs}})
+	if err != nil {
+		return err
+	}
+
+	// Compare the subtitles found on disk to subtitles found in DB
+	toAdd, toDelete, err := utils.DiffSliceOfStructsByKey(subtitles, existingSubtitles, "Path")
+	if err != nil {
+		return err
+	}
+
+	// Delete subtitles first as a subtitle may be replaced by another file of the same language
+	for _, subtitle := range toDelete {
+		err := dao.Delete(ctx, subtitle, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add subtitles
+	for _, subtitle := range toAdd {
+		if err := dao.CreateSubtitle(ctx, subtitle); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -3,6 +3,7 @@ package coursescan
 import (
 	"context"
 	"database/sql"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -302,6 +303,46 @@ func TestScanner_Processor(t *testing.T) {
 		require.Equal(t, filepath.Join(course.Path, "card.jpg"), courseResult.CardPath)
 	})
 
+	t.Run("card override", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		scan := &models.Scan{CourseID: course.ID, Status: types.NewScanStatusWaiting()}
+		require.NoError(t, scanner.dao.CreateScan(ctx, scan))
+
+		scanner.appFs.Fs.Mkdir(course.Path, os.ModePerm)
+		scanner.appFs.Fs.Create(filepath.Join(course.Path, "card.jpg"))
+		scanner.appFs.Fs.Create(filepath.Join(course.Path, "01 Chapter 1", "cover.png"))
+
+		course.CardPath = filepath.Join(course.Path, "01 Chapter 1", "cover.png")
+		course.CardOverride = true
+		require.NoError(t, scanner.dao.UpdateCourse(ctx, course))
+
+		// The override is kept
+		err := Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		courseResult := &models.Course{Base: models.Base{ID: course.ID}}
+		err = scanner.dao.GetById(ctx, courseResult)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(course.Path, "01 Chapter 1", "cover.png"), courseResult.CardPath)
+		require.True(t, courseResult.CardOverride)
+
+		// Revert to detection when the override is removed from disk
+		scanner.appFs.Fs.Remove(filepath.Join(course.Path, "01 Chapter 1", "cover.png"))
+
+		err = Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		courseResult = &models.Course{Base: models.Base{ID: course.ID}}
+		err = scanner.dao.GetById(ctx, courseResult)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(course.Path, "card.jpg"), courseResult.CardPath)
+		require.False(t, courseResult.CardOverride)
+	})
+
 	t.Run("ignore files", func(t *testing.T) {
 		scanner, ctx, _ := setup(t)
 
@@ -450,7 +491,7 @@ func TestScanner_Processor(t *testing.T) {
 		require.Equal(t, "ca934260de4b6eb696e4e9912447bc7f2bd7b614da6879b7addef8e03dca71d1", assets[0].Hash)
 
 		// Add attachment
-		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 file 1.txt", course.Path), []byte("file 1"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 file 1.zip", course.Path), []byte("file 1"), os.ModePerm)
 
 		err = Processor(ctx, scanner, scan)
 		require.NoError(t, err)
@@ -465,12 +506,12 @@ func TestScanner_Processor(t *testing.T) {
 		require.NoError(t, err)
 		require.Len(t, attachments, 1)
 
-		require.Equal(t, "file 1.txt", attachments[0].Title)
+		require.Equal(t, "file 1.zip", attachments[0].Title)
 		require.Equal(t, assets[0].ID, attachments[0].AssetID)
-		require.Equal(t, filepath.Join(course.Path, "01 file 1.txt"), attachments[0].Path)
+		require.Equal(t, filepath.Join(course.Path, "01 file 1.zip"), attachments[0].Path)
 
 		// Add another attachment
-		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 file 2.txt", course.Path), []byte("file 2"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 file 2.zip", course.Path), []byte("file 2"), os.ModePerm)
 
 		err = Processor(ctx, scanner, scan)
 		require.NoError(t, err)
@@ -479,16 +520,16 @@ func TestScanner_Processor(t *testing.T) {
 		require.NoError(t, err)
 		require.Len(t, attachments, 2)
 
-		require.Equal(t, "file 1.txt", attachments[0].Title)
+		require.Equal(t, "file 1.zip", attachments[0].Title)
 		require.Equal(t, assets[0].ID, attachments[0].AssetID)
-		require.Equal(t, filepath.Join(course.Path, "01 file 1.txt"), attachments[0].Path)
+		require.Equal(t, filepath.Join(course.Path, "01 file 1.zip"), attachments[0].Path)
 
-		require.Equal(t, "file 2.txt", attachments[1].Title)
+		require.Equal(t, "file 2.zip", attachments[1].Title)
 		require.Equal(t, assets[0].ID, attachments[0].AssetID)
-		require.Equal(t, filepath.Join(course.Path, "01 file 2.txt"), attachments[1].Path)
+		require.Equal(t, filepath.Join(course.Path, "01 file 2.zip"), attachments[1].Path)
 
 		// Delete attachment
-		scanner.appFs.Fs.Remove(fmt.Sprintf("%s/01 file 1.txt", course.Path))
+		scanner.appFs.Fs.Remove(fmt.Sprintf("%s/01 file 1.zip", course.Path))
 
 		err = Processor(ctx, scanner, scan)
 		require.NoError(t, err)
@@ -497,9 +538,9 @@ func TestScanner_Processor(t *testing.T) {
 		require.NoError(t, err)
 		require.Len(t, attachments, 1)
 
-		require.Equal(t, "file 2.txt", attachments[0].Title)
+		require.Equal(t, "file 2.zip", attachments[0].Title)
 		require.Equal(t, assets[0].ID, attachments[0].AssetID)
-		require.Equal(t, filepath.Join(course.Path, "01 file 2.txt"), attachments[0].Path)
+		require.Equal(t, filepath.Join(course.Path, "01 file 2.zip"), attachments[0].Path)
 	})
 
 	t.Run("asset priority", func(t *testing.T) {
@@ -617,6 +658,396 @@ func TestScanner_Processor(t *testing.T) {
 		require.Equal(t, filepath.Join(course.Path, "01 index.html"), attachments[1].Path)
 		require.Equal(t, filepath.Join(course.Path, "01 doc 2.pdf"), attachments[2].Path)
 	})
+
+	t.Run("document priority", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		// ----------------------------
+		// Priority is NOTEBOOK -> MARKDOWN -> PDF -> TEXT
+		// ----------------------------
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		scan := &models.Scan{CourseID: course.ID, Status: types.NewScanStatusWaiting()}
+		require.NoError(t, scanner.dao.CreateScan(ctx, scan))
+
+		scanner.appFs.Fs.Mkdir(course.Path, os.ModePerm)
+
+		assetOptions := &database.Options{
+			Where: squirrel.Eq{models.ASSET_TABLE + ".course_id": course.ID},
+		}
+
+		var tests = []struct {
+			filename    string
+			title       string
+			expected    types.AssetType
+			attachments int
+		}{
+			{"01 notes.txt", "notes", types.AssetText, 0},
+			{"01 slides.pdf", "slides", types.AssetPDF, 1},
+			{"01 readme.md", "readme", types.AssetMarkdown, 2},
+			{"01 lab.ipynb", "lab", types.AssetNotebook, 3},
+			{"01 other.txt", "lab", types.AssetNotebook, 4},
+		}
+
+		for _, tt := range tests {
+			afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/%s", course.Path, tt.filename), []byte(tt.filename), os.ModePerm)
+
+			err := Processor(ctx, scanner, scan)
+			require.NoError(t, err)
+
+			assets := []*models.Asset{}
+			err = scanner.dao.List(ctx, &assets, assetOptions)
+			require.NoError(t, err)
+			require.Len(t, assets, 1, tt.filename)
+
+			require.Equal(t, tt.title, assets[0].Title, tt.filename)
+			require.Equal(t, string(tt.expected), assets[0].Type.String(), tt.filename)
+			require.Len(t, assets[0].Attachments, tt.attachments, tt.filename)
+		}
+	})
+
+	t.Run("slides", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		scan := &models.Scan{CourseID: course.ID, Status: types.NewScanStatusWaiting()}
+		require.NoError(t, scanner.dao.CreateScan(ctx, scan))
+
+		for _, fp := range []string{
+			"01 diagram.png",
+			"02 notes.txt",
+			"02 Deck/1.png",
+			"02 Deck/2.png",
+			"Chapter 1/03 Deck 1.5/slide10.png",
+			"Chapter 1/03 Deck 1.5/slide2.png",
+			"Chapter 1/03 Deck 1.5/slide1.png",
+			"Chapter 1/03 Deck 1.5/.DS_Store",
+			"Chapter 1/04 Mixed/1.png",
+			"Chapter 1/04 Mixed/2.zip",
+			"Chapter 1/05 Nested/1.png",
+			"Chapter 1/05 Nested/sub/1.png",
+		} {
+			require.NoError(t, afero.WriteFile(scanner.appFs.Fs, filepath.Join(course.Path, fp), []byte(fp), os.ModePerm))
+		}
+
+		assetOptions := &database.Options{
+			OrderBy: []string{models.ASSET_TABLE + ".chapter asc", models.ASSET_TABLE + ".prefix asc"},
+			Where:   squirrel.Eq{models.ASSET_TABLE + ".course_id": course.ID},
+		}
+
+		// Directories are not grouped by default
+		require.NoError(t, Processor(ctx, scanner, scan))
+
+		assets := []*models.Asset{}
+		require.NoError(t, scanner.dao.List(ctx, &assets, assetOptions))
+		require.Len(t, assets, 2)
+
+		require.Equal(t, "diagram", assets[0].Title)
+		require.Equal(t, string(types.AssetSlides), assets[0].Type.String())
+		require.Equal(t, filepath.Join(course.Path, "01 diagram.png"), assets[0].Path)
+
+		require.Equal(t, "notes", assets[1].Title)
+		require.Equal(t, string(types.AssetText), assets[1].Type.String())
+
+		// Group directories
+		require.NoError(t, scanner.dao.UpdateSlidesGroupDirectories(ctx, true))
+		require.NoError(t, Processor(ctx, scanner, scan))
+
+		assets = []*models.Asset{}
+		require.NoError(t, scanner.dao.List(ctx, &assets, assetOptions))
+		require.Len(t, assets, 3)
+
+		require.Equal(t, "diagram", assets[0].Title)
+		require.Empty(t, assets[0].Chapter)
+
+		// The deck has a higher priority than the text asset
+		require.Equal(t, "Deck", assets[1].Title)
+		require.Empty(t, assets[1].Chapter)
+		require.Equal(t, string(types.AssetSlides), assets[1].Type.String())
+		require.Equal(t, filepath.Join(course.Path, "02 Deck"), assets[1].Path)
+		require.NotEmpty(t, assets[1].Hash)
+		require.Len(t, assets[1].Attachments, 1)
+		require.Equal(t, filepath.Join(course.Path, "02 notes.txt"), assets[1].Attachments[0].Path)
+
+		require.Equal(t, "Deck 1.5", assets[2].Title)
+		require.Equal(t, "Chapter 1", assets[2].Chapter)
+		require.Equal(t, string(types.AssetSlides), assets[2].Type.String())
+		require.Equal(t, filepath.Join(course.Path, "Chapter 1", "03 Deck 1.5"), assets[2].Path)
+
+		// Adding a slide changes the hash of the deck
+		hash := assets[1].Hash
+		require.NoError(t, afero.WriteFile(scanner.appFs.Fs, filepath.Join(course.Path, "02 Deck", "3.png"), []byte("3"), os.ModePerm))
+		require.NoError(t, Processor(ctx, scanner, scan))
+
+		assets = []*models.Asset{}
+		require.NoError(t, scanner.dao.List(ctx, &assets, assetOptions))
+		require.Len(t, assets, 3)
+		require.NotEqual(t, hash, assets[1].Hash)
+	})
+
+	t.Run("links", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		scan := &models.Scan{CourseID: course.ID, Status: types.NewScanStatusWaiting()}
+		require.NoError(t, scanner.dao.CreateScan(ctx, scan))
+
+		for fp, content := range map[string]string{
+			"01 Docs.url":          "[InternetShortcut]\nURL=https://example.com/docs\n",
+			"02 video.mp4":         "video",
+			"02 Source.webloc":     `<plist><dict><key>URL</key><string>https://example.com/src</string></dict></plist>`,
+			"02 launcher.desktop":  "[Desktop Entry]\nType=Link\nName=Homepage\nURL=https://example.com\n",
+			"02 broken.url":        "[InternetShortcut]\nURL=javascript:alert(1)\n",
+			"03 Unparsable.webloc": "garbage",
+		} {
+			require.NoError(t, afero.WriteFile(scanner.appFs.Fs, filepath.Join(course.Path, fp), []byte(content), os.ModePerm))
+		}
+
+		require.NoError(t, Processor(ctx, scanner, scan))
+
+		assets := []*models.Asset{}
+		require.NoError(t, scanner.dao.List(ctx, &assets, &database.Options{
+			OrderBy: []string{models.ASSET_TABLE + ".prefix asc"},
+			Where:   squirrel.Eq{models.ASSET_TABLE + ".course_id": course.ID},
+		}))
+
+		// The unparsable link file is not an asset
+		require.Len(t, assets, 2)
+
+		require.Equal(t, "Docs", assets[0].Title)
+		require.Equal(t, string(types.AssetLink), assets[0].Type.String())
+		require.Equal(t, "https://example.com/docs", assets[0].URL)
+
+		require.Equal(t, string(types.AssetVideo), assets[1].Type.String())
+		require.Len(t, assets[1].Attachments, 3)
+
+		attachments := map[string]*models.Attachment{}
+		for _, attachment := range assets[1].Attachments {
+			attachments[filepath.Base(attachment.Path)] = attachment
+		}
+
+		require.Equal(t, "Source", attachments["02 Source.webloc"].Title)
+		require.Equal(t, "https://example.com/src", attachments["02 Source.webloc"].URL)
+
+		require.Equal(t, "Homepage", attachments["02 launcher.desktop"].Title)
+		require.Equal(t, "https://example.com", attachments["02 launcher.desktop"].URL)
+
+		// A link that cannot be parsed is a file attachment
+		require.Equal(t, "broken.url", attachments["02 broken.url"].Title)
+		require.Empty(t, attachments["02 broken.url"].URL)
+	})
+
+	t.Run("media", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		scan := &models.Scan{CourseID: course.ID, Status: types.NewScanStatusWaiting()}
+		require.NoError(t, scanner.dao.CreateScan(ctx, scan))
+
+		box := func(typ string, payload []byte) []byte {
+			b := make([]byte, 8, 8+len(payload))
+			binary.BigEndian.PutUint32(b[0:4], uint32(8+len(payload)))
+			copy(b[4:8], typ)
+			return append(b, payload...)
+		}
+
+		// A MP4 with a 90 second duration (timescale 1000) and no tracks
+		mvhd := make([]byte, 100)
+		binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+		binary.BigEndian.PutUint32(mvhd[16:20], 90000)
+		mp4 := append(box("ftyp", []byte("isom\x00\x00\x00\x00")), box("moov", box("mvhd", mvhd))...)
+
+		scanner.appFs.Fs.Mkdir(course.Path, os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video 1.mp4", course.Path), mp4, os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/02 video 2.mp4", course.Path), []byte("not a video"), os.ModePerm)
+
+		err := Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		options := &database.Options{
+			OrderBy: []string{models.ASSET_TABLE + ".prefix asc"},
+			Where:   squirrel.Eq{models.ASSET_TABLE + ".course_id": course.ID},
+		}
+
+		assets := []*models.Asset{}
+		err = scanner.dao.List(ctx, &assets, options)
+		require.NoError(t, err)
+		require.Len(t, assets, 2)
+
+		require.Equal(t, 90, assets[0].Duration)
+
+		// Unparsable files do not fail the scan
+		require.Zero(t, assets[1].Duration)
+		require.Empty(t, assets[1].VideoCodec)
+	})
+
+	t.Run("chapter markers", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		scan := &models.Scan{CourseID: course.ID, Status: types.NewScanStatusWaiting()}
+		require.NoError(t, scanner.dao.CreateScan(ctx, scan))
+
+		box := func(typ string, payload []byte) []byte {
+			b := make([]byte, 8, 8+len(payload))
+			binary.BigEndian.PutUint32(b[0:4], uint32(8+len(payload)))
+			copy(b[4:8], typ)
+			return append(b, payload...)
+		}
+
+		// A MP4 with a Nero chapter list. Start times are in 100 nanosecond units
+		chpl := []byte{0, 0, 0, 0, 2}
+		for i, title := range []string{"Intro", "Install"} {
+			start := make([]byte, 8)
+			binary.BigEndian.PutUint64(start, uint64(i)*300000000)
+			chpl = append(append(append(chpl, start...), byte(len(title))), title...)
+		}
+
+		mp4 := append(box("ftyp", []byte("isom\x00\x00\x00\x00")), box("moov", box("udta", box("chpl", chpl)))...)
+
+		scanner.appFs.Fs.Mkdir(course.Path, os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.mp4", course.Path), mp4, os.ModePerm)
+
+		err := Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		assets := []*models.Asset{}
+		err = scanner.dao.List(ctx, &assets, &database.Options{Where: squirrel.Eq{models.ASSET_TABLE + ".course_id": course.ID}})
+		require.NoError(t, err)
+		require.Len(t, assets, 1)
+
+		markers, err := scanner.dao.ListChapterMarkers(ctx, assets[0].ID)
+		require.NoError(t, err)
+		require.Len(t, markers, 2)
+		require.Equal(t, "Intro", markers[0].Title)
+		require.Zero(t, markers[0].Start)
+		require.Equal(t, "Install", markers[1].Title)
+		require.Equal(t, 30000, markers[1].Start)
+
+		// Rescanning keeps the markers
+		err = Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		rescanned, err := scanner.dao.ListChapterMarkers(ctx, assets[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, markers[0].ID, rescanned[0].ID)
+	})
+
+	t.Run("subtitles", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		scan := &models.Scan{CourseID: course.ID, Status: types.NewScanStatusWaiting()}
+		require.NoError(t, scanner.dao.CreateScan(ctx, scan))
+
+		scanner.appFs.Fs.Mkdir(course.Path, os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.mp4", course.Path), []byte("video"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.en.srt", course.Path), []byte("en"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.fr.vtt", course.Path), []byte("fr"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.vtt", course.Path), []byte("und"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.en.vtt", course.Path), []byte("duplicate"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/02 doc.pdf", course.Path), []byte("doc"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/02 doc.en.srt", course.Path), []byte("not video"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/03 orphan.srt", course.Path), []byte("orphan"), os.ModePerm)
+
+		err := Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		options := &database.Options{
+			OrderBy: []string{models.ASSET_TABLE + ".prefix asc"},
+			Where:   squirrel.Eq{models.ASSET_TABLE + ".course_id": course.ID},
+		}
+
+		assets := []*models.Asset{}
+		err = scanner.dao.List(ctx, &assets, options)
+		require.NoError(t, err)
+		require.Len(t, assets, 2)
+
+		// Video asset
+		require.Len(t, assets[0].Subtitles, 3)
+
+		languages := map[string]*models.Subtitle{}
+		for _, subtitle := range assets[0].Subtitles {
+			languages[subtitle.Language] = subtitle
+		}
+
+		require.Equal(t, "srt", languages["en"].Format)
+		require.Equal(t, filepath.Join(course.Path, "01 video.en.srt"), languages["en"].Path)
+		require.Equal(t, "vtt", languages["fr"].Format)
+		require.Equal(t, filepath.Join(course.Path, "01 video.vtt"), languages["und"].Path)
+
+		// The duplicate language is added as an attachment
+		require.Len(t, assets[0].Attachments, 1)
+		require.Equal(t, "video.en.vtt", assets[0].Attachments[0].Title)
+
+		// Subtitles of non-video assets are added as attachments
+		require.Empty(t, assets[1].Subtitles)
+		require.Len(t, assets[1].Attachments, 1)
+		require.Equal(t, "doc.en.srt", assets[1].Attachments[0].Title)
+
+		// Remove a subtitle
+		scanner.appFs.Fs.Remove(fmt.Sprintf("%s/01 video.fr.vtt", course.Path))
+
+		err = Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		err = scanner.dao.List(ctx, &assets, options)
+		require.NoError(t, err)
+		require.Len(t, assets[0].Subtitles, 2)
+	})
+
+	t.Run("transcripts", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		scan := &models.Scan{CourseID: course.ID, Status: types.NewScanStatusWaiting()}
+		require.NoError(t, scanner.dao.CreateScan(ctx, scan))
+
+		srt := "1\n00:00:01,000 --> 00:00:02,500\n<i>Hello</i> world\n\n2\n00:01:05,000 --> 00:01:07,000\nConfigure the server\n"
+
+		scanner.appFs.Fs.Mkdir(course.Path, os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.mp4", course.Path), []byte("video"), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.en.srt", course.Path), []byte(srt), os.ModePerm)
+		afero.WriteFile(scanner.appFs.Fs, fmt.Sprintf("%s/01 video.fr.srt", course.Path), []byte("invalid"), os.ModePerm)
+
+		err := Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		matches, err := scanner.dao.SearchTranscripts(ctx, "server", nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		require.Equal(t, int64(65000), matches[0].Start)
+		require.Equal(t, "en", matches[0].Language)
+
+		matches, err = scanner.dao.SearchTranscripts(ctx, "hello world", nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		require.Equal(t, "Hello world", matches[0].Text)
+
+		// Removing the subtitle removes its transcript
+		scanner.appFs.Fs.Remove(fmt.Sprintf("%s/01 video.en.srt", course.Path))
+
+		err = Processor(ctx, scanner, scan)
+		require.NoError(t, err)
+
+		matches, err = scanner.dao.SearchTranscripts(ctx, "server", nil)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+	})
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -661,6 +1092,15 @@ func TestScanner_parseFilename(t *testing.T) {
 			{"0100 file.mp3", &parsedFilename{prefix: 100, title: "file", asset: types.NewAsset("mp3")}},
 			// PDF
 			{"1 - doc.pdf", &parsedFilename{prefix: 1, title: "doc", asset: types.NewAsset("pdf")}},
+			// Markdown
+			{"1 - readme.md", &parsedFilename{prefix: 1, title: "readme", asset: types.NewAsset("md")}},
+			{"1 notes.markdown", &parsedFilename{prefix: 1, title: "notes", asset: types.NewAsset("markdown")}},
+			// Text
+			{"1 file.txt", &parsedFilename{prefix: 1, title: "file", asset: types.NewAsset("txt")}},
+			// Notebook
+			{"1-lab.ipynb", &parsedFilename{prefix: 1, title: "lab", asset: types.NewAsset("ipynb")}},
+			// EPUB
+			{"2 - book.epub", &parsedFilename{prefix: 2, title: "book", asset: types.NewAsset("epub")}},
 			// HTML
 			{"1 index.html", &parsedFilename{prefix: 1, title: "index", asset: types.NewAsset("html")}},
 		}
@@ -679,8 +1119,8 @@ func TestScanner_parseFilename(t *testing.T) {
 			// No title
 			{"01", &parsedFilename{prefix: 1, title: "01"}},
 			{"200.pdf", &parsedFilename{prefix: 200, title: "200.pdf"}},
-			{"1 -.txt", &parsedFilename{prefix: 1, title: "1 -.txt"}},
-			{"1 .txt", &parsedFilename{prefix: 1, title: "1 .txt"}},
+			{"1 -.zip", &parsedFilename{prefix: 1, title: "1 -.zip"}},
+			{"1 .zip", &parsedFilename{prefix: 1, title: "1 .zip"}},
 			{"1     .pdf", &parsedFilename{prefix: 1, title: "1     .pdf"}},
 			// No extension (fileName should have no prefix)
 			{"0    file 0", &parsedFilename{prefix: 0, title: "file 0"}},
@@ -689,7 +1129,7 @@ func TestScanner_parseFilename(t *testing.T) {
 			{"0123-file", &parsedFilename{prefix: 123, title: "file"}},
 			{"1 --- file", &parsedFilename{prefix: 1, title: "file"}},
 			// Non-asset extension (fileName should have no prefix)
-			{"1 file.txt", &parsedFilename{prefix: 1, title: "file.txt"}},
+			{"1 file.zip", &parsedFilename{prefix: 1, title: "file.zip"}},
 		}
 
 		for _, tt := range tests {
@@ -701,6 +1141,69 @@ func TestScanner_parseFilename(t *testing.T) {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+func TestScanner_parseSubtitleFilename(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		var tests = []string{
+			"file.srt",
+			"01 file.mp4",
+			"01 file.txt",
+			"01 file.en",
+			"1file.srt",
+			"a - file.en.srt",
+		}
+
+		for _, tt := range tests {
+			require.Nil(t, parseSubtitleFilename(tt), tt)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		var tests = []struct {
+			in       string
+			expected *parsedSubtitleFilename
+		}{
+			{"01 Intro.srt", &parsedSubtitleFilename{prefix: 1, title: "Intro.srt", language: "und", format: "srt"}},
+			{"01 Intro.en.srt", &parsedSubtitleFilename{prefix: 1, title: "Intro.en.srt", language: "en", format: "srt"}},
+			{"2 - Intro.fre.VTT", &parsedSubtitleFilename{prefix: 2, title: "Intro.fre.VTT", language: "fre", format: "vtt"}},
+			{"3-Intro.pt_BR.ass", &parsedSubtitleFilename{prefix: 3, title: "Intro.pt_BR.ass", language: "pt-br", format: "ass"}},
+			{"04.en.ssa", &parsedSubtitleFilename{prefix: 4, title: "04.en.ssa", language: "en", format: "ssa"}},
+			{"05.vtt", &parsedSubtitleFilename{prefix: 5, title: "05.vtt", language: "und", format: "vtt"}},
+		}
+
+		for _, tt := range tests {
+			require.Equal(t, tt.expected, parseSubtitleFilename(tt.in), tt.in)
+		}
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestScanner_parseDirname(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		for _, dirname := range []string{"", "Deck", "-1 Deck", "Deck 1", " - Deck"} {
+			require.Nil(t, parseDirname(dirname), dirname)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		var tests = []struct {
+			in       string
+			expected *parsedDirname
+		}{
+			{"1", &parsedDirname{prefix: 1, title: "1"}},
+			{"01 Deck", &parsedDirname{prefix: 1, title: "Deck"}},
+			{"02-Deck", &parsedDirname{prefix: 2, title: "Deck"}},
+			{"03 - Deck 1.5", &parsedDirname{prefix: 3, title: "Deck 1.5"}},
+		}
+
+		for _, tt := range tests {
+			require.Equal(t, tt.expected, parseDirname(tt.in), tt.in)
+		}
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 func TestScanner_IsCard(t *testing.T) {
 	t.Run("invalid", func(t *testing.T) {
 		var tests = []string{
@@ -1098,4 +1601,45 @@ func TestScanner_UpdateAttachments(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, 6, count)
 	})
+	t.Run("update", func(t *testing.T) {
+		scanner, ctx, _ := setup(t)
+
+		course := &models.Course{Title: "Course 1", Path: "/course-1"}
+		require.NoError(t, scanner.dao.CreateCourse(ctx, course))
+
+		asset := &models.Asset{
+			CourseID: course.ID,
+			Title:    "Asset 1",
+			Prefix:   sql.NullInt16{Int16: 1, Valid: true},
+			Type:     *types.NewAsset("mp4"),
+			Path:     "/course-1/1 Asset 1.mp4",
+			Hash:     security.RandomString(64),
+		}
+		require.NoError(t, scanner.dao.CreateAsset(ctx, asset))
+
+		attachment := &models.Attachment{
+			AssetID: asset.ID,
+			Title:   "docs",
+			Path:    "/course-1/1 docs.url",
+			URL:     "https://example.com",
+		}
+		require.NoError(t, scanner.dao.CreateAttachment(ctx, attachment))
+
+		// The link target has changed
+		updated := &models.Attachment{
+			AssetID: asset.ID,
+			Title:   "Docs",
+			Path:    attachment.Path,
+			URL:     "https://example.com/v2",
+		}
+
+		require.NoError(t, updateAttachments(ctx, scanner.dao, []string{asset.ID}, []*models.Attachment{updated}))
+
+		attachments := []*models.Attachment{}
+		require.NoError(t, scanner.dao.List(ctx, &attachments, nil))
+		require.Len(t, attachments, 1)
+		require.Equal(t, attachment.ID, attachments[0].ID)
+		require.Equal(t, "Docs", attachments[0].Title)
+		require.Equal(t, "https://example.com/v2", attachments[0].URL)
+	})
 }
@@ -0,0 +1,491 @@
+package epub
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/geerew/off-course/utils/archive"
+	"github.com/spf13/afero"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Book is an EPUB (2 or 3) read from an afero.Fs. Nothing is extracted to disk; resources are read
+// from the underlying ZIP archive as needed
+type Book struct {
+	Title string
+
+	// The reading order
+	Spine []*SpineItem
+
+	// The table of contents, from the EPUB 3 navigation document or the EPUB 2 NCX
+	TOC []*TOCEntry
+
+	archive *archive.Archive
+
+	// The media types of the manifest items, by path
+	mediaTypes map[string]string
+}
+
+// SpineItem is a document in the reading order of a book
+type SpineItem struct {
+	Index int
+	ID    string
+
+	// The slash separated path of the document within the EPUB
+	Path      string
+	MediaType string
+
+	// False for auxiliary content (such as footnotes), which is not part of the default reading
+	// order
+	Linear bool
+}
+
+// TOCEntry is an entry in the table of contents of a book
+type TOCEntry struct {
+	Title string
+
+	// The slash separated path of the document within the EPUB, and the fragment (without the
+	// leading #) within that document, if any
+	Path     string
+	Fragment string
+
+	// The index of the spine item the entry points to, or -1 when it points outside the spine
+	SpineIndex int
+
+	Children []*TOCEntry
+}
+
+// Resource is a file within a book
+type Resource struct {
+	Path      string
+	MediaType string
+	Size      int64
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// container is META-INF/container.xml, which points to the package document
+type container struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// opfPackage is the package document (OPF)
+type opfPackage struct {
+	Titles   []string  `xml:"metadata>title"`
+	Manifest []opfItem `xml:"manifest>item"`
+	Spine    struct {
+		TOC      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type opfItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// ncx is the EPUB 2 table of contents
+type ncx struct {
+	NavPoints []ncxNavPoint `xml:"navMap>navPoint"`
+}
+
+type ncxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	NavPoints []ncxNavPoint `xml:"navPoint"`
+}
+
+// navList is a list in the EPUB 3 navigation document
+type navList struct {
+	Items []struct {
+		Link  navLabel `xml:"a"`
+		Label navLabel `xml:"span"`
+		List  *navList `xml:"ol"`
+	} `xml:"li"`
+}
+
+type navLabel struct {
+	Href  string `xml:"href,attr"`
+	Inner string `xml:",innerxml"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Matches markup, which is stripped from navigation labels
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Open opens the EPUB at path and parses its package document and table of contents. The book must
+// be closed once done
+func Open(fs afero.Fs, path string) (*Book, error) {
+	a, err := archive.Open(fs, path)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalidArchive) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidEPUB, err)
+		}
+
+		return nil, err
+	}
+
+	b := &Book{archive: a, mediaTypes: map[string]string{}}
+	if err := b.parse(); err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Close closes the book
+func (b *Book) Close() error {
+	return b.archive.Close()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SpineIndex returns the index of the spine item with the given path, or -1 when the path is not
+// in the spine
+func (b *Book) SpineIndex(name string) int {
+	for _, item := range b.Spine {
+		if item.Path == name {
+			return item.Index
+		}
+	}
+
+	return -1
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// LinearCount returns the number of spine items in the default reading order
+func (b *Book) LinearCount() int {
+	count := 0
+	for _, item := range b.Spine {
+		if item.Linear {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// OpenResource opens the file with the given path within the book. ErrResourceNotFound is returned
+// when there is no such file
+func (b *Book) OpenResource(name string) (*Resource, io.ReadCloser, error) {
+	entry, err := b.archive.Entry(name)
+	if err != nil {
+		if errors.Is(err, archive.ErrEntryNotFound) {
+			return nil, nil, ErrResourceNotFound
+		}
+
+		return nil, nil, err
+	}
+
+	rc, err := b.archive.OpenEntry(entry.Path, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mediaType := b.mediaTypes[entry.Path]
+	if mediaType == "" {
+		mediaType = mime.TypeByExtension(path.Ext(entry.Path))
+	}
+
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+
+	return &Resource{Path: entry.Path, MediaType: mediaType, Size: entry.Size}, rc, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parse parses the package document, building the spine and the table of contents
+func (b *Book) parse() error {
+	c := &container{}
+	if err := b.decode("META-INF/container.xml", c); err != nil {
+		return err
+	}
+
+	opfPath := ""
+	for _, rootfile := range c.Rootfiles {
+		if rootfile.MediaType == "" || rootfile.MediaType == "application/oebps-package+xml" {
+			opfPath = rootfile.FullPath
+			break
+		}
+	}
+
+	opfPath, ok := archive.CleanName(opfPath)
+	if !ok {
+		return fmt.Errorf("%w: missing package document", ErrInvalidEPUB)
+	}
+
+	pkg := &opfPackage{}
+	if err := b.decode(opfPath, pkg); err != nil {
+		return err
+	}
+
+	for _, title := range pkg.Titles {
+		if title = strings.TrimSpace(title); title != "" {
+			b.Title = title
+			break
+		}
+	}
+
+	// Manifest items, by ID
+	items := map[string]opfItem{}
+	for _, item := range pkg.Manifest {
+		p, _, ok := resolve(opfPath, item.Href)
+		if !ok {
+			continue
+		}
+
+		item.Href = p
+		items[item.ID] = item
+		b.mediaTypes[p] = item.MediaType
+	}
+
+	for _, ref := range pkg.Spine.ItemRefs {
+		item, ok := items[ref.IDRef]
+		if !ok {
+			continue
+		}
+
+		b.Spine = append(b.Spine, &SpineItem{
+			Index:     len(b.Spine),
+			ID:        item.ID,
+			Path:      item.Href,
+			MediaType: item.MediaType,
+			Linear:    ref.Linear != "no",
+		})
+	}
+
+	if len(b.Spine) == 0 {
+		return fmt.Errorf("%w: empty spine", ErrInvalidEPUB)
+	}
+
+	// Prefer the EPUB 3 navigation document, falling back to the NCX. A broken table of contents
+	// is not fatal, as the spine is enough to read the book
+	for _, item := range pkg.Manifest {
+		if hasProperty(item.Properties, "nav") {
+			if toc, err := b.parseNav(items[item.ID].Href); err == nil && len(toc) > 0 {
+				b.TOC = toc
+				return nil
+			}
+		}
+	}
+
+	if item, ok := items[pkg.Spine.TOC]; ok {
+		if toc, err := b.parseNCX(item.Href); err == nil {
+			b.TOC = toc
+		}
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseNCX parses the EPUB 2 table of contents
+func (b *Book) parseNCX(name string) ([]*TOCEntry, error) {
+	n := &ncx{}
+	if err := b.decode(name, n); err != nil {
+		return nil, err
+	}
+
+	var convert func(points []ncxNavPoint) []*TOCEntry
+	convert = func(points []ncxNavPoint) []*TOCEntry {
+		entries := []*TOCEntry{}
+		for _, point := range points {
+			entry := b.tocEntry(name, strings.TrimSpace(point.Label), point.Content.Src)
+			entry.Children = convert(point.NavPoints)
+			entries = append(entries, entry)
+		}
+
+		return entries
+	}
+
+	return convert(n.NavPoints), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseNav parses the `toc` nav of the EPUB 3 navigation document
+func (b *Book) parseNav(name string) ([]*TOCEntry, error) {
+	rc, err := b.archive.OpenEntry(name, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEPUB, err)
+	}
+	defer rc.Close()
+
+	d := newDecoder(rc)
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidEPUB, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "nav" || !isTOCNav(start) {
+			continue
+		}
+
+		nav := &struct {
+			List navList `xml:"ol"`
+		}{}
+
+		if err := d.DecodeElement(nav, &start); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidEPUB, err)
+		}
+
+		var convert func(list *navList) []*TOCEntry
+		convert = func(list *navList) []*TOCEntry {
+			entries := []*TOCEntry{}
+			if list == nil {
+				return entries
+			}
+
+			for _, li := range list.Items {
+				label := li.Link
+				if label.Inner == "" {
+					label = li.Label
+				}
+
+				entry := b.tocEntry(name, navText(label.Inner), label.Href)
+				entry.Children = convert(li.List)
+				entries = append(entries, entry)
+			}
+
+			return entries
+		}
+
+		return convert(&nav.List), nil
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// tocEntry builds a table of contents entry, resolving href relative to the document it is in
+func (b *Book) tocEntry(base, title, href string) *TOCEntry {
+	entry := &TOCEntry{Title: title, SpineIndex: -1}
+
+	if p, fragment, ok := resolve(base, href); ok && href != "" {
+		entry.Path = p
+		entry.Fragment = fragment
+		entry.SpineIndex = b.SpineIndex(p)
+	}
+
+	return entry
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// decode decodes the XML file with the given name into v
+func (b *Book) decode(name string, v any) error {
+	rc, err := b.archive.OpenEntry(name, 0)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidEPUB, name, err)
+	}
+	defer rc.Close()
+
+	if err := newDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidEPUB, name, err)
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// newDecoder creates a lenient XML decoder, as EPUBs in the wild are not always well formed and
+// often use HTML entities
+func newDecoder(r io.Reader) *xml.Decoder {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Everything else is decoded as is, which is good enough for ASCII compatible charsets
+		return input, nil
+	}
+
+	return d
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// resolve resolves href relative to the document at base, returning the cleaned path and the
+// fragment. False is returned when href is invalid, external or escapes the book
+func resolve(base, href string) (string, string, bool) {
+	u, err := url.Parse(href)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "", "", false
+	}
+
+	if u.Path == "" {
+		return base, u.Fragment, true
+	}
+
+	p, ok := archive.CleanName(path.Join(path.Dir(base), u.Path))
+	return p, u.Fragment, ok
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// isTOCNav returns true when the nav element is the table of contents (epub:type="toc")
+func isTOCNav(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" && hasProperty(attr.Value, "toc") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// hasProperty returns true when the space separated list of properties contains property
+func hasProperty(properties, property string) bool {
+	for _, p := range strings.Fields(properties) {
+		if p == property {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// navText returns the text of a navigation label, stripping markup and collapsing whitespace
+func navText(inner string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tagRegex.ReplaceAllString(inner, ""))), " ")
+}
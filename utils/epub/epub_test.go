@@ -0,0 +1,257 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const testContainer = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+	<rootfiles>
+		<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+	</rootfiles>
+</container>`
+
+const testOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+	<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+		<dc:title>  A Book  </dc:title>
+	</metadata>
+	<manifest>
+		<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+		<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+		<item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+		<item id="c2" href="text/chapter2.xhtml" media-type="application/xhtml+xml"/>
+		<item id="notes" href="text/notes.xhtml" media-type="application/xhtml+xml"/>
+		<item id="css" href="styles/book.css" media-type="text/css"/>
+	</manifest>
+	<spine toc="ncx">
+		<itemref idref="c1"/>
+		<itemref idref="c2"/>
+		<itemref idref="notes" linear="no"/>
+		<itemref idref="missing"/>
+	</spine>
+</package>`
+
+const testNav = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<body>
+	<nav epub:type="landmarks"><ol><li><a href="text/chapter2.xhtml">Landmark</a></li></ol></nav>
+	<nav epub:type="toc">
+		<ol>
+			<li><a href="text/chapter%201.xhtml"><span>Chapter</span> &amp; One</a></li>
+			<li>
+				<span>Part 2</span>
+				<ol>
+					<li><a href="text/chapter2.xhtml#s1">Section 1</a></li>
+					<li><a href="http://example.com">External</a></li>
+				</ol>
+			</li>
+		</ol>
+	</nav>
+</body>
+</html>`
+
+const testNCX = `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+	<navMap>
+		<navPoint id="p1">
+			<navLabel><text>NCX One</text></navLabel>
+			<content src="text/chapter%201.xhtml"/>
+			<navPoint id="p2">
+				<navLabel><text>NCX Two</text></navLabel>
+				<content src="text/chapter2.xhtml#s1"/>
+			</navPoint>
+		</navPoint>
+	</navMap>
+</ncx>`
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// setup writes an EPUB to an in-memory fs
+func setup(t *testing.T, files map[string]string) (afero.Fs, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for name, content := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/book.epub", buf.Bytes(), os.ModePerm))
+
+	return fs, "/book.epub"
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// testFiles returns the files of a valid EPUB
+func testFiles() map[string]string {
+	return map[string]string{
+		"mimetype":                   "application/epub+zip",
+		"META-INF/container.xml":     testContainer,
+		"OEBPS/content.opf":          testOPF,
+		"OEBPS/nav.xhtml":            testNav,
+		"OEBPS/toc.ncx":              testNCX,
+		"OEBPS/text/chapter 1.xhtml": "<html><body>one</body></html>",
+		"OEBPS/text/chapter2.xhtml":  "<html><body>two</body></html>",
+		"OEBPS/text/notes.xhtml":     "<html><body>notes</body></html>",
+		"OEBPS/styles/book.css":      "body {}",
+		"OEBPS/images/cover.png":     "png",
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestEPUB_Open(t *testing.T) {
+	t.Run("nav", func(t *testing.T) {
+		fs, path := setup(t, testFiles())
+
+		b, err := Open(fs, path)
+		require.NoError(t, err)
+		defer b.Close()
+
+		require.Equal(t, "A Book", b.Title)
+
+		// The spine item with a missing manifest item is skipped
+		require.Len(t, b.Spine, 3)
+		require.Equal(t, &SpineItem{Index: 0, ID: "c1", Path: "OEBPS/text/chapter 1.xhtml", MediaType: "application/xhtml+xml", Linear: true}, b.Spine[0])
+		require.Equal(t, "OEBPS/text/chapter2.xhtml", b.Spine[1].Path)
+		require.False(t, b.Spine[2].Linear)
+		require.Equal(t, 2, b.LinearCount())
+
+		// The landmarks nav is ignored
+		require.Len(t, b.TOC, 2)
+		require.Equal(t, "Chapter & One", b.TOC[0].Title)
+		require.Equal(t, "OEBPS/text/chapter 1.xhtml", b.TOC[0].Path)
+		require.Equal(t, 0, b.TOC[0].SpineIndex)
+		require.Empty(t, b.TOC[0].Children)
+
+		require.Equal(t, "Part 2", b.TOC[1].Title)
+		require.Empty(t, b.TOC[1].Path)
+		require.Equal(t, -1, b.TOC[1].SpineIndex)
+		require.Len(t, b.TOC[1].Children, 2)
+
+		require.Equal(t, &TOCEntry{Title: "Section 1", Path: "OEBPS/text/chapter2.xhtml", Fragment: "s1", SpineIndex: 1, Children: []*TOCEntry{}}, b.TOC[1].Children[0])
+		require.Equal(t, "External", b.TOC[1].Children[1].Title)
+		require.Empty(t, b.TOC[1].Children[1].Path)
+		require.Equal(t, -1, b.TOC[1].Children[1].SpineIndex)
+	})
+
+	t.Run("ncx", func(t *testing.T) {
+		files := testFiles()
+		delete(files, "OEBPS/nav.xhtml")
+
+		fs, path := setup(t, files)
+
+		b, err := Open(fs, path)
+		require.NoError(t, err)
+		defer b.Close()
+
+		require.Len(t, b.TOC, 1)
+		require.Equal(t, "NCX One", b.TOC[0].Title)
+		require.Equal(t, 0, b.TOC[0].SpineIndex)
+		require.Len(t, b.TOC[0].Children, 1)
+		require.Equal(t, "NCX Two", b.TOC[0].Children[0].Title)
+		require.Equal(t, "s1", b.TOC[0].Children[0].Fragment)
+		require.Equal(t, 1, b.TOC[0].Children[0].SpineIndex)
+	})
+
+	t.Run("no toc", func(t *testing.T) {
+		files := testFiles()
+		delete(files, "OEBPS/nav.xhtml")
+		delete(files, "OEBPS/toc.ncx")
+
+		fs, path := setup(t, files)
+
+		b, err := Open(fs, path)
+		require.NoError(t, err)
+		defer b.Close()
+
+		require.Len(t, b.Spine, 3)
+		require.Empty(t, b.TOC)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/book.epub", []byte("not a zip"), os.ModePerm))
+
+		_, err := Open(fs, "/book.epub")
+		require.ErrorIs(t, err, ErrInvalidEPUB)
+
+		// Missing container
+		files := testFiles()
+		delete(files, "META-INF/container.xml")
+		fs, path := setup(t, files)
+
+		_, err = Open(fs, path)
+		require.ErrorIs(t, err, ErrInvalidEPUB)
+
+		// Package document escaping the book
+		files = testFiles()
+		files["META-INF/container.xml"] = `<container><rootfiles><rootfile full-path="../content.opf"/></rootfiles></container>`
+		fs, path = setup(t, files)
+
+		_, err = Open(fs, path)
+		require.ErrorIs(t, err, ErrInvalidEPUB)
+
+		// Empty spine
+		files = testFiles()
+		files["OEBPS/content.opf"] = `<package><manifest/><spine/></package>`
+		fs, path = setup(t, files)
+
+		_, err = Open(fs, path)
+		require.ErrorIs(t, err, ErrInvalidEPUB)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestEPUB_OpenResource(t *testing.T) {
+	fs, path := setup(t, testFiles())
+
+	b, err := Open(fs, path)
+	require.NoError(t, err)
+	defer b.Close()
+
+	// Media type from the manifest
+	res, rc, err := b.OpenResource("OEBPS/text/chapter 1.xhtml")
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	require.Equal(t, "<html><body>one</body></html>", string(data))
+	require.Equal(t, &Resource{Path: "OEBPS/text/chapter 1.xhtml", MediaType: "application/xhtml+xml", Size: int64(len(data))}, res)
+
+	// Media type from the extension
+	res, rc, err = b.OpenResource("OEBPS/images/../images/cover.png")
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	require.Equal(t, "OEBPS/images/cover.png", res.Path)
+	require.Equal(t, "image/png", res.MediaType)
+
+	// Not found
+	_, _, err = b.OpenResource("OEBPS/missing.xhtml")
+	require.ErrorIs(t, err, ErrResourceNotFound)
+
+	_, _, err = b.OpenResource("../etc/passwd")
+	require.ErrorIs(t, err, ErrResourceNotFound)
+}
@@ -0,0 +1,8 @@
+package epub
+
+import "errors"
+
+var (
+	ErrInvalidEPUB      = errors.New("invalid epub")
+	ErrResourceNotFound = errors.New("resource not found")
+)
@@ -0,0 +1,148 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The maximum size of the hdrl list that will be read into memory
+const maxAVIHeaderSize = 4 * 1024 * 1024
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// aviAudioFormats maps a WAVEFORMATEX format tag to a codec name
+var aviAudioFormats = map[uint16]string{
+	0x0001: "pcm",
+	0x0055: "mp3",
+	0x00FF: "aac",
+	0x0161: "wma",
+	0x2000: "ac3",
+	0x2001: "dts",
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseAVI extracts the metadata from the hdrl list, which is the first chunk of an AVI file
+func parseAVI(r io.ReadSeeker) (*Metadata, error) {
+	// Skip `RIFF <size> AVI `
+	if _, err := r.Seek(12, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, ErrMalformed
+	}
+
+	if string(hdr[:4]) != "LIST" || string(hdr[8:12]) != "hdrl" {
+		return nil, ErrMalformed
+	}
+
+	size := binary.LittleEndian.Uint32(hdr[4:8])
+	if size < 4 || size > maxAVIHeaderSize {
+		return nil, ErrMalformed
+	}
+
+	hdrl := make([]byte, size-4)
+	if _, err := io.ReadFull(r, hdrl); err != nil {
+		return nil, ErrMalformed
+	}
+
+	meta := &Metadata{}
+
+	err := eachChunk(hdrl, func(id string, payload []byte) error {
+		switch id {
+		case "avih":
+			// MainAVIHeader
+			if len(payload) < 40 {
+				return ErrMalformed
+			}
+
+			microSecPerFrame := binary.LittleEndian.Uint32(payload[0:4])
+			totalFrames := binary.LittleEndian.Uint32(payload[16:20])
+
+			meta.Duration = float64(totalFrames) * float64(microSecPerFrame) / float64(1000000)
+			meta.Width = int(binary.LittleEndian.Uint32(payload[32:36]))
+			meta.Height = int(binary.LittleEndian.Uint32(payload[36:40]))
+		case "LIST":
+			if len(payload) < 4 || string(payload[:4]) != "strl" {
+				return nil
+			}
+
+			parseAVIStream(payload[4:], meta)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseAVIStream extracts the codec from a strl list and updates the metadata
+func parseAVIStream(strl []byte, meta *Metadata) {
+	streamType := ""
+
+	eachChunk(strl, func(id string, payload []byte) error {
+		switch id {
+		case "strh":
+			if len(payload) >= 4 {
+				streamType = string(payload[:4])
+			}
+		case "strf":
+			switch streamType {
+			case "vids":
+				// BITMAPINFOHEADER
+				if meta.VideoCodec == "" && len(payload) >= 20 {
+					meta.VideoCodec = codecName(string(payload[16:20]))
+				}
+			case "auds":
+				// WAVEFORMATEX
+				if len(payload) >= 2 {
+					if meta.AudioCodec == "" {
+						meta.AudioCodec = aviAudioFormats[binary.LittleEndian.Uint16(payload[0:2])]
+					}
+
+					meta.AudioTracks++
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// eachChunk iterates the RIFF chunks in data, calling fn with the ID and payload of each chunk
+func eachChunk(data []byte, fn func(id string, payload []byte) error) error {
+	for len(data) >= 8 {
+		id := string(data[:4])
+		size := uint64(binary.LittleEndian.Uint32(data[4:8]))
+
+		if size > uint64(len(data)-8) {
+			return ErrMalformed
+		}
+
+		if err := fn(id, data[8:8+size]); err != nil {
+			return err
+		}
+
+		// Chunks are padded to an even size
+		next := 8 + size + size%2
+		if next > uint64(len(data)) {
+			break
+		}
+
+		data = data[next:]
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+package media
+
+import "errors"
+
+var (
+	ErrUnsupported = errors.New("unsupported media container")
+	ErrMalformed   = errors.New("malformed media container")
+)
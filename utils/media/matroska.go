@@ -0,0 +1,558 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The magic bytes at the start of an EBML (Matroska/WebM) file
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// EBML element IDs
+const (
+	ebmlIDHeader        = 0x1A45DFA3
+	ebmlIDSegment       = 0x18538067
+	ebmlIDInfo          = 0x1549A966
+	ebmlIDTimecodeScale = 0x2AD7B1
+	ebmlIDDuration      = 0x4489
+	ebmlIDTracks        = 0x1654AE6B
+	ebmlIDTrackEntry    = 0xAE
+	ebmlIDTrackType     = 0x83
+	ebmlIDCodecID       = 0x86
+	ebmlIDVideo         = 0xE0
+	ebmlIDPixelWidth    = 0xB0
+	ebmlIDPixelHeight   = 0xBA
+	ebmlIDCluster       = 0x1F43B675
+
+	// Seeking
+	ebmlIDSeekHead     = 0x114D9B74
+	ebmlIDSeek         = 0x4DBB
+	ebmlIDSeekID       = 0x53AB
+	ebmlIDSeekPosition = 0x53AC
+
+	// Chapters
+	ebmlIDChapters           = 0x1043A770
+	ebmlIDEditionEntry       = 0x45B9
+	ebmlIDEditionFlagDefault = 0x45DB
+	ebmlIDEditionFlagHidden  = 0x45BD
+	ebmlIDChapterAtom        = 0xB6
+	ebmlIDChapterTimeStart   = 0x91
+	ebmlIDChapterFlagHidden  = 0x98
+	ebmlIDChapterFlagEnabled = 0x4598
+	ebmlIDChapterDisplay     = 0x80
+	ebmlIDChapString         = 0x85
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The maximum size of a top level element (Info, Tracks, ...) that will be read into memory
+const maxEBMLElementSize = 16 * 1024 * 1024
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// matroskaSegment holds the raw payloads of the segment children that are of interest
+type matroskaSegment struct {
+	info     []byte
+	tracks   []byte
+	chapters []byte
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseMatroska extracts the metadata from the Info and Tracks elements of the first segment
+func parseMatroska(r io.ReadSeeker) (*Metadata, error) {
+	segment, err := readMatroskaSegment(r)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{}
+
+	// Info
+	timecodeScale := uint64(1000000)
+	duration := float64(0)
+
+	err = eachElement(segment.info, func(id uint32, payload []byte) error {
+		switch id {
+		case ebmlIDTimecodeScale:
+			if v := readUint(payload); v > 0 {
+				timecodeScale = v
+			}
+		case ebmlIDDuration:
+			duration = readFloat(payload)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	meta.Duration = duration * float64(timecodeScale) / float64(1000000000)
+
+	// Tracks
+	err = eachElement(segment.tracks, func(id uint32, payload []byte) error {
+		if id != ebmlIDTrackEntry {
+			return nil
+		}
+
+		var trackType uint64
+		var codec string
+		var width, height int
+
+		err := eachElement(payload, func(id uint32, payload []byte) error {
+			switch id {
+			case ebmlIDTrackType:
+				trackType = readUint(payload)
+			case ebmlIDCodecID:
+				codec = string(payload)
+			case ebmlIDVideo:
+				return eachElement(payload, func(id uint32, payload []byte) error {
+					switch id {
+					case ebmlIDPixelWidth:
+						width = int(readUint(payload))
+					case ebmlIDPixelHeight:
+						height = int(readUint(payload))
+					}
+
+					return nil
+				})
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		switch trackType {
+		case 1:
+			if meta.VideoCodec == "" {
+				meta.VideoCodec = codecName(codec)
+				meta.Width = width
+				meta.Height = height
+			}
+		case 2:
+			if meta.AudioCodec == "" {
+				meta.AudioCodec = codecName(codec)
+			}
+
+			meta.AudioTracks++
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	meta.Chapters = parseMatroskaChapters(segment.chapters)
+
+	return meta, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseMatroskaChapters extracts the chapter markers from the payload of a Chapters element. The
+// default edition is used, falling back to the first edition that is not hidden. Hidden and
+// disabled chapters are ignored, as are nested chapters. Chapters are optional, so a malformed
+// element results in no chapters
+func parseMatroskaChapters(data []byte) []*Chapter {
+	var edition []byte
+
+	eachElement(data, func(id uint32, payload []byte) error {
+		if id != ebmlIDEditionEntry {
+			return nil
+		}
+
+		isDefault, isHidden := false, false
+		eachElement(payload, func(id uint32, payload []byte) error {
+			switch id {
+			case ebmlIDEditionFlagDefault:
+				isDefault = readUint(payload) == 1
+			case ebmlIDEditionFlagHidden:
+				isHidden = readUint(payload) == 1
+			}
+
+			return nil
+		})
+
+		if isDefault {
+			edition = payload
+			return io.EOF
+		}
+
+		if edition == nil && !isHidden {
+			edition = payload
+		}
+
+		return nil
+	})
+
+	chapters := []*Chapter{}
+
+	eachElement(edition, func(id uint32, payload []byte) error {
+		if id != ebmlIDChapterAtom {
+			return nil
+		}
+
+		chapter := &Chapter{}
+		enabled := true
+
+		err := eachElement(payload, func(id uint32, payload []byte) error {
+			switch id {
+			case ebmlIDChapterTimeStart:
+				// Nanoseconds
+				chapter.Start = int64(readUint(payload) / 1000000)
+			case ebmlIDChapterFlagHidden:
+				enabled = enabled && readUint(payload) != 1
+			case ebmlIDChapterFlagEnabled:
+				enabled = enabled && readUint(payload) != 0
+			case ebmlIDChapterDisplay:
+				if chapter.Title != "" {
+					return nil
+				}
+
+				return eachElement(payload, func(id uint32, payload []byte) error {
+					if id == ebmlIDChapString {
+						chapter.Title = string(payload)
+					}
+
+					return nil
+				})
+			}
+
+			return nil
+		})
+
+		if err == nil && enabled {
+			chapters = append(chapters, chapter)
+		}
+
+		return nil
+	})
+
+	return normalizeChapters(chapters)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readMatroskaSegment reads the EBML header and walks the children of the first segment, reading
+// the elements of interest into memory. The walk stops at the first cluster as the header elements
+// are written before the media data. Chapters may be written after the media data, in which case
+// they are located using the seek head
+func readMatroskaSegment(r io.ReadSeeker) (*matroskaSegment, error) {
+	id, size, err := readElementHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if id != ebmlIDHeader || size < 0 {
+		return nil, ErrMalformed
+	}
+
+	if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+
+	id, size, err = readElementHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if id != ebmlIDSegment {
+		return nil, ErrMalformed
+	}
+
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	end := int64(-1)
+	if size >= 0 {
+		end = start + size
+	}
+
+	segment := &matroskaSegment{}
+	segmentStart := start
+	chaptersPos := int64(-1)
+
+	for end < 0 || start < end {
+		id, size, err := readElementHeader(r)
+		if err != nil {
+			// Reaching the end of the file is expected when the segment size is unknown
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+
+			return nil, err
+		}
+
+		if id == ebmlIDCluster || size < 0 {
+			break
+		}
+
+		switch id {
+		case ebmlIDInfo, ebmlIDTracks, ebmlIDChapters, ebmlIDSeekHead:
+			if size > maxEBMLElementSize {
+				return nil, ErrMalformed
+			}
+
+			payload := make([]byte, size)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return nil, ErrMalformed
+			}
+
+			switch id {
+			case ebmlIDInfo:
+				segment.info = payload
+			case ebmlIDTracks:
+				segment.tracks = payload
+			case ebmlIDChapters:
+				segment.chapters = payload
+			case ebmlIDSeekHead:
+				if pos := matroskaSeekPosition(payload, ebmlIDChapters); pos >= 0 {
+					chaptersPos = pos
+				}
+			}
+		default:
+			if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+
+		if start, err = r.Seek(0, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+
+	if segment.chapters == nil && chaptersPos >= 0 {
+		segment.chapters = readMatroskaElementAt(r, segmentStart+chaptersPos, ebmlIDChapters)
+	}
+
+	return segment, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// matroskaSeekPosition returns the position, relative to the start of the segment data, of the
+// element with the given ID from the payload of a SeekHead element. -1 is returned when the
+// element is not referenced
+func matroskaSeekPosition(seekHead []byte, elementID uint32) int64 {
+	position := int64(-1)
+
+	eachElement(seekHead, func(id uint32, payload []byte) error {
+		if id != ebmlIDSeek {
+			return nil
+		}
+
+		var seekID uint64
+		pos := int64(-1)
+
+		eachElement(payload, func(id uint32, payload []byte) error {
+			switch id {
+			case ebmlIDSeekID:
+				seekID = readUint(payload)
+			case ebmlIDSeekPosition:
+				if len(payload) <= 8 {
+					pos = int64(readUint(payload))
+				}
+			}
+
+			return nil
+		})
+
+		if uint32(seekID) == elementID && pos >= 0 {
+			position = pos
+			return io.EOF
+		}
+
+		return nil
+	})
+
+	return position
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readMatroskaElementAt reads the payload of the element at the given position when it has the
+// expected ID. nil is returned when the element cannot be read
+func readMatroskaElementAt(r io.ReadSeeker, pos int64, expectedID uint32) []byte {
+	if _, err := r.Seek(pos, io.SeekStart); err != nil {
+		return nil
+	}
+
+	id, size, err := readElementHeader(r)
+	if err != nil || id != expectedID || size < 0 || size > maxEBMLElementSize {
+		return nil
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil
+	}
+
+	return payload
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readElementHeader reads an element ID and size from r. A size of -1 means the size is unknown
+func readElementHeader(r io.Reader) (uint32, int64, error) {
+	id, _, err := readVint(r, true)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	size, unknown, err := readVint(r, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if unknown {
+		return uint32(id), -1, nil
+	}
+
+	return uint32(id), int64(size), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readVint reads a variable length integer from r. When keepMarker is true the length marker is
+// kept, as is the case for element IDs
+func readVint(r io.Reader, keepMarker bool) (uint64, bool, error) {
+	var first [1]byte
+	if _, err := io.ReadFull(r, first[:]); err != nil {
+		return 0, false, err
+	}
+
+	length := vintLength(first[0])
+	if length == 0 || (keepMarker && length > 4) {
+		return 0, false, ErrMalformed
+	}
+
+	buf := make([]byte, length)
+	buf[0] = first[0]
+	if length > 1 {
+		if _, err := io.ReadFull(r, buf[1:]); err != nil {
+			return 0, false, err
+		}
+	}
+
+	v, unknown := decodeVint(buf, keepMarker)
+	return v, unknown, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// vintLength returns the length of a variable length integer based upon the number of leading
+// zeros in the first byte. 0 is returned for an invalid first byte
+func vintLength(b byte) int {
+	for i := 0; i < 8; i++ {
+		if b&(0x80>>i) != 0 {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// decodeVint decodes a variable length integer, returning the value and whether all the value
+// bits are set (an unknown size)
+func decodeVint(buf []byte, keepMarker bool) (uint64, bool) {
+	length := len(buf)
+
+	v := uint64(buf[0])
+	if !keepMarker {
+		v &= uint64(0xFF >> length)
+	}
+
+	for _, b := range buf[1:] {
+		v = v<<8 | uint64(b)
+	}
+
+	unknown := !keepMarker && v == (uint64(1)<<(7*length))-1
+	return v, unknown
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// eachElement iterates the EBML elements in data, calling fn with the ID and payload of each
+// element
+func eachElement(data []byte, fn func(id uint32, payload []byte) error) error {
+	for len(data) > 0 {
+		idLen := vintLength(data[0])
+		if idLen == 0 || idLen > 4 || len(data) < idLen+1 {
+			return ErrMalformed
+		}
+
+		id, _ := decodeVint(data[:idLen], true)
+		data = data[idLen:]
+
+		sizeLen := vintLength(data[0])
+		if sizeLen == 0 || len(data) < sizeLen {
+			return ErrMalformed
+		}
+
+		size, unknown := decodeVint(data[:sizeLen], false)
+		data = data[sizeLen:]
+
+		// An unknown size consumes the remainder of the data
+		if unknown || size > uint64(len(data)) {
+			if !unknown {
+				return ErrMalformed
+			}
+
+			size = uint64(len(data))
+		}
+
+		if err := fn(uint32(id), data[:size]); err != nil {
+			return err
+		}
+
+		data = data[size:]
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readUint decodes a big endian unsigned integer of up to 8 bytes
+func readUint(p []byte) uint64 {
+	if len(p) > 8 {
+		return 0
+	}
+
+	v := uint64(0)
+	for _, b := range p {
+		v = v<<8 | uint64(b)
+	}
+
+	return v
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readFloat decodes a 4 or 8 byte big endian float
+func readFloat(p []byte) float64 {
+	switch len(p) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(p)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(p))
+	}
+
+	return 0
+}
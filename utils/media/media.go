@@ -0,0 +1,187 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Metadata defines the information extracted from the headers of a media container
+type Metadata struct {
+	// Duration in seconds
+	Duration float64
+
+	// Resolution of the first video track
+	Width  int
+	Height int
+
+	// Normalized codec names of the first video and audio tracks (ex h264, aac)
+	VideoCodec string
+	AudioCodec string
+
+	// The number of audio tracks
+	AudioTracks int
+
+	// Embedded chapter markers, ordered by start time
+	Chapters []*Chapter
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Chapter defines a chapter marker embedded in a media container
+type Chapter struct {
+	Title string
+
+	// Start in milliseconds
+	Start int64
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Seconds returns the duration rounded to the nearest second
+func (m *Metadata) Seconds() int {
+	if m == nil {
+		return 0
+	}
+
+	return int(math.Round(m.Duration))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Probe identifies the container by sniffing the first bytes of r and then parses the container
+// headers. Only the headers are read, which means the media data itself is never decoded
+//
+// Supported containers are MP4/MOV (ISO BMFF), Matroska/WebM and AVI. ErrUnsupported is returned
+// for anything else
+func Probe(r io.ReadSeeker) (*Metadata, error) {
+	var magic [12]byte
+	n, err := io.ReadFull(r, magic[:])
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return nil, ErrUnsupported
+		}
+
+		return nil, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case n >= 4 && bytes.Equal(magic[:4], ebmlMagic):
+		return parseMatroska(r)
+	case n >= 12 && string(magic[:4]) == "RIFF" && string(magic[8:12]) == "AVI ":
+		return parseAVI(r)
+	case n >= 8 && isMP4BoxType(string(magic[4:8])):
+		return parseMP4(r)
+	}
+
+	return nil, ErrUnsupported
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// normalizeChapters orders the chapters by start time, removing duplicate start times, and trims
+// the titles. Chapters without a title are titled `Chapter <n>`
+func normalizeChapters(chapters []*Chapter) []*Chapter {
+	if len(chapters) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Start < chapters[j].Start
+	})
+
+	normalized := make([]*Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		if chapter.Start < 0 {
+			continue
+		}
+
+		if len(normalized) > 0 && normalized[len(normalized)-1].Start == chapter.Start {
+			continue
+		}
+
+		chapter.Title = strings.TrimSpace(strings.TrimRight(chapter.Title, "\x00"))
+		if chapter.Title == "" {
+			chapter.Title = fmt.Sprintf("Chapter %d", len(normalized)+1)
+		}
+
+		normalized = append(normalized, chapter)
+	}
+
+	return normalized
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// codecNames maps the codec identifiers used by the various containers to a common name
+var codecNames = map[string]string{
+	// MP4 sample entries
+	"avc1": "h264",
+	"avc3": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"vp08": "vp8",
+	"vp09": "vp9",
+	"av01": "av1",
+	"mp4v": "mpeg4",
+	"mp4a": "aac",
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"opus": "opus",
+	"flac": "flac",
+	".mp3": "mp3",
+
+	// Matroska codec IDs
+	"v_mpeg4/iso/avc":  "h264",
+	"v_mpegh/iso/hevc": "hevc",
+	"v_mpeg4/iso/asp":  "mpeg4",
+	"v_vp8":            "vp8",
+	"v_vp9":            "vp9",
+	"v_av1":            "av1",
+	"v_theora":         "theora",
+	"a_aac":            "aac",
+	"a_opus":           "opus",
+	"a_vorbis":         "vorbis",
+	"a_ac3":            "ac3",
+	"a_eac3":           "eac3",
+	"a_dts":            "dts",
+	"a_flac":           "flac",
+	"a_mpeg/l3":        "mp3",
+
+	// AVI fourcc
+	"h264": "h264",
+	"x264": "h264",
+	"xvid": "mpeg4",
+	"divx": "mpeg4",
+	"dx50": "mpeg4",
+	"fmp4": "mpeg4",
+	"mjpg": "mjpeg",
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// codecName normalizes a codec identifier. Unknown identifiers are returned trimmed and in lower
+// case
+func codecName(id string) string {
+	id = strings.ToLower(strings.TrimSpace(strings.TrimRight(id, "\x00")))
+
+	if name, ok := codecNames[id]; ok {
+		return name
+	}
+
+	// Matroska allows suffixes such as A_AAC/MPEG4/LC
+	if strings.HasPrefix(id, "a_aac") {
+		return "aac"
+	}
+
+	return id
+}
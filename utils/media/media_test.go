@@ -0,0 +1,494 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4Box builds an ISO BMFF box
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b[0:4], uint32(8+len(body)))
+	copy(b[4:8], typ)
+
+	return append(b, body...)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4TestTrack builds a trak box with a handler, codec and resolution
+func mp4TestTrack(handler, codec string, width, height int) []byte {
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:80], uint32(width<<16))
+	binary.BigEndian.PutUint32(tkhd[80:84], uint32(height<<16))
+
+	hdlr := make([]byte, 24)
+	copy(hdlr[8:12], handler)
+
+	stsd := make([]byte, 8)
+	binary.BigEndian.PutUint32(stsd[4:8], 1)
+
+	return mp4Box("trak",
+		mp4Box("tkhd", tkhd),
+		mp4Box("mdia",
+			mp4Box("hdlr", hdlr),
+			mp4Box("minf",
+				mp4Box("stbl",
+					mp4Box("stsd", stsd, mp4Box(codec, make([]byte, 28))),
+				),
+			),
+		),
+	)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4TestFile builds a MP4 file with a video track and 2 audio tracks. The moov box is placed after
+// the mdat box, as is the case for files that are not optimized for streaming
+func mp4TestFile(timescale, duration uint32) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:20], duration)
+
+	return bytes.Join([][]byte{
+		mp4Box("ftyp", []byte("isom"), make([]byte, 4)),
+		mp4Box("mdat", make([]byte, 1024)),
+		mp4Box("moov",
+			mp4Box("mvhd", mvhd),
+			mp4TestTrack("vide", "avc1", 1920, 1080),
+			mp4TestTrack("soun", "mp4a", 0, 0),
+			mp4TestTrack("soun", "ac-3", 0, 0),
+		),
+	}, nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4FullBox builds the payload of a full box (version 0) with the given big endian uint32 values
+func mp4FullBox(values ...uint32) []byte {
+	b := make([]byte, 4+len(values)*4)
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[4+i*4:], v)
+	}
+
+	return b
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4ChapterTrackFile builds a MP4 file with a video track that references a QuickTime chapter
+// track. The chapter samples are stored in the mdat box across 2 chunks, with the last title
+// encoded as UTF-16
+func mp4ChapterTrackFile() []byte {
+	utf16Title := []byte{0xFE, 0xFF, 0x00, 'W', 0x00, 'r', 0x00, 'a', 0x00, 'p'}
+
+	samples := [][]byte{
+		append([]byte{0x00, 0x05}, "Intro"...),
+		append([]byte{0x00, 0x07}, "Install"...),
+		append([]byte{0x00, byte(len(utf16Title))}, utf16Title...),
+	}
+
+	ftyp := mp4Box("ftyp", []byte("isom"), make([]byte, 4))
+	mdat := mp4Box("mdat", bytes.Join(samples, nil))
+
+	// The samples start after the ftyp box and mdat header
+	chunk1 := uint32(len(ftyp) + 8)
+	chunk2 := chunk1 + uint32(len(samples[0])+len(samples[1]))
+
+	tkhd := func(id uint32) []byte {
+		b := make([]byte, 84)
+		binary.BigEndian.PutUint32(b[12:16], id)
+		return b
+	}
+
+	hdlr := func(handler string) []byte {
+		b := make([]byte, 24)
+		copy(b[8:12], handler)
+		return b
+	}
+
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:16], 600)
+
+	videoTrack := mp4Box("trak",
+		mp4Box("tkhd", tkhd(1)),
+		mp4Box("tref", mp4Box("chap", []byte{0, 0, 0, 2})),
+		mp4Box("mdia", mp4Box("hdlr", hdlr("vide"))),
+	)
+
+	chapterTrack := mp4Box("trak",
+		mp4Box("tkhd", tkhd(2)),
+		mp4Box("mdia",
+			mp4Box("mdhd", mdhd),
+			mp4Box("hdlr", hdlr("text")),
+			mp4Box("minf",
+				mp4Box("stbl",
+					mp4Box("stts", mp4FullBox(3, 1, 6000, 1, 30000, 1, 36000)),
+					mp4Box("stsz", mp4FullBox(0, 3, uint32(len(samples[0])), uint32(len(samples[1])), uint32(len(samples[2])))),
+					mp4Box("stsc", mp4FullBox(2, 1, 2, 1, 2, 1, 1)),
+					mp4Box("stco", mp4FullBox(2, chunk1, chunk2)),
+				),
+			),
+		),
+	)
+
+	return bytes.Join([][]byte{ftyp, mdat, mp4Box("moov", videoTrack, chapterTrack)}, nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4ChplFile builds a MP4 file with a Nero chapter list. Start times are in 100 nanosecond units
+func mp4ChplFile(titles []string, starts []uint64) []byte {
+	chpl := []byte{1, 0, 0, 0, 0, 0, 0, 0, byte(len(titles))}
+	for i, title := range titles {
+		start := make([]byte, 8)
+		binary.BigEndian.PutUint64(start, starts[i])
+
+		chpl = append(chpl, start...)
+		chpl = append(chpl, byte(len(title)))
+		chpl = append(chpl, title...)
+	}
+
+	return bytes.Join([][]byte{
+		mp4Box("ftyp", []byte("isom"), make([]byte, 4)),
+		mp4Box("moov",
+			mp4TestTrack("vide", "avc1", 1920, 1080),
+			mp4Box("udta", mp4Box("chpl", chpl)),
+		),
+	}, nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ebmlElement builds an EBML element with an 8 byte size
+func ebmlElement(id uint32, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+
+	idBytes := []byte{}
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(idBytes) > 0 {
+			idBytes = append(idBytes, b)
+		}
+	}
+
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+
+	return bytes.Join([][]byte{idBytes, size, body}, nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ebmlUint builds an unsigned integer payload
+func ebmlUint(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ebmlFloat builds an 8 byte float payload
+func ebmlFloat(v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return b
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// matroskaTestFile builds a Matroska file with a video track and an audio track, followed by a
+// cluster
+func matroskaTestFile(durationMs float64, segmentChildren ...[]byte) []byte {
+	children := [][]byte{
+		ebmlElement(ebmlIDInfo,
+			ebmlElement(ebmlIDTimecodeScale, ebmlUint(1000000)),
+			ebmlElement(ebmlIDDuration, ebmlFloat(durationMs)),
+		),
+		ebmlElement(ebmlIDTracks,
+			ebmlElement(ebmlIDTrackEntry,
+				ebmlElement(ebmlIDTrackType, ebmlUint(1)),
+				ebmlElement(ebmlIDCodecID, []byte("V_MPEGH/ISO/HEVC")),
+				ebmlElement(ebmlIDVideo,
+					ebmlElement(ebmlIDPixelWidth, ebmlUint(1280)),
+					ebmlElement(ebmlIDPixelHeight, ebmlUint(720)),
+				),
+			),
+			ebmlElement(ebmlIDTrackEntry,
+				ebmlElement(ebmlIDTrackType, ebmlUint(2)),
+				ebmlElement(ebmlIDCodecID, []byte("A_OPUS")),
+			),
+		),
+	}
+
+	children = append(children, segmentChildren...)
+	children = append(children, ebmlElement(ebmlIDCluster, make([]byte, 512)))
+
+	return bytes.Join([][]byte{
+		ebmlElement(ebmlIDHeader, ebmlElement(0x4282, []byte("matroska"))),
+		ebmlElement(ebmlIDSegment, children...),
+	}, nil)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// matroskaChapterAtom builds a ChapterAtom element. The start is in milliseconds
+func matroskaChapterAtom(title string, startMs uint64, children ...[]byte) []byte {
+	children = append(children,
+		ebmlElement(ebmlIDChapterTimeStart, ebmlUint(startMs*1000000)),
+		ebmlElement(ebmlIDChapterDisplay, ebmlElement(ebmlIDChapString, []byte(title))),
+	)
+
+	return ebmlElement(ebmlIDChapterAtom, children...)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// riffChunk builds a RIFF chunk
+func riffChunk(id string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+
+	b := make([]byte, 8, 8+len(body)+1)
+	copy(b[0:4], id)
+	binary.LittleEndian.PutUint32(b[4:8], uint32(len(body)))
+	b = append(b, body...)
+
+	if len(body)%2 == 1 {
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// aviTestFile builds an AVI file with a video stream and an audio stream
+func aviTestFile(microSecPerFrame, totalFrames uint32) []byte {
+	avih := make([]byte, 56)
+	binary.LittleEndian.PutUint32(avih[0:4], microSecPerFrame)
+	binary.LittleEndian.PutUint32(avih[16:20], totalFrames)
+	binary.LittleEndian.PutUint32(avih[32:36], 640)
+	binary.LittleEndian.PutUint32(avih[36:40], 480)
+
+	vidsStrh := make([]byte, 56)
+	copy(vidsStrh[0:4], "vids")
+	vidsStrf := make([]byte, 40)
+	copy(vidsStrf[16:20], "XVID")
+
+	audsStrh := make([]byte, 56)
+	copy(audsStrh[0:4], "auds")
+	audsStrf := make([]byte, 18)
+	binary.LittleEndian.PutUint16(audsStrf[0:2], 0x0055)
+
+	riff := riffChunk("RIFF",
+		[]byte("AVI "),
+		riffChunk("LIST",
+			[]byte("hdrl"),
+			riffChunk("avih", avih),
+			riffChunk("LIST", []byte("strl"), riffChunk("strh", vidsStrh), riffChunk("strf", vidsStrf)),
+			riffChunk("LIST", []byte("strl"), riffChunk("strh", audsStrh), riffChunk("strf", audsStrf)),
+		),
+		riffChunk("LIST", []byte("movi"), make([]byte, 256)),
+	)
+
+	return riff
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestMedia_Probe(t *testing.T) {
+	t.Run("mp4", func(t *testing.T) {
+		meta, err := Probe(bytes.NewReader(mp4TestFile(1000, 125500)))
+		require.NoError(t, err)
+		require.Equal(t, 125.5, meta.Duration)
+		require.Equal(t, 126, meta.Seconds())
+		require.Equal(t, 1920, meta.Width)
+		require.Equal(t, 1080, meta.Height)
+		require.Equal(t, "h264", meta.VideoCodec)
+		require.Equal(t, "aac", meta.AudioCodec)
+		require.Equal(t, 2, meta.AudioTracks)
+	})
+
+	t.Run("mp4 unknown duration", func(t *testing.T) {
+		meta, err := Probe(bytes.NewReader(mp4TestFile(1000, 0xFFFFFFFF)))
+		require.NoError(t, err)
+		require.Zero(t, meta.Duration)
+	})
+
+	t.Run("mp4 without moov", func(t *testing.T) {
+		data := bytes.Join([][]byte{
+			mp4Box("ftyp", []byte("isom"), make([]byte, 4)),
+			mp4Box("mdat", make([]byte, 64)),
+		}, nil)
+
+		meta, err := Probe(bytes.NewReader(data))
+		require.ErrorIs(t, err, ErrMalformed)
+		require.Nil(t, meta)
+	})
+
+	t.Run("matroska", func(t *testing.T) {
+		meta, err := Probe(bytes.NewReader(matroskaTestFile(61000)))
+		require.NoError(t, err)
+		require.Equal(t, float64(61), meta.Duration)
+		require.Equal(t, 1280, meta.Width)
+		require.Equal(t, 720, meta.Height)
+		require.Equal(t, "hevc", meta.VideoCodec)
+		require.Equal(t, "opus", meta.AudioCodec)
+		require.Equal(t, 1, meta.AudioTracks)
+	})
+
+	t.Run("matroska truncated", func(t *testing.T) {
+		data := matroskaTestFile(61000)
+
+		meta, err := Probe(bytes.NewReader(data[:60]))
+		require.ErrorIs(t, err, ErrMalformed)
+		require.Nil(t, meta)
+	})
+
+	t.Run("mp4 chapter track", func(t *testing.T) {
+		meta, err := Probe(bytes.NewReader(mp4ChapterTrackFile()))
+		require.NoError(t, err)
+		require.Equal(t, []*Chapter{
+			{Title: "Intro", Start: 0},
+			{Title: "Install", Start: 10000},
+			{Title: "Wrap", Start: 60000},
+		}, meta.Chapters)
+	})
+
+	t.Run("mp4 nero chapters", func(t *testing.T) {
+		data := mp4ChplFile([]string{"Setup", "", "Intro"}, []uint64{50000000, 1000000000, 0})
+
+		meta, err := Probe(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, []*Chapter{
+			{Title: "Intro", Start: 0},
+			{Title: "Setup", Start: 5000},
+			{Title: "Chapter 3", Start: 100000},
+		}, meta.Chapters)
+	})
+
+	t.Run("mp4 without chapters", func(t *testing.T) {
+		meta, err := Probe(bytes.NewReader(mp4TestFile(1000, 125500)))
+		require.NoError(t, err)
+		require.Empty(t, meta.Chapters)
+	})
+
+	t.Run("matroska chapters", func(t *testing.T) {
+		chapters := ebmlElement(ebmlIDChapters,
+			// Hidden edition
+			ebmlElement(ebmlIDEditionEntry,
+				ebmlElement(ebmlIDEditionFlagHidden, ebmlUint(1)),
+				matroskaChapterAtom("Hidden edition", 0),
+			),
+			ebmlElement(ebmlIDEditionEntry,
+				matroskaChapterAtom("Intro", 0),
+				matroskaChapterAtom("Hidden", 5000, ebmlElement(ebmlIDChapterFlagHidden, ebmlUint(1))),
+				matroskaChapterAtom("Disabled", 6000, ebmlElement(ebmlIDChapterFlagEnabled, ebmlUint(0))),
+				matroskaChapterAtom("Install", 30500),
+			),
+		)
+
+		meta, err := Probe(bytes.NewReader(matroskaTestFile(61000, chapters)))
+		require.NoError(t, err)
+		require.Equal(t, []*Chapter{
+			{Title: "Intro", Start: 0},
+			{Title: "Install", Start: 30500},
+		}, meta.Chapters)
+	})
+
+	t.Run("matroska default edition", func(t *testing.T) {
+		chapters := ebmlElement(ebmlIDChapters,
+			ebmlElement(ebmlIDEditionEntry, matroskaChapterAtom("First", 0)),
+			ebmlElement(ebmlIDEditionEntry,
+				ebmlElement(ebmlIDEditionFlagDefault, ebmlUint(1)),
+				matroskaChapterAtom("Default", 0),
+			),
+		)
+
+		meta, err := Probe(bytes.NewReader(matroskaTestFile(61000, chapters)))
+		require.NoError(t, err)
+		require.Equal(t, []*Chapter{{Title: "Default", Start: 0}}, meta.Chapters)
+	})
+
+	t.Run("matroska chapters after clusters", func(t *testing.T) {
+		info := ebmlElement(ebmlIDInfo, ebmlElement(ebmlIDDuration, ebmlFloat(61000)))
+		cluster := ebmlElement(ebmlIDCluster, make([]byte, 512))
+		chapters := ebmlElement(ebmlIDChapters,
+			ebmlElement(ebmlIDEditionEntry, matroskaChapterAtom("Intro", 0), matroskaChapterAtom("Outro", 60000)),
+		)
+
+		seekHead := func(pos uint64) []byte {
+			return ebmlElement(ebmlIDSeekHead,
+				ebmlElement(ebmlIDSeek,
+					ebmlElement(ebmlIDSeekID, []byte{0x10, 0x43, 0xA7, 0x70}),
+					ebmlElement(ebmlIDSeekPosition, ebmlUint(pos)),
+				),
+			)
+		}
+
+		// The position is relative to the start of the segment data
+		pos := uint64(len(seekHead(0)) + len(info) + len(cluster))
+
+		data := bytes.Join([][]byte{
+			ebmlElement(ebmlIDHeader, ebmlElement(0x4282, []byte("matroska"))),
+			ebmlElement(ebmlIDSegment, seekHead(pos), info, cluster, chapters),
+		}, nil)
+
+		meta, err := Probe(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, float64(61), meta.Duration)
+		require.Equal(t, []*Chapter{
+			{Title: "Intro", Start: 0},
+			{Title: "Outro", Start: 60000},
+		}, meta.Chapters)
+	})
+
+	t.Run("avi", func(t *testing.T) {
+		meta, err := Probe(bytes.NewReader(aviTestFile(40000, 750)))
+		require.NoError(t, err)
+		require.Equal(t, float64(30), meta.Duration)
+		require.Equal(t, 640, meta.Width)
+		require.Equal(t, 480, meta.Height)
+		require.Equal(t, "mpeg4", meta.VideoCodec)
+		require.Equal(t, "mp3", meta.AudioCodec)
+		require.Equal(t, 1, meta.AudioTracks)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		for _, data := range [][]byte{nil, []byte("video"), []byte("ID3 an mp3 file header")} {
+			meta, err := Probe(bytes.NewReader(data))
+			require.ErrorIs(t, err, ErrUnsupported)
+			require.Nil(t, meta)
+		}
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestMedia_CodecName(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"avc1", "h264"},
+		{"hev1", "hevc"},
+		{"V_VP9", "vp9"},
+		{"A_AAC/MPEG4/LC", "aac"},
+		{"DIVX", "mpeg4"},
+		{"H264\x00", "h264"},
+		{"wmv3", "wmv3"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, codecName(tt.in), tt.in)
+	}
+}
@@ -0,0 +1,617 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const (
+	// The maximum size of a moov box that will be read into memory
+	maxMoovSize = 64 * 1024 * 1024
+
+	// The maximum number of samples read from a QuickTime chapter track
+	maxChapterSamples = 4096
+
+	// The maximum size of a QuickTime chapter track sample that will be read into memory
+	maxChapterSampleSize = 4096
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// isMP4BoxType returns true when the box type is one that can appear at the start of an ISO BMFF
+// (MP4/MOV) file
+func isMP4BoxType(typ string) bool {
+	switch typ {
+	case "ftyp", "moov", "mdat", "free", "skip", "wide", "pnot":
+		return true
+	}
+
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseMP4 finds the moov box and extracts the metadata from it
+func parseMP4(r io.ReadSeeker) (*Metadata, error) {
+	moov, err := readMoov(r)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := parseMoov(moov)
+	if err != nil {
+		return nil, err
+	}
+
+	meta.Chapters = parseMP4Chapters(r, moov)
+	return meta, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readMoov walks the top level boxes, skipping over the (potentially huge) mdat box, and returns
+// the payload of the moov box
+func readMoov(r io.ReadSeeker) ([]byte, error) {
+	pos := int64(0)
+
+	for {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		var hdr [16]byte
+		if _, err := io.ReadFull(r, hdr[:8]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, ErrMalformed
+			}
+
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		typ := string(hdr[4:8])
+		hdrLen := int64(8)
+
+		if size == 1 {
+			if _, err := io.ReadFull(r, hdr[8:16]); err != nil {
+				return nil, ErrMalformed
+			}
+
+			size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			hdrLen = 16
+		}
+
+		if typ == "moov" {
+			// A size of 0 means the box extends to the end of the file
+			if size == 0 {
+				end, err := r.Seek(0, io.SeekEnd)
+				if err != nil {
+					return nil, err
+				}
+
+				size = end - pos
+
+				if _, err := r.Seek(pos+hdrLen, io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+
+			if size < hdrLen || size-hdrLen > maxMoovSize {
+				return nil, ErrMalformed
+			}
+
+			moov := make([]byte, size-hdrLen)
+			if _, err := io.ReadFull(r, moov); err != nil {
+				return nil, ErrMalformed
+			}
+
+			return moov, nil
+		}
+
+		// Any other box that extends to the end of the file means there is no moov box
+		if size == 0 {
+			return nil, ErrMalformed
+		}
+
+		if size < hdrLen {
+			return nil, ErrMalformed
+		}
+
+		pos += size
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// eachBox iterates the boxes in data, calling fn with the type and payload of each box
+func eachBox(data []byte, fn func(typ string, payload []byte) error) error {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[:4]))
+		typ := string(data[4:8])
+		hdrLen := uint64(8)
+
+		if size == 1 {
+			if len(data) < 16 {
+				return ErrMalformed
+			}
+
+			size = binary.BigEndian.Uint64(data[8:16])
+			hdrLen = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+
+		if size < hdrLen || size > uint64(len(data)) {
+			return ErrMalformed
+		}
+
+		if err := fn(typ, data[hdrLen:size]); err != nil {
+			return err
+		}
+
+		data = data[size:]
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// findBox returns the payload of the first box matching the path of box types. For example,
+// findBox(trak, "mdia", "hdlr") returns the payload of trak/mdia/hdlr
+func findBox(data []byte, path ...string) []byte {
+	if len(path) == 0 {
+		return data
+	}
+
+	var found []byte
+	eachBox(data, func(typ string, payload []byte) error {
+		if typ == path[0] {
+			found = findBox(payload, path[1:]...)
+			if found != nil {
+				return io.EOF
+			}
+		}
+
+		return nil
+	})
+
+	return found
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4Track defines the information extracted from a trak box
+type mp4Track struct {
+	handler string
+	codec   string
+	width   int
+	height  int
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseMoov extracts the metadata from the payload of a moov box
+func parseMoov(moov []byte) (*Metadata, error) {
+	meta := &Metadata{}
+
+	err := eachBox(moov, func(typ string, payload []byte) error {
+		switch typ {
+		case "mvhd":
+			meta.Duration = parseMvhd(payload)
+		case "trak":
+			track := parseTrak(payload)
+
+			switch track.handler {
+			case "vide":
+				if meta.VideoCodec == "" {
+					meta.VideoCodec = codecName(track.codec)
+					meta.Width = track.width
+					meta.Height = track.height
+				}
+			case "soun":
+				if meta.AudioCodec == "" {
+					meta.AudioCodec = codecName(track.codec)
+				}
+
+				meta.AudioTracks++
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseMvhd returns the movie duration, in seconds, from the payload of a mvhd box
+func parseMvhd(p []byte) float64 {
+	if len(p) < 1 {
+		return 0
+	}
+
+	var timescale, duration uint64
+
+	if p[0] == 1 {
+		if len(p) < 32 {
+			return 0
+		}
+
+		timescale = uint64(binary.BigEndian.Uint32(p[20:24]))
+		duration = binary.BigEndian.Uint64(p[24:32])
+	} else {
+		if len(p) < 20 {
+			return 0
+		}
+
+		timescale = uint64(binary.BigEndian.Uint32(p[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(p[16:20]))
+	}
+
+	// A duration of all 1s means the duration is unknown
+	if timescale == 0 || duration == 0xFFFFFFFF || duration == 0xFFFFFFFFFFFFFFFF {
+		return 0
+	}
+
+	return float64(duration) / float64(timescale)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseTrak extracts the handler, codec and resolution from the payload of a trak box
+func parseTrak(trak []byte) *mp4Track {
+	track := &mp4Track{}
+
+	// Handler (vide, soun, text, ...)
+	if hdlr := findBox(trak, "mdia", "hdlr"); len(hdlr) >= 12 {
+		track.handler = string(hdlr[8:12])
+	}
+
+	// Resolution from the track header. This is a 16.16 fixed point number
+	if tkhd := findBox(trak, "tkhd"); len(tkhd) > 0 {
+		offset := 76
+		if tkhd[0] == 1 {
+			offset = 88
+		}
+
+		if len(tkhd) >= offset+8 {
+			track.width = int(binary.BigEndian.Uint32(tkhd[offset:offset+4]) >> 16)
+			track.height = int(binary.BigEndian.Uint32(tkhd[offset+4:offset+8]) >> 16)
+		}
+	}
+
+	// Codec from the first sample description
+	if stsd := findBox(trak, "mdia", "minf", "stbl", "stsd"); len(stsd) >= 8 {
+		eachBox(stsd[8:], func(typ string, payload []byte) error {
+			track.codec = typ
+
+			// Fallback to the resolution in the visual sample entry
+			if track.handler == "vide" && (track.width == 0 || track.height == 0) && len(payload) >= 28 {
+				track.width = int(binary.BigEndian.Uint16(payload[24:26]))
+				track.height = int(binary.BigEndian.Uint16(payload[26:28]))
+			}
+
+			return io.EOF
+		})
+	}
+
+	return track
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseMP4Chapters extracts the chapter markers from a QuickTime chapter track, falling back to a
+// Nero chapter list (moov/udta/chpl). Chapters are optional, so any failure results in no chapters
+func parseMP4Chapters(r io.ReadSeeker, moov []byte) []*Chapter {
+	traks := map[uint32][]byte{}
+	chapterTrackIDs := []uint32{}
+
+	eachBox(moov, func(typ string, payload []byte) error {
+		if typ != "trak" {
+			return nil
+		}
+
+		if id := mp4TrackID(payload); id != 0 {
+			traks[id] = payload
+		}
+
+		// A track references its chapter track(s) through tref/chap
+		chap := findBox(payload, "tref", "chap")
+		for i := 0; i+4 <= len(chap); i += 4 {
+			chapterTrackIDs = append(chapterTrackIDs, binary.BigEndian.Uint32(chap[i:i+4]))
+		}
+
+		return nil
+	})
+
+	for _, id := range chapterTrackIDs {
+		if trak, ok := traks[id]; ok {
+			if chapters := normalizeChapters(readMP4ChapterTrack(r, trak)); len(chapters) > 0 {
+				return chapters
+			}
+		}
+	}
+
+	return normalizeChapters(parseChpl(findBox(moov, "udta", "chpl")))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4TrackID returns the track ID from the tkhd box of a trak box, or 0 when it cannot be found
+func mp4TrackID(trak []byte) uint32 {
+	tkhd := findBox(trak, "tkhd")
+	if len(tkhd) < 1 {
+		return 0
+	}
+
+	offset := 12
+	if tkhd[0] == 1 {
+		offset = 20
+	}
+
+	if len(tkhd) < offset+4 {
+		return 0
+	}
+
+	return binary.BigEndian.Uint32(tkhd[offset : offset+4])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readMP4ChapterTrack reads the samples of a QuickTime chapter (text) track. Each sample is a
+// 16-bit length followed by the title and the start of the sample is the start of the chapter
+func readMP4ChapterTrack(r io.ReadSeeker, trak []byte) []*Chapter {
+	// The timescale of the media, from mdhd
+	mdhd := findBox(trak, "mdia", "mdhd")
+	if len(mdhd) < 1 {
+		return nil
+	}
+
+	offset := 12
+	if mdhd[0] == 1 {
+		offset = 20
+	}
+
+	if len(mdhd) < offset+4 {
+		return nil
+	}
+
+	timescale := uint64(binary.BigEndian.Uint32(mdhd[offset : offset+4]))
+	if timescale == 0 {
+		return nil
+	}
+
+	stbl := findBox(trak, "mdia", "minf", "stbl")
+	starts := mp4SampleStarts(findBox(stbl, "stts"))
+	sizes := mp4SampleSizes(findBox(stbl, "stsz"))
+	offsets := mp4SampleOffsets(stbl, sizes)
+
+	count := min(len(starts), len(sizes), len(offsets), maxChapterSamples)
+
+	chapters := make([]*Chapter, 0, count)
+	for i := 0; i < count; i++ {
+		size := min(sizes[i], maxChapterSampleSize)
+		if size < 2 {
+			continue
+		}
+
+		if _, err := r.Seek(offsets[i], io.SeekStart); err != nil {
+			return nil
+		}
+
+		sample := make([]byte, size)
+		if _, err := io.ReadFull(r, sample); err != nil {
+			return nil
+		}
+
+		length := int(binary.BigEndian.Uint16(sample[:2]))
+		text := sample[2:]
+		if length < len(text) {
+			text = text[:length]
+		}
+
+		chapters = append(chapters, &Chapter{
+			Title: decodeMP4Text(text),
+			Start: int64(starts[i] * 1000 / timescale),
+		})
+	}
+
+	return chapters
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4SampleStarts returns the start time of each sample, in media timescale units, from the
+// payload of a stts box
+func mp4SampleStarts(stts []byte) []uint64 {
+	if len(stts) < 8 {
+		return nil
+	}
+
+	entries := int(binary.BigEndian.Uint32(stts[4:8]))
+	starts := []uint64{}
+	time := uint64(0)
+
+	for i := 0; i < entries && 8+i*8+8 <= len(stts); i++ {
+		entry := stts[8+i*8:]
+		count := int(binary.BigEndian.Uint32(entry[0:4]))
+		delta := uint64(binary.BigEndian.Uint32(entry[4:8]))
+
+		for j := 0; j < count && len(starts) < maxChapterSamples; j++ {
+			starts = append(starts, time)
+			time += delta
+		}
+	}
+
+	return starts
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4SampleSizes returns the size of each sample from the payload of a stsz box
+func mp4SampleSizes(stsz []byte) []int {
+	if len(stsz) < 12 {
+		return nil
+	}
+
+	size := int(binary.BigEndian.Uint32(stsz[4:8]))
+	count := min(int(binary.BigEndian.Uint32(stsz[8:12])), maxChapterSamples)
+
+	sizes := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		// A non-zero size means all samples are the same size
+		if size != 0 {
+			sizes = append(sizes, size)
+			continue
+		}
+
+		if 12+i*4+4 > len(stsz) {
+			break
+		}
+
+		sizes = append(sizes, int(binary.BigEndian.Uint32(stsz[12+i*4:])))
+	}
+
+	return sizes
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// mp4SampleOffsets returns the file offset of each sample using the chunk offsets (stco or co64)
+// and the sample-to-chunk (stsc) boxes of a stbl box
+func mp4SampleOffsets(stbl []byte, sizes []int) []int64 {
+	chunks := []int64{}
+
+	if stco := findBox(stbl, "stco"); len(stco) >= 8 {
+		count := int(binary.BigEndian.Uint32(stco[4:8]))
+		for i := 0; i < count && 8+i*4+4 <= len(stco); i++ {
+			chunks = append(chunks, int64(binary.BigEndian.Uint32(stco[8+i*4:])))
+		}
+	} else if co64 := findBox(stbl, "co64"); len(co64) >= 8 {
+		count := int(binary.BigEndian.Uint32(co64[4:8]))
+		for i := 0; i < count && 8+i*8+8 <= len(co64); i++ {
+			chunks = append(chunks, int64(binary.BigEndian.Uint64(co64[8+i*8:])))
+		}
+	}
+
+	// Each stsc entry is the first chunk (1-based) and the number of samples per chunk, which
+	// applies until the next entry
+	type stscEntry struct {
+		firstChunk      int
+		samplesPerChunk int
+	}
+
+	entries := []stscEntry{}
+	if stsc := findBox(stbl, "stsc"); len(stsc) >= 8 {
+		count := int(binary.BigEndian.Uint32(stsc[4:8]))
+		for i := 0; i < count && 8+i*12+12 <= len(stsc); i++ {
+			entry := stsc[8+i*12:]
+			entries = append(entries, stscEntry{
+				firstChunk:      int(binary.BigEndian.Uint32(entry[0:4])),
+				samplesPerChunk: int(binary.BigEndian.Uint32(entry[4:8])),
+			})
+		}
+	}
+
+	offsets := make([]int64, 0, len(sizes))
+	entry := 0
+
+	for i, offset := range chunks {
+		for entry+1 < len(entries) && entries[entry+1].firstChunk <= i+1 {
+			entry++
+		}
+
+		if len(entries) == 0 || entries[entry].firstChunk > i+1 {
+			break
+		}
+
+		for j := 0; j < entries[entry].samplesPerChunk && len(offsets) < len(sizes); j++ {
+			offsets = append(offsets, offset)
+			offset += int64(sizes[len(offsets)-1])
+		}
+
+		if len(offsets) == len(sizes) {
+			break
+		}
+	}
+
+	return offsets
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// decodeMP4Text decodes the text of a QuickTime text sample, which is UTF-8 unless it starts with
+// a UTF-16 byte order mark
+func decodeMP4Text(text []byte) string {
+	if len(text) < 2 || len(text)%2 != 0 {
+		return string(text)
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case text[0] == 0xFE && text[1] == 0xFF:
+		order = binary.BigEndian
+	case text[0] == 0xFF && text[1] == 0xFE:
+		order = binary.LittleEndian
+	default:
+		return string(text)
+	}
+
+	units := make([]uint16, 0, len(text)/2-1)
+	for i := 2; i+2 <= len(text); i += 2 {
+		units = append(units, order.Uint16(text[i:i+2]))
+	}
+
+	return string(utf16.Decode(units))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseChpl parses the payload of a Nero chapter list (chpl) box. Start times are in 100
+// nanosecond units
+func parseChpl(chpl []byte) []*Chapter {
+	if len(chpl) < 5 {
+		return nil
+	}
+
+	offset := 4
+	if chpl[0] == 1 {
+		offset += 4
+	}
+
+	if len(chpl) < offset+1 {
+		return nil
+	}
+
+	count := int(chpl[offset])
+	offset++
+
+	chapters := make([]*Chapter, 0, count)
+	for i := 0; i < count && offset+9 <= len(chpl); i++ {
+		start := binary.BigEndian.Uint64(chpl[offset : offset+8])
+		length := int(chpl[offset+8])
+		offset += 9
+
+		if offset+length > len(chpl) {
+			break
+		}
+
+		chapters = append(chapters, &Chapter{
+			Title: string(chpl[offset : offset+length]),
+			Start: int64(start / 10000),
+		})
+
+		offset += length
+	}
+
+	return chapters
+}
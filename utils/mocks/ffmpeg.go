@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ffmpegStub is a shell script standing in for ffmpeg. It answers `-version` and writes a small
+// placeholder to the output path (the last argument). For HLS, a playlist and a single segment are
+// written. For images, `jpeg` is written. An input path containing `fail` makes it exit with an error
+//
+// When FFMPEG_STUB_SLEEP is set, the run takes that many more seconds. The HLS segment and an
+// unended playlist are written first, as ffmpeg does while it runs
+const ffmpegStub = `#!/bin/sh
+if [ "$1" = "-version" ]; then
+	echo "ffmpeg version stub Copyright (c) off-course"
+	exit 0
+fi
+
+for arg in "$@"; do
+	case "$arg" in
+		*fail*) echo "stub failure" >&2; exit 1 ;;
+	esac
+	out="$arg"
+done
+
+if [ -n "$FFMPEG_STUB_LOG" ]; then
+	echo "$@" >> "$FFMPEG_STUB_LOG"
+fi
+
+case "$out" in
+	*.m3u8)
+		dir=$(dirname "$out")
+		printf 'segment' > "$dir/segment00000.ts"
+		printf '#EXTM3U\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXTINF:6.0,\nsegment00000.ts\n' > "$out"
+		sleep "${FFMPEG_STUB_SLEEP:-0}"
+		printf '#EXT-X-ENDLIST\n' >> "$out"
+		;;
+	*.jpg)
+		sleep "${FFMPEG_STUB_SLEEP:-0}"
+		printf 'jpeg' > "$out"
+		;;
+	*)
+		sleep "${FFMPEG_STUB_SLEEP:-0}"
+		printf 'fragmented mp4' > "$out"
+		;;
+esac
+`
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// StubFFmpeg writes an executable ffmpeg stub to dir and returns its path. Only unix-like systems
+// are supported
+func StubFFmpeg(dir string) (string, error) {
+	path := filepath.Join(dir, "ffmpeg")
+
+	if err := os.WriteFile(path, []byte(ffmpegStub), 0o755); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
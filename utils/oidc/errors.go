@@ -0,0 +1,19 @@
+package oidc
+
+import "errors"
+
+var (
+	ErrIssuerMismatch     = errors.New("issuer does not match the discovery document")
+	ErrMissingIDToken     = errors.New("token response is missing the id_token")
+	ErrMalformedToken     = errors.New("malformed token")
+	ErrUnsupportedAlg     = errors.New("unsupported signing algorithm")
+	ErrUnknownKey         = errors.New("no key matches the token")
+	ErrInvalidSignature   = errors.New("invalid token signature")
+	ErrInvalidIssuer      = errors.New("token issuer does not match")
+	ErrInvalidAudience    = errors.New("token audience does not match the client")
+	ErrTokenExpired       = errors.New("token has expired")
+	ErrTokenNotYetValid   = errors.New("token is not yet valid")
+	ErrInvalidNonce       = errors.New("token nonce does not match")
+	ErrMissingSubject     = errors.New("token is missing the subject")
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	// Registers the hashes of the supported algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The clock skew allowed when checking the expiry of a token
+const clockSkew = time.Minute
+
+// The hashes of the supported signing algorithms. Symmetric algorithms and `none` are not
+// supported, as an ID token must be signed by a key of the provider
+var signingHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"PS256": crypto.SHA256,
+	"PS384": crypto.SHA384,
+	"PS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// token is a parsed, but not yet verified, JWT
+type token struct {
+	alg    string
+	kid    string
+	claims Claims
+
+	signingInput []byte
+	signature    []byte
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseToken parses a JWT in compact serialization
+func parseToken(raw string) (*token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if _, ok := signingHashes[header.Alg]; !ok {
+		return nil, ErrUnsupportedAlg
+	}
+
+	claims := Claims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	return &token{
+		alg:          header.Alg,
+		kid:          header.Kid,
+		claims:       claims,
+		signingInput: []byte(parts[0] + "." + parts[1]),
+		signature:    signature,
+	}, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// verify verifies the signature of the token with the public key. The key type must match the
+// algorithm
+func (t *token) verify(publicKey crypto.PublicKey) error {
+	hash := signingHashes[t.alg]
+
+	h := hash.New()
+	h.Write(t.signingInput)
+	digest := h.Sum(nil)
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		switch t.alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(key, hash, digest, t.signature)
+		case "PS":
+			return rsa.VerifyPSS(key, hash, digest, t.signature, nil)
+		}
+	case *ecdsa.PublicKey:
+		if t.alg[:2] != "ES" {
+			break
+		}
+
+		// The signature is R and S, each padded to the size of the curve
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return ErrInvalidSignature
+		}
+
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+
+		if ecdsa.Verify(key, digest, r, s) {
+			return nil
+		}
+
+		return ErrInvalidSignature
+	}
+
+	return ErrUnsupportedAlg
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// decodeSegment decodes a base64url encoded JSON segment of a JWT
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Claims holds the claims of an ID token
+type Claims map[string]any
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Subject returns the `sub` claim, which identifies the user at the provider
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// String returns a string claim. An empty string is returned when the claim is missing or is not
+// a string
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Strings returns a claim that is either a string or an array of strings, such as `aud` or
+// `groups`. Values that are not strings are skipped
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := []string{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+
+		return values
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// time returns a numeric date claim. False is returned when the claim is missing
+func (c Claims) time(name string) (time.Time, bool) {
+	v, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(v), 0), true
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// validate validates the claims of an ID token for the issuer and client
+func (c Claims) validate(issuer, clientID, nonce string, now time.Time) error {
+	if strings.TrimSuffix(c.String("iss"), "/") != strings.TrimSuffix(issuer, "/") {
+		return ErrInvalidIssuer
+	}
+
+	audience := c.Strings("aud")
+	if !slices.Contains(audience, clientID) {
+		return ErrInvalidAudience
+	}
+
+	// With several audiences, the authorized party must be the client
+	if azp := c.String("azp"); (len(audience) > 1 || azp != "") && azp != clientID {
+		return ErrInvalidAudience
+	}
+
+	exp, ok := c.time("exp")
+	if !ok || now.After(exp.Add(clockSkew)) {
+		return ErrTokenExpired
+	}
+
+	if nbf, ok := c.time("nbf"); ok && now.Add(clockSkew).Before(nbf) {
+		return ErrTokenNotYetValid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.String("nonce")), []byte(nonce)) != 1 {
+		return ErrInvalidNonce
+	}
+
+	if c.Subject() == "" {
+		return ErrMissingSubject
+	}
+
+	return nil
+}
@@ -0,0 +1,372 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geerew/off-course/utils/security"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The scopes requested when none are configured
+var defaultScopes = []string{"openid", "profile", "email"}
+
+// How long a request to the provider may take
+const requestTimeout = 10 * time.Second
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Config defines the configuration for a Provider
+type Config struct {
+	// The issuer URL. The discovery document is fetched from
+	// `<issuer>/.well-known/openid-configuration`
+	IssuerURL string
+
+	ClientID     string
+	ClientSecret string
+
+	// The URL the provider redirects back to after the user signs in
+	RedirectURL string
+
+	// The scopes to request. Defaults to `openid profile email`. `openid` is always requested
+	Scopes []string
+
+	// Defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Provider is an OpenID Connect relying party using the authorization code flow with PKCE. The
+// discovery document and signing keys are fetched on first use, so the app starts when the
+// provider is unreachable
+type Provider struct {
+	config *Config
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      []*jsonWebKey
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// discovery holds the fields used from the discovery document
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// New creates a new Provider
+func New(config *Config) *Provider {
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	return &Provider{
+		config: config,
+		client: client,
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewVerifier returns a random PKCE code verifier
+func NewVerifier() string {
+	return security.RandomString(64)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Challenge returns the S256 PKCE code challenge of a code verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// AuthCodeURL returns the URL of the provider to send the user to. The state and nonce are echoed
+// back in the redirect and the ID token respectively. The code challenge is derived from the
+// verifier, which must be passed to `Login`
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return d.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Login exchanges the authorization code for an ID token and returns its verified claims. The
+// verifier and nonce must be those passed to `AuthCodeURL`
+func (p *Provider) Login(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	rawIDToken, err := p.exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Verify(ctx, rawIDToken, nonce)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Verify verifies the signature and claims of an ID token and returns its claims. The token must
+// be signed by a key of the provider and be issued by the provider for this client. It must not
+// have expired and its nonce must match
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (Claims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := parseToken(rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.verifySignature(ctx, token); err != nil {
+		return nil, err
+	}
+
+	if err := token.claims.validate(d.Issuer, p.config.ClientID, nonce, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return token.claims, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// scopes returns the configured scopes, making sure `openid` is included
+func (p *Provider) scopes() []string {
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+
+	for _, scope := range scopes {
+		if scope == "openid" {
+			return scopes
+		}
+	}
+
+	return append([]string{"openid"}, scopes...)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// discover returns the discovery document, fetching it on first use. A failed fetch is retried on
+// the next call
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(p.config.IssuerURL, "/")
+
+	d := &discovery{}
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, fmt.Errorf("failed to fetch the discovery document: %w", err)
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, ErrIssuerMismatch
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JwksURI == "" {
+		return nil, fmt.Errorf("the discovery document is missing an endpoint")
+	}
+
+	p.discovery = d
+
+	return d, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// exchange exchanges an authorization code for the raw ID token. The client authenticates with
+// HTTP basic auth when it has a secret
+func (p *Provider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body := struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode the token response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("token request failed (status %d): %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	if body.IDToken == "" {
+		return "", ErrMissingIDToken
+	}
+
+	return body.IDToken, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// verifySignature verifies the signature of a token against the signing keys of the provider.
+// When no key matches, the keys are fetched again as the provider may have rotated them
+func (p *Provider) verifySignature(ctx context.Context, token *token) error {
+	for _, refresh := range []bool{false, true} {
+		keys, err := p.signingKeys(ctx, refresh)
+		if err != nil {
+			return err
+		}
+
+		var candidates []crypto.PublicKey
+		for _, key := range keys {
+			if token.kid != "" && key.Kid != token.kid {
+				continue
+			}
+
+			publicKey, err := key.publicKey()
+			if err != nil {
+				continue
+			}
+
+			candidates = append(candidates, publicKey)
+		}
+
+		if len(candidates) == 0 {
+			continue
+		}
+
+		for _, publicKey := range candidates {
+			if err := token.verify(publicKey); err == nil {
+				return nil
+			}
+		}
+
+		return ErrInvalidSignature
+	}
+
+	return ErrUnknownKey
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// signingKeys returns the signing keys of the provider, fetching them on first use or when
+// refresh is true
+func (p *Provider) signingKeys(ctx context.Context, refresh bool) ([]*jsonWebKey, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil && !refresh {
+		return p.keys, nil
+	}
+
+	set := &jsonWebKeySet{}
+	if err := p.getJSON(ctx, d.JwksURI, set); err != nil {
+		return nil, fmt.Errorf("failed to fetch the signing keys: %w", err)
+	}
+
+	p.keys = set.Keys
+
+	return p.keys, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// getJSON fetches a URL and decodes the JSON response into v
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,369 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geerew/off-course/utils/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestChallenge(t *testing.T) {
+	require.Equal(t, "FhTTrPZaq6fL5-jOz_bWMZBcCPlJg0HWREM__8LyDh4", Challenge("dBjftJeZ4CVP-mJ0oWMwPBkHbHZcxqXSu-1lX2aEnL0"))
+
+	verifier := NewVerifier()
+	require.Len(t, verifier, 64)
+	require.NotEqual(t, verifier, NewVerifier())
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		issuer, provider := setup(t)
+
+		u, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(u, issuer.URL()+"/authorize?"))
+
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+
+		q := parsed.Query()
+		require.Equal(t, "code", q.Get("response_type"))
+		require.Equal(t, "client", q.Get("client_id"))
+		require.Equal(t, "http://localhost/callback", q.Get("redirect_uri"))
+		require.Equal(t, "openid profile email", q.Get("scope"))
+		require.Equal(t, "state", q.Get("state"))
+		require.Equal(t, "nonce", q.Get("nonce"))
+		require.Equal(t, Challenge("verifier"), q.Get("code_challenge"))
+		require.Equal(t, "S256", q.Get("code_challenge_method"))
+	})
+
+	t.Run("openid scope added", func(t *testing.T) {
+		_, provider := setup(t)
+		provider.config.Scopes = []string{"profile", "groups"}
+
+		u, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+		require.NoError(t, err)
+
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+		require.Equal(t, "openid profile groups", parsed.Query().Get("scope"))
+	})
+
+	t.Run("issuer mismatch", func(t *testing.T) {
+		issuer, _ := setup(t)
+
+		// The discovery document is served by the issuer, but declares a different issuer
+		provider := New(&Config{IssuerURL: issuer.URL() + "/", ClientID: "client"})
+		provider.config.IssuerURL = strings.Replace(issuer.URL(), "127.0.0.1", "localhost", 1)
+
+		_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+		require.ErrorIs(t, err, ErrIssuerMismatch)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		issuer, provider := setup(t)
+		issuer.Close()
+
+		_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+		require.ErrorContains(t, err, "failed to fetch the discovery document")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestProvider_Login(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		issuer, provider := setup(t)
+		issuer.Claims["sub"] = "user-1"
+		issuer.Claims["preferred_username"] = "bob"
+		issuer.Claims["groups"] = []string{"dev", "admins"}
+
+		verifier := NewVerifier()
+		code := authorizeHelper(t, provider, "state", "nonce", verifier)
+
+		claims, err := provider.Login(context.Background(), code, verifier, "nonce")
+		require.NoError(t, err)
+		require.Equal(t, "user-1", claims.Subject())
+		require.Equal(t, "bob", claims.String("preferred_username"))
+		require.Equal(t, []string{"dev", "admins"}, claims.Strings("groups"))
+
+		// A code can only be used once
+		_, err = provider.Login(context.Background(), code, verifier, "nonce")
+		require.ErrorContains(t, err, "invalid_grant")
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		_, provider := setup(t)
+
+		code := authorizeHelper(t, provider, "state", "nonce", NewVerifier())
+
+		_, err := provider.Login(context.Background(), code, NewVerifier(), "nonce")
+		require.ErrorContains(t, err, "PKCE verification failed")
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		_, provider := setup(t)
+
+		verifier := NewVerifier()
+		code := authorizeHelper(t, provider, "state", "nonce", verifier)
+
+		_, err := provider.Login(context.Background(), code, verifier, "other")
+		require.ErrorIs(t, err, ErrInvalidNonce)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		_, provider := setup(t)
+		provider.config.ClientSecret = "wrong"
+
+		verifier := NewVerifier()
+		code := authorizeHelper(t, provider, "state", "nonce", verifier)
+
+		_, err := provider.Login(context.Background(), code, verifier, "nonce")
+		require.ErrorContains(t, err, "invalid_client")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestProvider_Verify(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		issuer, provider := setup(t)
+
+		idToken, err := issuer.SignIDToken(issuer.IDTokenClaims("nonce"))
+		require.NoError(t, err)
+
+		claims, err := provider.Verify(context.Background(), idToken, "nonce")
+		require.NoError(t, err)
+		require.Equal(t, "subject", claims.Subject())
+	})
+
+	t.Run("invalid claims", func(t *testing.T) {
+		issuer, provider := setup(t)
+
+		tests := []struct {
+			name   string
+			modify func(claims map[string]any)
+			err    error
+		}{
+			{"issuer", func(c map[string]any) { c["iss"] = "https://other" }, ErrInvalidIssuer},
+			{"audience", func(c map[string]any) { c["aud"] = "other" }, ErrInvalidAudience},
+			{"audiences", func(c map[string]any) { c["aud"] = []string{"other", "client"} }, ErrInvalidAudience},
+			{"authorized party", func(c map[string]any) { c["azp"] = "other" }, ErrInvalidAudience},
+			{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, ErrTokenExpired},
+			{"missing expiry", func(c map[string]any) { delete(c, "exp") }, ErrTokenExpired},
+			{"not yet valid", func(c map[string]any) { c["nbf"] = time.Now().Add(time.Hour).Unix() }, ErrTokenNotYetValid},
+			{"nonce", func(c map[string]any) { c["nonce"] = "other" }, ErrInvalidNonce},
+			{"subject", func(c map[string]any) { delete(c, "sub") }, ErrMissingSubject},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				claims := issuer.IDTokenClaims("nonce")
+				tt.modify(claims)
+
+				idToken, err := issuer.SignIDToken(claims)
+				require.NoError(t, err)
+
+				_, err = provider.Verify(context.Background(), idToken, "nonce")
+				require.ErrorIs(t, err, tt.err)
+			})
+		}
+
+		// Several audiences, with the client as the authorized party
+		claims := issuer.IDTokenClaims("nonce")
+		claims["aud"] = []string{"other", "client"}
+		claims["azp"] = "client"
+
+		idToken, err := issuer.SignIDToken(claims)
+		require.NoError(t, err)
+
+		_, err = provider.Verify(context.Background(), idToken, "nonce")
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		issuer, provider := setup(t)
+
+		idToken, err := issuer.SignIDToken(issuer.IDTokenClaims("nonce"))
+		require.NoError(t, err)
+
+		// Swap the payload for one claiming another subject
+		claims := issuer.IDTokenClaims("nonce")
+		claims["sub"] = "admin"
+		payload, err := json.Marshal(claims)
+		require.NoError(t, err)
+
+		parts := strings.Split(idToken, ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+
+		_, err = provider.Verify(context.Background(), strings.Join(parts, "."), "nonce")
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		issuer, provider := setup(t)
+
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+		payload, err := json.Marshal(issuer.IDTokenClaims("nonce"))
+		require.NoError(t, err)
+
+		idToken := header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+
+		_, err = provider.Verify(context.Background(), idToken, "nonce")
+		require.ErrorIs(t, err, ErrUnsupportedAlg)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, provider := setup(t)
+
+		for _, idToken := range []string{"", "a.b", "a.b.c", "e30.e30.!"} {
+			_, err := provider.Verify(context.Background(), idToken, "nonce")
+			require.Error(t, err, idToken)
+		}
+	})
+
+	t.Run("key rotation", func(t *testing.T) {
+		issuer, provider := setup(t)
+
+		oldToken, err := issuer.SignIDToken(issuer.IDTokenClaims("nonce"))
+		require.NoError(t, err)
+
+		_, err = provider.Verify(context.Background(), oldToken, "nonce")
+		require.NoError(t, err)
+
+		require.NoError(t, issuer.RotateKey())
+
+		// The keys are fetched again for the new key
+		newToken, err := issuer.SignIDToken(issuer.IDTokenClaims("nonce"))
+		require.NoError(t, err)
+
+		_, err = provider.Verify(context.Background(), newToken, "nonce")
+		require.NoError(t, err)
+
+		// The old key is no longer published
+		_, err = provider.Verify(context.Background(), oldToken, "nonce")
+		require.ErrorIs(t, err, ErrUnknownKey)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestToken_VerifyEC(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"ec"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"subject"}`))
+	signingInput := header + "." + payload
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	tok, err := parseToken(signingInput + "." + base64.RawURLEncoding.EncodeToString(signature))
+	require.NoError(t, err)
+
+	// Round trip the key through its JWK form
+	jwk := &jsonWebKey{
+		Kty: "EC",
+		Kid: "ec",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+	}
+
+	publicKey, err := jwk.publicKey()
+	require.NoError(t, err)
+	require.NoError(t, tok.verify(publicKey))
+
+	// The wrong key type
+	rsaKey := crypto.PublicKey(&struct{}{})
+	require.ErrorIs(t, tok.verify(rsaKey), ErrUnsupportedAlg)
+
+	// An encryption key is rejected
+	jwk.Use = "enc"
+	_, err = jwk.publicKey()
+	require.ErrorIs(t, err, ErrUnsupportedKeyType)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestClaims_Strings(t *testing.T) {
+	claims := Claims{
+		"single":   "admins",
+		"multiple": []any{"dev", 1, "admins"},
+		"number":   1.0,
+	}
+
+	require.Equal(t, []string{"admins"}, claims.Strings("single"))
+	require.Equal(t, []string{"dev", "admins"}, claims.Strings("multiple"))
+	require.Nil(t, claims.Strings("number"))
+	require.Nil(t, claims.Strings("missing"))
+	require.Empty(t, claims.String("number"))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func setup(t *testing.T) (*mocks.OIDCIssuer, *Provider) {
+	t.Helper()
+
+	issuer, err := mocks.NewOIDCIssuer("client", "secret")
+	require.NoError(t, err)
+	t.Cleanup(issuer.Close)
+
+	provider := New(&Config{
+		IssuerURL:    issuer.URL(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	})
+
+	return issuer, provider
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// authorizeHelper follows the auth code URL and returns the code from the redirect
+func authorizeHelper(t *testing.T, provider *Provider, state, nonce, verifier string) string {
+	t.Helper()
+
+	u, err := provider.AuthCodeURL(context.Background(), state, nonce, verifier)
+	require.NoError(t, err)
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	resp, err := client.Get(u)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, state, location.Query().Get("state"))
+
+	return location.Query().Get("code")
+}
@@ -0,0 +1,8 @@
+package preview
+
+import "errors"
+
+var (
+	ErrUnsupported     = errors.New("preview not supported")
+	ErrInvalidNotebook = errors.New("invalid notebook")
+)
@@ -0,0 +1,269 @@
+package preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// notebook is the subset of the Jupyter notebook format (nbformat 4) that is rendered
+type notebook struct {
+	NBFormat int             `json:"nbformat"`
+	Cells    []notebookCell  `json:"cells"`
+	Metadata notebookMetdata `json:"metadata"`
+}
+
+type notebookMetdata struct {
+	LanguageInfo struct {
+		Name string `json:"name"`
+	} `json:"language_info"`
+	KernelSpec struct {
+		Language string `json:"language"`
+	} `json:"kernelspec"`
+}
+
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   multiline        `json:"source"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string               `json:"output_type"`
+	Name       string               `json:"name"`
+	Text       multiline            `json:"text"`
+	Data       map[string]multiline `json:"data"`
+	EName      string               `json:"ename"`
+	EValue     string               `json:"evalue"`
+	Traceback  []string             `json:"traceback"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// multiline is a string in a notebook, which is stored as either a string or a list of lines
+type multiline string
+
+// UnmarshalJSON implements the `json.Unmarshaler` interface
+func (m *multiline) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = multiline(s)
+		return nil
+	}
+
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+
+	*m = multiline(strings.Join(lines, ""))
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Images in notebook outputs, in order of preference. SVG is excluded, as it can run scripts
+var notebookImageTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// Matches ANSI escape sequences, which are used to color tracebacks
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// The policy used to sanitize rendered notebooks
+var notebookPolicy = newNotebookPolicy()
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// RenderNotebook renders a Jupyter notebook (nbformat 4) to HTML. Markdown cells are rendered as
+// markdown and code cells as code blocks, followed by their outputs (streams, results, images and
+// errors). The HTML is sanitized, removing scripts, event handlers and the like
+func RenderNotebook(src []byte) (string, error) {
+	nb := &notebook{}
+	if err := json.Unmarshal(src, nb); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidNotebook, err)
+	}
+
+	if nb.NBFormat < 4 {
+		return "", fmt.Errorf("%w: unsupported nbformat %d", ErrInvalidNotebook, nb.NBFormat)
+	}
+
+	language := nb.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = nb.Metadata.KernelSpec.Language
+	}
+
+	var b strings.Builder
+	for _, cell := range nb.Cells {
+		switch cell.CellType {
+		case "markdown":
+			out, err := renderMarkdown([]byte(cell.Source))
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteString(`<div class="nb-cell nb-markdown">`)
+			b.WriteString(out)
+			b.WriteString(`</div>`)
+		case "code":
+			b.WriteString(`<div class="nb-cell nb-code">`)
+			writeCode(&b, string(cell.Source), language)
+
+			for _, output := range cell.Outputs {
+				if err := writeOutput(&b, output); err != nil {
+					return "", err
+				}
+			}
+
+			b.WriteString(`</div>`)
+		default:
+			// Raw cells are shown as is
+			b.WriteString(`<div class="nb-cell nb-raw">`)
+			writePre(&b, string(cell.Source), "")
+			b.WriteString(`</div>`)
+		}
+	}
+
+	return notebookPolicy.Sanitize(b.String()), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// RenderText renders plain text to HTML, as an escaped preformatted block
+func RenderText(src []byte) string {
+	var b strings.Builder
+	writePre(&b, strings.ToValidUTF8(string(src), "�"), "")
+	return b.String()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// newNotebookPolicy creates the policy used to sanitize rendered notebooks. On top of the markdown
+// policy, it allows the classes of the notebook markup and images embedded as data URIs
+func newNotebookPolicy() *bluemonday.Policy {
+	p := newPolicy()
+	p.AllowDataURIImages()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^nb-[a-z]+( nb-[a-z]+)*$`)).OnElements("div", "pre")
+
+	return p
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// renderMarkdown renders markdown to unsanitized HTML. The caller must sanitize the result
+func renderMarkdown(src []byte) (string, error) {
+	var b strings.Builder
+	if err := markdown.Convert(src, &b); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// writeOutput writes the HTML for a code cell output
+func writeOutput(b *strings.Builder, output notebookOutput) error {
+	switch output.OutputType {
+	case "stream":
+		class := "nb-output nb-stream"
+		if output.Name == "stderr" {
+			class += " nb-stderr"
+		}
+
+		writePre(b, string(output.Text), class)
+	case "execute_result", "display_data":
+		return writeData(b, output.Data)
+	case "error":
+		text := strings.Join(output.Traceback, "\n")
+		if text == "" {
+			text = output.EName + ": " + output.EValue
+		}
+
+		writePre(b, ansiRegex.ReplaceAllString(text, ""), "nb-output nb-error")
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// writeData writes the richest representation of a result. Images are preferred, followed by HTML,
+// markdown and plain text
+func writeData(b *strings.Builder, data map[string]multiline) error {
+	for _, mimeType := range notebookImageTypes {
+		if img, ok := data[mimeType]; ok {
+			// The base64 data may be split over several lines
+			encoded := strings.Join(strings.Fields(string(img)), "")
+
+			b.WriteString(`<div class="nb-output nb-image"><img src="data:`)
+			b.WriteString(mimeType)
+			b.WriteString(`;base64,`)
+			b.WriteString(html.EscapeString(encoded))
+			b.WriteString(`" alt=""></div>`)
+
+			return nil
+		}
+	}
+
+	if out, ok := data["text/html"]; ok {
+		b.WriteString(`<div class="nb-output nb-html">`)
+		b.WriteString(string(out))
+		b.WriteString(`</div>`)
+		return nil
+	}
+
+	if out, ok := data["text/markdown"]; ok {
+		rendered, err := renderMarkdown([]byte(out))
+		if err != nil {
+			return err
+		}
+
+		b.WriteString(`<div class="nb-output nb-markdown">`)
+		b.WriteString(rendered)
+		b.WriteString(`</div>`)
+		return nil
+	}
+
+	if out, ok := data["text/plain"]; ok {
+		writePre(b, string(out), "nb-output nb-result")
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// writeCode writes an escaped code block, with the language class used for highlighting
+func writeCode(b *strings.Builder, code, language string) {
+	b.WriteString(`<pre><code`)
+	if language != "" {
+		b.WriteString(` class="language-`)
+		b.WriteString(html.EscapeString(strings.ToLower(language)))
+		b.WriteString(`"`)
+	}
+	b.WriteString(`>`)
+	b.WriteString(html.EscapeString(code))
+	b.WriteString(`</code></pre>`)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// writePre writes an escaped preformatted block
+func writePre(b *strings.Builder, text, class string) {
+	b.WriteString(`<pre`)
+	if class != "" {
+		b.WriteString(` class="`)
+		b.WriteString(class)
+		b.WriteString(`"`)
+	}
+	b.WriteString(`>`)
+	b.WriteString(html.EscapeString(text))
+	b.WriteString(`</pre>`)
+}
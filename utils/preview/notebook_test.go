@@ -0,0 +1,87 @@
+package preview
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestPreview_RenderNotebook(t *testing.T) {
+	t.Run("cells", func(t *testing.T) {
+		src := `{
+			"nbformat": 4,
+			"metadata": {"language_info": {"name": "python"}},
+			"cells": [
+				{"cell_type": "markdown", "source": ["# Title\n", "Some *text*"]},
+				{"cell_type": "code", "source": "print(1 < 2)", "outputs": [
+					{"output_type": "stream", "name": "stdout", "text": ["True\n"]},
+					{"output_type": "stream", "name": "stderr", "text": "warning"}
+				]},
+				{"cell_type": "code", "source": "x", "outputs": [
+					{"output_type": "execute_result", "data": {"text/plain": "42", "text/html": "<b>42</b>"}}
+				]},
+				{"cell_type": "code", "source": "plot()", "outputs": [
+					{"output_type": "display_data", "data": {"image/png": ["iVBORw0K\n", "GgoAAAA="], "text/plain": "<Figure>"}}
+				]},
+				{"cell_type": "code", "source": "1/0", "outputs": [
+					{"output_type": "error", "ename": "ZeroDivisionError", "evalue": "division by zero", "traceback": ["\u001b[0;31mZeroDivisionError\u001b[0m: division by zero"]}
+				]},
+				{"cell_type": "raw", "source": "raw <text>"}
+			]
+		}`
+
+		out, err := RenderNotebook([]byte(src))
+		require.NoError(t, err)
+
+		require.Contains(t, out, `<div class="nb-cell nb-markdown"><h1>Title</h1>`)
+		require.Contains(t, out, `<em>text</em>`)
+		require.Contains(t, out, `<pre><code class="language-python">print(1 &lt; 2)</code></pre>`)
+		require.Contains(t, out, `<pre class="nb-output nb-stream">True`)
+		require.Contains(t, out, `<pre class="nb-output nb-stream nb-stderr">warning</pre>`)
+		require.Contains(t, out, `<div class="nb-output nb-html"><b>42</b></div>`)
+		require.Contains(t, out, `<img src="data:image/png;base64,iVBORw0KGgoAAAA="`)
+		require.NotContains(t, out, "&lt;Figure&gt;")
+		require.Contains(t, out, `<pre class="nb-output nb-error">ZeroDivisionError: division by zero</pre>`)
+		require.Contains(t, out, `<pre>raw &lt;text&gt;</pre>`)
+	})
+
+	t.Run("sanitized", func(t *testing.T) {
+		src := `{
+			"nbformat": 4,
+			"metadata": {"kernelspec": {"language": "R"}},
+			"cells": [
+				{"cell_type": "markdown", "source": "<script>alert(1)</script><a href=\"javascript:alert(1)\">x</a>"},
+				{"cell_type": "code", "source": "x", "outputs": [
+					{"output_type": "display_data", "data": {"text/html": "<div onclick=\"alert(1)\">html</div><script>alert(1)</script>"}},
+					{"output_type": "display_data", "data": {"image/svg+xml": "<svg onload=\"alert(1)\"></svg>", "text/plain": "svg"}}
+				]}
+			]
+		}`
+
+		out, err := RenderNotebook([]byte(src))
+		require.NoError(t, err)
+
+		require.NotContains(t, out, "<script")
+		require.NotContains(t, out, "javascript:")
+		require.NotContains(t, out, "onclick")
+		require.NotContains(t, out, "<svg")
+		require.Contains(t, out, `class="language-r"`)
+		require.Contains(t, out, `<pre class="nb-output nb-result">svg</pre>`)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := RenderNotebook([]byte("not json"))
+		require.ErrorIs(t, err, ErrInvalidNotebook)
+
+		_, err = RenderNotebook([]byte(`{"nbformat": 3, "worksheets": []}`))
+		require.ErrorIs(t, err, ErrInvalidNotebook)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestPreview_RenderText(t *testing.T) {
+	require.Equal(t, "<pre>a &lt;b&gt; &amp; c\n</pre>", RenderText([]byte("a <b> & c\n")))
+}
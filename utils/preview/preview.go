@@ -0,0 +1,326 @@
+package preview
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Kind defines how a file is previewed
+type Kind string
+
+const (
+	// KindMarkdown is rendered to sanitized HTML
+	KindMarkdown Kind = "markdown"
+
+	// KindCode is returned as is, along with the language to highlight it with
+	KindCode Kind = "code"
+
+	// KindText is returned as is
+	KindText Kind = "text"
+
+	// KindImage is served inline
+	KindImage Kind = "image"
+
+	// KindArchive is a ZIP archive, whose entries can be listed
+	KindArchive Kind = "archive"
+
+	// KindDownload is not previewed. The file has to be downloaded
+	KindDownload Kind = "download"
+)
+
+const (
+	// MaxTextSize is the max number of bytes of a text file that are previewed. Larger files are
+	// truncated
+	MaxTextSize = 1 << 20
+
+	// MaxImageSize is the max size of an image that is previewed
+	MaxImageSize = 20 << 20
+
+	// SniffSize is the number of bytes to pass to Detect, to sniff the content of a file with an
+	// unknown extension
+	SniffSize = 512
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Images a browser can display. SVG is excluded, as it can run scripts
+var imageExtensions = map[string]bool{
+	"png":  true,
+	"jpg":  true,
+	"jpeg": true,
+	"gif":  true,
+	"webp": true,
+	"bmp":  true,
+	"avif": true,
+	"ico":  true,
+}
+
+// Markdown extensions
+var markdownExtensions = map[string]bool{
+	"md":       true,
+	"markdown": true,
+	"mdown":    true,
+	"mkd":      true,
+}
+
+// Plain text extensions
+var textExtensions = map[string]bool{
+	"txt": true,
+	"log": true,
+	"csv": true,
+	"tsv": true,
+	"nfo": true,
+	"srt": true,
+	"vtt": true,
+}
+
+// The languages of code files, by extension. The names match the ones used by highlight.js
+var languages = map[string]string{
+	"bash":   "bash",
+	"c":      "c",
+	"cc":     "cpp",
+	"cpp":    "cpp",
+	"cs":     "csharp",
+	"css":    "css",
+	"dart":   "dart",
+	"diff":   "diff",
+	"go":     "go",
+	"h":      "c",
+	"hpp":    "cpp",
+	"html":   "xml",
+	"ini":    "ini",
+	"java":   "java",
+	"js":     "javascript",
+	"json":   "json",
+	"jsx":    "javascript",
+	"kt":     "kotlin",
+	"lua":    "lua",
+	"m":      "objectivec",
+	"mjs":    "javascript",
+	"patch":  "diff",
+	"php":    "php",
+	"pl":     "perl",
+	"ps1":    "powershell",
+	"py":     "python",
+	"r":      "r",
+	"rb":     "ruby",
+	"rs":     "rust",
+	"scala":  "scala",
+	"scss":   "scss",
+	"sh":     "bash",
+	"sql":    "sql",
+	"svelte": "xml",
+	"svg":    "xml",
+	"swift":  "swift",
+	"toml":   "ini",
+	"ts":     "typescript",
+	"tsx":    "typescript",
+	"vue":    "xml",
+	"xml":    "xml",
+	"yaml":   "yaml",
+	"yml":    "yaml",
+	"zsh":    "bash",
+}
+
+// The languages of code files without an extension, by name
+var filenameLanguages = map[string]string{
+	"dockerfile":  "dockerfile",
+	"makefile":    "makefile",
+	"gemfile":     "ruby",
+	"rakefile":    "ruby",
+	"jenkinsfile": "groovy",
+}
+
+// The languages of scripts, by shebang interpreter
+var shebangLanguages = map[string]string{
+	"bash":    "bash",
+	"sh":      "bash",
+	"zsh":     "bash",
+	"python":  "python",
+	"python3": "python",
+	"node":    "javascript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	policy = newPolicy()
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Preview defines the preview of a file
+type Preview struct {
+	Kind Kind
+
+	// The language of KindCode
+	Language string
+
+	// The content of KindText and KindCode
+	Content string
+
+	// The rendered HTML of KindMarkdown
+	HTML string
+
+	// True when the file is larger than MaxTextSize and only the start was previewed
+	Truncated bool
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Detect returns the kind of preview of a file based on its name. When the extension is unknown,
+// head (the start of the file) is sniffed for text. The language is set for KindCode
+func Detect(filename string, head []byte) (Kind, string) {
+	name := strings.ToLower(filepath.Base(filename))
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+
+	switch {
+	case imageExtensions[ext]:
+		return KindImage, ""
+	case ext == "zip":
+		return KindArchive, ""
+	case markdownExtensions[ext]:
+		return KindMarkdown, ""
+	case textExtensions[ext]:
+		return KindText, ""
+	case languages[ext] != "":
+		return KindCode, languages[ext]
+	case filenameLanguages[name] != "":
+		return KindCode, filenameLanguages[name]
+	}
+
+	if !isText(head) {
+		return KindDownload, ""
+	}
+
+	if lang := shebangLanguage(head); lang != "" {
+		return KindCode, lang
+	}
+
+	return KindText, ""
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Text builds the preview of a text file (KindMarkdown, KindCode or KindText), reading up to
+// MaxTextSize bytes. ErrUnsupported is returned for other kinds
+func Text(filename string, r io.Reader) (*Preview, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxTextSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	truncated := len(data) > MaxTextSize
+	if truncated {
+		data = data[:MaxTextSize]
+
+		// Do not cut a multi-byte character in half
+		for range utf8.UTFMax - 1 {
+			if r, size := utf8.DecodeLastRune(data); r != utf8.RuneError || size != 1 {
+				break
+			}
+
+			data = data[:len(data)-1]
+		}
+	}
+
+	kind, language := Detect(filename, data[:min(len(data), SniffSize)])
+
+	preview := &Preview{Kind: kind, Language: language, Truncated: truncated}
+
+	switch kind {
+	case KindMarkdown:
+		html, err := RenderMarkdown(data)
+		if err != nil {
+			return nil, err
+		}
+
+		preview.HTML = html
+	case KindCode, KindText:
+		preview.Content = string(bytes.ToValidUTF8(data, []byte("�")))
+	default:
+		return nil, ErrUnsupported
+	}
+
+	return preview, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// RenderMarkdown renders GitHub flavored markdown to HTML. The HTML is sanitized, removing
+// scripts, event handlers and the like
+func RenderMarkdown(src []byte) (string, error) {
+	out, err := renderMarkdown(src)
+	if err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(out), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// newPolicy creates the policy used to sanitize rendered markdown. It allows the markup of user
+// generated content, plus the language class of code blocks (for highlighting) and the checkboxes
+// of task lists
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	return p
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// isText returns true when the data looks like text
+func isText(data []byte) bool {
+	if len(data) == 0 {
+		return true
+	}
+
+	return strings.HasPrefix(http.DetectContentType(data), "text/plain")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// shebangLanguage returns the language of a script based on its shebang, such as `#!/bin/bash` or
+// `#!/usr/bin/env python3`
+func shebangLanguage(data []byte) string {
+	if !bytes.HasPrefix(data, []byte("#!")) {
+		return ""
+	}
+
+	line, _, _ := bytes.Cut(data[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+
+	return shebangLanguages[interpreter]
+}
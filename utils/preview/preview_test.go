@@ -0,0 +1,128 @@
+package preview
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestPreview_Detect(t *testing.T) {
+	tests := []struct {
+		filename string
+		head     string
+		kind     Kind
+		language string
+	}{
+		// Extension
+		{"diagram.PNG", "", KindImage, ""},
+		{"photo.jpeg", "", KindImage, ""},
+		{"README.md", "", KindMarkdown, ""},
+		{"01 Resources.ZIP", "", KindArchive, ""},
+		{"notes.txt", "", KindText, ""},
+		{"main.go", "", KindCode, "go"},
+		{"script.PY", "", KindCode, "python"},
+		{"index.tsx", "", KindCode, "typescript"},
+		// Filename
+		{"Dockerfile", "", KindCode, "dockerfile"},
+		{"/a/b/Makefile", "", KindCode, "makefile"},
+		// Sniffed
+		{"run", "#!/usr/bin/env python3\nprint(1)", KindCode, "python"},
+		{"run", "#!/bin/bash\necho 1", KindCode, "bash"},
+		{"run", "#!/opt/unknown\n", KindText, ""},
+		{"LICENSE", "MIT License", KindText, ""},
+		{"empty", "", KindText, ""},
+		{"archive.bin", "\x00\x01\x02\x03PK", KindDownload, ""},
+		// SVG is code, never an image
+		{"logo.svg", "", KindCode, "xml"},
+		{"course.pdf", "%PDF-1.4", KindDownload, ""},
+	}
+
+	for _, tt := range tests {
+		kind, language := Detect(tt.filename, []byte(tt.head))
+		require.Equal(t, tt.kind, kind, tt.filename)
+		require.Equal(t, tt.language, language, tt.filename)
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestPreview_Text(t *testing.T) {
+	t.Run("markdown", func(t *testing.T) {
+		p, err := Text("notes.md", strings.NewReader("# Title\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n```go\nfmt.Println()\n```\n"))
+		require.NoError(t, err)
+		require.Equal(t, KindMarkdown, p.Kind)
+		require.Contains(t, p.HTML, "<h1")
+		require.Contains(t, p.HTML, "<table>")
+		require.Contains(t, p.HTML, `<code class="language-go">`)
+		require.Empty(t, p.Content)
+		require.False(t, p.Truncated)
+	})
+
+	t.Run("code", func(t *testing.T) {
+		p, err := Text("main.go", strings.NewReader("package main\n"))
+		require.NoError(t, err)
+		require.Equal(t, KindCode, p.Kind)
+		require.Equal(t, "go", p.Language)
+		require.Equal(t, "package main\n", p.Content)
+		require.Empty(t, p.HTML)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		p, err := Text("notes.txt", strings.NewReader("<b>not html</b>"))
+		require.NoError(t, err)
+		require.Equal(t, KindText, p.Kind)
+		require.Equal(t, "<b>not html</b>", p.Content)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		// A multi-byte character straddles the limit
+		data := strings.Repeat("a", MaxTextSize-1) + "é" + "tail"
+
+		p, err := Text("notes.txt", strings.NewReader(data))
+		require.NoError(t, err)
+		require.True(t, p.Truncated)
+		require.Equal(t, strings.Repeat("a", MaxTextSize-1), p.Content)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := Text("image.png", bytes.NewReader([]byte{0x89, 'P', 'N', 'G'}))
+		require.ErrorIs(t, err, ErrUnsupported)
+
+		_, err = Text("data.bin", bytes.NewReader([]byte{0x00, 0x01, 0x02}))
+		require.ErrorIs(t, err, ErrUnsupported)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestPreview_RenderMarkdown(t *testing.T) {
+	tests := []struct {
+		markdown    string
+		contains    string
+		notContains string
+	}{
+		{"<script>alert(1)</script>", "", "<script"},
+		{"<img src=x onerror=alert(1)>", "", "onerror"},
+		{"[link](javascript:alert(1))", "", "javascript:"},
+		{"[link](https://example.com)", `target="_blank"`, ""},
+		{"- [x] done", `type="checkbox"`, ""},
+		{"~~gone~~", "<del>gone</del>", ""},
+	}
+
+	for _, tt := range tests {
+		html, err := RenderMarkdown([]byte(tt.markdown))
+		require.NoError(t, err)
+
+		if tt.contains != "" {
+			require.Contains(t, html, tt.contains, tt.markdown)
+		}
+
+		if tt.notContains != "" {
+			require.NotContains(t, html, tt.notContains, tt.markdown)
+		}
+	}
+}
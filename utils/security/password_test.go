@@ -0,0 +1,60 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_ValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		expected error
+	}{
+		{"", ErrPasswordTooShort},
+		{"1234567", ErrPasswordTooShort},
+		{"12345678", nil},
+		{strings.Repeat("a", 72), nil},
+		{strings.Repeat("a", 73), ErrPasswordTooLong},
+	}
+
+	for _, tt := range tests {
+		require.ErrorIs(t, ValidatePassword(tt.password), tt.expected, "password of length %d", len(tt.password))
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_HashPassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		hash, err := HashPassword("password")
+		require.NoError(t, err)
+		require.NotEqual(t, "password", hash)
+
+		require.True(t, ComparePassword(hash, "password"))
+		require.False(t, ComparePassword(hash, "Password"))
+		require.False(t, ComparePassword(hash, ""))
+	})
+
+	t.Run("salted", func(t *testing.T) {
+		hash1, err := HashPassword("password")
+		require.NoError(t, err)
+
+		hash2, err := HashPassword("password")
+		require.NoError(t, err)
+
+		require.NotEqual(t, hash1, hash2)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := HashPassword("short")
+		require.ErrorIs(t, err, ErrPasswordTooShort)
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		require.False(t, ComparePassword("not a hash", "password"))
+	})
+}
@@ -0,0 +1,240 @@
+package security
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ThrottlePolicy defines how failures slow down and then lock out a key
+type ThrottlePolicy struct {
+	// The number of consecutive failures after which the key is locked out
+	MaxFailures int
+
+	// The wait after the first failure. It doubles with every failure, up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// How long a key is locked out for. Failures are also forgotten once this long has passed
+	// since the last one
+	LockoutDuration time.Duration
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Throttle tracks the failures of keys, such as usernames or IP addresses, in memory. After every
+// failure the key must wait before trying again, with the wait growing exponentially, until it is
+// locked out after `MaxFailures` failures
+type Throttle struct {
+	policy ThrottlePolicy
+
+	// Returns the current time. Replaced in tests
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*throttleEntry
+}
+
+// throttleEntry holds the failures of a key
+type throttleEntry struct {
+	failures    int
+	lastFailure time.Time
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ThrottleState describes a key that has failed
+type ThrottleState struct {
+	Key         string
+	Failures    int
+	LastFailure time.Time
+
+	// When the key may try again
+	RetryAt time.Time
+
+	// Whether the key reached `MaxFailures` and is locked out, rather than backing off
+	Locked bool
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// NewThrottle creates a new Throttle
+func NewThrottle(policy ThrottlePolicy) *Throttle {
+	return &Throttle{
+		policy:  policy,
+		now:     time.Now,
+		entries: map[string]*throttleEntry{},
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Wait returns how long the key must wait before trying again. Zero is returned when the key may
+// try now
+func (t *Throttle) Wait(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := t.entry(key)
+	if entry == nil {
+		return 0
+	}
+
+	wait := t.retryAt(entry).Sub(t.now())
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Fail records a failure of the key and returns its state after the failure
+func (t *Throttle) Fail(key string) *ThrottleState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune()
+
+	entry := t.entry(key)
+	if entry == nil {
+		entry = &throttleEntry{}
+		t.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.lastFailure = t.now()
+
+	return t.state(key, entry)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Reset forgets the failures of the key. False is returned when the key had no failures
+func (t *Throttle) Reset(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := t.entry(key)
+	delete(t.entries, key)
+
+	return entry != nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ResetAll forgets the failures of every key
+func (t *Throttle) ResetAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries = map[string]*throttleEntry{}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// States returns the state of every key with failures, ordered by the most recent failure first
+func (t *Throttle) States() []*ThrottleState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune()
+
+	states := make([]*ThrottleState, 0, len(t.entries))
+	for key, entry := range t.entries {
+		states = append(states, t.state(key, entry))
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].LastFailure.Equal(states[j].LastFailure) {
+			return states[i].Key < states[j].Key
+		}
+
+		return states[i].LastFailure.After(states[j].LastFailure)
+	})
+
+	return states
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// entry returns the entry of the key. Nil is returned when the key has no failures or they have
+// been forgotten. The caller must hold the lock
+func (t *Throttle) entry(key string) *throttleEntry {
+	entry, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if t.expired(entry) {
+		delete(t.entries, key)
+		return nil
+	}
+
+	return entry
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// expired returns whether the failures of an entry are forgotten
+func (t *Throttle) expired(entry *throttleEntry) bool {
+	return !t.now().Before(entry.lastFailure.Add(t.policy.LockoutDuration))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// prune deletes the entries whose failures are forgotten, so the map does not grow with every key
+// ever seen. The caller must hold the lock
+func (t *Throttle) prune() {
+	for key, entry := range t.entries {
+		if t.expired(entry) {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// locked returns whether an entry is locked out
+func (t *Throttle) locked(entry *throttleEntry) bool {
+	return t.policy.MaxFailures > 0 && entry.failures >= t.policy.MaxFailures
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// retryAt returns when an entry may try again. A locked out entry waits for the lockout duration.
+// Otherwise the wait doubles with every failure
+func (t *Throttle) retryAt(entry *throttleEntry) time.Time {
+	if t.locked(entry) {
+		return entry.lastFailure.Add(t.policy.LockoutDuration)
+	}
+
+	delay := t.policy.BaseDelay
+	for i := 1; i < entry.failures && delay < t.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > t.policy.MaxDelay {
+		delay = t.policy.MaxDelay
+	}
+
+	return entry.lastFailure.Add(delay)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// state returns the state of an entry. The caller must hold the lock
+func (t *Throttle) state(key string, entry *throttleEntry) *ThrottleState {
+	return &ThrottleState{
+		Key:         key,
+		Failures:    entry.failures,
+		LastFailure: entry.lastFailure,
+		RetryAt:     t.retryAt(entry),
+		Locked:      t.locked(entry),
+	}
+}
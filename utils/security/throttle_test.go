@@ -0,0 +1,133 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_Throttle(t *testing.T) {
+	t.Run("backoff", func(t *testing.T) {
+		throttle, clock := throttleHelper()
+
+		require.Zero(t, throttle.Wait("bob"))
+
+		// The wait doubles with every failure, up to the max delay
+		for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+			state := throttle.Fail("bob")
+			require.False(t, state.Locked)
+			require.Equal(t, expected, throttle.Wait("bob"))
+			require.Equal(t, clock.now.Add(expected), state.RetryAt)
+
+			clock.advance(expected)
+			require.Zero(t, throttle.Wait("bob"))
+		}
+
+		// Other keys are not affected
+		require.Zero(t, throttle.Wait("alice"))
+	})
+
+	t.Run("lockout", func(t *testing.T) {
+		throttle, clock := throttleHelper()
+
+		for range 4 {
+			require.False(t, throttle.Fail("bob").Locked)
+		}
+
+		state := throttle.Fail("bob")
+		require.True(t, state.Locked)
+		require.Equal(t, 5, state.Failures)
+		require.Equal(t, 15*time.Minute, throttle.Wait("bob"))
+
+		clock.advance(15*time.Minute - time.Second)
+		require.Equal(t, time.Second, throttle.Wait("bob"))
+
+		// The failures are forgotten once the lockout ends
+		clock.advance(time.Second)
+		require.Zero(t, throttle.Wait("bob"))
+
+		state = throttle.Fail("bob")
+		require.Equal(t, 1, state.Failures)
+		require.False(t, state.Locked)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		throttle, _ := throttleHelper()
+
+		for range 5 {
+			throttle.Fail("bob")
+			throttle.Fail("alice")
+		}
+
+		require.True(t, throttle.Reset("bob"))
+		require.False(t, throttle.Reset("bob"))
+		require.Zero(t, throttle.Wait("bob"))
+		require.NotZero(t, throttle.Wait("alice"))
+
+		throttle.ResetAll()
+		require.Zero(t, throttle.Wait("alice"))
+		require.Empty(t, throttle.States())
+	})
+
+	t.Run("states", func(t *testing.T) {
+		throttle, clock := throttleHelper()
+
+		for range 5 {
+			throttle.Fail("bob")
+		}
+
+		clock.advance(time.Minute)
+		throttle.Fail("alice")
+
+		states := throttle.States()
+		require.Len(t, states, 2)
+
+		require.Equal(t, "alice", states[0].Key)
+		require.Equal(t, 1, states[0].Failures)
+		require.False(t, states[0].Locked)
+
+		require.Equal(t, "bob", states[1].Key)
+		require.Equal(t, 5, states[1].Failures)
+		require.True(t, states[1].Locked)
+
+		// Forgotten failures are pruned
+		clock.advance(14 * time.Minute)
+		states = throttle.States()
+		require.Len(t, states, 1)
+		require.Equal(t, "alice", states[0].Key)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Helpers
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// testClock is a clock that only moves when advanced
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// throttleHelper returns a throttle that locks out after 5 failures and waits at most 5 seconds
+// before then, using a test clock
+func throttleHelper() (*Throttle, *testClock) {
+	clock := &testClock{now: time.Now()}
+
+	throttle := NewThrottle(ThrottlePolicy{
+		MaxFailures:     5,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Second,
+		LockoutDuration: 15 * time.Minute,
+	})
+	throttle.now = func() time.Time { return clock.now }
+
+	return throttle, clock
+}
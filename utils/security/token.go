@@ -0,0 +1,66 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TokenPrefix is prepended to generated tokens so they are easy to identify, for example by secret
+// scanners
+const TokenPrefix = "oc_"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GenerateToken generates a cryptographically random token
+func GenerateToken() string {
+	return TokenPrefix + RandomString(40)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// HashToken returns the SHA-256 hash of a token, hex encoded. Tokens are random so, unlike passwords,
+// a fast hash is enough and allows a token to be looked up by its hash
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SignToken signs a value with a HMAC-SHA256 of the secret, returning a token of the form
+// `<value>.<signature>`. The value is readable by anyone holding the token, but cannot be changed
+// without knowing the secret
+func SignToken(secret, value string) string {
+	return value + "." + signature(secret, value)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// VerifySignedToken verifies a token created by `SignToken` and returns the signed value. False is
+// returned when the token is malformed or the signature does not match
+func VerifySignedToken(secret, token string) (string, bool) {
+	value, sig, ok := strings.Cut(token, ".")
+	if !ok || value == "" || sig == "" {
+		return "", false
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, value))) {
+		return "", false
+	}
+
+	return value, true
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// signature returns the base64url encoded HMAC-SHA256 of the value
+func signature(secret, value string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,63 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_GenerateToken(t *testing.T) {
+	token := GenerateToken()
+	require.True(t, strings.HasPrefix(token, TokenPrefix))
+	require.Len(t, token, len(TokenPrefix)+40)
+	require.NotEqual(t, token, GenerateToken())
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_HashToken(t *testing.T) {
+	token := GenerateToken()
+
+	hash := HashToken(token)
+	require.Len(t, hash, 64)
+	require.NotContains(t, hash, token)
+	require.Equal(t, hash, HashToken(token))
+	require.NotEqual(t, hash, HashToken(GenerateToken()))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func Test_SignToken(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		token := SignToken("secret", "abc123")
+		require.True(t, strings.HasPrefix(token, "abc123."))
+
+		value, ok := VerifySignedToken("secret", token)
+		require.True(t, ok)
+		require.Equal(t, "abc123", value)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		token := SignToken("secret", "abc123")
+		_, sig, _ := strings.Cut(token, ".")
+
+		for _, invalid := range []string{
+			"",
+			"abc123",
+			"abc123.",
+			"." + sig,
+			"abc124." + sig,
+			token + "x",
+		} {
+			_, ok := VerifySignedToken("secret", invalid)
+			require.False(t, ok, invalid)
+		}
+
+		// A different secret
+		_, ok := VerifySignedToken("other", token)
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,146 @@
+package slides
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/geerew/off-course/utils/types"
+	"github.com/spf13/afero"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsImage returns true when the file is an image that can be a slide, based on its extension
+func IsImage(path string) bool {
+	a := types.NewAsset(strings.TrimPrefix(filepath.Ext(path), "."))
+	return a != nil && a.IsSlides()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsHidden returns true when the file is hidden (starts with a dot), such as `.DS_Store`. Hidden
+// files are ignored in a slide deck
+func IsHidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// List returns the slides of a slides asset, in order. The path is either a single image or a
+// directory of images (a slide deck)
+func List(fs afero.Fs, path string) ([]string, error) {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := afero.ReadDir(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || IsHidden(entry.Name()) || !IsImage(entry.Name()) {
+			continue
+		}
+
+		images = append(images, filepath.Join(path, entry.Name()))
+	}
+
+	Sort(images)
+
+	return images, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Sort sorts the paths of slides in natural order, so `slide2.png` comes before `slide10.png`
+func Sort(paths []string) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		return naturalLess(filepath.Base(paths[i]), filepath.Base(paths[j]))
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Hash returns a hash of a slide deck, based upon the names and sizes of its slides. The content
+// is not read, as a deck can be made up of many large images
+func Hash(fs afero.Fs, paths []string) (string, error) {
+	h := sha256.New()
+
+	for _, p := range paths {
+		info, err := fs.Stat(p)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.Base(p), info.Size())
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// naturalLess compares a and b case-insensitively, comparing runs of digits by their numeric value
+func naturalLess(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			numA, restA := splitNumber(a)
+			numB, restB := splitNumber(b)
+
+			// Compare by length first (after trimming leading zeros), then lexically
+			trimmedA, trimmedB := strings.TrimLeft(numA, "0"), strings.TrimLeft(numB, "0")
+			if len(trimmedA) != len(trimmedB) {
+				return len(trimmedA) < len(trimmedB)
+			}
+
+			if trimmedA != trimmedB {
+				return trimmedA < trimmedB
+			}
+
+			a, b = restA, restB
+			continue
+		}
+
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+
+		a, b = a[1:], b[1:]
+	}
+
+	return len(a) < len(b)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// splitNumber splits s into its leading run of digits and the rest
+func splitNumber(s string) (string, string) {
+	i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i == -1 {
+		return s, ""
+	}
+
+	return s[:i], s[i:]
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// isDigit returns true when b is an ASCII digit
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
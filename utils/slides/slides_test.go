@@ -0,0 +1,81 @@
+package slides
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSlides_IsImage(t *testing.T) {
+	for _, path := range []string{"slide.png", "/a/b/Slide.JPG", "s.jpeg", "s.webp", "s.gif"} {
+		require.True(t, IsImage(path), path)
+	}
+
+	for _, path := range []string{"slide", "slide.svg", "slide.mp4", "slide.png.txt"} {
+		require.False(t, IsImage(path), path)
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSlides_List(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/course/01 slide.png", []byte("png"), os.ModePerm))
+
+		slides, err := List(fs, "/course/01 slide.png")
+		require.NoError(t, err)
+		require.Equal(t, []string{"/course/01 slide.png"}, slides)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		for _, name := range []string{"Slide10.png", "slide2.png", "slide1.jpg", "notes.txt", ".hidden.png", "sub/slide3.png"} {
+			require.NoError(t, afero.WriteFile(fs, "/course/01 deck/"+name, []byte("png"), os.ModePerm))
+		}
+
+		slides, err := List(fs, "/course/01 deck")
+		require.NoError(t, err)
+		require.Equal(t, []string{"/course/01 deck/slide1.jpg", "/course/01 deck/slide2.png", "/course/01 deck/Slide10.png"}, slides)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := List(afero.NewMemMapFs(), "/missing")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSlides_Sort(t *testing.T) {
+	paths := []string{"/d/10.png", "/d/9.png", "/d/009b.png", "/d/a.png", "/d/1.png", "/d/01.png", "/d/B.png"}
+	Sort(paths)
+	require.Equal(t, []string{"/d/1.png", "/d/01.png", "/d/9.png", "/d/009b.png", "/d/10.png", "/d/a.png", "/d/B.png"}, paths)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSlides_Hash(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/deck/1.png", []byte("one"), os.ModePerm))
+	require.NoError(t, afero.WriteFile(fs, "/deck/2.png", []byte("two"), os.ModePerm))
+
+	hash1, err := Hash(fs, []string{"/deck/1.png", "/deck/2.png"})
+	require.NoError(t, err)
+	require.Len(t, hash1, 64)
+
+	// Changing a size changes the hash
+	require.NoError(t, afero.WriteFile(fs, "/deck/2.png", []byte("three"), os.ModePerm))
+
+	hash2, err := Hash(fs, []string{"/deck/1.png", "/deck/2.png"})
+	require.NoError(t, err)
+	require.NotEqual(t, hash1, hash2)
+
+	_, err = Hash(fs, []string{"/deck/missing.png"})
+	require.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package subtitles
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The field order of a dialogue line when the [Events] section does not define a format
+var assDefaultFormat = []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// assOverrideRegex matches an override block, such as `{\i1}` or `{\pos(10,20)}`
+var assOverrideRegex = regexp.MustCompile(`\{[^}]*\}`)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseASS parses the dialogue lines of an ASS/SSA file into cues. Styling, positioning and effects
+// are dropped, leaving only the timing and the plain text
+func parseASS(content string) ([]*Cue, error) {
+	format := assDefaultFormat
+	inEvents := false
+	cues := []*Cue{}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+
+		if !inEvents {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "format":
+			format = []string{}
+			for _, field := range strings.Split(value, ",") {
+				format = append(format, strings.ToLower(strings.TrimSpace(field)))
+			}
+		case "dialogue":
+			cue, err := parseAssDialogue(format, value)
+			if err != nil {
+				return nil, err
+			}
+
+			if cue != nil {
+				cues = append(cues, cue)
+			}
+		}
+	}
+
+	if len(cues) == 0 {
+		return nil, ErrMalformed
+	}
+
+	// Dialogue lines are not required to be in order
+	sort.SliceStable(cues, func(i, j int) bool {
+		return cues[i].Start < cues[j].Start
+	})
+
+	return cues, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseAssDialogue parses the value of a dialogue line based upon the format of the [Events]
+// section. The text field is always last and may contain commas. nil is returned when the dialogue
+// has no text
+func parseAssDialogue(format []string, value string) (*Cue, error) {
+	fields := strings.SplitN(value, ",", len(format))
+	if len(fields) != len(format) {
+		return nil, ErrMalformed
+	}
+
+	cue := &Cue{}
+
+	for i, name := range format {
+		field := strings.TrimSpace(fields[i])
+
+		switch name {
+		case "start", "end":
+			ts, err := parseTimestamp(field)
+			if err != nil {
+				return nil, err
+			}
+
+			if name == "start" {
+				cue.Start = ts
+			} else {
+				cue.End = ts
+			}
+		case "text":
+			cue.Text = assText(fields[i])
+		}
+	}
+
+	if strings.TrimSpace(cue.Text) == "" {
+		return nil, nil
+	}
+
+	return cue, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// assText converts the text of a dialogue line to WebVTT cue text by removing override blocks,
+// converting line breaks and escaping the characters that have a meaning in WebVTT
+func assText(text string) string {
+	text = assOverrideRegex.ReplaceAllString(text, "")
+
+	text = strings.NewReplacer(
+		`\N`, "\n",
+		`\n`, "\n",
+		`\h`, " ",
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	).Replace(text)
+
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
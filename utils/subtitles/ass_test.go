@@ -0,0 +1,56 @@
+package subtitles
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSubtitles_AssToWebVTT(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ass := `[Script Info]
+Title: Test
+ScriptType: v4.00+
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize
+Style: Default,Arial,20
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:05.00,0:00:06.50,Default,,0,0,0,,Second, with a comma
+Comment: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,A comment
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\i1}First{\i0}\Nline 2 & <more>
+Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,{\pos(10,10)}
+`
+
+		vtt, err := ToWebVTT(strings.NewReader(ass), "ass")
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n"+
+			"\n00:00:01.000 --> 00:00:02.000\nFirst\nline 2 &amp; &lt;more&gt;\n"+
+			"\n00:00:05.000 --> 00:00:06.500\nSecond, with a comma\n", string(vtt))
+	})
+
+	t.Run("ssa custom format", func(t *testing.T) {
+		ssa := "[Events]\nFormat: Start, End, Text\nDialogue: 0:00:01.00,0:00:02.00,Hello\n"
+
+		vtt, err := ToWebVTT(strings.NewReader(ssa), "ssa")
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n", string(vtt))
+	})
+
+	t.Run("no dialogue", func(t *testing.T) {
+		vtt, err := ToWebVTT(strings.NewReader("[Script Info]\nTitle: Test\n"), "ass")
+		require.ErrorIs(t, err, ErrMalformed)
+		require.Nil(t, vtt)
+	})
+
+	t.Run("malformed dialogue", func(t *testing.T) {
+		vtt, err := ToWebVTT(strings.NewReader("[Events]\nFormat: Start, End, Text\nDialogue: bob\n"), "ass")
+		require.ErrorIs(t, err, ErrMalformed)
+		require.Nil(t, vtt)
+	})
+}
@@ -0,0 +1,10 @@
+package subtitles
+
+import "errors"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	ErrUnsupported = errors.New("unsupported subtitle format")
+	ErrMalformed   = errors.New("malformed subtitle file")
+)
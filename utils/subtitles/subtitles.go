@@ -0,0 +1,366 @@
+package subtitles
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The subtitle formats that can be converted to WebVTT
+const (
+	FormatSRT = "srt"
+	FormatVTT = "vtt"
+	FormatASS = "ass"
+	FormatSSA = "ssa"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The header every WebVTT file must start with
+const vttHeader = "WEBVTT"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The UTF-8 byte order mark, which some editors write at the start of subtitle files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	// srtTimingRegex matches a SRT timing line, `00:00:01,000 --> 00:00:02,500`, with optional
+	// trailing coordinates
+	srtTimingRegex = regexp.MustCompile(`^\s*(\d+:\d{2}:\d{2}[,.]\d{1,3})\s*-->\s*(\d+:\d{2}:\d{2}[,.]\d{1,3})`)
+
+	// srtUnsupportedTagRegex matches the tags that are common in SRT files but not supported by
+	// WebVTT, such as `<font color="...">` and `{\an8}`
+	srtUnsupportedTagRegex = regexp.MustCompile(`(?i)</?font[^>]*>|\{\\[^}]*\}`)
+
+	// vttTimingRegex matches a WebVTT timing line, `00:01.000 --> 00:02.000`, with optional cue
+	// settings
+	vttTimingRegex = regexp.MustCompile(`^\s*((?:\d+:)?\d{2}:\d{2}\.\d{3})\s+-->\s+((?:\d+:)?\d{2}:\d{2}\.\d{3})`)
+
+	// cueTagRegex matches a tag in cue text, such as `<i>`, `</b>` or `<00:00:01.000>`
+	cueTagRegex = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// cueEntityReplacer decodes the character references that may appear in cue text
+var cueEntityReplacer = strings.NewReplacer(
+	"&amp;", "&",
+	"&lt;", "<",
+	"&gt;", ">",
+	"&nbsp;", " ",
+	"&lrm;", "",
+	"&rlm;", "",
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// cueEscaper escapes the characters that have a special meaning in cue text
+var cueEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsFormat returns true when the extension (without the leading dot) is a supported subtitle
+// format
+func IsFormat(ext string) bool {
+	switch strings.ToLower(ext) {
+	case FormatSRT, FormatVTT, FormatASS, FormatSSA:
+		return true
+	}
+
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Cue defines a single subtitle cue. Start and End are in milliseconds and Text is WebVTT cue
+// text, which may contain tags such as <i> and escaped characters such as &amp;
+type Cue struct {
+	ID    string
+	Start int64
+	End   int64
+	Text  string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// PlainText returns the text of the cue without tags, with escaped characters decoded and with
+// the lines joined by a space
+func (c *Cue) PlainText() string {
+	text := cueTagRegex.ReplaceAllString(c.Text, "")
+	text = cueEntityReplacer.Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// EscapeText escapes plain text so it can be used as cue text
+func EscapeText(text string) string {
+	return cueEscaper.Replace(text)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Parse reads a subtitle file of the given format and returns the cues
+func Parse(r io.Reader, format string) ([]*Cue, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	content := normalize(data)
+
+	switch strings.ToLower(format) {
+	case FormatVTT:
+		return parseVTT(content)
+	case FormatSRT:
+		return parseSRT(content)
+	case FormatASS, FormatSSA:
+		return parseASS(content)
+	}
+
+	return nil, ErrUnsupported
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ToWebVTT reads a subtitle file of the given format and converts it to WebVTT. WebVTT files are
+// returned as is, as they may contain styling and positioning that would otherwise be lost
+func ToWebVTT(r io.Reader, format string) ([]byte, error) {
+	if strings.ToLower(format) == FormatVTT {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return vttToWebVTT(normalize(data)), nil
+	}
+
+	cues, err := Parse(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return WriteWebVTT(cues), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// normalize removes the byte order mark and converts the line endings to `\n`
+func normalize(data []byte) string {
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	return strings.ReplaceAll(content, "\r", "\n")
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// WriteWebVTT writes the cues as a WebVTT file. The text of each cue must already be WebVTT cue
+// text, meaning & and < are escaped
+func WriteWebVTT(cues []*Cue) []byte {
+	var b strings.Builder
+	b.WriteString(vttHeader + "\n")
+
+	for _, cue := range cues {
+		b.WriteString("\n")
+
+		if cue.ID != "" {
+			b.WriteString(cue.ID + "\n")
+		}
+
+		b.WriteString(formatTimestamp(cue.Start) + " --> " + formatTimestamp(cue.End) + "\n")
+		b.WriteString(cue.Text + "\n")
+	}
+
+	return []byte(b.String())
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// vttToWebVTT returns the content as is, adding the WebVTT header when it is missing
+func vttToWebVTT(content string) []byte {
+	if strings.HasPrefix(content, vttHeader) {
+		return []byte(content)
+	}
+
+	return []byte(vttHeader + "\n\n" + content)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseVTT parses the cues of WebVTT content. The header block and NOTE, STYLE and REGION blocks
+// are skipped
+func parseVTT(content string) ([]*Cue, error) {
+	cues := []*Cue{}
+
+	for _, block := range strings.Split(content, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+
+		// Find the timing line, which is either the first or second line
+		timingIdx := -1
+		for i := 0; i < len(lines) && i < 2; i++ {
+			if strings.Contains(lines[i], "-->") {
+				timingIdx = i
+				break
+			}
+		}
+
+		if timingIdx == -1 {
+			continue
+		}
+
+		matches := vttTimingRegex.FindStringSubmatch(lines[timingIdx])
+		if matches == nil {
+			return nil, ErrMalformed
+		}
+
+		cue, err := newCue(lines, timingIdx, matches[1], matches[2])
+		if err != nil {
+			return nil, err
+		}
+
+		if cue != nil {
+			cues = append(cues, cue)
+		}
+	}
+
+	return cues, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseSRT parses the cues of SRT content. Each SRT block is an optional numeric identifier, a
+// timing line and one or more lines of text. The identifier is kept as the cue identifier and tags
+// that are not supported by WebVTT are removed
+func parseSRT(content string) ([]*Cue, error) {
+	cues := []*Cue{}
+
+	for _, block := range strings.Split(content, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+
+		// Find the timing line, which is either the first or second line
+		timingIdx := -1
+		for i := 0; i < len(lines) && i < 2; i++ {
+			if srtTimingRegex.MatchString(lines[i]) {
+				timingIdx = i
+				break
+			}
+		}
+
+		if timingIdx == -1 {
+			continue
+		}
+
+		for i := timingIdx + 1; i < len(lines); i++ {
+			lines[i] = srtUnsupportedTagRegex.ReplaceAllString(lines[i], "")
+		}
+
+		matches := srtTimingRegex.FindStringSubmatch(lines[timingIdx])
+
+		cue, err := newCue(lines, timingIdx, matches[1], matches[2])
+		if err != nil {
+			return nil, err
+		}
+
+		if cue != nil {
+			cues = append(cues, cue)
+		}
+	}
+
+	if len(cues) == 0 && strings.TrimSpace(content) != "" {
+		return nil, ErrMalformed
+	}
+
+	return cues, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// newCue builds a cue from the lines of a block, where lines[timingIdx] is the timing line. The
+// line before the timing line, when there is one, is the identifier. nil is returned when the cue
+// has no text
+func newCue(lines []string, timingIdx int, start, end string) (*Cue, error) {
+	startMs, err := parseTimestamp(start)
+	if err != nil {
+		return nil, err
+	}
+
+	endMs, err := parseTimestamp(end)
+	if err != nil {
+		return nil, err
+	}
+
+	text := []string{}
+	for _, line := range lines[timingIdx+1:] {
+		if strings.TrimSpace(line) != "" {
+			text = append(text, line)
+		}
+	}
+
+	if len(text) == 0 {
+		return nil, nil
+	}
+
+	cue := &Cue{
+		Start: startMs,
+		End:   endMs,
+		Text:  strings.Join(text, "\n"),
+	}
+
+	if timingIdx == 1 {
+		cue.ID = strings.TrimSpace(lines[0])
+	}
+
+	return cue, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseTimestamp parses a `h:mm:ss,fff`, `h:mm:ss.ff` or `mm:ss.fff` timestamp and returns the number of
+// milliseconds. The fractional part is interpreted as a fraction of a second, so `.5`, `.50` and
+// `.500` are all 500ms
+func parseTimestamp(ts string) (int64, error) {
+	var h, m, s int64
+
+	ts = strings.Replace(ts, ",", ".", 1)
+
+	clock, frac, _ := strings.Cut(ts, ".")
+
+	// WebVTT allows the hours to be omitted
+	if strings.Count(clock, ":") == 1 {
+		clock = "0:" + clock
+	}
+
+	if _, err := fmt.Sscanf(clock, "%d:%d:%d", &h, &m, &s); err != nil {
+		return 0, ErrMalformed
+	}
+
+	ms := int64(0)
+	for i, scale := 0, int64(100); i < len(frac) && i < 3; i, scale = i+1, scale/10 {
+		if frac[i] < '0' || frac[i] > '9' {
+			return 0, ErrMalformed
+		}
+
+		ms += int64(frac[i]-'0') * scale
+	}
+
+	return ((h*60+m)*60+s)*1000 + ms, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// formatTimestamp formats milliseconds as a WebVTT timestamp, `hh:mm:ss.fff`
+func formatTimestamp(ms int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, ms%1000)
+}
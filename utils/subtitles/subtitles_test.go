@@ -0,0 +1,174 @@
+package subtitles
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSubtitles_IsFormat(t *testing.T) {
+	for _, ext := range []string{"srt", "vtt", "ass", "ssa", "SRT"} {
+		require.True(t, IsFormat(ext), ext)
+	}
+
+	for _, ext := range []string{"", "txt", "sub", "mp4"} {
+		require.False(t, IsFormat(ext), ext)
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSubtitles_ToWebVTT(t *testing.T) {
+	t.Run("srt", func(t *testing.T) {
+		srt := "\xEF\xBB\xBF1\r\n00:00:01,000 --> 00:00:02,500\r\nHello <i>world</i>\r\n\r\n" +
+			"2\r\n00:01:02,05 --> 01:00:03,100 X1:10 X2:20\r\n<font color=\"red\">{\\an8}Line 1</font>\r\nLine 2\r\n\r\n" +
+			"3\r\n00:02:00,000 --> 00:02:01,000\r\n\r\n"
+
+		vtt, err := ToWebVTT(strings.NewReader(srt), "srt")
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n"+
+			"\n1\n00:00:01.000 --> 00:00:02.500\nHello <i>world</i>\n"+
+			"\n2\n00:01:02.050 --> 01:00:03.100\nLine 1\nLine 2\n", string(vtt))
+	})
+
+	t.Run("srt without identifiers", func(t *testing.T) {
+		vtt, err := ToWebVTT(strings.NewReader("00:00:01,000 --> 00:00:02,000\nHello\n"), "SRT")
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n", string(vtt))
+	})
+
+	t.Run("srt malformed", func(t *testing.T) {
+		vtt, err := ToWebVTT(strings.NewReader("this is not\na subtitle file"), "srt")
+		require.ErrorIs(t, err, ErrMalformed)
+		require.Nil(t, vtt)
+	})
+
+	t.Run("vtt", func(t *testing.T) {
+		vtt, err := ToWebVTT(strings.NewReader("WEBVTT\r\n\r\n00:01.000 --> 00:02.000\r\nHello\r\n"), "vtt")
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n\n00:01.000 --> 00:02.000\nHello\n", string(vtt))
+
+		// Missing header
+		vtt, err = ToWebVTT(strings.NewReader("00:01.000 --> 00:02.000\nHello\n"), "vtt")
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n\n00:01.000 --> 00:02.000\nHello\n", string(vtt))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		vtt, err := ToWebVTT(strings.NewReader(""), "sub")
+		require.ErrorIs(t, err, ErrUnsupported)
+		require.Nil(t, vtt)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSubtitles_Parse(t *testing.T) {
+	t.Run("srt", func(t *testing.T) {
+		cues, err := Parse(strings.NewReader("1\n00:00:01,000 --> 00:00:02,500\nHello\n<i>world</i>\n"), "srt")
+		require.NoError(t, err)
+		require.Len(t, cues, 1)
+		require.Equal(t, &Cue{ID: "1", Start: 1000, End: 2500, Text: "Hello\n<i>world</i>"}, cues[0])
+	})
+
+	t.Run("vtt", func(t *testing.T) {
+		vtt := "WEBVTT - Some title\n\n" +
+			"NOTE this is a comment\n\n" +
+			"STYLE\n::cue { color: red }\n\n" +
+			"intro\n00:01.000 --> 00:02.000 align:start\nHello\n\n" +
+			"01:00:00.000 --> 01:00:01.500\n<v Bob>Bye &amp; thanks</v>\n"
+
+		cues, err := Parse(strings.NewReader(vtt), "vtt")
+		require.NoError(t, err)
+		require.Len(t, cues, 2)
+		require.Equal(t, &Cue{ID: "intro", Start: 1000, End: 2000, Text: "Hello"}, cues[0])
+		require.Equal(t, &Cue{Start: 3600000, End: 3601500, Text: "<v Bob>Bye &amp; thanks</v>"}, cues[1])
+	})
+
+	t.Run("vtt malformed", func(t *testing.T) {
+		cues, err := Parse(strings.NewReader("WEBVTT\n\n1 --> 2\nHello\n"), "vtt")
+		require.ErrorIs(t, err, ErrMalformed)
+		require.Nil(t, cues)
+	})
+
+	t.Run("ass", func(t *testing.T) {
+		cues, err := Parse(strings.NewReader("[Events]\nFormat: Start, End, Text\nDialogue: 0:00:01.00,0:00:02.00,Hello\n"), "ass")
+		require.NoError(t, err)
+		require.Len(t, cues, 1)
+		require.Equal(t, &Cue{Start: 1000, End: 2000, Text: "Hello"}, cues[0])
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		cues, err := Parse(strings.NewReader(""), "sub")
+		require.ErrorIs(t, err, ErrUnsupported)
+		require.Nil(t, cues)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSubtitles_PlainText(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"Hello", "Hello"},
+		{"<i>Hello</i>\n<b>world</b>", "Hello world"},
+		{"<v Bob>Fish &amp; chips</v>", "Fish & chips"},
+		{"<00:00:01.000>Karaoke  <c.red>style</c>", "Karaoke style"},
+	}
+
+	for _, tt := range tests {
+		cue := &Cue{Text: tt.in}
+		require.Equal(t, tt.expected, cue.PlainText(), tt.in)
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSubtitles_EscapeText(t *testing.T) {
+	text := "Tips & <tricks> -> more"
+	require.Equal(t, "Tips &amp; &lt;tricks&gt; -&gt; more", EscapeText(text))
+
+	// Escaped text round trips through PlainText
+	cue := &Cue{Text: EscapeText(text)}
+	require.Equal(t, text, cue.PlainText())
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSubtitles_ParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected int64
+	}{
+		{"00:00:01,000", 1000},
+		{"00:00:01.5", 1500},
+		{"0:01:02.05", 62050},
+		{"1:00:00.123", 3600123},
+		{"00:00:00", 0},
+		{"01:02.003", 62003},
+	}
+
+	for _, tt := range tests {
+		ms, err := parseTimestamp(tt.in)
+		require.NoError(t, err, tt.in)
+		require.Equal(t, tt.expected, ms, tt.in)
+	}
+
+	for _, in := range []string{"", "bob", "00:00:01,ab"} {
+		_, err := parseTimestamp(in)
+		require.ErrorIs(t, err, ErrMalformed, in)
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestSubtitles_FormatTimestamp(t *testing.T) {
+	require.Equal(t, "00:00:00.000", formatTimestamp(0))
+	require.Equal(t, "00:01:02.050", formatTimestamp(62050))
+	require.Equal(t, "10:00:00.001", formatTimestamp(36000001))
+}
@@ -0,0 +1,13 @@
+package transcode
+
+import "errors"
+
+var (
+	ErrNotConfigured = errors.New("ffmpeg is not configured")
+	ErrInvalidFFmpeg = errors.New("invalid ffmpeg executable")
+	ErrInvalidFormat = errors.New("invalid stream format")
+	ErrInvalidSource = errors.New("invalid source")
+	ErrFailed        = errors.New("ffmpeg failed")
+	ErrNotReady      = errors.New("output is not ready")
+	ErrNoThumbnails  = errors.New("thumbnails not found")
+)
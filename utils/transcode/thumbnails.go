@@ -0,0 +1,294 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/geerew/off-course/utils/subtitles"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const (
+	// PosterFile is the name of the poster image
+	PosterFile = "poster.jpg"
+
+	// SpriteFile is the name of the sprite sheet
+	SpriteFile = "sprite.jpg"
+
+	// The name of the file describing the sprite sheet
+	thumbnailsFile = "thumbnails.json"
+
+	// The width of the poster. The height follows the aspect ratio
+	posterWidth = 640
+
+	// The width of each thumbnail in the sprite sheet
+	thumbnailWidth = 160
+
+	// The max number of thumbnails in a sprite sheet and the number of thumbnails per row
+	maxThumbnails    = 100
+	thumbnailColumns = 10
+
+	// The min number of seconds between thumbnails
+	minThumbnailInterval = 10
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Thumbnails describes the sprite sheet generated for a video. The thumbnails are laid out left to
+// right, top to bottom, each covering Interval seconds of the video
+type Thumbnails struct {
+	// The key of the source the thumbnails were generated from
+	Key string `json:"key"`
+
+	// Duration of the video in seconds
+	Duration int `json:"duration"`
+
+	Interval int `json:"interval"`
+	Count    int `json:"count"`
+	Columns  int `json:"columns"`
+	Width    int `json:"width"`
+	Height   int `json:"height"`
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Cues returns a cue per thumbnail, pointing to the thumbnail within the sprite sheet using a
+// `#xywh=` media fragment
+func (t *Thumbnails) Cues(spriteURL string) []*subtitles.Cue {
+	cues := make([]*subtitles.Cue, 0, t.Count)
+
+	for i := range t.Count {
+		start := i * t.Interval
+		end := min((i+1)*t.Interval, t.Duration)
+
+		cues = append(cues, &subtitles.Cue{
+			Start: int64(start) * 1000,
+			End:   int64(end) * 1000,
+			Text:  fmt.Sprintf("%s#xywh=%d,%d,%d,%d", spriteURL, (i%t.Columns)*t.Width, (i/t.Columns)*t.Height, t.Width, t.Height),
+		})
+	}
+
+	return cues
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Thumbnails returns the thumbnails of the video with the given id. ErrNoThumbnails is returned
+// when the thumbnails have not been generated or were generated for a different key
+func (t *Transcoder) Thumbnails(id, key string) (*Thumbnails, error) {
+	if !validID(id) {
+		return nil, ErrInvalidSource
+	}
+
+	data, err := os.ReadFile(filepath.Join(t.thumbnailsDir, id, thumbnailsFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNoThumbnails
+		}
+
+		return nil, err
+	}
+
+	thumbnails := &Thumbnails{}
+	if err := json.Unmarshal(data, thumbnails); err != nil {
+		return nil, err
+	}
+
+	if thumbnails.Key != key {
+		return nil, ErrNoThumbnails
+	}
+
+	return thumbnails, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ThumbnailPath returns the path of a thumbnail file (PosterFile or SpriteFile) of the video with
+// the given id
+func (t *Transcoder) ThumbnailPath(id, file string) string {
+	return filepath.Join(t.thumbnailsDir, id, file)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// GenerateThumbnails generates a poster and a sprite sheet for the video with the given id,
+// replacing any existing thumbnails. The source duration must be set
+func (t *Transcoder) GenerateThumbnails(ctx context.Context, ffmpegPath, id string, src *Source) (*Thumbnails, error) {
+	if ffmpegPath == "" {
+		return nil, ErrNotConfigured
+	}
+
+	if !validID(id) || src == nil || src.Path == "" || src.Key == "" || src.Duration <= 0 {
+		return nil, ErrInvalidSource
+	}
+
+	if err := os.MkdirAll(t.thumbnailsDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp(t.thumbnailsDir, ".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	thumbnails := newThumbnails(src)
+
+	start := time.Now()
+
+	for _, args := range [][]string{posterArgs(src, tmpDir), spriteArgs(src, thumbnails, tmpDir)} {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			t.logger.Error(
+				"ffmpeg failed to generate thumbnails",
+				loggerType,
+				slog.String("path", src.Path),
+				slog.String("error", err.Error()),
+				slog.String("stderr", lastLines(stderr.String(), 10)),
+			)
+
+			return nil, fmt.Errorf("%w: %w", ErrFailed, err)
+		}
+	}
+
+	data, err := json.Marshal(thumbnails)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, thumbnailsFile), data, 0o644); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(t.thumbnailsDir, id)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return nil, err
+	}
+
+	t.logger.Debug(
+		"Generated thumbnails",
+		loggerType,
+		slog.String("path", src.Path),
+		slog.Int("count", thumbnails.Count),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	return thumbnails, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ThumbnailIDs returns the ids of the videos with generated thumbnails
+func (t *Transcoder) ThumbnailIDs() ([]string, error) {
+	entries, err := os.ReadDir(t.thumbnailsDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []string{}, nil
+		}
+
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && validID(entry.Name()) {
+			ids = append(ids, entry.Name())
+		}
+	}
+
+	return ids, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// RemoveThumbnails removes the thumbnails of the video with the given id
+func (t *Transcoder) RemoveThumbnails(id string) error {
+	if !validID(id) {
+		return ErrInvalidSource
+	}
+
+	return os.RemoveAll(filepath.Join(t.thumbnailsDir, id))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// newThumbnails calculates the layout of the sprite sheet for the source
+func newThumbnails(src *Source) *Thumbnails {
+	interval := max(minThumbnailInterval, int(math.Ceil(float64(src.Duration)/maxThumbnails)))
+	count := int(math.Ceil(float64(src.Duration) / float64(interval)))
+
+	// 16:9 when the resolution is unknown
+	height := thumbnailWidth * 9 / 16
+	if src.Width > 0 && src.Height > 0 {
+		height = int(math.Round(float64(thumbnailWidth*src.Height)/float64(src.Width)/2)) * 2
+	}
+
+	return &Thumbnails{
+		Key:      src.Key,
+		Duration: src.Duration,
+		Interval: interval,
+		Count:    count,
+		Columns:  min(thumbnailColumns, count),
+		Width:    thumbnailWidth,
+		Height:   max(height, 2),
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// posterArgs builds the ffmpeg arguments to extract the poster. The frame is taken at 10% of the
+// duration, skipping intros that are usually black
+func posterArgs(src *Source, outDir string) []string {
+	return []string{
+		"-hide_banner", "-loglevel", "error", "-nostdin", "-y",
+		"-ss", strconv.Itoa(src.Duration / 10),
+		"-i", src.Path,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:-2", posterWidth),
+		"-q:v", "3",
+		filepath.Join(outDir, PosterFile),
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// spriteArgs builds the ffmpeg arguments to generate the sprite sheet
+func spriteArgs(src *Source, thumbnails *Thumbnails, outDir string) []string {
+	rows := int(math.Ceil(float64(thumbnails.Count) / float64(thumbnails.Columns)))
+
+	return []string{
+		"-hide_banner", "-loglevel", "error", "-nostdin", "-y",
+		"-i", src.Path,
+		"-an", "-sn",
+		"-vf", fmt.Sprintf("fps=1/%d,scale=%d:%d,tile=%dx%d", thumbnails.Interval, thumbnails.Width, thumbnails.Height, thumbnails.Columns, rows),
+		"-frames:v", "1",
+		"-q:v", "5",
+		filepath.Join(outDir, SpriteFile),
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// validID returns true when id can safely be used as a directory name
+func validID(id string) bool {
+	return id != "" && id != "." && id != ".." && filepath.Base(id) == id && id[0] != '.'
+}
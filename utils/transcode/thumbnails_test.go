@@ -0,0 +1,165 @@
+package transcode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestThumbnails_Layout(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      *Source
+		expected *Thumbnails
+	}{
+		{
+			"short",
+			&Source{Key: "1", Duration: 95, Width: 1920, Height: 1080},
+			&Thumbnails{Key: "1", Duration: 95, Interval: 10, Count: 10, Columns: 10, Width: 160, Height: 90},
+		},
+		{
+			"very short",
+			&Source{Key: "1", Duration: 25, Width: 640, Height: 480},
+			&Thumbnails{Key: "1", Duration: 25, Interval: 10, Count: 3, Columns: 3, Width: 160, Height: 120},
+		},
+		{
+			"long",
+			&Source{Key: "1", Duration: 3600, Width: 1280, Height: 720},
+			&Thumbnails{Key: "1", Duration: 3600, Interval: 36, Count: 100, Columns: 10, Width: 160, Height: 90},
+		},
+		{
+			"unknown resolution",
+			&Source{Key: "1", Duration: 60},
+			&Thumbnails{Key: "1", Duration: 60, Interval: 10, Count: 6, Columns: 6, Width: 160, Height: 90},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, newThumbnails(tt.src))
+		})
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestThumbnails_Cues(t *testing.T) {
+	thumbnails := &Thumbnails{Duration: 25, Interval: 10, Count: 3, Columns: 2, Width: 160, Height: 90}
+
+	cues := thumbnails.Cues("/sprite.jpg")
+	require.Len(t, cues, 3)
+
+	require.Equal(t, int64(0), cues[0].Start)
+	require.Equal(t, int64(10000), cues[0].End)
+	require.Equal(t, "/sprite.jpg#xywh=0,0,160,90", cues[0].Text)
+
+	require.Equal(t, "/sprite.jpg#xywh=160,0,160,90", cues[1].Text)
+
+	// The last cue ends with the video and wraps to the next row
+	require.Equal(t, int64(20000), cues[2].Start)
+	require.Equal(t, int64(25000), cues[2].End)
+	require.Equal(t, "/sprite.jpg#xywh=0,90,160,90", cues[2].Text)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestThumbnails_Args(t *testing.T) {
+	src := &Source{Path: "/in.mkv", Key: "1", Duration: 3600, Width: 1280, Height: 720}
+
+	args := strings.Join(posterArgs(src, "/out"), " ")
+	require.Contains(t, args, "-ss 360 -i /in.mkv")
+	require.Contains(t, args, "-frames:v 1")
+	require.True(t, strings.HasSuffix(args, filepath.Join("/out", PosterFile)))
+
+	args = strings.Join(spriteArgs(src, newThumbnails(src), "/out"), " ")
+	require.Contains(t, args, "fps=1/36,scale=160:90,tile=10x10")
+	require.True(t, strings.HasSuffix(args, filepath.Join("/out", SpriteFile)))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestThumbnails_Generate(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		src := &Source{Path: "/course/video.mkv", Key: "1234", Duration: 60}
+
+		_, err := transcoder.Thumbnails("asset1", "1234")
+		require.ErrorIs(t, err, ErrNoThumbnails)
+
+		generated, err := transcoder.GenerateThumbnails(context.Background(), ffmpegPath, "asset1", src)
+		require.NoError(t, err)
+		require.Equal(t, 6, generated.Count)
+
+		thumbnails, err := transcoder.Thumbnails("asset1", "1234")
+		require.NoError(t, err)
+		require.Equal(t, generated, thumbnails)
+
+		for _, file := range []string{PosterFile, SpriteFile} {
+			b, err := os.ReadFile(transcoder.ThumbnailPath("asset1", file))
+			require.NoError(t, err)
+			require.Equal(t, "jpeg", string(b))
+		}
+
+		// A different key is stale
+		_, err = transcoder.Thumbnails("asset1", "5678")
+		require.ErrorIs(t, err, ErrNoThumbnails)
+
+		// Regenerate for the new key
+		_, err = transcoder.GenerateThumbnails(context.Background(), ffmpegPath, "asset1", &Source{Path: src.Path, Key: "5678", Duration: 60})
+		require.NoError(t, err)
+
+		_, err = transcoder.Thumbnails("asset1", "5678")
+		require.NoError(t, err)
+
+		ids, err := transcoder.ThumbnailIDs()
+		require.NoError(t, err)
+		require.Equal(t, []string{"asset1"}, ids)
+
+		// Remove
+		require.NoError(t, transcoder.RemoveThumbnails("asset1"))
+
+		ids, err = transcoder.ThumbnailIDs()
+		require.NoError(t, err)
+		require.Empty(t, ids)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		transcoder, _ := setup(t, 0)
+
+		_, err := transcoder.GenerateThumbnails(context.Background(), "", "asset1", &Source{Path: "/course/video.mkv", Key: "1234", Duration: 60})
+		require.ErrorIs(t, err, ErrNotConfigured)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		for _, id := range []string{"", ".", "..", "../asset1", ".tmp-1"} {
+			_, err := transcoder.GenerateThumbnails(context.Background(), ffmpegPath, id, &Source{Path: "/course/video.mkv", Key: "1234", Duration: 60})
+			require.ErrorIs(t, err, ErrInvalidSource, id)
+
+			_, err = transcoder.Thumbnails(id, "1234")
+			require.ErrorIs(t, err, ErrInvalidSource, id)
+		}
+
+		_, err := transcoder.GenerateThumbnails(context.Background(), ffmpegPath, "asset1", &Source{Path: "/course/video.mkv", Key: "1234"})
+		require.ErrorIs(t, err, ErrInvalidSource)
+	})
+
+	t.Run("ffmpeg error", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		_, err := transcoder.GenerateThumbnails(context.Background(), ffmpegPath, "asset1", &Source{Path: "/course/fail.mkv", Key: "1234", Duration: 60})
+		require.ErrorIs(t, err, ErrFailed)
+
+		ids, err := transcoder.ThumbnailIDs()
+		require.NoError(t, err)
+		require.Empty(t, ids)
+	})
+}
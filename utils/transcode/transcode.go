@@ -0,0 +1,543 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geerew/off-course/utils/types"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var loggerType = slog.Any("type", types.LogTypeTranscode)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Format defines the output format of a stream
+type Format string
+
+const (
+	// FormatMP4 is a single fragmented MP4 file
+	FormatMP4 Format = "mp4"
+
+	// FormatHLS is an event HLS playlist with MPEG-TS segments. The playlist grows as the segments
+	// are written and is ended once ffmpeg finishes
+	FormatHLS Format = "hls"
+)
+
+const (
+	// MP4File is the name of the output file for FormatMP4
+	MP4File = "video.mp4"
+
+	// PlaylistFile is the name of the playlist for FormatHLS
+	PlaylistFile = "index.m3u8"
+
+	// The length of each HLS segment in seconds
+	hlsSegmentTime = 6
+
+	// The default max size of the cache (5GB)
+	defaultMaxCacheSize = 5 << 30
+
+	// The default max number of ffmpeg runs at once
+	defaultMaxJobs = 2
+
+	// How long a request waits for an output to be ready before ErrNotReady is returned
+	defaultReadyTimeout = 30 * time.Second
+
+	// How often a request checks whether the first HLS segments are ready
+	readyPollInterval = 250 * time.Millisecond
+
+	// The file written to an output directory once ffmpeg has finished
+	completeFile = ".complete"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Codecs a browser can play when wrapped in MP4. Anything else is transcoded
+var (
+	browserVideoCodecs = map[string]bool{"h264": true, "vp8": true, "vp9": true, "av1": true}
+	browserAudioCodecs = map[string]bool{"aac": true, "mp3": true, "opus": true, "vorbis": true, "flac": true}
+)
+
+// Containers a browser can play directly
+var browserContainers = map[string]bool{"mp4": true, "m4v": true, "webm": true, "mov": true}
+
+// The name of the HLS segments written by ffmpeg
+var segmentRegex = regexp.MustCompile(`^segment\d{5,}\.ts$`)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Source defines the video to remux or transcode
+type Source struct {
+	// The path of the video on disk
+	Path string
+
+	// Identifies the content of the video. When it changes, a new output is generated
+	Key string
+
+	// Normalized codec names of the video and audio tracks, as extracted by the media package. An
+	// unknown (empty) codec is always transcoded
+	VideoCodec string
+	AudioCodec string
+
+	// Duration in seconds and resolution, as extracted by the media package. Used when generating
+	// thumbnails
+	Duration int
+	Width    int
+	Height   int
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Config defines the configuration for a Transcoder
+type Config struct {
+	// The directory to write the outputs to
+	CacheDir string
+
+	// The directory to write the thumbnails to. Unlike the outputs, thumbnails are not pruned
+	ThumbnailsDir string
+
+	// The max size in bytes of the cache. When exceeded, the least recently used outputs are
+	// removed. Defaults to 5GB
+	MaxCacheSize int64
+
+	// The max number of ffmpeg runs at once. Further outputs wait for a run to finish. Defaults to 2
+	MaxJobs int
+
+	Logger *slog.Logger
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Transcoder remuxes or transcodes videos, using ffmpeg, into a format a browser can play. The
+// outputs are cached on disk. It also generates the thumbnails of videos
+type Transcoder struct {
+	cacheDir      string
+	thumbnailsDir string
+	maxCacheSize  int64
+	readyTimeout  time.Duration
+	logger        *slog.Logger
+
+	// Limits the number of ffmpeg runs at once
+	sem chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// job defines an in-flight ffmpeg run, which runs in the background. Requests for the output wait
+// on done, or for HLS, until the playlist has been written
+type job struct {
+	done chan struct{}
+	err  error
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// New creates a new Transcoder
+func New(config *Config) *Transcoder {
+	maxCacheSize := config.MaxCacheSize
+	if maxCacheSize <= 0 {
+		maxCacheSize = defaultMaxCacheSize
+	}
+
+	maxJobs := config.MaxJobs
+	if maxJobs <= 0 {
+		maxJobs = defaultMaxJobs
+	}
+
+	return &Transcoder{
+		cacheDir:      config.CacheDir,
+		thumbnailsDir: config.ThumbnailsDir,
+		maxCacheSize:  maxCacheSize,
+		readyTimeout:  defaultReadyTimeout,
+		logger:        config.Logger,
+		sem:           make(chan struct{}, maxJobs),
+		jobs:          map[string]*job{},
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Version runs `ffmpeg -version` and returns the first line of the output. It is used to validate
+// the configured ffmpeg path
+func Version(ctx context.Context, ffmpegPath string) (string, error) {
+	if ffmpegPath == "" {
+		return "", ErrNotConfigured
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, ffmpegPath, "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidFFmpeg, err)
+	}
+
+	line, _, _ := strings.Cut(string(out), "\n")
+	if !strings.HasPrefix(line, "ffmpeg version") {
+		return "", ErrInvalidFFmpeg
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsBrowserPlayable returns true when a video with the given extension and codecs can be played by
+// a browser without remuxing. When the video codec is unknown, the container alone decides
+func IsBrowserPlayable(ext, videoCodec, audioCodec string) bool {
+	if !browserContainers[strings.ToLower(strings.TrimPrefix(ext, "."))] {
+		return false
+	}
+
+	if videoCodec == "" {
+		return true
+	}
+
+	return browserVideoCodecs[videoCodec] && (audioCodec == "" || browserAudioCodecs[audioCodec])
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsSegmentFile returns true when name is the name of a HLS segment
+func IsSegmentFile(name string) bool {
+	return segmentRegex.MatchString(name)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Prepare generates the output of the source in the given format, when not already cached, and
+// returns the directory holding it. For FormatMP4 the directory holds MP4File and for FormatHLS
+// it holds PlaylistFile plus the segments
+//
+// ffmpeg runs in the background, so a client disconnecting does not kill an output other requests
+// may be waiting on. A MP4 is returned once complete, while a HLS playlist is returned as soon as
+// its first segments are written. When the output is not ready in time, ErrNotReady is returned
+// and the run continues. Concurrent calls for the same source and format share a single run
+func (t *Transcoder) Prepare(ctx context.Context, ffmpegPath string, src *Source, format Format) (string, error) {
+	if ffmpegPath == "" {
+		return "", ErrNotConfigured
+	}
+
+	if src == nil || src.Path == "" || src.Key == "" {
+		return "", ErrInvalidSource
+	}
+
+	if format != FormatMP4 && format != FormatHLS {
+		return "", ErrInvalidFormat
+	}
+
+	dir := filepath.Join(t.cacheDir, cacheKey(src, format))
+
+	t.mu.Lock()
+
+	// Cached
+	if isComplete(dir) {
+		t.mu.Unlock()
+		touch(dir)
+		return dir, nil
+	}
+
+	j, ok := t.jobs[dir]
+	if !ok {
+		// An incomplete output left behind by an interrupted run is removed before the job is
+		// started, so a request cannot be served its stale playlist
+		if err := os.RemoveAll(dir); err != nil {
+			t.mu.Unlock()
+			return "", err
+		}
+
+		j = &job{done: make(chan struct{})}
+		t.jobs[dir] = j
+		go t.runJob(j, ffmpegPath, src, format, dir)
+	}
+
+	t.mu.Unlock()
+
+	ready := ""
+	if format == FormatHLS {
+		ready = filepath.Join(dir, PlaylistFile)
+	}
+
+	if err := t.wait(ctx, j, ready); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// wait waits for the job to finish or, when ready is not empty, for the file at ready to exist
+func (t *Transcoder) wait(ctx context.Context, j *job, ready string) error {
+	timeout := time.NewTimer(t.readyTimeout)
+	defer timeout.Stop()
+
+	poll := time.NewTicker(readyPollInterval)
+	defer poll.Stop()
+
+	for {
+		if ready != "" {
+			if _, err := os.Stat(ready); err == nil {
+				return nil
+			}
+		}
+
+		select {
+		case <-j.done:
+			return j.err
+		case <-poll.C:
+		case <-timeout.C:
+			return ErrNotReady
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// runJob runs the job once a slot is free, then prunes the cache
+func (t *Transcoder) runJob(j *job, ffmpegPath string, src *Source, format Format, dir string) {
+	t.sem <- struct{}{}
+	j.err = t.run(context.Background(), ffmpegPath, src, format, dir)
+	<-t.sem
+
+	if j.err == nil {
+		t.prune(dir)
+	}
+
+	t.mu.Lock()
+	delete(t.jobs, dir)
+	t.mu.Unlock()
+	close(j.done)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// run runs ffmpeg, writing the output to dir. Once ffmpeg has finished, completeFile is written to
+// dir. On failure, dir is removed
+func (t *Transcoder) run(ctx context.Context, ffmpegPath string, src *Source, format Format, dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	args := buildArgs(src, format, dir)
+
+	t.logger.Debug(
+		"Running ffmpeg",
+		loggerType,
+		slog.String("path", src.Path),
+		slog.String("format", string(format)),
+		slog.String("args", strings.Join(args, " ")),
+	)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+
+		t.logger.Error(
+			"ffmpeg failed",
+			loggerType,
+			slog.String("path", src.Path),
+			slog.String("error", err.Error()),
+			slog.String("stderr", lastLines(stderr.String(), 10)),
+		)
+
+		return fmt.Errorf("%w: %w", ErrFailed, err)
+	}
+
+	t.logger.Info(
+		"ffmpeg finished",
+		loggerType,
+		slog.String("path", src.Path),
+		slog.String("format", string(format)),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	if err := os.WriteFile(filepath.Join(dir, completeFile), nil, os.ModePerm); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// prune removes the least recently used outputs until the cache fits within the max size. The
+// output at keep and the outputs still being written are never removed
+func (t *Transcoder) prune(keep string) {
+	entries, err := os.ReadDir(t.cacheDir)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	inFlight := make(map[string]bool, len(t.jobs))
+	for dir := range t.jobs {
+		inFlight[dir] = true
+	}
+	t.mu.Unlock()
+
+	type output struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	outputs := []*output{}
+	var total int64
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		o := &output{path: filepath.Join(t.cacheDir, entry.Name()), size: dirSize(filepath.Join(t.cacheDir, entry.Name())), modTime: info.ModTime()}
+		outputs = append(outputs, o)
+		total += o.size
+	}
+
+	// Oldest first
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].modTime.Before(outputs[j].modTime)
+	})
+
+	for _, o := range outputs {
+		if total <= t.maxCacheSize {
+			break
+		}
+
+		if o.path == keep || inFlight[o.path] {
+			continue
+		}
+
+		if err := os.RemoveAll(o.path); err != nil {
+			t.logger.Error("Failed to prune transcode cache", loggerType, slog.String("path", o.path), slog.String("error", err.Error()))
+			continue
+		}
+
+		t.logger.Debug("Pruned transcode cache", loggerType, slog.String("path", o.path))
+		total -= o.size
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// buildArgs builds the ffmpeg arguments. Streams with a codec the browser supports are copied and
+// the rest are transcoded to H.264/AAC
+func buildArgs(src *Source, format Format, outDir string) []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-nostdin", "-y", "-i", src.Path, "-map", "0:v:0", "-map", "0:a:0?", "-sn", "-dn"}
+
+	if browserVideoCodecs[src.VideoCodec] && (format == FormatMP4 || src.VideoCodec == "h264") {
+		args = append(args, "-c:v", "copy")
+	} else {
+		args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p")
+	}
+
+	if browserAudioCodecs[src.AudioCodec] && (format == FormatMP4 || src.AudioCodec == "aac" || src.AudioCodec == "mp3") {
+		args = append(args, "-c:a", "copy")
+	} else {
+		args = append(args, "-c:a", "aac", "-b:a", "160k", "-ac", "2")
+	}
+
+	switch format {
+	case FormatHLS:
+		args = append(args,
+			"-f", "hls",
+			"-hls_time", fmt.Sprint(hlsSegmentTime),
+			"-hls_playlist_type", "event",
+			"-hls_flags", "temp_file",
+			"-hls_segment_filename", filepath.Join(outDir, "segment%05d.ts"),
+			filepath.Join(outDir, PlaylistFile),
+		)
+	default:
+		args = append(args,
+			"-movflags", "frag_keyframe+empty_moov+default_base_moof",
+			"-f", "mp4",
+			filepath.Join(outDir, MP4File),
+		)
+	}
+
+	return args
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// cacheKey returns the name of the cache directory for the source and format
+func cacheKey(src *Source, format Format) string {
+	sum := sha256.Sum256([]byte(src.Path + "\x00" + src.Key))
+	return hex.EncodeToString(sum[:16]) + "-" + string(format)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// isComplete returns true when ffmpeg has finished writing the output at dir
+func isComplete(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, completeFile))
+	return err == nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// touch updates the modification time of path, marking it as recently used
+func touch(path string) {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// dirSize returns the total size of the files in dir
+func dirSize(dir string) int64 {
+	var size int64
+
+	_ = filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return strings.Join(lines, "\n")
+}
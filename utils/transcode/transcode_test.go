@@ -0,0 +1,382 @@
+package transcode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geerew/off-course/utils/logger"
+	"github.com/geerew/off-course/utils/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func setup(t *testing.T, maxCacheSize int64) (*Transcoder, string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("ffmpeg stub requires a unix shell")
+	}
+
+	// Logger
+	var logs []*logger.Log
+	var logsMux sync.Mutex
+	logger, _, err := logger.InitLogger(&logger.BatchOptions{
+		BatchSize: 1,
+		WriteFn:   logger.TestWriteFn(&logs, &logsMux),
+	})
+	require.NoError(t, err, "Failed to initialize logger")
+
+	ffmpegPath, err := mocks.StubFFmpeg(t.TempDir())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+
+	transcoder := New(&Config{
+		CacheDir:      filepath.Join(dir, "transcode"),
+		ThumbnailsDir: filepath.Join(dir, "thumbnails"),
+		MaxCacheSize:  maxCacheSize,
+		Logger:        logger,
+	})
+
+	return transcoder, ffmpegPath
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTranscode_Version(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, ffmpegPath := setup(t, 0)
+
+		version, err := Version(context.Background(), ffmpegPath)
+		require.NoError(t, err)
+		require.Equal(t, "ffmpeg version stub Copyright (c) off-course", version)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		_, err := Version(context.Background(), "")
+		require.ErrorIs(t, err, ErrNotConfigured)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := Version(context.Background(), filepath.Join(t.TempDir(), "ffmpeg"))
+		require.ErrorIs(t, err, ErrInvalidFFmpeg)
+	})
+
+	t.Run("not ffmpeg", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("requires a unix shell")
+		}
+
+		path := filepath.Join(t.TempDir(), "other")
+		require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho other 1.0\n"), 0o755))
+
+		_, err := Version(context.Background(), path)
+		require.ErrorIs(t, err, ErrInvalidFFmpeg)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTranscode_IsBrowserPlayable(t *testing.T) {
+	tests := []struct {
+		ext        string
+		videoCodec string
+		audioCodec string
+		expected   bool
+	}{
+		{"mp4", "h264", "aac", true},
+		{".MP4", "h264", "", true},
+		{"webm", "vp9", "opus", true},
+		{"mp4", "hevc", "aac", false},
+		{"mp4", "h264", "ac3", false},
+		{"mkv", "h264", "aac", false},
+		{"avi", "mpeg4", "mp3", false},
+		{"mp4", "", "", true},
+		{"mkv", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ext+"/"+tt.videoCodec+"/"+tt.audioCodec, func(t *testing.T) {
+			require.Equal(t, tt.expected, IsBrowserPlayable(tt.ext, tt.videoCodec, tt.audioCodec))
+		})
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTranscode_IsSegmentFile(t *testing.T) {
+	require.True(t, IsSegmentFile("segment00000.ts"))
+	require.True(t, IsSegmentFile("segment123456.ts"))
+	require.False(t, IsSegmentFile("segment1.ts"))
+	require.False(t, IsSegmentFile("../segment00000.ts"))
+	require.False(t, IsSegmentFile("segment00000.ts.bak"))
+	require.False(t, IsSegmentFile(PlaylistFile))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTranscode_BuildArgs(t *testing.T) {
+	t.Run("remux", func(t *testing.T) {
+		args := strings.Join(buildArgs(&Source{Path: "/in.mkv", VideoCodec: "h264", AudioCodec: "aac"}, FormatMP4, "/out"), " ")
+		require.Contains(t, args, "-i /in.mkv")
+		require.Contains(t, args, "-c:v copy")
+		require.Contains(t, args, "-c:a copy")
+		require.Contains(t, args, "-movflags frag_keyframe+empty_moov+default_base_moof")
+		require.True(t, strings.HasSuffix(args, filepath.Join("/out", MP4File)))
+	})
+
+	t.Run("transcode", func(t *testing.T) {
+		args := strings.Join(buildArgs(&Source{Path: "/in.mkv", VideoCodec: "hevc", AudioCodec: "ac3"}, FormatMP4, "/out"), " ")
+		require.Contains(t, args, "-c:v libx264")
+		require.Contains(t, args, "-c:a aac")
+	})
+
+	t.Run("unknown codecs", func(t *testing.T) {
+		args := strings.Join(buildArgs(&Source{Path: "/in.avi"}, FormatMP4, "/out"), " ")
+		require.Contains(t, args, "-c:v libx264")
+		require.Contains(t, args, "-c:a aac")
+	})
+
+	t.Run("hls", func(t *testing.T) {
+		args := strings.Join(buildArgs(&Source{Path: "/in.mkv", VideoCodec: "h264", AudioCodec: "aac"}, FormatHLS, "/out"), " ")
+		require.Contains(t, args, "-c:v copy")
+		require.Contains(t, args, "-c:a copy")
+		require.Contains(t, args, "-f hls")
+		require.Contains(t, args, "-hls_playlist_type event")
+		require.Contains(t, args, "-hls_flags temp_file")
+		require.True(t, strings.HasSuffix(args, filepath.Join("/out", PlaylistFile)))
+	})
+
+	t.Run("hls vp9", func(t *testing.T) {
+		// MPEG-TS segments cannot hold VP9 or Opus
+		args := strings.Join(buildArgs(&Source{Path: "/in.webm", VideoCodec: "vp9", AudioCodec: "opus"}, FormatHLS, "/out"), " ")
+		require.Contains(t, args, "-c:v libx264")
+		require.Contains(t, args, "-c:a aac")
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTranscode_Prepare(t *testing.T) {
+	t.Run("mp4", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		dir, err := transcoder.Prepare(context.Background(), ffmpegPath, &Source{Path: "/course/video.mkv", Key: "1234"}, FormatMP4)
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(filepath.Join(dir, MP4File))
+		require.NoError(t, err)
+		require.Equal(t, "fragmented mp4", string(b))
+	})
+
+	t.Run("hls", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		dir, err := transcoder.Prepare(context.Background(), ffmpegPath, &Source{Path: "/course/video.mkv", Key: "1234"}, FormatHLS)
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(filepath.Join(dir, PlaylistFile))
+		require.NoError(t, err)
+		require.Contains(t, string(b), "segment00000.ts")
+		require.FileExists(t, filepath.Join(dir, "segment00000.ts"))
+	})
+
+	t.Run("hls in progress", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+		t.Setenv("FFMPEG_STUB_SLEEP", "1")
+
+		src := &Source{Path: "/course/video.mkv", Key: "1234"}
+
+		// The playlist is returned once the first segment is written, before ffmpeg finishes
+		dir, err := transcoder.Prepare(context.Background(), ffmpegPath, src, FormatHLS)
+		require.NoError(t, err)
+		require.FileExists(t, filepath.Join(dir, "segment00000.ts"))
+
+		b, err := os.ReadFile(filepath.Join(dir, PlaylistFile))
+		require.NoError(t, err)
+		require.NotContains(t, string(b), "#EXT-X-ENDLIST")
+
+		require.Eventually(t, func() bool { return isComplete(dir) }, 5*time.Second, 50*time.Millisecond)
+
+		b, err = os.ReadFile(filepath.Join(dir, PlaylistFile))
+		require.NoError(t, err)
+		require.Contains(t, string(b), "#EXT-X-ENDLIST")
+	})
+
+	t.Run("stale hls", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+		transcoder.readyTimeout = 5 * time.Second
+		t.Setenv("FFMPEG_STUB_SLEEP", "1")
+
+		src := &Source{Path: "/course/video.mkv", Key: "1234"}
+
+		// An interrupted run left a playlist but no segments
+		stale := filepath.Join(transcoder.cacheDir, cacheKey(src, FormatHLS))
+		require.NoError(t, os.MkdirAll(stale, os.ModePerm))
+		require.NoError(t, os.WriteFile(filepath.Join(stale, PlaylistFile), []byte("stale"), os.ModePerm))
+
+		dir, err := transcoder.Prepare(context.Background(), ffmpegPath, src, FormatHLS)
+		require.NoError(t, err)
+		require.Equal(t, stale, dir)
+
+		b, err := os.ReadFile(filepath.Join(dir, PlaylistFile))
+		require.NoError(t, err)
+		require.Contains(t, string(b), "segment00000.ts")
+		require.FileExists(t, filepath.Join(dir, "segment00000.ts"))
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+		transcoder.readyTimeout = 100 * time.Millisecond
+		t.Setenv("FFMPEG_STUB_SLEEP", "1")
+
+		src := &Source{Path: "/course/video.mkv", Key: "1234"}
+
+		_, err := transcoder.Prepare(context.Background(), ffmpegPath, src, FormatMP4)
+		require.ErrorIs(t, err, ErrNotReady)
+
+		// The run continues in the background
+		require.Eventually(t, func() bool {
+			_, err := transcoder.Prepare(context.Background(), ffmpegPath, src, FormatMP4)
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("max jobs", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+		transcoder.sem = make(chan struct{}, 1)
+		transcoder.readyTimeout = 100 * time.Millisecond
+		t.Setenv("FFMPEG_STUB_SLEEP", "1")
+
+		src1 := &Source{Path: "/course/video.mkv", Key: "1"}
+		src2 := &Source{Path: "/course/video.mkv", Key: "2"}
+
+		_, err := transcoder.Prepare(context.Background(), ffmpegPath, src1, FormatMP4)
+		require.ErrorIs(t, err, ErrNotReady)
+
+		_, err = transcoder.Prepare(context.Background(), ffmpegPath, src2, FormatMP4)
+		require.ErrorIs(t, err, ErrNotReady)
+
+		// The second run waits for the first to finish
+		require.Eventually(t, func() bool {
+			_, err := transcoder.Prepare(context.Background(), ffmpegPath, src1, FormatMP4)
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		_, err = os.Stat(filepath.Join(transcoder.cacheDir, cacheKey(src2, FormatMP4), completeFile))
+		require.True(t, os.IsNotExist(err))
+
+		require.Eventually(t, func() bool {
+			_, err := transcoder.Prepare(context.Background(), ffmpegPath, src2, FormatMP4)
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		log := filepath.Join(t.TempDir(), "calls.log")
+		t.Setenv("FFMPEG_STUB_LOG", log)
+
+		src := &Source{Path: "/course/video.mkv", Key: "1234"}
+
+		dir1, err := transcoder.Prepare(context.Background(), ffmpegPath, src, FormatMP4)
+		require.NoError(t, err)
+
+		dir2, err := transcoder.Prepare(context.Background(), ffmpegPath, src, FormatMP4)
+		require.NoError(t, err)
+		require.Equal(t, dir1, dir2)
+
+		b, err := os.ReadFile(log)
+		require.NoError(t, err)
+		require.Equal(t, 1, strings.Count(string(b), "\n"))
+
+		// A new key generates a new output
+		dir3, err := transcoder.Prepare(context.Background(), ffmpegPath, &Source{Path: src.Path, Key: "5678"}, FormatMP4)
+		require.NoError(t, err)
+		require.NotEqual(t, dir1, dir3)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		log := filepath.Join(t.TempDir(), "calls.log")
+		t.Setenv("FFMPEG_STUB_LOG", log)
+
+		src := &Source{Path: "/course/video.mkv", Key: "1234"}
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := transcoder.Prepare(context.Background(), ffmpegPath, src, FormatMP4)
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		b, err := os.ReadFile(log)
+		require.NoError(t, err)
+		require.Equal(t, 1, strings.Count(string(b), "\n"))
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		// Each mp4 output is 14 bytes, so only 2 fit
+		transcoder, ffmpegPath := setup(t, 30)
+
+		dirs := []string{}
+		for i := range 3 {
+			dir, err := transcoder.Prepare(context.Background(), ffmpegPath, &Source{Path: "/course/video.mkv", Key: string(rune('a' + i))}, FormatMP4)
+			require.NoError(t, err)
+			dirs = append(dirs, dir)
+
+			// Ensure distinct modification times
+			past := time.Now().Add(time.Duration(i-10) * time.Minute)
+			require.NoError(t, os.Chtimes(dir, past, past))
+		}
+
+		require.NoDirExists(t, dirs[0])
+		require.DirExists(t, dirs[1])
+		require.DirExists(t, dirs[2])
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		transcoder, _ := setup(t, 0)
+
+		_, err := transcoder.Prepare(context.Background(), "", &Source{Path: "/course/video.mkv", Key: "1234"}, FormatMP4)
+		require.ErrorIs(t, err, ErrNotConfigured)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		_, err := transcoder.Prepare(context.Background(), ffmpegPath, nil, FormatMP4)
+		require.ErrorIs(t, err, ErrInvalidSource)
+
+		_, err = transcoder.Prepare(context.Background(), ffmpegPath, &Source{Path: "/course/video.mkv"}, FormatMP4)
+		require.ErrorIs(t, err, ErrInvalidSource)
+
+		_, err = transcoder.Prepare(context.Background(), ffmpegPath, &Source{Path: "/course/video.mkv", Key: "1234"}, Format("avi"))
+		require.ErrorIs(t, err, ErrInvalidFormat)
+	})
+
+	t.Run("ffmpeg error", func(t *testing.T) {
+		transcoder, ffmpegPath := setup(t, 0)
+
+		_, err := transcoder.Prepare(context.Background(), ffmpegPath, &Source{Path: "/course/fail.mkv", Key: "1234"}, FormatMP4)
+		require.ErrorIs(t, err, ErrFailed)
+
+		// Nothing is left behind
+		entries, err := os.ReadDir(transcoder.cacheDir)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+}
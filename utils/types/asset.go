@@ -24,11 +24,31 @@ type Asset struct {
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 const (
-	AssetVideo AssetType = "video"
-	AssetHTML  AssetType = "html"
-	AssetPDF   AssetType = "pdf"
+	AssetVideo    AssetType = "video"
+	AssetHTML     AssetType = "html"
+	AssetEPUB     AssetType = "epub"
+	AssetNotebook AssetType = "notebook"
+	AssetMarkdown AssetType = "markdown"
+	AssetPDF      AssetType = "pdf"
+	AssetSlides   AssetType = "slides"
+	AssetText     AssetType = "text"
+	AssetLink     AssetType = "link"
 )
 
+// The priority of each asset type, used to pick the asset when several files share a prefix. The
+// higher the value, the higher the priority
+var assetPriorities = map[AssetType]int{
+	AssetVideo:    9,
+	AssetHTML:     8,
+	AssetEPUB:     7,
+	AssetNotebook: 6,
+	AssetMarkdown: 5,
+	AssetPDF:      4,
+	AssetSlides:   3,
+	AssetText:     2,
+	AssetLink:     1,
+}
+
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // NewAsset creates an Asset based upon an extension. For example "mp4" => AssetVideo. When an
@@ -53,6 +73,18 @@ func NewAsset(ext string) *Asset {
 		return &Asset{s: AssetHTML}
 	case "pdf":
 		return &Asset{s: AssetPDF}
+	case "md", "markdown":
+		return &Asset{s: AssetMarkdown}
+	case "txt":
+		return &Asset{s: AssetText}
+	case "ipynb":
+		return &Asset{s: AssetNotebook}
+	case "epub":
+		return &Asset{s: AssetEPUB}
+	case "png", "jpg", "jpeg", "gif", "webp", "avif", "bmp":
+		return &Asset{s: AssetSlides}
+	case "url", "webloc", "desktop":
+		return &Asset{s: AssetLink}
 	}
 
 	return nil
@@ -102,6 +134,107 @@ func (a Asset) IsPDF() bool {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// SetMarkdown sets the asset type to Markdown
+func (a *Asset) SetMarkdown() {
+	a.s = AssetMarkdown
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsMarkdown returns true is the asset is of type Markdown
+func (a Asset) IsMarkdown() bool {
+	return a.s == AssetMarkdown
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SetText sets the asset type to plain text
+func (a *Asset) SetText() {
+	a.s = AssetText
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsText returns true is the asset is of type plain text
+func (a Asset) IsText() bool {
+	return a.s == AssetText
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SetNotebook sets the asset type to Jupyter notebook
+func (a *Asset) SetNotebook() {
+	a.s = AssetNotebook
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsNotebook returns true is the asset is of type Jupyter notebook
+func (a Asset) IsNotebook() bool {
+	return a.s == AssetNotebook
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SetEPUB sets the asset type to EPUB
+func (a *Asset) SetEPUB() {
+	a.s = AssetEPUB
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsEPUB returns true is the asset is of type EPUB
+func (a Asset) IsEPUB() bool {
+	return a.s == AssetEPUB
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SetSlides sets the asset type to slides
+func (a *Asset) SetSlides() {
+	a.s = AssetSlides
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsSlides returns true is the asset is of type slides (a single image or a directory of images)
+func (a Asset) IsSlides() bool {
+	return a.s == AssetSlides
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// SetLink sets the asset type to link
+func (a *Asset) SetLink() {
+	a.s = AssetLink
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsLink returns true is the asset is of type link (a shortcut to an external resource)
+func (a Asset) IsLink() bool {
+	return a.s == AssetLink
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsDocument returns true when the asset is a text based document (Markdown, plain text or Jupyter
+// notebook), which is rendered to HTML when served
+func (a Asset) IsDocument() bool {
+	return a.s == AssetMarkdown || a.s == AssetText || a.s == AssetNotebook
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Priority returns the priority of the asset type. When several files share a prefix, the one with
+// the highest priority is the asset and the others are attachments. The order is video > html >
+// epub > notebook > markdown > pdf > slides > text > link
+func (a Asset) Priority() int {
+	return assetPriorities[a.s]
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 // String implements the `Stringer` interface
 func (a Asset) String() string {
 	return fmt.Sprint(a.s)
@@ -146,6 +279,18 @@ func (a *Asset) Scan(value any) error {
 		a.s = AssetHTML
 	case string(AssetPDF):
 		a.s = AssetPDF
+	case string(AssetMarkdown):
+		a.s = AssetMarkdown
+	case string(AssetText):
+		a.s = AssetText
+	case string(AssetNotebook):
+		a.s = AssetNotebook
+	case string(AssetEPUB):
+		a.s = AssetEPUB
+	case string(AssetSlides):
+		a.s = AssetSlides
+	case string(AssetLink):
+		a.s = AssetLink
 	default:
 		return errors.New("invalid asset type")
 	}
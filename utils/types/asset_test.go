@@ -32,6 +32,20 @@ func TestAsset_NewAsset(t *testing.T) {
 		{"html", AssetHTML},
 		{"htm", AssetHTML},
 		{"pdf", AssetPDF},
+		{"md", AssetMarkdown},
+		{"markdown", AssetMarkdown},
+		{"txt", AssetText},
+		{"ipynb", AssetNotebook},
+		{"epub", AssetEPUB},
+		// Slides
+		{"png", AssetSlides},
+		{"JPG", AssetSlides},
+		{"jpeg", AssetSlides},
+		{"webp", AssetSlides},
+		// Links
+		{"url", AssetLink},
+		{"webloc", AssetLink},
+		{"desktop", AssetLink},
 	}
 
 	for _, tt := range tests {
@@ -61,6 +75,30 @@ func TestAsset_Set(t *testing.T) {
 	// Set to PDF
 	a.SetPDF()
 	require.Equal(t, AssetPDF, a.s)
+
+	// Set to Markdown
+	a.SetMarkdown()
+	require.Equal(t, AssetMarkdown, a.s)
+
+	// Set to text
+	a.SetText()
+	require.Equal(t, AssetText, a.s)
+
+	// Set to notebook
+	a.SetNotebook()
+	require.Equal(t, AssetNotebook, a.s)
+
+	// Set to EPUB
+	a.SetEPUB()
+	require.Equal(t, AssetEPUB, a.s)
+
+	// Set to slides
+	a.SetSlides()
+	require.Equal(t, AssetSlides, a.s)
+
+	// Set to link
+	a.SetLink()
+	require.Equal(t, AssetLink, a.s)
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -77,6 +115,49 @@ func TestAsset_Is(t *testing.T) {
 	// Is PDF
 	a = NewAsset("pdf")
 	require.True(t, a.IsPDF())
+
+	// Is Markdown
+	a = NewAsset("md")
+	require.True(t, a.IsMarkdown())
+	require.True(t, a.IsDocument())
+
+	// Is text
+	a = NewAsset("txt")
+	require.True(t, a.IsText())
+	require.True(t, a.IsDocument())
+
+	// Is notebook
+	a = NewAsset("ipynb")
+	require.True(t, a.IsNotebook())
+	require.True(t, a.IsDocument())
+
+	// Is EPUB
+	a = NewAsset("epub")
+	require.True(t, a.IsEPUB())
+
+	// Is slides
+	a = NewAsset("png")
+	require.True(t, a.IsSlides())
+	require.False(t, a.IsDocument())
+
+	// Is link
+	a = NewAsset("url")
+	require.True(t, a.IsLink())
+	require.False(t, a.IsDocument())
+
+	// Not a document
+	a = NewAsset("pdf")
+	require.False(t, a.IsDocument())
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestAsset_Priority(t *testing.T) {
+	order := []string{"mp4", "html", "epub", "ipynb", "md", "pdf", "png", "txt", "url"}
+
+	for i := 1; i < len(order); i++ {
+		require.Greater(t, NewAsset(order[i-1]).Priority(), NewAsset(order[i]).Priority(), order[i])
+	}
 }
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -179,6 +260,12 @@ func TestAsset_Scan(t *testing.T) {
 			{"video", "video"},
 			{"html", "html"},
 			{"pdf", "pdf"},
+			{"markdown", "markdown"},
+			{"text", "text"},
+			{"notebook", "notebook"},
+			{"epub", "epub"},
+			{"slides", "slides"},
+			{"link", "link"},
 		}
 
 		for _, tt := range tests {
@@ -14,6 +14,7 @@ const (
 	LogTypeCourseScan
 	LogTypeFileSystem
 	LogTypeDB
+	LogTypeTranscode
 )
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -26,6 +27,7 @@ func AllLogTypes() []string {
 		LogTypeCourseScan.String(),
 		LogTypeFileSystem.String(),
 		LogTypeDB.String(),
+		LogTypeTranscode.String(),
 	}
 }
 
@@ -33,7 +35,7 @@ func AllLogTypes() []string {
 
 // String returns the string representation of the LogType
 func (lt LogType) String() string {
-	names := [...]string{"request", "cron", "course scan", "file system", "db"}
+	names := [...]string{"request", "cron", "course scan", "file system", "db", "transcode"}
 
 	if int(lt) < 0 || int(lt) >= len(names) {
 		return "unknown"
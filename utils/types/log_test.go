@@ -19,6 +19,7 @@ func TestLog_String(t *testing.T) {
 		{LogTypeCourseScan, "course scan"},
 		{LogTypeFileSystem, "file system"},
 		{LogTypeDB, "db"},
+		{LogTypeTranscode, "transcode"},
 		{LogType(999), "unknown"},
 	}
 
@@ -32,7 +33,7 @@ func TestLog_String(t *testing.T) {
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 func TestLog_AllLogTypes(t *testing.T) {
-	expected := []string{"request", "cron", "course scan", "file system", "db"}
+	expected := []string{"request", "cron", "course scan", "file system", "db", "transcode"}
 	require.Equal(t, expected, AllLogTypes())
 }
 
@@ -48,6 +49,7 @@ func TestLog_LogValue(t *testing.T) {
 		{LogTypeCourseScan, "course scan"},
 		{LogTypeFileSystem, "file system"},
 		{LogTypeDB, "db"},
+		{LogTypeTranscode, "transcode"},
 	}
 
 	for _, tt := range tests {
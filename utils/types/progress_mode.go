@@ -0,0 +1,34 @@
+package types
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// ProgressMode defines how the percent complete of a course is calculated
+type ProgressMode string
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const (
+	// ProgressModeCount weights each asset equally
+	ProgressModeCount ProgressMode = "count"
+
+	// ProgressModeDuration weights each asset by its duration
+	ProgressModeDuration ProgressMode = "duration"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsValid checks if the progress mode is valid
+func (m ProgressMode) IsValid() bool {
+	switch m {
+	case ProgressModeCount, ProgressModeDuration:
+		return true
+	}
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// String implements the Stringer interface
+func (m ProgressMode) String() string {
+	return string(m)
+}
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestProgressMode_String(t *testing.T) {
+	assert.Equal(t, "count", ProgressModeCount.String())
+	assert.Equal(t, "duration", ProgressModeDuration.String())
+	assert.Equal(t, "invalid", ProgressMode("invalid").String())
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestProgressMode_IsValid(t *testing.T) {
+	tests := []struct {
+		mode     ProgressMode
+		expected bool
+	}{
+		{ProgressModeCount, true},
+		{ProgressModeDuration, true},
+		{ProgressMode(""), false},
+		{ProgressMode("invalid"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.mode.IsValid())
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// TokenScope defines the possible scopes of an API token. The scopes are ordered, with each scope
+// granting everything the previous one does
+type TokenScope string
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+const (
+	// TokenScopeReadOnly allows reading
+	TokenScopeReadOnly TokenScope = "read-only"
+
+	// TokenScopeProgressWrite allows reading and recording progress
+	TokenScopeProgressWrite TokenScope = "progress-write"
+
+	// TokenScopeAdmin allows everything the user is allowed
+	TokenScopeAdmin TokenScope = "admin"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsValid checks if the scope is valid
+func (s TokenScope) IsValid() bool {
+	switch s {
+	case TokenScopeReadOnly, TokenScopeProgressWrite, TokenScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Allows checks if the scope grants access to something that requires the given scope
+func (s TokenScope) Allows(required TokenScope) bool {
+	if !s.IsValid() || !required.IsValid() {
+		return false
+	}
+
+	return s.level() >= required.level()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// level returns the position of the scope in the order of scopes
+func (s TokenScope) level() int {
+	switch s {
+	case TokenScopeReadOnly:
+		return 1
+	case TokenScopeProgressWrite:
+		return 2
+	case TokenScopeAdmin:
+		return 3
+	}
+	return 0
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// String implements the Stringer interface
+func (s TokenScope) String() string {
+	return string(s)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// MarshalJSON implements the json.Marshaler interface
+func (s TokenScope) MarshalJSON() ([]byte, error) {
+	if !s.IsValid() {
+		return nil, fmt.Errorf("invalid token scope: %s", s)
+	}
+	return json.Marshal(string(s))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (s *TokenScope) UnmarshalJSON(data []byte) error {
+	var scope string
+	if err := json.Unmarshal(data, &scope); err != nil {
+		return err
+	}
+
+	tokenScope := TokenScope(scope)
+	if !tokenScope.IsValid() {
+		return fmt.Errorf("invalid token scope: %s", scope)
+	}
+
+	*s = tokenScope
+	return nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Value implements the driver.Valuer interface for database serialization
+func (s TokenScope) Value() (driver.Value, error) {
+	if !s.IsValid() {
+		return nil, fmt.Errorf("invalid token scope: %s", s)
+	}
+	return string(s), nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Scan implements the sql.Scanner interface
+func (s *TokenScope) Scan(value interface{}) error {
+	scope, ok := value.(string)
+	if !ok {
+		return errors.New("invalid data type for TokenScope")
+	}
+
+	tokenScope := TokenScope(scope)
+	if !tokenScope.IsValid() {
+		return fmt.Errorf("invalid token scope: %s", scope)
+	}
+
+	*s = tokenScope
+	return nil
+}
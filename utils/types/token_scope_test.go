@@ -0,0 +1,99 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTokenScope_IsValid(t *testing.T) {
+	tests := []struct {
+		scope    TokenScope
+		expected bool
+	}{
+		{TokenScopeReadOnly, true},
+		{TokenScopeProgressWrite, true},
+		{TokenScopeAdmin, true},
+		{TokenScope("invalid"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.scope), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.scope.IsValid())
+		})
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTokenScope_Allows(t *testing.T) {
+	tests := []struct {
+		scope    TokenScope
+		required TokenScope
+		expected bool
+	}{
+		{TokenScopeAdmin, TokenScopeAdmin, true},
+		{TokenScopeAdmin, TokenScopeProgressWrite, true},
+		{TokenScopeAdmin, TokenScopeReadOnly, true},
+		{TokenScopeProgressWrite, TokenScopeAdmin, false},
+		{TokenScopeProgressWrite, TokenScopeProgressWrite, true},
+		{TokenScopeProgressWrite, TokenScopeReadOnly, true},
+		{TokenScopeReadOnly, TokenScopeProgressWrite, false},
+		{TokenScopeReadOnly, TokenScopeReadOnly, true},
+		{TokenScopeAdmin, TokenScope("invalid"), false},
+		{TokenScope("invalid"), TokenScopeReadOnly, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.scope)+"/"+string(tt.required), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.scope.Allows(tt.required))
+		})
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTokenScope_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(TokenScopeProgressWrite)
+	assert.NoError(t, err)
+	assert.Equal(t, `"progress-write"`, string(data))
+
+	_, err = json.Marshal(TokenScope("invalid"))
+	assert.Error(t, err)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTokenScope_UnmarshalJSON(t *testing.T) {
+	var scope TokenScope
+	assert.NoError(t, json.Unmarshal([]byte(`"read-only"`), &scope))
+	assert.Equal(t, TokenScopeReadOnly, scope)
+
+	assert.Error(t, json.Unmarshal([]byte(`"invalid"`), &scope))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTokenScope_Scan(t *testing.T) {
+	var scope TokenScope
+	assert.NoError(t, scope.Scan("admin"))
+	assert.Equal(t, TokenScopeAdmin, scope)
+
+	assert.Error(t, scope.Scan("invalid"))
+	assert.Error(t, scope.Scan(1))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestTokenScope_Value(t *testing.T) {
+	value, err := TokenScopeAdmin.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Value("admin"), value)
+
+	_, err = TokenScope("invalid").Value()
+	assert.Error(t, err)
+}
@@ -32,6 +32,20 @@ func (r UserRole) IsValid() bool {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// Allows checks if the role grants access to something that requires the given role. An admin is
+// allowed everything a user is
+func (r UserRole) Allows(required UserRole) bool {
+	switch r {
+	case UserRoleAdmin:
+		return required.IsValid()
+	case UserRoleUser:
+		return required == UserRoleUser
+	}
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 // String implements the Stringer interface
 func (r UserRole) String() string {
 	return string(r)
@@ -37,6 +37,29 @@ func TestUserRole_IsValid(t *testing.T) {
 
 // ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+func TestUserRole_Allows(t *testing.T) {
+	tests := []struct {
+		role     UserRole
+		required UserRole
+		expected bool
+	}{
+		{UserRoleAdmin, UserRoleAdmin, true},
+		{UserRoleAdmin, UserRoleUser, true},
+		{UserRoleUser, UserRoleUser, true},
+		{UserRoleUser, UserRoleAdmin, false},
+		{UserRoleAdmin, UserRole("invalid"), false},
+		{UserRole("invalid"), UserRoleUser, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.required), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.role.Allows(tt.required))
+		})
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
 func TestUserRole_MarshalJSON(t *testing.T) {
 	tests := []struct {
 		role     UserRole
@@ -0,0 +1,11 @@
+package weblink
+
+import "errors"
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+var (
+	ErrUnsupported = errors.New("unsupported link format")
+	ErrMalformed   = errors.New("malformed link file")
+	ErrInvalidURL  = errors.New("invalid link url")
+)
@@ -0,0 +1,80 @@
+package weblink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseURL parses a Windows internet shortcut, which is an INI file with the link in the `URL` key
+// of the [InternetShortcut] section
+func parseURL(content string) (*Link, error) {
+	section, found := iniSection(content, "InternetShortcut")
+	if !found {
+		return nil, fmt.Errorf("%w: missing [InternetShortcut] section", ErrMalformed)
+	}
+
+	return &Link{URL: section["url"]}, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseDesktop parses a freedesktop desktop entry. Only entries of type `Link` are links, with the
+// link in the `URL` key and the title in the `Name` key
+func parseDesktop(content string) (*Link, error) {
+	section, found := iniSection(content, "Desktop Entry")
+	if !found {
+		return nil, fmt.Errorf("%w: missing [Desktop Entry] section", ErrMalformed)
+	}
+
+	if section["type"] != "Link" {
+		return nil, fmt.Errorf("%w: desktop entry is not a link", ErrMalformed)
+	}
+
+	return &Link{URL: section["url"], Title: section["name"]}, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// iniSection returns the keys of a section in an INI file. Keys are lowercased and the first
+// occurrence of a key wins. Localized keys, such as `Name[fr]`, are skipped
+func iniSection(content, name string) (map[string]string, bool) {
+	keys := map[string]string{}
+	found := false
+	inSection := false
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.EqualFold(line[1:len(line)-1], name)
+			found = found || inSection
+			continue
+		}
+
+		if !inSection {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.Contains(key, "[") {
+			continue
+		}
+
+		if _, exists := keys[key]; !exists {
+			keys[key] = strings.TrimSpace(value)
+		}
+	}
+
+	return keys, found
+}
@@ -0,0 +1,255 @@
+package weblink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The header of a binary property list
+var bplistHeader = []byte("bplist00")
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// parseWebloc parses a macOS web location, which is a property list (XML or binary) with the link
+// in the `URL` key of the top level dictionary
+func parseWebloc(data []byte) (*Link, error) {
+	var url string
+	var err error
+
+	if bytes.HasPrefix(data, bplistHeader) {
+		url, err = binaryPlistURL(data)
+	} else {
+		url, err = xmlPlistURL(data)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Link{URL: url}, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// xmlPlistURL returns the `URL` key of the top level dictionary of an XML property list
+func xmlPlistURL(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	// The depth of the current element, where the top level dictionary is at depth 2 (inside
+	// <plist>)
+	depth := 0
+	key := ""
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("%w: missing URL key", ErrMalformed)
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrMalformed, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+
+			if depth != 3 {
+				continue
+			}
+
+			var value string
+			if err := decoder.DecodeElement(&value, &t); err != nil {
+				return "", fmt.Errorf("%w: %s", ErrMalformed, err)
+			}
+			depth--
+
+			if t.Name.Local == "key" {
+				key = value
+				continue
+			}
+
+			if key == "URL" && t.Name.Local == "string" {
+				return value, nil
+			}
+
+			key = ""
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// binaryPlist is a binary property list, as described by Apple's CFBinaryPList.c
+type binaryPlist struct {
+	data          []byte
+	offsetIntSize int
+	objectRefSize int
+	offsets       []uint64
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// binaryPlistURL returns the `URL` key of the top level dictionary of a binary property list
+func binaryPlistURL(data []byte) (string, error) {
+	// The trailer is the last 32 bytes
+	if len(data) < len(bplistHeader)+32 {
+		return "", fmt.Errorf("%w: truncated binary plist", ErrMalformed)
+	}
+
+	trailer := data[len(data)-32:]
+	p := &binaryPlist{
+		data:          data,
+		offsetIntSize: int(trailer[6]),
+		objectRefSize: int(trailer[7]),
+	}
+
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	offsetTable := binary.BigEndian.Uint64(trailer[24:32])
+
+	if p.offsetIntSize < 1 || p.offsetIntSize > 8 || p.objectRefSize < 1 || p.objectRefSize > 8 ||
+		numObjects == 0 || topObject >= numObjects ||
+		offsetTable > uint64(len(data)) || numObjects > (uint64(len(data))-offsetTable)/uint64(p.offsetIntSize) {
+		return "", fmt.Errorf("%w: invalid binary plist trailer", ErrMalformed)
+	}
+
+	p.offsets = make([]uint64, numObjects)
+	for i := range p.offsets {
+		start := int(offsetTable) + i*p.offsetIntSize
+		p.offsets[i] = readUint(data[start : start+p.offsetIntSize])
+	}
+
+	keys, values, err := p.dict(topObject)
+	if err != nil {
+		return "", err
+	}
+
+	for i, keyRef := range keys {
+		key, err := p.string(keyRef)
+		if err != nil || key != "URL" {
+			continue
+		}
+
+		return p.string(values[i])
+	}
+
+	return "", fmt.Errorf("%w: missing URL key", ErrMalformed)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// dict returns the key and value references of a dictionary object
+func (p *binaryPlist) dict(ref uint64) ([]uint64, []uint64, error) {
+	marker, count, start, err := p.object(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if marker != 0xD {
+		return nil, nil, fmt.Errorf("%w: top level object is not a dictionary", ErrMalformed)
+	}
+
+	end := start + 2*count*p.objectRefSize
+	if count < 0 || end > len(p.data) {
+		return nil, nil, fmt.Errorf("%w: truncated dictionary", ErrMalformed)
+	}
+
+	keys := make([]uint64, count)
+	values := make([]uint64, count)
+	for i := 0; i < count; i++ {
+		keys[i] = readUint(p.data[start+i*p.objectRefSize : start+(i+1)*p.objectRefSize])
+		values[i] = readUint(p.data[start+(count+i)*p.objectRefSize : start+(count+i+1)*p.objectRefSize])
+	}
+
+	return keys, values, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// string returns the value of an ASCII or UTF-16 string object
+func (p *binaryPlist) string(ref uint64) (string, error) {
+	marker, count, start, err := p.object(ref)
+	if err != nil {
+		return "", err
+	}
+
+	switch marker {
+	case 0x5:
+		if count < 0 || start+count > len(p.data) {
+			return "", fmt.Errorf("%w: truncated string", ErrMalformed)
+		}
+
+		return string(p.data[start : start+count]), nil
+	case 0x6:
+		if count < 0 || start+2*count > len(p.data) {
+			return "", fmt.Errorf("%w: truncated string", ErrMalformed)
+		}
+
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(p.data[start+2*i:])
+		}
+
+		return string(utf16.Decode(units)), nil
+	}
+
+	return "", fmt.Errorf("%w: object is not a string", ErrMalformed)
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// object returns the type marker, the count (length) and the start of the content of an object.
+// A count of 0xF means the count follows the marker as an integer object
+func (p *binaryPlist) object(ref uint64) (byte, int, int, error) {
+	if ref >= uint64(len(p.offsets)) || p.offsets[ref] >= uint64(len(p.data)) {
+		return 0, 0, 0, fmt.Errorf("%w: invalid object reference", ErrMalformed)
+	}
+
+	offset := int(p.offsets[ref])
+	marker := p.data[offset] >> 4
+	count := int(p.data[offset] & 0x0F)
+	start := offset + 1
+
+	if count == 0x0F {
+		if start >= len(p.data) || p.data[start]>>4 != 0x1 {
+			return 0, 0, 0, fmt.Errorf("%w: invalid object length", ErrMalformed)
+		}
+
+		size := 1 << (p.data[start] & 0x0F)
+		if size > 8 || start+1+size > len(p.data) {
+			return 0, 0, 0, fmt.Errorf("%w: invalid object length", ErrMalformed)
+		}
+
+		length := readUint(p.data[start+1 : start+1+size])
+		if length > uint64(len(p.data)) {
+			return 0, 0, 0, fmt.Errorf("%w: invalid object length", ErrMalformed)
+		}
+
+		count = int(length)
+		start += 1 + size
+	}
+
+	return marker, count, start, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readUint reads a big endian unsigned integer of 1 to 8 bytes
+func readUint(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+
+	return n
+}
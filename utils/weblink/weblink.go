@@ -0,0 +1,142 @@
+package weblink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The link file formats that can be parsed
+const (
+	// Windows internet shortcut
+	FormatURL = "url"
+
+	// macOS web location (XML or binary property list)
+	FormatWebloc = "webloc"
+
+	// freedesktop desktop entry
+	FormatDesktop = "desktop"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Link files are tiny. Anything larger is not a link file
+const maxSize = 64 * 1024
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// The UTF-8 byte order mark, which Windows editors write at the start of files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Link is a link to an external resource
+type Link struct {
+	URL string
+
+	// The title of the link, when the file has one (only desktop entries do)
+	Title string
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsFormat returns true when the extension (without the leading dot) is a supported link format
+func IsFormat(ext string) bool {
+	switch strings.ToLower(ext) {
+	case FormatURL, FormatWebloc, FormatDesktop:
+		return true
+	}
+
+	return false
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// IsLink returns true when the file is a link file, based on its extension
+func IsLink(path string) bool {
+	return IsFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Open reads and parses a link file. The format is determined by the extension
+func Open(fs afero.Fs, path string) (*Link, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f, strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// Parse parses a link file in the given format (the extension without the leading dot). Only
+// http(s) links are returned, as other schemes (such as `file:` or `javascript:`) are not safe to
+// render as hyperlinks
+func Parse(r io.Reader, format string) (*Link, error) {
+	if !IsFormat(format) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, format)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("%w: file too large", ErrMalformed)
+	}
+
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	var link *Link
+	switch strings.ToLower(format) {
+	case FormatURL:
+		link, err = parseURL(string(data))
+	case FormatDesktop:
+		link, err = parseDesktop(string(data))
+	case FormatWebloc:
+		link, err = parseWebloc(data)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateURL(link.URL); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+// Private
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// validateURL checks the URL is an absolute http(s) URL
+func validateURL(raw string) error {
+	if raw == "" {
+		return fmt.Errorf("%w: missing url", ErrMalformed)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, raw)
+	}
+
+	return nil
+}
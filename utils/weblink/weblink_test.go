@@ -0,0 +1,145 @@
+package weblink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// binaryWebloc builds a binary property list with a single `URL` key
+func binaryWebloc(url string) []byte {
+	var b bytes.Buffer
+	b.WriteString("bplist00")
+
+	// Object 0: a dictionary with 1 entry, key object 1 and value object 2
+	offsets := []byte{byte(b.Len())}
+	b.Write([]byte{0xD1, 0x01, 0x02})
+
+	// Object 1: the ASCII string "URL"
+	offsets = append(offsets, byte(b.Len()))
+	b.Write([]byte{0x53, 'U', 'R', 'L'})
+
+	// Object 2: the ASCII string url, with the length as a 1 byte integer object
+	offsets = append(offsets, byte(b.Len()))
+	b.Write([]byte{0x5F, 0x10, byte(len(url))})
+	b.WriteString(url)
+
+	offsetTable := b.Len()
+	b.Write(offsets)
+
+	trailer := make([]byte, 32)
+	trailer[6] = 1
+	trailer[7] = 1
+	binary.BigEndian.PutUint64(trailer[8:16], uint64(len(offsets)))
+	binary.BigEndian.PutUint64(trailer[24:32], uint64(offsetTable))
+	b.Write(trailer)
+
+	return b.Bytes()
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestWeblink_IsLink(t *testing.T) {
+	for _, path := range []string{"/a/01 docs.url", "01 docs.webloc", "docs.desktop", "docs.URL"} {
+		require.True(t, IsLink(path), path)
+	}
+
+	for _, path := range []string{"", "docs", "docs.txt", "docs.url.zip"} {
+		require.False(t, IsLink(path), path)
+	}
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestWeblink_Parse(t *testing.T) {
+	t.Run("url", func(t *testing.T) {
+		link, err := Parse(strings.NewReader("\xEF\xBB\xBF[{000214A0-0000-0000-C000-000000000046}]\r\nProp3=19,11\r\n[InternetShortcut]\r\nIDList=\r\nURL=https://example.com/docs?a=1\r\n"), "url")
+		require.NoError(t, err)
+		require.Equal(t, &Link{URL: "https://example.com/docs?a=1"}, link)
+	})
+
+	t.Run("desktop", func(t *testing.T) {
+		link, err := Parse(strings.NewReader("# comment\n[Desktop Entry]\nName[fr]=Docs FR\nName=Docs\nType=Link\nURL=http://example.com\nIcon=text-html\n"), "desktop")
+		require.NoError(t, err)
+		require.Equal(t, &Link{URL: "http://example.com", Title: "Docs"}, link)
+
+		// Not a link
+		_, err = Parse(strings.NewReader("[Desktop Entry]\nName=App\nType=Application\nExec=app\n"), "desktop")
+		require.ErrorIs(t, err, ErrMalformed)
+	})
+
+	t.Run("webloc (xml)", func(t *testing.T) {
+		plist := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Other</key>
+	<dict><key>URL</key><string>https://nested.example.com</string></dict>
+	<key>URL</key>
+	<string>https://example.com/a&amp;b</string>
+</dict>
+</plist>`
+
+		link, err := Parse(strings.NewReader(plist), "webloc")
+		require.NoError(t, err)
+		require.Equal(t, &Link{URL: "https://example.com/a&b"}, link)
+
+		_, err = Parse(strings.NewReader(`<plist><dict><key>Other</key><string>x</string></dict></plist>`), "webloc")
+		require.ErrorIs(t, err, ErrMalformed)
+	})
+
+	t.Run("webloc (binary)", func(t *testing.T) {
+		link, err := Parse(bytes.NewReader(binaryWebloc("https://example.com")), "webloc")
+		require.NoError(t, err)
+		require.Equal(t, &Link{URL: "https://example.com"}, link)
+
+		// Truncated
+		data := binaryWebloc("https://example.com")
+		_, err = Parse(bytes.NewReader(data[:len(data)-40]), "webloc")
+		require.ErrorIs(t, err, ErrMalformed)
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		for _, url := range []string{"javascript:alert(1)", "file:///etc/passwd", "example.com", "https://"} {
+			_, err := Parse(strings.NewReader("[InternetShortcut]\nURL="+url), "url")
+			require.ErrorIs(t, err, ErrInvalidURL, url)
+		}
+
+		_, err := Parse(strings.NewReader("[InternetShortcut]\nIDList=\n"), "url")
+		require.ErrorIs(t, err, ErrMalformed)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("URL=https://example.com"), "url")
+		require.ErrorIs(t, err, ErrMalformed)
+
+		_, err = Parse(strings.NewReader(strings.Repeat("a", maxSize+1)), "url")
+		require.ErrorIs(t, err, ErrMalformed)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("[InternetShortcut]\nURL=https://example.com"), "lnk")
+		require.ErrorIs(t, err, ErrUnsupported)
+	})
+}
+
+// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func TestWeblink_Open(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/docs.webloc", binaryWebloc("https://example.com"), os.ModePerm))
+
+	link, err := Open(fs, "/docs.webloc")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", link.URL)
+
+	_, err = Open(fs, "/missing.url")
+	require.Error(t, err)
+}